@@ -0,0 +1,137 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Extraction stages timed by --benchmark
+const (
+	stageRead     = iota // fetching and scanning rows from the server
+	stageConvert         // converting column values to SQL literals
+	stageFormat          // assembling INSERT statements
+	stageCompress        // compressing output
+	stageWrite           // writing output to disk
+	numStages
+)
+
+var stageNames = [numStages]string{"read", "convert", "format", "compress", "write"}
+
+// tableBenchmark accumulates per-stage durations for one table. A nil
+// *tableBenchmark is valid and records nothing, so callers need no checks.
+type tableBenchmark struct {
+	table  string
+	rows   int64
+	stages [numStages]time.Duration
+}
+
+// start returns the current time when benchmarking, for a later track call
+func (b *tableBenchmark) start() time.Time {
+	if b == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// track adds the time elapsed since start to stage
+func (b *tableBenchmark) track(stage int, start time.Time) {
+	if b == nil {
+		return
+	}
+	b.stages[stage] += time.Since(start)
+}
+
+func (b *tableBenchmark) total() time.Duration {
+	var total time.Duration
+	for _, d := range b.stages {
+		total += d
+	}
+	return total
+}
+
+// bottleneck returns the name of the slowest stage
+func (b *tableBenchmark) bottleneck() string {
+	slowest := 0
+	for stage, d := range b.stages {
+		if d > b.stages[slowest] {
+			slowest = stage
+		}
+	}
+	return stageNames[slowest]
+}
+
+// timedWriter attributes time spent in the underlying writer to a stage of the
+// benchmark currently assigned to it, and counts the bytes written for
+// progress events, the progress bar and the manifest
+type timedWriter struct {
+	w     io.Writer
+	stage int
+	bench *tableBenchmark
+}
+
+func (t *timedWriter) Write(p []byte) (int, error) {
+	start := t.bench.start()
+	n, err := t.w.Write(p)
+	t.bench.track(t.stage, start)
+	dataProgressEvents.wrote(n)
+	dataProgressBar.wrote(n)
+	dataManifest.wrote(n)
+	return n, err
+}
+
+// printBenchmarkReport prints the per-table stage breakdown and totals
+func printBenchmarkReport(results []*tableBenchmark) {
+	if len(results) == 0 {
+		return
+	}
+
+	header := fmt.Sprintf("%-40s %10s", "TABLE", "ROWS")
+	for _, name := range stageNames {
+		header += fmt.Sprintf(" %10s", strings.ToUpper(name))
+	}
+	header += fmt.Sprintf(" %10s  %s", "TOTAL", "BOTTLENECK")
+
+	fmt.Printf("\n⏱️  Benchmark (time per stage):\n")
+	fmt.Println(header)
+
+	totals := &tableBenchmark{table: "TOTAL"}
+	for _, b := range results {
+		printBenchmarkRow(b)
+		totals.rows += b.rows
+		for stage, d := range b.stages {
+			totals.stages[stage] += d
+		}
+	}
+	printBenchmarkRow(totals)
+
+	total := totals.total()
+	if total > 0 {
+		fmt.Printf("\nStage share:")
+		for stage, d := range totals.stages {
+			fmt.Printf(" %s %.1f%%", stageNames[stage], float64(d)*100/float64(total))
+		}
+		fmt.Printf("\n")
+		if secs := total.Seconds(); secs > 0 {
+			fmt.Printf("Throughput: %.0f rows/sec (bottleneck: %s)\n", float64(totals.rows)/secs, totals.bottleneck())
+		}
+	}
+}
+
+func printBenchmarkRow(b *tableBenchmark) {
+	name := b.table
+	if len(name) > 40 {
+		name = "..." + name[len(name)-37:]
+	}
+
+	line := fmt.Sprintf("%-40s %10d", name, b.rows)
+	for _, d := range b.stages {
+		line += fmt.Sprintf(" %10s", d.Round(time.Millisecond))
+	}
+	line += fmt.Sprintf(" %10s  %s", b.total().Round(time.Millisecond), b.bottleneck())
+	fmt.Println(line)
+}
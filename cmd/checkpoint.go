@@ -0,0 +1,86 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/state"
+)
+
+// dataCheckpoints records the progress of the table being extracted, nil
+// outside of data extractions
+var dataCheckpoints *tableCheckpoints
+
+// tableCheckpoints saves the key of the last row written every --chunk-size
+// rows of a table, so --resume continues a large table after it instead of
+// extracting the table again. Only INSERT output read in primary key order
+// is checkpointed: the rows up to a key are then exactly what the output
+// holds. Methods are safe on a nil value.
+type tableCheckpoints struct {
+	progress *state.Progress
+	out      *bufio.Writer
+	sum      *checksum.Writer
+	// base is the output offset the run started writing at
+	base  int64
+	spool *updateSpool
+
+	// table is the table being extracted and rows the rows it wrote before
+	// the run resumed it
+	table string
+	rows  int64
+}
+
+// begin starts checkpointing a table that already has rows written
+func (c *tableCheckpoints) begin(table string, rows int64) {
+	if c != nil {
+		c.table, c.rows = table, rows
+	}
+}
+
+// save flushes the output and records the last key read by reader, which
+// must have been written with all rows before it, and rows, the rows the
+// run wrote of the table
+func (c *tableCheckpoints) save(reader *tableReader, rows int64) error {
+	if c == nil || reader.lastKey == nil {
+		return nil
+	}
+	if err := c.out.Flush(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	spool, err := c.spool.offset()
+	if err != nil {
+		return err
+	}
+
+	key := make([]string, len(reader.lastKey))
+	for i, v := range reader.lastKey {
+		key[i] = formatSQLValue(v)
+	}
+	item := state.Item{Name: c.table, Offset: c.base + c.sum.Size(), Spool: spool, Rows: c.rows + rows, Key: key}
+	if err := c.progress.Checkpoint(item); err != nil {
+		log.Printf("Warning: failed to save extraction progress: %v", err)
+	}
+	return nil
+}
+
+// resumeKey returns the checkpointed key of table as values for
+// keysetCondition, or nil when the table has no checkpoint
+func resumeKey(progress *state.Progress, table string) []interface{} {
+	if progress.Partial == nil || progress.Partial.Name != table {
+		return nil
+	}
+	key := make([]interface{}, len(progress.Partial.Key))
+	for i, v := range progress.Partial.Key {
+		key[i] = sqlLiteral(v)
+	}
+	return key
+}
+
+// sqlLiteral is a value already formatted as an SQL literal, such as a key
+// read back from a checkpoint
+type sqlLiteral string
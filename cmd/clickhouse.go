@@ -0,0 +1,98 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// clickhouseCreateTable returns a ClickHouse CREATE TABLE for a planned
+// table: a MergeTree ordered by the primary key, with the column types
+// mapped by clickhouseType
+func clickhouseCreateTable(ctx context.Context, db *sql.DB, plan TableExtractionPlan) (string, error) {
+	rows, err := queryWithRetry(ctx, db, dataMaxRetries, `
+		SELECT COLUMN_NAME, DATA_TYPE, COLUMN_TYPE, IS_NULLABLE,
+			COALESCE(NUMERIC_PRECISION, 0), COALESCE(NUMERIC_SCALE, 0), COALESCE(DATETIME_PRECISION, 0)
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION`, plan.DatabaseName, plan.TableName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name, dataType, columnType, nullable string
+		var precision, scale, fsp int
+		if err := rows.Scan(&name, &dataType, &columnType, &nullable, &precision, &scale, &fsp); err != nil {
+			return "", fmt.Errorf("failed to scan column: %w", err)
+		}
+		chType := clickhouseType(dataType, columnType, precision, scale, fsp)
+		if nullable == "YES" {
+			chType = "Nullable(" + chType + ")"
+		}
+		columns = append(columns, fmt.Sprintf("  `%s` %s", name, chType))
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	key, err := getPrimaryKey(ctx, db, plan.DatabaseName, plan.TableName)
+	if err != nil {
+		return "", err
+	}
+	orderBy := "tuple()"
+	if len(key) > 0 {
+		orderBy = "(" + quoteColumns(key) + ")"
+	}
+
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s`.`%s` (\n%s\n) ENGINE = MergeTree ORDER BY %s;",
+		plan.DatabaseName, plan.TableName, strings.Join(columns, ",\n"), orderBy), nil
+}
+
+// clickhouseType maps a MariaDB column type to the ClickHouse type that
+// holds all its values. Dates use the extended Date32 and DateTime64 ranges,
+// since MariaDB dates start at year 1000. Types without a ClickHouse
+// counterpart, such as TIME, BIT and spatial types, become String.
+func clickhouseType(dataType, columnType string, precision, scale, fsp int) string {
+	unsigned := strings.Contains(columnType, "unsigned")
+	integer := func(bits int) string {
+		if unsigned {
+			return fmt.Sprintf("UInt%d", bits)
+		}
+		return fmt.Sprintf("Int%d", bits)
+	}
+
+	switch dataType {
+	case "tinyint":
+		return integer(8)
+	case "smallint":
+		return integer(16)
+	case "mediumint", "int", "integer":
+		return integer(32)
+	case "bigint":
+		return integer(64)
+	case "year":
+		return "UInt16"
+	case "decimal", "numeric":
+		return fmt.Sprintf("Decimal(%d, %d)", max(precision, 1), scale)
+	case "float":
+		return "Float32"
+	case "double", "real":
+		return "Float64"
+	case "date":
+		return "Date32"
+	case "datetime", "timestamp":
+		return fmt.Sprintf("DateTime64(%d)", fsp)
+	case "enum", "set":
+		return "LowCardinality(String)"
+	default:
+		// Text, binary, BIT, JSON, TIME and spatial values
+		return "String"
+	}
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path"
+)
+
+// excludedColumn reports whether --exclude-columns drops column col of the
+// planned table. Patterns match db.table.column or table.column.
+func excludedColumn(plan TableExtractionPlan, col string) bool {
+	for _, pattern := range dataExcludeColumns {
+		if matched, _ := path.Match(pattern, plan.DatabaseName+"."+plan.TableName+"."+col); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, plan.TableName+"."+col); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// selectedColumns returns the columns of the planned table left after
+// --exclude-columns, or nil when none is excluded and the table is read with
+// SELECT *. Primary key columns cannot be excluded: tables are read in key
+// order and continue after the last key read.
+func selectedColumns(ctx context.Context, db *sql.DB, plan TableExtractionPlan, key []string) ([]string, error) {
+	if len(dataExcludeColumns) == 0 {
+		return nil, nil
+	}
+	for _, col := range key {
+		if excludedColumn(plan, col) {
+			return nil, fmt.Errorf("--exclude-columns cannot drop primary key column %s.%s.%s", plan.DatabaseName, plan.TableName, col)
+		}
+	}
+
+	columns, err := getTableColumns(ctx, db, plan.DatabaseName, plan.TableName)
+	if err != nil {
+		return nil, err
+	}
+	var selected []string
+	for _, col := range columns {
+		if !excludedColumn(plan, col) {
+			selected = append(selected, col)
+		}
+	}
+	if len(selected) == len(columns) {
+		return nil, nil
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("--exclude-columns drops every column of %s.%s; exclude the table instead", plan.DatabaseName, plan.TableName)
+	}
+	return selected, nil
+}
+
+// checkExcludedColumns rejects excluding foreign key columns on either side,
+// which consistent sampling, --seed and deferred keys read from the rows
+func checkExcludedColumns(plans []TableExtractionPlan) error {
+	if len(dataExcludeColumns) == 0 {
+		return nil
+	}
+	byName := make(map[string]TableExtractionPlan, len(plans))
+	for _, plan := range plans {
+		byName[plan.DatabaseName+"."+plan.TableName] = plan
+	}
+
+	for _, plan := range plans {
+		for _, c := range planConstraints(plan) {
+			for i, col := range c.columns {
+				if excludedColumn(plan, col) {
+					return fmt.Errorf("--exclude-columns cannot drop foreign key column %s.%s.%s", plan.DatabaseName, plan.TableName, col)
+				}
+				if parent, ok := byName[c.parent]; ok && excludedColumn(parent, c.parentColumns[i]) {
+					return fmt.Errorf("--exclude-columns cannot drop %s.%s, which foreign key %s of %s references",
+						c.parent, c.parentColumns[i], c.name, plan.TableName)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// insertColumnList returns the column list INSERTs name when columns are
+// excluded, so the remaining values land in the right columns, and "" when
+// the table is read whole
+func insertColumnList(reader *tableReader) string {
+	if reader.selected == nil {
+		return ""
+	}
+	return " (" + quoteColumns(reader.columns) + ")"
+}
@@ -0,0 +1,424 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"mariadb-extractor/internal/checksum"
+
+	"github.com/spf13/cobra"
+)
+
+// checkCompatCmd represents the check-compat command
+var checkCompatCmd = &cobra.Command{
+	Use:   "check-compat <file.sql>...",
+	Short: "Check generated SQL for features a target server version lacks",
+	Long: `Scan SQL written by ddl, data or dump for features the --target server
+does not support: MariaDB 10.10+ uca1400 and MySQL 8.0 0900 collations, CHECK
+constraints, INVISIBLE columns and sequences. Findings are reported with their
+file and line; with --rewrite, those that can be are rewritten in place.
+The command fails while findings remain, so it can gate a CI job.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runCheckCompat(args)
+	},
+}
+
+var (
+	compatTarget  string
+	compatRewrite bool
+	compatFormat  string
+)
+
+// compatRule finds one feature in a line of DDL, or only in column
+// definitions with column. Supported reports whether the target has it;
+// rewrite, nil when there is no equivalent, replaces a match in a line.
+type compatRule struct {
+	name      string
+	pattern   *regexp.Regexp
+	column    bool
+	supported func(target serverInfo) bool
+	message   string
+	rewrite   func(line string, match []int) string
+}
+
+// compatFinding is a feature the target does not support
+type compatFinding struct {
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Rule       string `json:"rule"`
+	Message    string `json:"message"`
+	Text       string `json:"text"`
+	Rewritable bool   `json:"rewritable"`
+	Rewritten  bool   `json:"rewritten"`
+}
+
+// compatReport is the result of checking files against a target
+type compatReport struct {
+	Target   string          `json:"target"`
+	Files    []string        `json:"files"`
+	Findings []compatFinding `json:"findings"`
+}
+
+// compatRules are checked in order on every DDL line
+var compatRules = []compatRule{
+	{
+		name:    "uca1400-collation",
+		pattern: regexp.MustCompile(`\b(utf8mb[34]|utf8)_uca1400\w*`),
+		supported: func(t serverInfo) bool {
+			return t.isMariaDB() && t.atLeast(10, 10, 0)
+		},
+		message: "uca1400 collations need MariaDB 10.10 (rewrite: unicode_520_ci)",
+		rewrite: replaceCollation,
+	},
+	{
+		name:    "mysql-0900-collation",
+		pattern: regexp.MustCompile(`\butf8mb4_(\w+_)?0900_\w+`),
+		supported: func(t serverInfo) bool {
+			if t.isMariaDB() {
+				return t.atLeast(11, 4, 5)
+			}
+			return t.atLeast(8, 0, 1)
+		},
+		message: "0900 collations need MySQL 8.0 or MariaDB 11.4.5 (rewrite: unicode_520_ci)",
+		rewrite: replaceCollation,
+	},
+	{
+		name:    "check-constraint",
+		pattern: regexp.MustCompile(`(?i)(^\s*CONSTRAINT\s+\S+\s+|\s)CHECK\s*\(`),
+		supported: func(t serverInfo) bool {
+			if t.isMariaDB() {
+				return t.atLeast(10, 2, 1)
+			}
+			return t.atLeast(8, 0, 16)
+		},
+		message: "CHECK constraints are not enforced before MariaDB 10.2.1 or MySQL 8.0.16 (rewrite: drop them)",
+		rewrite: removeCheckConstraint,
+	},
+	{
+		name:    "invisible-column",
+		pattern: regexp.MustCompile(`\s*(/\*!\d+\s+)?\bINVISIBLE\b(\s*\*/)?`),
+		column:  true,
+		supported: func(t serverInfo) bool {
+			if t.isMariaDB() {
+				return t.atLeast(10, 3, 3)
+			}
+			return t.atLeast(8, 0, 23)
+		},
+		message: "INVISIBLE columns need MariaDB 10.3.3 or MySQL 8.0.23 (rewrite: make them visible, so SELECT * returns them)",
+		rewrite: func(line string, match []int) string {
+			return line[:match[0]] + line[match[1]:]
+		},
+	},
+	{
+		name:      "sequence",
+		pattern:   regexp.MustCompile(`(?i)\bCREATE\s+(OR\s+REPLACE\s+)?SEQUENCE\b|\b(NEXT|PREVIOUS)\s+VALUE\s+FOR\b|\b(NEXTVAL|LASTVAL|SETVAL)\s*\(`),
+		supported: serverInfo.supportsSequences,
+		message:   "sequences need MariaDB 10.3 (no rewrite: use AUTO_INCREMENT)",
+	},
+}
+
+// collationCharset captures the character set of a collation name
+var collationCharset = regexp.MustCompile(`^(utf8mb[34]|utf8)_`)
+
+// replaceCollation replaces a collation by the unicode_520_ci one of its
+// character set, the closest that MariaDB 10.0+ and MySQL 5.6+ share
+func replaceCollation(line string, match []int) string {
+	charset := collationCharset.FindStringSubmatch(line[match[0]:match[1]])[1]
+	return line[:match[0]] + charset + "_unicode_520_ci" + line[match[1]:]
+}
+
+// removeCheckConstraint removes a CHECK clause with its balanced
+// parentheses, and a table constraint line entirely; the caller fixes the
+// comma of the line before
+func removeCheckConstraint(line string, match []int) string {
+	depth, end := 0, -1
+	masked := maskQuoted(line)
+	for i := match[1] - 1; i < len(masked); i++ {
+		switch masked[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 {
+			end = i + 1
+			break
+		}
+	}
+	if end < 0 {
+		return line
+	}
+	if strings.TrimSpace(line[:match[0]]) == "" && strings.HasPrefix(strings.TrimSpace(line[match[0]:]), "CONSTRAINT") {
+		return ""
+	}
+	return line[:match[0]] + line[end:]
+}
+
+func init() {
+	rootCmd.AddCommand(checkCompatCmd)
+
+	checkCompatCmd.Flags().StringVar(&compatTarget, "target", os.Getenv("MARIADB_COMPAT_TARGET"), "Target server as flavor:version, e.g. mariadb:10.6 or mysql:8.0.36 (env: MARIADB_COMPAT_TARGET)")
+	checkCompatCmd.Flags().BoolVar(&compatRewrite, "rewrite", false, "Rewrite the files in place where the target has an equivalent")
+	checkCompatCmd.Flags().StringVar(&compatFormat, "format", "text", "Report format: text or json")
+}
+
+func runCheckCompat(files []string) {
+	if compatFormat != "text" && compatFormat != "json" {
+		log.Fatalf("Invalid --format %q: must be text or json", compatFormat)
+	}
+	target, err := parseCompatTarget(compatTarget)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report := compatReport{Target: target.flavor + " " + target.version, Files: files, Findings: []compatFinding{}}
+	for _, file := range files {
+		findings, err := checkCompatFile(file, target, compatRewrite)
+		if err != nil {
+			log.Fatal(err)
+		}
+		report.Findings = append(report.Findings, findings...)
+	}
+
+	if compatFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	} else {
+		printCompatReport(report)
+	}
+
+	remaining := 0
+	for _, f := range report.Findings {
+		if !f.Rewritten {
+			remaining++
+		}
+	}
+	if remaining > 0 {
+		log.Fatalf("%d findings are not supported by %s", remaining, report.Target)
+	}
+}
+
+var compatTargetPattern = regexp.MustCompile(`^(?i)(mariadb|mysql):(\d+)\.(\d+)(?:\.(\d+))?$`)
+
+// parseCompatTarget parses flavor:major.minor[.patch]; a missing patch level
+// is the first release of the series
+func parseCompatTarget(target string) (serverInfo, error) {
+	m := compatTargetPattern.FindStringSubmatch(target)
+	if m == nil {
+		return serverInfo{}, fmt.Errorf("invalid --target %q: use mariadb:<version> or mysql:<version>, e.g. mariadb:10.6", target)
+	}
+	info := serverInfo{flavor: flavorMySQL}
+	if strings.EqualFold(m[1], "mariadb") {
+		info.flavor = flavorMariaDB
+	}
+	info.major, _ = strconv.Atoi(m[2])
+	info.minor, _ = strconv.Atoi(m[3])
+	info.patch, _ = strconv.Atoi(m[4])
+	info.version = strings.TrimPrefix(target, m[1]+":")
+	return info, nil
+}
+
+// checkCompatFile reports the unsupported features of one script and, with
+// rewrite, rewrites the file with the rewritable ones replaced. Only DDL is
+// checked: comments and the rows of INSERT statements are skipped.
+func checkCompatFile(path string, target serverInfo, rewrite bool) ([]compatFinding, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer in.Close()
+
+	var out *os.File
+	var w *bufio.Writer
+	if rewrite {
+		info, err := in.Stat()
+		if err == nil {
+			out, err = os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to rewrite %s: %w", path, err)
+		}
+		defer os.Remove(out.Name())
+		defer out.Close()
+		if err := out.Chmod(info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to rewrite %s: %w", path, err)
+		}
+		w = bufio.NewWriterSize(out, 1<<20)
+	}
+
+	var findings []compatFinding
+	// pending holds the last line until the next one is known, so dropping
+	// the last table constraint can drop the comma before it
+	var pending *string
+	emit := func(line string) {
+		if w == nil {
+			return
+		}
+		if pending != nil {
+			w.WriteString(*pending)
+		}
+		pending = &line
+	}
+
+	reader := bufio.NewReaderSize(in, 1<<20)
+	inInsert := false
+	for number := 1; ; number++ {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("failed to read %s: %w", path, readErr)
+		}
+		if line == "" && readErr == io.EOF {
+			break
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "INSERT INTO") || strings.HasPrefix(trimmed, "REPLACE INTO") {
+			inInsert = true
+		}
+		if inInsert || strings.HasPrefix(trimmed, "--") {
+			inInsert = inInsert && !strings.HasSuffix(trimmed, ";")
+			emit(line)
+			continue
+		}
+
+		for _, rule := range compatRules {
+			if rule.supported(target) || (rule.column && !strings.HasPrefix(trimmed, "`")) {
+				continue
+			}
+			for {
+				match := rule.pattern.FindStringIndex(maskQuoted(line))
+				if match == nil {
+					break
+				}
+				finding := compatFinding{File: path, Line: number, Rule: rule.name, Message: rule.message,
+					Text: strings.TrimSpace(line[match[0]:match[1]]), Rewritable: rule.rewrite != nil}
+				if !rewrite || rule.rewrite == nil {
+					findings = append(findings, finding)
+					break
+				}
+				rewritten := rule.rewrite(line, match)
+				finding.Rewritten = rewritten != line
+				findings = append(findings, finding)
+				if !finding.Rewritten {
+					break
+				}
+				if rewritten == "" {
+					// The dropped constraint was the last one when it had
+					// no comma, so the line before now is
+					if pending != nil && !strings.HasSuffix(trimmed, ",") {
+						fixed := strings.TrimRight(*pending, "\r\n")
+						fixed = strings.TrimSuffix(fixed, ",") + (*pending)[len(fixed):]
+						pending = &fixed
+					}
+					line = ""
+					break
+				}
+				line = rewritten
+			}
+			if line == "" {
+				break
+			}
+		}
+		if line != "" {
+			emit(line)
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if !rewrite {
+		return findings, nil
+	}
+	if pending != nil {
+		w.WriteString(*pending)
+	}
+	if err := w.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	if err := out.Close(); err != nil {
+		return nil, fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	in.Close()
+	if err := os.Rename(out.Name(), path); err != nil {
+		return nil, fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+
+	// Keep the checksums of generated files current
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(filepath.Join(dir, checksum.SumsFile)); err == nil {
+		if _, err := checksum.RecordFiles(dir, path); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return findings, nil
+}
+
+// maskQuoted returns line with the contents of quoted strings and
+// identifiers replaced by x, so rules only match SQL keywords
+func maskQuoted(line string) string {
+	masked := []byte(line)
+	var quote byte
+	for i := 0; i < len(masked); i++ {
+		c := masked[i]
+		switch {
+		case quote == 0:
+			if c == '\'' || c == '"' || c == '`' {
+				quote = c
+			}
+		case c == '\\' && quote != '`' && i+1 < len(masked):
+			masked[i], masked[i+1] = 'x', 'x'
+			i++
+		case c == quote:
+			quote = 0
+		default:
+			masked[i] = 'x'
+		}
+	}
+	return string(masked)
+}
+
+func printCompatReport(report compatReport) {
+	fmt.Printf("🔍 Checked %d files against %s\n\n", len(report.Files), report.Target)
+
+	current := ""
+	rewritten := 0
+	for _, f := range report.Findings {
+		if f.File != current {
+			if current != "" {
+				fmt.Println()
+			}
+			fmt.Println(f.File)
+			current = f.File
+		}
+		icon := "❌"
+		if f.Rewritten {
+			icon = "✏️ "
+			rewritten++
+		}
+		fmt.Printf("  %s %6d  %-20s %s: %s\n", icon, f.Line, f.Rule, f.Text, f.Message)
+	}
+	if current != "" {
+		fmt.Println()
+	}
+
+	fmt.Printf("Findings: %d, rewritten: %d\n", len(report.Findings), rewritten)
+}
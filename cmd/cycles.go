@@ -0,0 +1,299 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// deferSelfKey defers a self-referencing constraint c of plan. Rows are read
+// in key order, so a parent row may come after the rows referencing it; the
+// UPDATEs set the key once every row exists, so the table also loads with
+// --fk-checks.
+func deferSelfKey(plan *TableExtractionPlan, c fkConstraint) {
+	deferKey(plan, c)
+	fmt.Printf("🔁 Self-referencing foreign key %s.%s.(%s) is written as NULL and set by UPDATE statements after all tables\n",
+		plan.DatabaseName, plan.TableName, strings.Join(c.columns, ", "))
+}
+
+// deferCyclicKey adds the constraint c of plan, which references the table
+// at path[i:] and so closes a dependency cycle, to the plan's DeferredKeys
+func deferCyclicKey(plans []TableExtractionPlan, path []int, parent int, plan *TableExtractionPlan, c fkConstraint) {
+	var cycle []string
+	for k := len(path) - 1; k >= 0; k-- {
+		cycle = append([]string{plans[path[k]].TableName}, cycle...)
+		if path[k] == parent {
+			break
+		}
+	}
+	cycle = append(cycle, plans[parent].TableName)
+
+	deferKey(plan, c)
+	fmt.Printf("🔁 Circular foreign keys in %s: %s; %s.(%s) is written as NULL and set by UPDATE statements after all tables\n",
+		plan.DatabaseName, strings.Join(cycle, " → "), plan.TableName, strings.Join(c.columns, ", "))
+}
+
+// deferKey adds the columns of constraint c to the plan's DeferredKeys
+func deferKey(plan *TableExtractionPlan, c fkConstraint) {
+	for _, fk := range plan.ForeignKeys {
+		if fk.ConstraintName == c.name {
+			plan.DeferredKeys = append(plan.DeferredKeys, fk)
+		}
+	}
+}
+
+// deferredKeys writes the deferred foreign keys of one table as NULL and
+// spools UPDATE statements that set them once every table is loaded. Methods
+// are safe on nil, which leaves rows unchanged.
+type deferredKeys struct {
+	spool       *updateSpool
+	table       string
+	keyColumns  []string
+	keyIndexes  []int
+	constraints []deferredConstraint
+}
+
+type deferredConstraint struct {
+	fkConstraint
+	indexes []int
+}
+
+// newDeferredKeys prepares deferring the DeferredKeys of plan for rows read
+// by reader. Keys of tables without a primary key, or with NOT NULL
+// columns, cannot be deferred; the table is then written as is with a
+// warning, and only imports with foreign key checks off. With --fk-checks
+// this fails the table instead.
+func newDeferredKeys(ctx context.Context, db *sql.DB, plan TableExtractionPlan, reader *tableReader, spool *updateSpool) (*deferredKeys, error) {
+	if len(plan.DeferredKeys) == 0 || spool == nil {
+		return nil, nil
+	}
+	if len(reader.keyIndexes) == 0 {
+		if dataForeignKeyChecks {
+			return nil, fmt.Errorf("foreign keys to later rows cannot be deferred without a primary key; run without --fk-checks")
+		}
+		fmt.Printf(" - Warning: foreign keys to later rows cannot be deferred without a primary key")
+		return nil, nil
+	}
+
+	rows, err := queryWithRetry(ctx, db, dataMaxRetries,
+		"SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND IS_NULLABLE = 'NO'",
+		plan.DatabaseName, plan.TableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column nullability: %w", err)
+	}
+	notNull := make(map[string]bool)
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		notNull[col] = true
+	}
+	rows.Close()
+
+	index := make(map[string]int, len(reader.columns))
+	for i, col := range reader.columns {
+		index[col] = i
+	}
+
+	d := &deferredKeys{
+		spool:      spool,
+		table:      fmt.Sprintf("`%s`.`%s`", plan.DatabaseName, plan.TableName),
+		keyColumns: reader.key,
+		keyIndexes: reader.keyIndexes,
+	}
+	deferred := TableExtractionPlan{DatabaseName: plan.DatabaseName, ForeignKeys: plan.DeferredKeys}
+	for _, c := range planConstraints(deferred) {
+		var blocked []string
+		for _, col := range c.columns {
+			if notNull[col] {
+				blocked = append(blocked, col)
+			}
+		}
+		if len(blocked) > 0 && dataForeignKeyChecks {
+			return nil, fmt.Errorf("foreign key %s cannot be deferred, %s NOT NULL; run without --fk-checks", c.name, strings.Join(blocked, ", "))
+		}
+		if len(blocked) > 0 {
+			fmt.Printf(" - Warning: foreign key %s cannot be deferred, %s NOT NULL", c.name, strings.Join(blocked, ", "))
+			continue
+		}
+		d.constraints = append(d.constraints, deferredConstraint{fkConstraint: c, indexes: columnIndexes(index, c.columns)})
+	}
+	if len(d.constraints) == 0 {
+		return nil, nil
+	}
+	return d, nil
+}
+
+// capture spools an UPDATE for each deferred key set in the row and then
+// clears it, so the INSERT writes NULL
+func (d *deferredKeys) capture(values []interface{}) error {
+	if d == nil {
+		return nil
+	}
+	for _, c := range d.constraints {
+		key, ok := fkKey(values, c.indexes)
+		if !ok {
+			continue
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "UPDATE %s SET ", d.table)
+		for i, idx := range c.indexes {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "`%s` = %s", c.columns[i], formatSQLValue(values[idx]))
+		}
+		b.WriteString(" WHERE ")
+		for i, idx := range d.keyIndexes {
+			if i > 0 {
+				b.WriteString(" AND ")
+			}
+			fmt.Fprintf(&b, "`%s` = %s", d.keyColumns[i], formatSQLValue(values[idx]))
+		}
+		b.WriteString(";")
+
+		if err := d.spool.add(c.parent, strings.Join(c.parentColumns, ","), key, b.String()); err != nil {
+			return err
+		}
+		for _, idx := range c.indexes {
+			values[idx] = nil
+		}
+	}
+	return nil
+}
+
+// updateSpool collects the UPDATE statements of deferred keys in a file next
+// to the output, so they survive a resume. Each line holds the referenced
+// table, its column list and the referenced key, then the statement,
+// separated by tabs. Methods are safe on a nil spool.
+type updateSpool struct {
+	file *os.File
+	out  *bufio.Writer
+}
+
+// openUpdateSpool opens the spool at path, cut back to offset
+func openUpdateSpool(path string, offset int64) (*updateSpool, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open deferred key spool: %w", err)
+	}
+	info, err := file.Stat()
+	if err == nil && info.Size() < offset {
+		fmt.Printf("⚠️  Deferred foreign key updates of tables completed before the resume were lost\n")
+		offset = 0
+	}
+	s := &updateSpool{file: file, out: bufio.NewWriterSize(file, 1<<20)}
+	if err == nil {
+		err = s.truncate(offset)
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open deferred key spool: %w", err)
+	}
+	return s, nil
+}
+
+func (s *updateSpool) add(parent, columns, key, statement string) error {
+	_, err := fmt.Fprintf(s.out, "%s\t%s\t%s\t%s\n", parent, columns, key, statement)
+	if err != nil {
+		return fmt.Errorf("failed to write deferred key spool: %w", err)
+	}
+	return nil
+}
+
+// offset flushes the spool and returns its size
+func (s *updateSpool) offset() (int64, error) {
+	if s == nil {
+		return 0, nil
+	}
+	if err := s.out.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to write deferred key spool: %w", err)
+	}
+	return s.file.Seek(0, io.SeekCurrent)
+}
+
+// truncate drops everything spooled after offset, such as the updates of a
+// table that failed
+func (s *updateSpool) truncate(offset int64) error {
+	if s == nil {
+		return nil
+	}
+	s.out.Reset(s.file)
+	if err := s.file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err := s.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// writeTo appends the spooled statements to w. With a tracker, updates
+// referencing rows of a partially extracted table that were not extracted
+// are skipped and the key stays NULL.
+func (s *updateSpool) writeTo(w io.Writer, tracker *fkTracker) (written, skipped int, err error) {
+	if s == nil {
+		return 0, 0, nil
+	}
+	end, err := s.offset()
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return 0, 0, fmt.Errorf("failed to read deferred key spool: %w", err)
+	}
+	defer s.file.Seek(end, io.SeekStart)
+
+	reader := bufio.NewReaderSize(s.file, 1<<20)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return written, skipped, fmt.Errorf("failed to read deferred key spool: %w", err)
+		}
+		fields := strings.SplitN(strings.TrimSuffix(line, "\n"), "\t", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		if tracker != nil {
+			if keys, ok := tracker.recorded[fields[0]][fields[1]]; ok {
+				if _, found := keys[fields[2]]; !found {
+					skipped++
+					continue
+				}
+			}
+		}
+		if _, err := fmt.Fprintln(w, fields[3]); err != nil {
+			return written, skipped, err
+		}
+		written++
+	}
+	return written, skipped, nil
+}
+
+// remove deletes the spool once the extraction is complete
+func (s *updateSpool) remove() {
+	if s == nil {
+		return
+	}
+	s.file.Close()
+	os.Remove(s.file.Name())
+}
+
+func (s *updateSpool) close() {
+	if s == nil {
+		return
+	}
+	s.out.Flush()
+	s.file.Close()
+}
@@ -4,15 +4,36 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/mask"
+	"mariadb-extractor/internal/metacache"
+	"mariadb-extractor/internal/snapshot"
+	"mariadb-extractor/internal/state"
+	"mariadb-extractor/internal/sink"
+	"mariadb-extractor/internal/transform"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/spf13/cobra"
@@ -25,6 +46,9 @@ type ForeignKeyInfo struct {
 	ColumnName     string
 	RefTableName   string
 	RefColumnName  string
+	// Inferred marks a relationship proposed by --infer-relationships
+	// rather than declared on the server
+	Inferred bool
 }
 
 // TableExtractionPlan represents the plan for extracting a single table
@@ -34,8 +58,20 @@ type TableExtractionPlan struct {
 	RowCount     int64
 	SampleSize   int64
 	WhereClause  string
-	Dependencies []string // Tables this table depends on
+	Dependencies []string         // Tables this table depends on
+	ForeignKeys  []ForeignKeyInfo // Foreign key columns referencing other tables
+	// IncludedChild limits the table to rows referencing extracted parent
+	// rows instead of sampling it (--include-children)
+	IncludedChild bool
+	// DeferredKeys are foreign keys closing a dependency cycle. They are
+	// written as NULL and set by UPDATE statements after all tables.
+	DeferredKeys []ForeignKeyInfo
 	Order        int      // Extraction order based on dependencies
+
+	// resumeAfter is the checkpointed key a resumed run continues the table
+	// after, and resumeRows the rows written up to it
+	resumeAfter []interface{}
+	resumeRows  int64
 }
 
 // dataCmd represents the data command
@@ -46,7 +82,7 @@ var dataCmd = &cobra.Command{
 Preserves referential integrity and allows resumable extractions for large datasets.
 This command should be run after DDL extraction to seed your local database with data.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		runDataExtraction()
+		runDataExtraction(cmd.Context())
 	},
 }
 
@@ -65,23 +101,152 @@ var (
 	dataExcludeDatabases []string
 
 	// Table filtering
-	dataIncludeTables []string
-	dataExcludeTables []string
+	dataIncludeTables  []string
+	dataExcludeTables  []string
+	dataExcludeColumns []string
 
 	// Data sampling
 	dataSampleTables   []string // Format: "table:count"
 	dataSamplePercent  int      // Global sample percentage
+	dataSampleStrategy string   // Which rows a sample reads
+	dataSampleSeed     string   // Seed making random samples repeatable
 	dataMaxRowsPerTable int     // Maximum rows per table
+	dataFullTables      []string // Tables always extracted in full
+	dataSeeds           []string // Format: "table:condition"
+	dataSampleCapsFile  string
+	dataSampleCaps      *sampleCaps
+
+	// Row filtering
+	dataWhere     []string // Format: "table:condition"
+	dataWhereFile string
+
+	// Time window by date column
+	dataSince        string
+	dataSinceColumns []string // Format: "table:column"
 
 	// Performance
 	dataChunkSize  int
 	dataBatchSize  int
 	dataTimeout    int
+	dataMaxRetries int
+	dataPool       poolOptions
+	dataMemBudget  string
+	dataFastCount  bool
+
+	// INSERT statements are cut at --max-statement-bytes
+	dataMaxStatementBytes string
+	dataStatementLimit    int64
+	dataBenchmark  bool
+	dataWithSchema bool
+	dataFormat     string
+	dataMaxRate    string
+	dataSplitSize  string
+	dataSplitOut   string
+
+	// Server the extracted statements are executed on as they are written
+	dataTargetDSN string
+
+	// Read throttles besides --max-rate
+	dataMaxMbps       int
+	dataMaxRowsPerSec int
+
+	// Per-tenant exports of the rows of each value of a column
+	dataPartitionByColumn string
+	dataTenants           *tenantOutputs
+
+	// Relationships not declared as foreign keys
+	dataRelationshipsFile  string
+	dataRelationships      *relationshipFile
+	dataInferRelationships bool
+	dataInferSample        int
+
+	// Intra-table parallelism
+	dataTableSegments  int
+	dataSegmentMinRows int64
+	dataSegmentTables  []string
+
+	// Lock waits
+	dataLockWaitTimeout       int
+	dataInnodbLockWaitTimeout int
+	dataSkipMetadataLocked    int
+
+	// Tables whose DATA_LENGTH exceeds --skip-larger-than are skipped
+	dataSkipLargerThan      string
+	dataSkipLargerThanBytes int64
+	sizeSkipped             []string
+
+	// Exit non-zero when a table failed or was skipped
+	dataFailOnError bool
+
+	// Per-run read-only account
+	dataTempUser    bool
+	dataTempUserTTL int
+
+	// Metadata cache
+	dataMetadataCache   string
+	dataRefreshMetadata []string
+
+	// Saved extraction plans
+	dataPlanOut      string
+	dataPlanFile     string
+	dataPlanChecksum string
+
+	// Buffer pool warming before extraction
+	dataWarmCache     bool
+	dataWarmCacheRate string
+
+	// Replica position to wait for before extracting
+	dataAtGTID        string
+	dataAtGTIDTimeout int
+
+	// Zero dates: keep, null or error
+	dataZeroDates string
+
+	// TIMESTAMP time zone in the output: none, source or utc
+	dataTimestampZone string
+	dataTimeZone      sourceTimeZone
+
+	// Progress monitoring
+	dataHeartbeat    int
+	dataStallTimeout int
+	dataOnStall      string
+
+	// dataRateLimiter throttles bytes read per --max-rate or --max-mbps, and
+	// dataRowLimiter rows read per --max-rows-per-sec; nil when unlimited
+	dataRateLimiter *rateLimiter
+	dataRowLimiter  *rateLimiter
+
+	// Row transforms
+	dataTransformsFile string
+	dataTransforms     *transform.Set
+	dataMaskConfig     string
+	dataMaskKey        string
+	dataMasks          *mask.Set
+
+	// Columns holding application-encrypted ciphertext, passed through as is
+	dataEncryptedColumns []string
+
+	// Galera cluster awareness
+	dataGalera         bool
+	dataGaleraNodes    []string
+	dataGaleraMaxQueue int
+
+	// Pause while a replica source is behind by more than this
+	dataMaxReplicaLag time.Duration
+
+	// dataServer is the detected source server
+	dataServer serverInfo
 
 	// Options
 	dataNoForeignKeyCheck bool
+	dataForeignKeyChecks  bool
+	dataFKConsistent      bool
+	dataIncludeChildren   int
 	dataProgressInterval  int
+	dataProgressFormat    string
 	dataResume            string
+	dataServerLock        bool
+	dataStableOutput      bool
 )
 
 func init() {
@@ -102,7 +267,7 @@ func init() {
 	dataCmd.Flags().IntVarP(&dataPort, "port", "P", defaultPort, "MariaDB port (env: MARIADB_PORT)")
 	dataCmd.Flags().StringVarP(&dataUser, "user", "u", defaultUser, "MariaDB username (env: MARIADB_USER)")
 	dataCmd.Flags().StringVarP(&dataPassword, "password", "p", defaultPassword, "MariaDB password (env: MARIADB_PASSWORD)")
-	dataCmd.Flags().StringVarP(&dataOutput, "output", "o", defaultOutput, "Output file prefix (env: MARIADB_OUTPUT_PREFIX)")
+	dataCmd.Flags().StringVarP(&dataOutput, "output", "o", defaultOutput, "Output file prefix, or a URI such as s3://bucket/path/prefix, ssh://host/path/prefix or - for stdout (env: MARIADB_OUTPUT_PREFIX)")
 
 	// Database selection flags
 	dataCmd.Flags().StringSliceVarP(&dataDatabases, "databases", "d", []string{}, "Specific databases to extract (comma-separated)")
@@ -113,21 +278,89 @@ func init() {
 	// Table filtering flags
 	dataCmd.Flags().StringSliceVar(&dataIncludeTables, "include-tables", []string{}, "Tables to include (supports wildcards)")
 	dataCmd.Flags().StringSliceVar(&dataExcludeTables, "exclude-tables", []string{}, "Tables to exclude (supports wildcards)")
+	dataCmd.Flags().StringSliceVar(&dataExcludeColumns, "exclude-columns", []string{}, "Columns to leave out of SELECTs and the output (table.column or db.table.column, supports wildcards)")
 
 	// Data sampling flags
 	dataCmd.Flags().StringSliceVar(&dataSampleTables, "sample-tables", []string{}, "Sample specific tables (format: table:count)")
 	dataCmd.Flags().IntVar(&dataSamplePercent, "sample-percent", 0, "Global sample percentage (0-100)")
+	dataCmd.Flags().StringVar(&dataSampleStrategy, "sample-strategy", getEnvWithDefault("MARIADB_SAMPLE_STRATEGY", sampleFirst), "Rows a sample reads: first, random, modulo (every k-th key) or newest (env: MARIADB_SAMPLE_STRATEGY)")
+	dataCmd.Flags().StringVar(&dataSampleSeed, "sample-seed", os.Getenv("MARIADB_SAMPLE_SEED"), "Seed for random samples, which then pick the same rows from the same data on every run; implies --sample-strategy random (env: MARIADB_SAMPLE_SEED)")
+	dataCmd.Flags().StringVar(&dataSampleCapsFile, "sample-caps", getEnvWithDefault("MARIADB_SAMPLE_CAPS", ""), "YAML file with per-table minimums and maximums for --sample-percent (env: MARIADB_SAMPLE_CAPS)")
 	dataCmd.Flags().IntVar(&dataMaxRowsPerTable, "max-rows", 0, "Maximum rows per table (0=unlimited)")
+	dataCmd.Flags().StringSliceVar(&dataFullTables, "full-tables", envList("MARIADB_FULL_TABLES"), "Reference tables extracted in full despite sampling and --max-rows (db.table or table, supports wildcards) (env: MARIADB_FULL_TABLES)")
+	dataCmd.Flags().StringArrayVar(&dataWhere, "where", []string{}, "Only extract rows of a table matching a condition (format: table:condition, e.g. \"orders:created_at > '2024-01-01'\"); repeatable")
+	dataCmd.Flags().StringVar(&dataSince, "since", os.Getenv("MARIADB_SINCE"), "Only extract rows of the last window, e.g. 90d, 2w or 12h, by each table's created_at, updated_at or similar date column (env: MARIADB_SINCE)")
+	dataCmd.Flags().StringSliceVar(&dataSinceColumns, "since-column", []string{}, "Date column --since uses for a table (format: table:column), or table:- to extract the table whole")
+	dataCmd.Flags().StringVar(&dataWhereFile, "where-file", getEnvWithDefault("MARIADB_WHERE_FILE", ""), "YAML file mapping table or db.table to a row condition (env: MARIADB_WHERE_FILE)")
+	dataCmd.Flags().StringArrayVar(&dataSeeds, "seed", []string{}, "Extract only these rows and every row they reference (format: table:condition, e.g. \"customers:id IN (1,2,3)\"); repeatable")
 
 	// Performance flags
 	dataCmd.Flags().IntVar(&dataChunkSize, "chunk-size", defaultChunkSize, "Rows per chunk for large tables (env: MARIADB_CHUNK_SIZE)")
 	dataCmd.Flags().IntVar(&dataBatchSize, "batch-size", defaultBatchSize, "Batch size for INSERT statements (env: MARIADB_BATCH_SIZE)")
 	dataCmd.Flags().IntVarP(&dataTimeout, "timeout", "t", defaultTimeout, "Query timeout in seconds (env: MARIADB_TIMEOUT)")
+	dataCmd.Flags().BoolVar(&dataWarmCache, "warm-cache", os.Getenv("MARIADB_WARM_CACHE") == "true", "Read the tables extracted in full into the buffer pool before extracting, as far as it holds them (env: MARIADB_WARM_CACHE)")
+	dataCmd.Flags().StringVar(&dataWarmCacheRate, "warm-cache-rate", getEnvWithDefault("MARIADB_WARM_CACHE_RATE", ""), "Maximum table size warmed per second, e.g. 200MB/s (env: MARIADB_WARM_CACHE_RATE)")
+	dataCmd.Flags().StringVar(&dataAtGTID, "at-gtid", os.Getenv("MARIADB_AT_GTID"), "Wait until the replica has applied this GTID set before extracting (env: MARIADB_AT_GTID)")
+	dataCmd.Flags().IntVar(&dataAtGTIDTimeout, "at-gtid-timeout", getEnvIntWithDefault("MARIADB_AT_GTID_TIMEOUT", 300), "Seconds to wait for --at-gtid before failing (0=no limit) (env: MARIADB_AT_GTID_TIMEOUT)")
+	dataCmd.Flags().StringVar(&dataZeroDates, "zero-dates", getEnvWithDefault("MARIADB_ZERO_DATES", "keep"), "How to write 0000-00-00 dates: keep, null or error (env: MARIADB_ZERO_DATES)")
+	dataCmd.Flags().StringVar(&dataTimestampZone, "timestamp-zone", getEnvWithDefault("MARIADB_TIMESTAMP_ZONE", "none"), "Time zone of TIMESTAMP values in the output: none, source (SET the server's offset on import) or utc (read and import in UTC) (env: MARIADB_TIMESTAMP_ZONE)")
+	dataCmd.Flags().IntVar(&dataLockWaitTimeout, "lock-wait-timeout", getEnvIntWithDefault("MARIADB_LOCK_WAIT_TIMEOUT", 0), "Session lock_wait_timeout in seconds for metadata locks (0=server default) (env: MARIADB_LOCK_WAIT_TIMEOUT)")
+	dataCmd.Flags().IntVar(&dataInnodbLockWaitTimeout, "innodb-lock-wait-timeout", getEnvIntWithDefault("MARIADB_INNODB_LOCK_WAIT_TIMEOUT", 0), "Session innodb_lock_wait_timeout in seconds for row locks (0=server default) (env: MARIADB_INNODB_LOCK_WAIT_TIMEOUT)")
+	dataCmd.Flags().IntVar(&dataSkipMetadataLocked, "skip-metadata-locked", getEnvIntWithDefault("MARIADB_SKIP_METADATA_LOCKED", 0), "Skip tables whose reads a metadata lock has blocked for at least N seconds, e.g. behind an ALTER TABLE (0=off) (env: MARIADB_SKIP_METADATA_LOCKED)")
+	dataCmd.Flags().StringVar(&dataSkipLargerThan, "skip-larger-than", os.Getenv("MARIADB_SKIP_LARGER_THAN"), "Skip tables whose data in information_schema is larger than this, e.g. 10GB (env: MARIADB_SKIP_LARGER_THAN)")
+	dataCmd.Flags().BoolVar(&dataTempUser, "temp-user", os.Getenv("MARIADB_TEMP_USER") == "true", "Create a read-only user for this run with the given admin account, extract as it and drop it afterwards (env: MARIADB_TEMP_USER)")
+	dataCmd.Flags().IntVar(&dataTempUserTTL, "temp-user-ttl", getEnvIntWithDefault("MARIADB_TEMP_USER_TTL", 1), "Days after which the temporary user's password expires, should the run fail to drop it (env: MARIADB_TEMP_USER_TTL)")
+	dataCmd.Flags().StringVar(&dataMetadataCache, "metadata-cache", os.Getenv("MARIADB_METADATA_CACHE"), "JSON file caching information_schema lookups across runs against the same server (env: MARIADB_METADATA_CACHE)")
+	dataCmd.Flags().StringSliceVar(&dataRefreshMetadata, "refresh-metadata", []string{}, "Databases whose cached metadata is read again, or all")
+	dataCmd.Flags().StringVar(&dataPlanOut, "plan-out", "", "Write the computed extraction plan and the options given to this YAML (or .json) file, then stop without extracting")
+	dataCmd.Flags().StringVar(&dataPlanFile, "plan", "", "Extract the tables of a plan written by --plan-out or plan export with its options; options given explicitly take precedence")
+	dataCmd.Flags().StringVar(&dataPlanChecksum, "plan-sha256", "", "Refuse to run --plan unless the file has this SHA-256, as printed when the plan was written")
+	dataCmd.Flags().BoolVar(&dataFailOnError, "fail-on-error", os.Getenv("MARIADB_FAIL_ON_ERROR") == "true", "Exit with an error when any table failed or was skipped, after writing the rest (env: MARIADB_FAIL_ON_ERROR)")
+	dataCmd.Flags().IntVar(&dataHeartbeat, "heartbeat", getEnvIntWithDefault("MARIADB_HEARTBEAT", 0), "Log the current table, chunk and rows/s every N seconds (0=off) (env: MARIADB_HEARTBEAT)")
+	dataCmd.Flags().IntVar(&dataStallTimeout, "stall-timeout", getEnvIntWithDefault("MARIADB_STALL_TIMEOUT", 0), "Seconds without progress after which the extraction is stalled (0=off) (env: MARIADB_STALL_TIMEOUT)")
+	dataCmd.Flags().StringVar(&dataOnStall, "on-stall", getEnvWithDefault("MARIADB_ON_STALL", "abort"), "What to do on a stall: abort or warn (env: MARIADB_ON_STALL)")
+	dataCmd.Flags().BoolVar(&dataFastCount, "fast-count", os.Getenv("MARIADB_FAST_COUNT") == "true", "Use information_schema row estimates instead of COUNT(*) for tables that are not sampled (env: MARIADB_FAST_COUNT)")
+	dataCmd.Flags().IntVar(&dataMaxRetries, "max-retries", getEnvIntWithDefault("MARIADB_MAX_RETRIES", 3), "Maximum retry attempts for failed queries (env: MARIADB_MAX_RETRIES)")
+	addPoolFlags(dataCmd, &dataPool, 5, 2, defaultTimeout)
+	dataCmd.Flags().IntVar(&dataTableSegments, "table-segments", getEnvIntWithDefault("MARIADB_TABLE_SEGMENTS", 1), "Split large tables into this many primary key ranges extracted concurrently (env: MARIADB_TABLE_SEGMENTS)")
+	dataCmd.Flags().Int64Var(&dataSegmentMinRows, "segment-min-rows", 1000000, "Only split tables with at least this many rows into --table-segments")
+	dataCmd.Flags().StringSliceVar(&dataSegmentTables, "segment-tables", []string{}, "Only split these tables (db.table or table, supports wildcards) into --table-segments, whatever their size")
+	dataCmd.Flags().StringVar(&dataMaxRate, "max-rate", getEnvWithDefault("MARIADB_MAX_RATE", ""), "Maximum bandwidth read from the server, e.g. 50MB/s (env: MARIADB_MAX_RATE)")
+	dataCmd.Flags().IntVar(&dataMaxMbps, "max-mbps", getEnvIntWithDefault("MARIADB_MAX_MBPS", 0), "Maximum bandwidth read from the server in megabits per second, instead of --max-rate (env: MARIADB_MAX_MBPS)")
+	dataCmd.Flags().IntVar(&dataMaxRowsPerSec, "max-rows-per-sec", getEnvIntWithDefault("MARIADB_MAX_ROWS_PER_SEC", 0), "Maximum rows read from the server per second, 0 for unlimited (env: MARIADB_MAX_ROWS_PER_SEC)")
+	dataCmd.Flags().StringVar(&dataSplitSize, "split-size", os.Getenv("MARIADB_SPLIT_SIZE"), "Split the SQL file into numbered files of at most this size, e.g. 256MB (env: MARIADB_SPLIT_SIZE)")
+	dataCmd.Flags().StringVar(&dataSplitOut, "split-output", os.Getenv("MARIADB_SPLIT_OUTPUT"), "Write one SQL file per-table or per-database plus a manifest.json, instead of a single file (env: MARIADB_SPLIT_OUTPUT)")
+	dataCmd.Flags().StringVar(&dataPartitionByColumn, "partition-by-column", os.Getenv("MARIADB_PARTITION_BY_COLUMN"), "Also write each value's rows of the tables with this column, e.g. tenant_id, to a separate export under <output>/tenants (env: MARIADB_PARTITION_BY_COLUMN)")
+	dataCmd.Flags().StringVar(&dataMaxStatementBytes, "max-statement-bytes", getEnvWithDefault("MARIADB_MAX_STATEMENT_BYTES", "1MB"), "Maximum size of an INSERT statement, below the target's max_allowed_packet; --batch-size still caps its rows (env: MARIADB_MAX_STATEMENT_BYTES)")
+	dataCmd.Flags().StringVar(&dataMemBudget, "memory-budget", getEnvWithDefault("MARIADB_MEMORY_BUDGET", "32MB"), "Memory for buffered output and pending INSERT batches, e.g. 64MB (env: MARIADB_MEMORY_BUDGET)")
+	dataCmd.Flags().BoolVar(&dataBenchmark, "benchmark", false, "Time read, convert, format, compress and write stages per table and print a breakdown")
 
 	// Options
 	dataCmd.Flags().BoolVar(&dataNoForeignKeyCheck, "no-foreign-key-check", false, "Skip foreign key dependency ordering")
-	dataCmd.Flags().IntVar(&dataProgressInterval, "progress-interval", 1000, "Show progress every N rows")
+	dataCmd.Flags().StringVar(&dataRelationshipsFile, "relationships", os.Getenv("MARIADB_RELATIONSHIPS"), "YAML file of parent/child relationships to treat as foreign keys (env: MARIADB_RELATIONSHIPS)")
+	dataCmd.Flags().BoolVar(&dataInferRelationships, "infer-relationships", os.Getenv("MARIADB_INFER_RELATIONSHIPS") == "true", "Treat <table>_id columns matching a table's primary key as foreign keys where none is declared (env: MARIADB_INFER_RELATIONSHIPS)")
+	dataCmd.Flags().IntVar(&dataInferSample, "infer-sample", 0, "Check up to N distinct values of each inferred relationship against the parent table and drop it when under 90% match (0=names and types only)")
+	dataCmd.Flags().BoolVar(&dataForeignKeyChecks, "fk-checks", false, "Keep foreign key checks on in the script: tables load in dependency order and circular and self-referencing keys are set by UPDATEs, and keys that cannot be deferred fail the table")
+	dataCmd.Flags().BoolVar(&dataFKConsistent, "fk-consistent", true, "When sampling, skip rows whose referenced parent rows are not in the extract")
+	dataCmd.Flags().IntVar(&dataIncludeChildren, "include-children", 0, "When sampling, extract every row referencing extracted parent rows in tables up to this many foreign key levels below a sampled table, instead of sampling them (0=off)")
+	dataCmd.Flags().IntVar(&dataProgressInterval, "progress-interval", 1000, "Update progress every N rows: the progress bar on a terminal, a line at most every 10s when stdout is redirected")
+	dataCmd.Flags().StringVar(&dataProgressFormat, "progress-format", getEnvWithDefault("MARIADB_PROGRESS_FORMAT", "text"), "Progress output: text, or json for JSON lines events on stderr (env: MARIADB_PROGRESS_FORMAT)")
 	dataCmd.Flags().StringVar(&dataResume, "resume", "", "Resume extraction with ID")
+	dataCmd.Flags().StringVar(&dataFormat, "format", "sql", "Output format: sql (INSERT statements), loaddata (per-table TSV files and a LOAD DATA LOCAL INFILE script), csv (per-table CSV files with a header row, and a LOAD DATA script), jsonl (per-table newline-delimited JSON objects keyed by column name) or clickhouse (per-table TSV files and a clickhouse-client script creating MergeTree tables)")
+	dataCmd.Flags().BoolVar(&dataGalera, "galera", false, "Require a ready Galera node and pause while the cluster is under flow control")
+	dataCmd.Flags().StringSliceVar(&dataGaleraNodes, "galera-nodes", []string{}, "Galera nodes (host:port) to choose from, preferring a Donor/Desynced node; overrides --host/--port")
+	dataCmd.Flags().IntVar(&dataGaleraMaxQueue, "galera-max-queue", 0, "Also pause while the node's wsrep receive queue exceeds this length (0=flow control only)")
+	dataCmd.Flags().DurationVar(&dataMaxReplicaLag, "max-replica-lag", 0, "When reading from a replica, pause while it is further behind its source than this, e.g. 30s (0=never pause)")
+	dataCmd.Flags().StringVar(&dataMaskConfig, "mask-config", os.Getenv("MARIADB_MASK_CONFIG"), "YAML file of per-column masking rules (null, fixed, hash, pseudonymize, shuffle, regex, faker.<kind>) applied before rows are written (env: MARIADB_MASK_CONFIG)")
+	dataCmd.Flags().StringVar(&dataMaskKey, "mask-key", os.Getenv("MARIADB_MASK_KEY"), "Secret key of pseudonymize masking rules; prefer the environment variable (env: MARIADB_MASK_KEY)")
+	dataCmd.Flags().StringSliceVar(&dataEncryptedColumns, "encrypted-columns", envList("MARIADB_ENCRYPTED_COLUMNS"), "Application-encrypted columns to write as untouched hex and leave out of transforms and masking (table.column or db.table.column, supports wildcards) (env: MARIADB_ENCRYPTED_COLUMNS)")
+	dataCmd.Flags().StringVar(&dataTransformsFile, "transforms", os.Getenv("MARIADB_TRANSFORMS"), "YAML file mapping db.table.column to transforms applied to extracted rows (env: MARIADB_TRANSFORMS)")
+	dataCmd.Flags().StringVar(&dataTargetDSN, "target-dsn", os.Getenv("MARIADB_TARGET_DSN"), "Also execute the extracted statements on this server as they are written, e.g. user:pass@tcp(localhost:3306)/ (env: MARIADB_TARGET_DSN)")
+	dataCmd.Flags().BoolVar(&dataWithSchema, "with-schema", false, "Write CREATE DATABASE/TABLE statements before each table's data")
+	dataCmd.Flags().BoolVar(&dataStableOutput, "stable-output", os.Getenv("MARIADB_STABLE_OUTPUT") == "true", "Order databases and rows deterministically and leave run-specific values out, so unchanged data gives byte-identical files (env: MARIADB_STABLE_OUTPUT)")
+	dataCmd.Flags().BoolVar(&dataServerLock, "server-lock", os.Getenv("MARIADB_SERVER_LOCK") == "true", "Also hold a GET_LOCK named after the output on the server, for runs on several hosts sharing the output directory (env: MARIADB_SERVER_LOCK)")
+
+	dataFlags = dataCmd.Flags()
 
 	// Mark required flags if not set via environment
 	if defaultUser == "" {
@@ -138,74 +371,569 @@ func init() {
 	}
 }
 
-func runDataExtraction() {
+func runDataExtraction(ctx context.Context) {
 	// Validate options
-	if !dataAllDatabases && !dataAllUserDatabases && len(dataDatabases) == 0 {
-		log.Fatal("Must specify one of: --all-databases, --all-user-databases, or --databases")
+	if err := validateDataOptions(); err != nil {
+		log.Fatal(err)
 	}
 
-	if dataAllDatabases && dataAllUserDatabases {
-		log.Fatal("Cannot specify both --all-databases and --all-user-databases")
+	// Pick the Galera node to read from
+	if len(dataGaleraNodes) > 0 {
+		fmt.Printf("Probing %d Galera nodes...\n", len(dataGaleraNodes))
+		host, port, err := selectGaleraNode(ctx, dataGaleraNodes, dataUser, dataPassword, dataTimeout)
+		if err != nil {
+			log.Fatalf("Failed to select Galera node: %v", err)
+		}
+		dataHost, dataPort = host, port
+		dataGalera = true
 	}
 
-	// Build connection string with timeout
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&parseTime=true&timeout=%ds&readTimeout=%ds&writeTimeout=%ds",
-		dataUser, dataPassword, dataHost, dataPort, dataTimeout, dataTimeout, dataTimeout)
-
-	db, err := sql.Open("mysql", dsn)
+	db, err := sql.Open("mysql", dataDSN(dataUser, dataPassword))
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
 	// Configure connection pool
-	db.SetMaxOpenConns(5)
-	db.SetMaxIdleConns(2)
-	db.SetConnMaxLifetime(time.Duration(dataTimeout) * time.Second)
+	dataPool.apply(db)
 
-	if err := db.Ping(); err != nil {
+	if err := pingWithRetry(ctx, db, dataMaxRetries); err != nil {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
 	fmt.Printf("Connected to MariaDB at %s:%d (timeout: %ds)\n", dataHost, dataPort, dataTimeout)
+
+	// Extract as a read-only user that only exists for this run
+	if dataTempUser {
+		tmp, err := createTemporaryUser(ctx, db, dataDatabases, dataTempUserTTL)
+		if err != nil {
+			log.Fatalf("Failed to create temporary user: %v", err)
+		}
+		fmt.Printf("🔑 Created temporary user %s (password expires in %d days)\n", tmp.user, dataTempUserTTL)
+
+		tmpDB, err := sql.Open("mysql", dataDSN(tmp.user, tmp.password))
+		if err == nil {
+			dataPool.apply(tmpDB)
+			err = pingWithRetry(ctx, tmpDB, dataMaxRetries)
+		}
+		if err == nil {
+			err = runDataWithDB(ctx, tmpDB)
+		}
+		if tmpDB != nil {
+			tmpDB.Close()
+		}
+		tmp.drop(db)
+		if err != nil {
+			log.Fatalf("Data extraction failed: %v", err)
+		}
+		return
+	}
+
+	if err := runDataWithDB(ctx, db); err != nil {
+		log.Fatalf("Data extraction failed: %v", err)
+	}
+}
+
+// dataDSN builds the connection string of the data command for an account
+func dataDSN(user, password string) string {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&parseTime=true&timeout=%ds&readTimeout=%ds&writeTimeout=%ds",
+		user, password, dataHost, dataPort, dataTimeout, dataTimeout, dataTimeout)
+	// Other DSN parameters are session variables set on every connection
+	if dataLockWaitTimeout > 0 {
+		dsn += fmt.Sprintf("&lock_wait_timeout=%d", dataLockWaitTimeout)
+	}
+	if dataInnodbLockWaitTimeout > 0 {
+		dsn += fmt.Sprintf("&innodb_lock_wait_timeout=%d", dataInnodbLockWaitTimeout)
+	}
+	if dataTimestampZone == "utc" {
+		dsn += utcTimeZoneParam
+	}
+	return dsn
+}
+
+// validateDataOptions checks flag combinations before connecting
+func validateDataOptions() error {
+	// A replayed plan supplies the options it was made with
+	if dataPlanChecksum != "" && dataPlanFile == "" {
+		return fmt.Errorf("--plan-sha256 verifies the file given to --plan")
+	}
+	if err := loadDataPlan(dataFlags); err != nil {
+		return err
+	}
+	if dataPlanFile != "" && dataPlanOut != "" {
+		return fmt.Errorf("--plan and --plan-out cannot be combined")
+	}
+
+	if dataPlan == nil && !dataAllDatabases && !dataAllUserDatabases && len(dataDatabases) == 0 {
+		return fmt.Errorf("must specify one of: --all-databases, --all-user-databases, or --databases")
+	}
+
+	if dataAllDatabases && dataAllUserDatabases {
+		return fmt.Errorf("cannot specify both --all-databases and --all-user-databases")
+	}
+
+	if dataProgressFormat != "text" && dataProgressFormat != "json" {
+		return fmt.Errorf("invalid --progress-format %q: must be text or json", dataProgressFormat)
+	}
+
+	if budget, err := parseByteSize(dataMemBudget); err != nil || budget < 64*1024 {
+		return fmt.Errorf("invalid --memory-budget %q: must be a size of at least 64KB", dataMemBudget)
+	}
+
+	if limit, err := parseByteSize(dataMaxStatementBytes); err != nil || limit < 1024 {
+		return fmt.Errorf("invalid --max-statement-bytes %q: must be a size of at least 1KB", dataMaxStatementBytes)
+	}
+
+	if dataSkipLargerThan != "" {
+		if limit, err := parseByteSize(dataSkipLargerThan); err != nil || limit <= 0 {
+			return fmt.Errorf("invalid --skip-larger-than %q: must be a size such as 10GB", dataSkipLargerThan)
+		}
+	}
+
+	if out, _, err := sink.Parse(dataOutput); err != nil {
+		return err
+	} else if sink.IsStream(out) && dataFormat != "sql" {
+		return fmt.Errorf("--format %s writes several files and cannot be streamed to stdout", dataFormat)
+	} else if sink.IsStream(out) && dataSplitSize != "" {
+		return fmt.Errorf("--split-size writes several files and cannot be streamed to stdout")
+	} else if sink.IsStream(out) && dataSplitOut != "" {
+		return fmt.Errorf("--split-output writes several files and cannot be streamed to stdout")
+	} else if sink.IsStream(out) && dataPartitionByColumn != "" {
+		return fmt.Errorf("--partition-by-column writes several files and cannot be streamed to stdout")
+	}
+	if dataPartitionByColumn != "" {
+		if dataFormat != "sql" && dataFormat != "csv" {
+			return fmt.Errorf("--partition-by-column supports --format sql and csv, not %s", dataFormat)
+		}
+		if dataResume != "" {
+			return fmt.Errorf("--partition-by-column cannot be combined with --resume; the tenant exports are written in one pass")
+		}
+	}
+
+	if dataSplitOut != "" && dataSplitOut != "per-table" && dataSplitOut != "per-database" {
+		return fmt.Errorf("invalid --split-output %q: must be per-table or per-database", dataSplitOut)
+	}
+	if dataSplitOut != "" && dataSplitSize != "" {
+		return fmt.Errorf("--split-output and --split-size cannot be combined")
+	}
+
+	if _, err := parseSplitSize(dataSplitSize); err != nil {
+		return err
+	}
+
+	if _, err := parseRate(dataMaxRate); err != nil {
+		return fmt.Errorf("invalid --max-rate %q: must be a size per second, e.g. 50MB/s", dataMaxRate)
+	}
+	if dataMaxMbps < 0 {
+		return fmt.Errorf("--max-mbps must not be negative")
+	}
+	if dataMaxMbps > 0 && dataMaxRate != "" {
+		return fmt.Errorf("--max-mbps and --max-rate both limit the bandwidth: use one of them")
+	}
+	if dataMaxRowsPerSec < 0 {
+		return fmt.Errorf("--max-rows-per-sec must not be negative")
+	}
+	if dataMaxReplicaLag < 0 {
+		return fmt.Errorf("--max-replica-lag must not be negative")
+	}
+
+	if dataTableSegments < 1 {
+		return fmt.Errorf("invalid --table-segments %d: must be at least 1", dataTableSegments)
+	}
+	if dataTableSegments > 1 && dataPool.maxOpenConns > 0 {
+		// The server lock holds a connection of its own
+		needed := dataTableSegments + 1
+		if dataServerLock {
+			needed++
+		}
+		if dataPool.maxOpenConns < needed {
+			return fmt.Errorf("--table-segments %d needs --max-open-conns of at least %d", dataTableSegments, needed)
+		}
+	}
+	for _, pattern := range dataExcludeColumns {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.Contains(pattern, ".") {
+			return fmt.Errorf("invalid --exclude-columns pattern %q: use table.column or db.table.column", pattern)
+		}
+	}
+
+	if err := checkEncryptedColumns(dataEncryptedColumns); err != nil {
+		return err
+	}
+
+	for _, pattern := range dataSegmentTables {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --segment-tables pattern %q: %w", pattern, err)
+		}
+	}
+	for _, pattern := range dataFullTables {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --full-tables pattern %q: %w", pattern, err)
+		}
+	}
+
+	if dataZeroDates != "keep" && dataZeroDates != "null" && dataZeroDates != "error" {
+		return fmt.Errorf("invalid --zero-dates %q: must be keep, null or error", dataZeroDates)
+	}
+	if _, err := parseRate(dataWarmCacheRate); err != nil {
+		return fmt.Errorf("invalid --warm-cache-rate %q: must be a size per second, e.g. 200MB/s", dataWarmCacheRate)
+	}
+	if dataAtGTIDTimeout < 0 {
+		return fmt.Errorf("--at-gtid-timeout must not be negative")
+	}
+	if dataTimestampZone != "none" && dataTimestampZone != "source" && dataTimestampZone != "utc" {
+		return fmt.Errorf("invalid --timestamp-zone %q: must be none, source or utc", dataTimestampZone)
+	}
+	if dataLockWaitTimeout < 0 || dataInnodbLockWaitTimeout < 0 || dataSkipMetadataLocked < 0 {
+		return fmt.Errorf("--lock-wait-timeout, --innodb-lock-wait-timeout and --skip-metadata-locked must not be negative")
+	}
+	if dataTempUserTTL < 1 {
+		return fmt.Errorf("--temp-user-ttl must be at least 1 day")
+	}
+
+	if dataHeartbeat < 0 || dataStallTimeout < 0 {
+		return fmt.Errorf("--heartbeat and --stall-timeout must not be negative")
+	}
+	if dataOnStall != "abort" && dataOnStall != "warn" {
+		return fmt.Errorf("invalid --on-stall %q: must be abort or warn", dataOnStall)
+	}
+
+	if dataChunkSize <= 0 {
+		return fmt.Errorf("invalid --chunk-size %d: must be positive", dataChunkSize)
+	}
+
+	if dataFormat != "sql" && dataFormat != "loaddata" && dataFormat != "csv" && dataFormat != "jsonl" && dataFormat != "clickhouse" {
+		return fmt.Errorf("invalid --format %q: must be sql, loaddata, csv, jsonl or clickhouse", dataFormat)
+	}
+
+	if dataTargetDSN != "" {
+		if _, err := parseTargetDSN(dataTargetDSN); err != nil {
+			return err
+		}
+		if dataFormat != "sql" {
+			return fmt.Errorf("--target-dsn executes INSERT statements and needs --format sql")
+		}
+		if dataResume != "" {
+			return fmt.Errorf("--target-dsn cannot be combined with --resume: the target already holds rows written after the last checkpoint")
+		}
+		if dataPartitionByColumn != "" {
+			return fmt.Errorf("--target-dsn cannot be combined with --partition-by-column")
+		}
+	}
+
+	if (dataInferRelationships || dataRelationshipsFile != "") && dataNoForeignKeyCheck {
+		return fmt.Errorf("--relationships and --infer-relationships add foreign keys for dependency ordering and cannot be combined with --no-foreign-key-check")
+	}
+	if dataForeignKeyChecks && dataNoForeignKeyCheck {
+		return fmt.Errorf("--fk-checks needs dependency ordering and cannot be combined with --no-foreign-key-check")
+	}
+	if dataForeignKeyChecks && !dataFKConsistent {
+		return fmt.Errorf("--fk-checks cannot be combined with --fk-consistent=false: sampled child rows could reference parent rows that are not extracted")
+	}
+	if dataForeignKeyChecks && dataPartitionByColumn != "" {
+		return fmt.Errorf("--fk-checks cannot be combined with --partition-by-column: tenant scripts leave circular foreign keys NULL and hold child rows of shared tables")
+	}
+	if dataInferSample < 0 {
+		return fmt.Errorf("invalid --infer-sample %d: must not be negative", dataInferSample)
+	}
+
+	if dataIncludeChildren < 0 {
+		return fmt.Errorf("invalid --include-children %d: must not be negative", dataIncludeChildren)
+	}
+	if dataIncludeChildren > 0 && (!dataFKConsistent || dataNoForeignKeyCheck) {
+		return fmt.Errorf("--include-children needs foreign key consistent sampling; remove --fk-consistent=false and --no-foreign-key-check")
+	}
+
+	for _, spec := range dataWhere {
+		if _, _, err := parseWhere(spec); err != nil {
+			return err
+		}
+	}
+	if dataSince != "" {
+		if _, err := parseSince(dataSince); err != nil {
+			return err
+		}
+	} else if len(dataSinceColumns) > 0 {
+		return fmt.Errorf("--since-column needs --since")
+	}
+	for _, spec := range dataSinceColumns {
+		if _, _, err := parseSinceColumn(spec); err != nil {
+			return err
+		}
+	}
+
+	validStrategy := false
+	for _, strategy := range sampleStrategies {
+		validStrategy = validStrategy || dataSampleStrategy == strategy
+	}
+	if !validStrategy {
+		return fmt.Errorf("invalid --sample-strategy %q: must be one of %s", dataSampleStrategy, strings.Join(sampleStrategies, ", "))
+	}
+	if dataSampleSeed != "" {
+		switch dataSampleStrategy {
+		case sampleFirst:
+			dataSampleStrategy = sampleRandom
+		case sampleModulo, sampleNewest:
+			return fmt.Errorf("--sample-seed only applies to --sample-strategy random, not %s", dataSampleStrategy)
+		}
+	}
+
+	if dataSampleCapsFile != "" && dataSamplePercent <= 0 {
+		return fmt.Errorf("--sample-caps bounds --sample-percent and needs it to be set")
+	}
+
+	if len(dataSeeds) > 0 {
+		if len(dataSampleTables) > 0 || dataSamplePercent > 0 || dataMaxRowsPerTable > 0 {
+			return fmt.Errorf("--seed cannot be combined with --sample-tables, --sample-percent or --max-rows")
+		}
+		if dataNoForeignKeyCheck {
+			return fmt.Errorf("--seed follows foreign keys and cannot be combined with --no-foreign-key-check")
+		}
+		for _, spec := range dataSeeds {
+			if _, _, err := parseSeed(spec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runDataWithDB plans and runs the data extraction over an open connection
+func runDataWithDB(ctx context.Context, db *sql.DB) error {
+	dataTransforms = nil
+	if dataTransformsFile != "" {
+		var err error
+		if dataTransforms, err = transform.Load(dataTransformsFile); err != nil {
+			return err
+		}
+	}
+
+	dataMasks = nil
+	if dataMaskConfig != "" {
+		var err error
+		if dataMasks, err = mask.Load(dataMaskConfig, dataMaskKey); err != nil {
+			return err
+		}
+	}
+
+	dataRelationships = nil
+	if dataRelationshipsFile != "" {
+		var err error
+		if dataRelationships, err = loadRelationships(dataRelationshipsFile); err != nil {
+			return err
+		}
+	}
+
+	dataSampleCaps = nil
+	if dataSampleCapsFile != "" {
+		var err error
+		if dataSampleCaps, err = loadSampleCaps(dataSampleCapsFile); err != nil {
+			return err
+		}
+	}
+
+	rate, err := parseRate(dataMaxRate)
+	if err != nil {
+		return err
+	}
+	if dataMaxMbps > 0 {
+		rate = int64(dataMaxMbps) * 1000 * 1000 / 8
+	}
+	dataRateLimiter = newRateLimiter(rate)
+	dataRowLimiter = newRateLimiter(int64(dataMaxRowsPerSec))
+	dataStatementLimit, _ = parseByteSize(dataMaxStatementBytes)
+	dataSkipLargerThanBytes = 0
+	if dataSkipLargerThan != "" {
+		dataSkipLargerThanBytes, _ = parseByteSize(dataSkipLargerThan)
+	}
+
+	out, restoreStdout, err := openOutputSink(dataOutput)
+	if err != nil {
+		return err
+	}
+	defer restoreStdout()
+
+	lock, err := acquireOutputLock(ctx, db, filepath.Join("output", sink.Prefix(dataOutput)+".sql"), dataServerLock)
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	dataServer = connectedServer(ctx, db)
+	if err := checkTimestampZone(ctx, db); err != nil {
+		return err
+	}
+	if err := openMetadataCache(dataMetadataCache, fmt.Sprintf("%s:%d", dataHost, dataPort), dataRefreshMetadata); err != nil {
+		return err
+	}
+	defer saveMetadataCache(dataMetadataCache)
+	metadataLockSkipped, sizeSkipped, tableFailures = nil, nil, nil
+	if dataSkipMetadataLocked > 0 {
+		detectMetadataLockSource(ctx, db)
+	}
+	if dataGalera {
+		if err := checkGaleraReady(ctx, db); err != nil {
+			return err
+		}
+	}
+	if dataMaxReplicaLag > 0 {
+		if err := checkReplicaLag(ctx, db, dataMaxReplicaLag); err != nil {
+			return err
+		}
+	}
+	if dataAtGTID != "" {
+		if err := waitForGTID(ctx, db, dataAtGTID, time.Duration(dataAtGTIDTimeout)*time.Second, dataTimeout); err != nil {
+			return err
+		}
+	}
 	fmt.Printf("Data extraction starting...\n\n")
 
+	plan, err := buildExtractionPlan(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if dataPlanOut != "" {
+		file, err := newPlanFile(ctx, db, fmt.Sprintf("%s:%d", dataHost, dataPort), dataFlags, plan)
+		if err != nil {
+			return err
+		}
+		if err := writePlanFile(dataPlanOut, file); err != nil {
+			return err
+		}
+		sum, err := checksum.File(dataPlanOut)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("📋 Wrote the extraction plan for %d tables to %s: about %d rows, %s\n", len(plan), dataPlanOut, file.EstimatedRows, formatBytes(file.EstimatedBytes))
+		fmt.Printf("   Run it as approved with: data --plan %s --plan-sha256 %s\n", dataPlanOut, sum)
+		return nil
+	}
+
+	// Execute extraction
+	if err := executeExtractionPlan(ctx, db, plan); err != nil {
+		return fmt.Errorf("failed to execute extraction: %w", err)
+	}
+
+	if err := publishOutputs(ctx, out, "output", dataOutputFiles()...); err != nil {
+		return err
+	}
+
+	if dataFailOnError && len(tableFailures) > 0 {
+		return fmt.Errorf("%d tables failed or were skipped, see %s", len(tableFailures), failuresFile())
+	}
+
+	fmt.Printf("\nData extraction completed successfully!\n")
+	scripts := scriptFiles(filepath.Join("output", sink.Prefix(dataOutput)+".sql"))
+	if filepath.Base(scripts[len(scripts)-1]) == "manifest.json" {
+		fmt.Printf("Output files: %s (%d scripts)\n", scripts[len(scripts)-1], len(scripts)-1)
+		return nil
+	}
+	if len(scripts) > 1 {
+		fmt.Printf("Output files: %s ... %s (%d parts)\n", filepath.Base(scripts[0]), filepath.Base(scripts[len(scripts)-1]), len(scripts))
+		return nil
+	}
+	fmt.Printf("Output file: %s.sql\n", sink.Prefix(dataOutput))
+	return nil
+}
+
+// buildExtractionPlan returns the tables to extract in order, as recorded
+// in --plan or else planned from the selected databases
+func buildExtractionPlan(ctx context.Context, db *sql.DB) ([]TableExtractionPlan, error) {
+	if dataPlan != nil {
+		if source := fmt.Sprintf("%s:%d", dataHost, dataPort); dataPlan.Source != source {
+			fmt.Printf("⚠️  Warning: plan %s was made on %s, extracting from %s\n", dataPlanFile, dataPlan.Source, source)
+		}
+		plan := dataPlan.planTables()
+		if err := checkExcludedColumns(plan); err != nil {
+			return nil, err
+		}
+		verified := ""
+		if dataPlanChecksum != "" {
+			verified = ", SHA-256 verified"
+		}
+		fmt.Printf("Loaded extraction plan for %d tables from %s (created %s%s)\n", len(plan), dataPlanFile, dataPlan.CreatedAt, verified)
+		return plan, nil
+	}
+
 	// Get databases to extract
-	databases, err := getDatabasesForExtraction(db)
+	databases, err := getDatabasesForExtraction(ctx, db)
 	if err != nil {
-		log.Fatalf("Failed to get databases: %v", err)
+		return nil, fmt.Errorf("failed to get databases: %w", err)
 	}
 
 	if len(databases) == 0 {
-		log.Fatal("No databases found to extract")
+		return nil, fmt.Errorf("no databases found to extract")
 	}
 
 	fmt.Printf("Found %d databases to process\n", len(databases))
 
 	// Create extraction plan
-	plan, err := createExtractionPlan(db, databases)
+	plan, err := createExtractionPlan(ctx, db, databases)
 	if err != nil {
-		log.Fatalf("Failed to create extraction plan: %v", err)
+		return nil, fmt.Errorf("failed to create extraction plan: %w", err)
 	}
+	dataRelationships.warnUnmatched(plan)
 
-	fmt.Printf("Created extraction plan for %d tables\n", len(plan))
+	if dataWhereFile != "" || len(dataWhere) > 0 {
+		var file map[string]string
+		if dataWhereFile != "" {
+			if file, err = loadWhereFile(dataWhereFile); err != nil {
+				return nil, err
+			}
+		}
+		if err := applyWhereClauses(plan, file, dataWhere); err != nil {
+			return nil, err
+		}
+	}
 
-	// Execute extraction
-	if err := executeExtractionPlan(db, plan); err != nil {
-		log.Fatalf("Failed to execute extraction: %v", err)
+	if dataSince != "" {
+		if err := applySince(ctx, db, plan); err != nil {
+			return nil, err
+		}
 	}
 
-	fmt.Printf("\nData extraction completed successfully!\n")
-	fmt.Printf("Output file: %s.sql\n", dataOutput)
+	if len(dataSeeds) > 0 {
+		if plan, err = applySeeds(ctx, db, plan, dataSeeds); err != nil {
+			return nil, fmt.Errorf("failed to resolve seeds: %w", err)
+		}
+		fmt.Printf("Seed rows reach %d tables\n", len(plan))
+	}
+
+	if dataIncludeChildren > 0 {
+		applyIncludeChildren(plan, dataIncludeChildren)
+	}
+
+	if err := checkExcludedColumns(plan); err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Created extraction plan for %d tables\n", len(plan))
+
+	return plan, nil
+}
+
+// dataOutputFiles lists the files of a finished extraction, the SQL script
+// first
+func dataOutputFiles() []string {
+	prefix := sink.Prefix(dataOutput)
+	files := scriptFiles(filepath.Join("output", prefix+".sql"))
+	if dataFormat != "sql" {
+		tables, _ := filepath.Glob(filepath.Join("output", prefix, "*."+tableFileExtension()))
+		files = append(files, tables...)
+	}
+	if len(tableFailures) > 0 {
+		files = append(files, failuresFile())
+	}
+	files = append(files, manifestFile())
+	return append(files, filepath.Join("output", "SHA256SUMS"))
 }
 
-func getDatabasesForExtraction(db *sql.DB) ([]string, error) {
+func getDatabasesForExtraction(ctx context.Context, db *sql.DB) ([]string, error) {
 	var databases []string
 
 	if dataAllDatabases {
 		// Get all databases
-		query := `SELECT SCHEMA_NAME FROM information_schema.SCHEMATA ORDER BY SCHEMA_NAME`
-		rows, err := db.Query(query)
+		rows, err := queryWithRetry(ctx, db, dataMaxRetries, schemataQuery(true))
 		if err != nil {
 			return nil, fmt.Errorf("failed to query databases: %w", err)
 		}
@@ -220,13 +948,7 @@ func getDatabasesForExtraction(db *sql.DB) ([]string, error) {
 		}
 	} else if dataAllUserDatabases {
 		// Get user databases only (exclude system databases)
-		query := `
-			SELECT SCHEMA_NAME 
-			FROM information_schema.SCHEMATA 
-			WHERE SCHEMA_NAME NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
-			ORDER BY SCHEMA_NAME
-		`
-		rows, err := db.Query(query)
+		rows, err := queryWithRetry(ctx, db, dataMaxRetries, schemataQuery(false))
 		if err != nil {
 			return nil, fmt.Errorf("failed to query databases: %w", err)
 		}
@@ -262,22 +984,32 @@ func getDatabasesForExtraction(db *sql.DB) ([]string, error) {
 	// Filter out trash databases
 	finalDatabases := []string{}
 	for _, dbName := range databases {
-		if !isTrashDatabase(dbName) {
-			finalDatabases = append(finalDatabases, dbName)
+		pattern, err := trashPattern(dbName)
+		if err != nil {
+			return nil, err
+		}
+		if pattern != "" {
+			skipTrash("", dbName, pattern)
+			continue
 		}
+		finalDatabases = append(finalDatabases, dbName)
+	}
+	if dataStableOutput {
+		sort.Strings(finalDatabases)
 	}
 
 	return finalDatabases, nil
 }
 
-func createExtractionPlan(db *sql.DB, databases []string) ([]TableExtractionPlan, error) {
+func createExtractionPlan(ctx context.Context, db *sql.DB, databases []string) ([]TableExtractionPlan, error) {
 	var allPlans []TableExtractionPlan
+	planWarnings = nil
 
 	for _, dbName := range databases {
 		fmt.Printf("Analyzing database: %s\n", dbName)
 
 		// Get tables for this database
-		tables, err := getTablesForDatabase(db, dbName)
+		tables, err := getTablesForDatabase(ctx, db, dbName)
 		if err != nil {
 			log.Printf("Warning: Failed to get tables for %s: %v", dbName, err)
 			continue
@@ -286,15 +1018,37 @@ func createExtractionPlan(db *sql.DB, databases []string) ([]TableExtractionPlan
 		// Get foreign key relationships if needed
 		var foreignKeys map[string][]ForeignKeyInfo
 		if !dataNoForeignKeyCheck {
-			foreignKeys, err = getForeignKeyRelationships(db, dbName)
+			foreignKeys, err = getForeignKeyRelationships(ctx, db, dbName)
 			if err != nil {
 				log.Printf("Warning: Failed to get foreign keys for %s: %v", dbName, err)
 			}
+			foreignKeys = mergeRelationships(foreignKeys, dataRelationships.forDatabase(dbName))
+			if dataInferRelationships {
+				inferred, err := inferRelationships(ctx, db, dataMaxRetries, dbName, dataInferSample)
+				if err != nil {
+					log.Printf("Warning: Failed to infer relationships for %s: %v", dbName, err)
+				}
+				foreignKeys = mergeRelationships(foreignKeys, inferred)
+			}
 		}
 
 		// Create extraction plan for each table
 		tablePlans := createTableExtractionPlans(dbName, tables, foreignKeys)
 		allPlans = append(allPlans, tablePlans...)
+
+		// Unindexed foreign keys make parent lookups scan whole tables
+		if len(foreignKeys) > 0 {
+			if err := warnUnindexedForeignKeys(ctx, db, dbName, tablePlans); err != nil {
+				log.Printf("Warning: Failed to check foreign key indexes for %s: %v", dbName, err)
+			}
+		}
+	}
+
+	if dataFixtureTables != nil {
+		var err error
+		if allPlans, err = fixturePlans(allPlans); err != nil {
+			return nil, err
+		}
 	}
 
 	// Sort by dependencies if foreign key checking is enabled
@@ -305,7 +1059,7 @@ func createExtractionPlan(db *sql.DB, databases []string) ([]TableExtractionPlan
 	return allPlans, nil
 }
 
-func getTablesForDatabase(db *sql.DB, dbName string) ([]string, error) {
+func getTablesForDatabase(ctx context.Context, db *sql.DB, dbName string) ([]string, error) {
 	query := `
 		SELECT TABLE_NAME 
 		FROM information_schema.TABLES 
@@ -313,18 +1067,14 @@ func getTablesForDatabase(db *sql.DB, dbName string) ([]string, error) {
 		ORDER BY TABLE_NAME
 	`
 
-	rows, err := db.Query(query, dbName)
+	rows, err := cachedRows(ctx, db, dataMaxRetries, metacache.Key{Kind: metaTables, Database: dbName}, query, dbName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tables: %w", err)
 	}
-	defer rows.Close()
 
 	var tables []string
-	for rows.Next() {
-		var tableName string
-		if err := rows.Scan(&tableName); err != nil {
-			return nil, fmt.Errorf("failed to scan table name: %w", err)
-		}
+	for _, row := range rows {
+		tableName := row[0]
 
 		// Apply include/exclude filters
 		if shouldIncludeTable(tableName) {
@@ -366,7 +1116,7 @@ func matchesPattern(text, pattern string) bool {
 	return strings.Contains(text, strings.ReplaceAll(strings.ReplaceAll(pattern, "^.*", ""), ".*$", ""))
 }
 
-func getForeignKeyRelationships(db *sql.DB, dbName string) (map[string][]ForeignKeyInfo, error) {
+func getForeignKeyRelationships(ctx context.Context, db *sql.DB, dbName string) (map[string][]ForeignKeyInfo, error) {
 	query := `
 		SELECT 
 			CONSTRAINT_NAME,
@@ -380,25 +1130,33 @@ func getForeignKeyRelationships(db *sql.DB, dbName string) (map[string][]Foreign
 		ORDER BY TABLE_NAME, ORDINAL_POSITION
 	`
 
-	rows, err := db.Query(query, dbName)
+	rows, err := cachedRows(ctx, db, dataMaxRetries, metacache.Key{Kind: metaForeignKeys, Database: dbName}, query, dbName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query foreign keys: %w", err)
 	}
-	defer rows.Close()
 
 	foreignKeys := make(map[string][]ForeignKeyInfo)
-	for rows.Next() {
-		var fk ForeignKeyInfo
-		if err := rows.Scan(&fk.ConstraintName, &fk.TableName, &fk.ColumnName, 
-			&fk.RefTableName, &fk.RefColumnName); err != nil {
-			return nil, fmt.Errorf("failed to scan foreign key: %w", err)
-		}
+	for _, row := range rows {
+		fk := ForeignKeyInfo{ConstraintName: row[0], TableName: row[1], ColumnName: row[2], RefTableName: row[3], RefColumnName: row[4]}
 		foreignKeys[fk.TableName] = append(foreignKeys[fk.TableName], fk)
 	}
 
 	return foreignKeys, nil
 }
 
+// matchTablePatterns reports whether any pattern matches db.table or table
+func matchTablePatterns(patterns []string, dbName, tableName string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, dbName+"."+tableName); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, tableName); matched {
+			return true
+		}
+	}
+	return false
+}
+
 func createTableExtractionPlans(dbName string, tables []string, foreignKeys map[string][]ForeignKeyInfo) []TableExtractionPlan {
 	var plans []TableExtractionPlan
 
@@ -420,7 +1178,9 @@ func createTableExtractionPlans(dbName string, tables []string, foreignKeys map[
 		}
 
 		// Set sample size
-		if sampleSize, ok := sampleMap[tableName]; ok {
+		if matchTablePatterns(dataFullTables, dbName, tableName) {
+			plan.SampleSize = 0
+		} else if sampleSize, ok := sampleMap[tableName]; ok {
 			plan.SampleSize = sampleSize
 		} else if dataSamplePercent > 0 {
 			// Will be calculated based on actual row count later
@@ -431,6 +1191,7 @@ func createTableExtractionPlans(dbName string, tables []string, foreignKeys map[
 
 		// Set dependencies
 		if fks, ok := foreignKeys[tableName]; ok {
+			plan.ForeignKeys = fks
 			for _, fk := range fks {
 				// Only add dependency if it's a different table
 				if fk.RefTableName != tableName {
@@ -445,42 +1206,68 @@ func createTableExtractionPlans(dbName string, tables []string, foreignKeys map[
 	return plans
 }
 
+// sortByDependencies orders plans so that referenced tables come first. No
+// order satisfies a foreign key closing a cycle or a self-reference; they are
+// added to the referencing plan's DeferredKeys instead.
 func sortByDependencies(plans []TableExtractionPlan) []TableExtractionPlan {
-	// Simple topological sort for foreign key dependencies
-	// This is a basic implementation - in production you'd want cycle detection
-	
+	index := make(map[string]int, len(plans))
+	for i, plan := range plans {
+		index[plan.DatabaseName+"."+plan.TableName] = i
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	states := make([]int, len(plans))
 	sorted := make([]TableExtractionPlan, 0, len(plans))
-	visited := make(map[string]bool)
-	
-	var visit func(string) 
-	visit = func(tableName string) {
-		if visited[tableName] {
-			return
-		}
-		visited[tableName] = true
-		
-		// Find the plan for this table
-		for _, plan := range plans {
-			if plan.TableName == tableName {
-				// Visit dependencies first
-				for _, dep := range plan.Dependencies {
-					visit(dep)
+	var path []int
+
+	var visit func(int)
+	visit = func(i int) {
+		states[i] = visiting
+		path = append(path, i)
+
+		// Visit dependencies first; one still being visited closes a cycle
+		plan := &plans[i]
+		for _, c := range planConstraints(*plan) {
+			j, ok := index[c.parent]
+			if !ok {
+				if dataForeignKeyChecks {
+					fmt.Printf("⚠️  %s.%s references %s, which is not extracted: with --fk-checks its rows must already exist on the target\n",
+						plan.DatabaseName, plan.TableName, c.parent)
 				}
-				sorted = append(sorted, plan)
-				break
+				continue
+			}
+			if j == i {
+				deferSelfKey(plan, c)
+				continue
+			}
+			switch states[j] {
+			case unvisited:
+				visit(j)
+			case visiting:
+				deferCyclicKey(plans, path, j, plan, c)
 			}
 		}
+
+		path = path[:len(path)-1]
+		states[i] = visited
+		sorted = append(sorted, *plan)
 	}
-	
+
 	// Visit all tables
-	for _, plan := range plans {
-		visit(plan.TableName)
+	for i := range plans {
+		if states[i] == unvisited {
+			visit(i)
+		}
 	}
-	
+
 	return sorted
 }
 
-func executeExtractionPlan(db *sql.DB, plans []TableExtractionPlan) error {
+func executeExtractionPlan(ctx context.Context, db *sql.DB, plans []TableExtractionPlan) error {
 	// Ensure output directory exists
 	outputDir := "output"
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
@@ -488,63 +1275,272 @@ func executeExtractionPlan(db *sql.DB, plans []TableExtractionPlan) error {
 	}
 
 	// Load progress if resuming
-	var completedTables map[string]bool
+	outputFile := filepath.Join(outputDir, fmt.Sprintf("%s.sql", sink.Prefix(dataOutput)))
+	var progress *state.Progress
 	if dataResume != "" {
-		completedTables = loadExtractionProgress()
-		fmt.Printf("Resuming extraction with %d completed tables\n", len(completedTables))
+		var err error
+		progress, err = state.Load("data", dataResume)
+		if err != nil {
+			return fmt.Errorf("cannot resume extraction: %w", err)
+		}
+		if progress.Output != outputFile {
+			return fmt.Errorf("run %s wrote to %s; resume it with the same --output", dataResume, progress.Output)
+		}
+		// Only INSERT output continues a table from its checkpoint
+		if progress.Partial != nil && dataFormat != "sql" {
+			progress.Partial = nil
+		}
+		fmt.Printf("Resuming extraction with %d completed tables\n", len(progress.Completed))
+		if progress.InterruptedAt != nil {
+			fmt.Printf("   (interrupted at %s", progress.InterruptedAt.Local().Format("2006-01-02 15:04:05"))
+			if progress.Partial != nil && progress.Partial.Name == progress.InterruptedIn {
+				fmt.Printf(" during %s, which continues after %d rows", progress.InterruptedIn, progress.Partial.Rows)
+			} else if progress.InterruptedIn != "" {
+				fmt.Printf(" during %s, which is extracted again", progress.InterruptedIn)
+			}
+			fmt.Printf(")\n")
+			progress.InterruptedAt, progress.InterruptedIn = nil, ""
+		}
 	} else {
-		completedTables = make(map[string]bool)
+		runID := snapshot.NewRunID(time.Now())
+		progress = state.New("data", runID, fmt.Sprintf("%s:%d", dataHost, dataPort), outputFile)
+		if err := progress.Save(); err != nil {
+			return fmt.Errorf("failed to save extraction state: %w", err)
+		}
+		fmt.Printf("Extraction run ID: %s (resume with --resume %s)\n", runID, runID)
 	}
 
-	// Create or append to output file
-	outputFile := filepath.Join(outputDir, fmt.Sprintf("%s.sql", dataOutput))
+	// Create or continue the output file. A resumed run drops anything written
+	// after the last completed table or checkpoint, such as the rest of a
+	// partially extracted table.
 	var file *os.File
 	var err error
-	if dataResume != "" && len(completedTables) > 0 {
-		file, err = os.OpenFile(outputFile, os.O_APPEND|os.O_WRONLY, 0644)
-		if err != nil {
-			file, err = os.Create(outputFile)
+	appending := len(progress.Completed) > 0 || progress.Partial != nil
+	var offset int64
+	if appending {
+		offset = progress.Offset()
+		file, err = os.OpenFile(outputFile, os.O_WRONLY, 0644)
+		if err == nil {
+			err = file.Truncate(offset)
+		}
+		if err == nil {
+			_, err = file.Seek(offset, io.SeekStart)
 		}
 	} else {
-		file, err = os.Create(outputFile)
+		err = removeScriptParts(outputFile)
+		if err == nil {
+			file, err = os.Create(outputFile)
+		}
 	}
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %w", err)
+		return fmt.Errorf("failed to open output file: %w", err)
 	}
 	defer file.Close()
 
-	// Write header (only if new file)
-	if dataResume == "" || len(completedTables) == 0 {
-		fmt.Fprintf(file, "-- MariaDB Data Extract\n")
-		fmt.Fprintf(file, "-- Generated on: %s\n", time.Now().Format("2006-01-02 15:04:05"))
-		fmt.Fprintf(file, "-- Source: %s:%d\n\n", dataHost, dataPort)
-
-		// Disable foreign key checks for import
-		fmt.Fprintf(file, "-- Disable foreign key checks for data import\n")
-		fmt.Fprintf(file, "SET FOREIGN_KEY_CHECKS=0;\n\n")
+	// Half of the memory budget buffers output; the other half bounds pending
+	// INSERT batches. A full buffer is flushed synchronously, so a slow disk
+	// slows down row reading instead of growing memory.
+	budget, _ := parseByteSize(dataMemBudget)
+	sum := checksum.NewWriter(file)
+	disk := &timedWriter{w: sum, stage: stageWrite}
+	if dataTargetDSN != "" {
+		if dataTarget, err = openTarget(ctx, dataTargetDSN); err != nil {
+			return err
+		}
+		defer func() {
+			dataTarget.close()
+			dataTarget = nil
+		}()
+		disk.w = io.MultiWriter(sum, dataTarget)
+		fmt.Printf("🎯 Executing the extracted statements on %s\n", dataTarget.addr)
 	}
+	out := bufio.NewWriterSize(disk, int(budget/2))
+	var benchmarks []*tableBenchmark
 
-	// Track progress
+	// Write header (only if new file)
+	if !appending {
+		fmt.Fprintf(out, "-- MariaDB Data Extract\n")
+		if !dataStableOutput {
+			fmt.Fprintf(out, "-- Generated on: %s\n", time.Now().Format("2006-01-02 15:04:05"))
+		}
+		fmt.Fprintf(out, "-- Source: %s:%d\n", dataHost, dataPort)
+		fmt.Fprintf(out, "-- Source flavor: %s (%s)\n", dataServer, dataServer.version)
+		if dataAtGTID != "" {
+			fmt.Fprintf(out, "-- Source at GTID: %s or later\n", dataAtGTID)
+		}
+		if dataTimeZone.name != "" {
+			fmt.Fprintf(out, "-- Source time zone: %s, UTC%s\n", dataTimeZone.name, dataTimeZone.offset)
+		}
+		fmt.Fprintf(out, "\n")
+
+		if dataFormat == "jsonl" {
+			fmt.Fprintf(out, "-- Table data is in %s/*.jsonl, one JSON object per row; this script only lists the files\n\n", sink.Prefix(dataOutput))
+		} else if dataFormat == "clickhouse" {
+			prefix := sink.Prefix(dataOutput)
+			fmt.Fprintf(out, "-- Table data is in %s/*.tsv; load into ClickHouse from the output directory with:\n", prefix)
+			fmt.Fprintf(out, "--   clickhouse-client --multiquery < %s.sql\n\n", prefix)
+		} else if dataFormat != "sql" {
+			prefix := sink.Prefix(dataOutput)
+			fmt.Fprintf(out, "-- Table data is in %s/*.%s; load from the output directory with:\n", prefix, tableFileExtension())
+			fmt.Fprintf(out, "--   mysql --local-infile=1 < %s.sql\n\n", prefix)
+		}
+
+		// Disable foreign key checks for import
+		if dataFormat != "clickhouse" {
+			writeSessionHeader(out)
+		}
+	}
+
+	// Keep sampled child rows consistent with the sampled parents
+	var tracker *fkTracker
+	if dataFKConsistent && !dataNoForeignKeyCheck {
+		tracker = newFKTracker(plans)
+		if tracker != nil && appending {
+			fmt.Printf("⚠️  Tables completed before the resume are not used to filter sampled child rows\n")
+		}
+	}
+
+	// Cyclic foreign keys are set after all tables, from a spool that is
+	// kept next to the output until the run completes. JSON lines are not
+	// loaded by the script, so their rows keep the keys.
+	var spool *updateSpool
+	for _, plan := range plans {
+		if len(plan.DeferredKeys) > 0 && dataFormat != "jsonl" && dataFormat != "clickhouse" {
+			spoolPath := filepath.Join(outputDir, "."+filepath.Base(outputFile)+".deferred")
+			if spool, err = openUpdateSpool(spoolPath, progress.SpoolOffset()); err != nil {
+				return err
+			}
+			break
+		}
+	}
+
+	if dataPartitionByColumn != "" {
+		if dataTenants, err = newTenantOutputs(outputDir, dataPartitionByColumn); err != nil {
+			return err
+		}
+	}
+
+	// Large tables are checkpointed every --chunk-size rows
+	dataCheckpoints = &tableCheckpoints{progress: progress, out: out, sum: sum, base: offset, spool: spool}
+	// rewound is set once a failed table was cut from the output
+	rewound := false
+	defer func() { dataCheckpoints = nil }()
+
+	// Track progress
 	totalTables := len(plans)
 	startTime := time.Now()
-	successCount := len(completedTables)
+	successCount := len(progress.Completed)
 	failCount := 0
+	if dataProgressFormat == "json" {
+		dataProgressEvents = newProgressEvents(os.Stderr)
+		defer func() { dataProgressEvents = nil }()
+	}
+	dataProgressEvents.begin(progress.RunID, totalTables)
+	dataProgressBar = newProgressBar(os.Stdout)
+	defer func() { dataProgressBar = nil }()
+	dataManifest = newExtractManifest(progress, fmt.Sprintf("%s:%d", dataHost, dataPort))
+	defer func() { dataManifest, dataTableSum = nil, nil }()
+
+	if dataWarmCache {
+		warmCache(ctx, db, plans, progress)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	stopMonitor := startMonitor(cancel, time.Duration(dataHeartbeat)*time.Second,
+		time.Duration(dataStallTimeout)*time.Second, dataOnStall == "abort")
+	defer stopMonitor()
+
+	// The first interrupt stops the extraction after the current batch
+	gracefulStop.Store(true)
+	defer gracefulStop.Store(false)
+	var stoppedIn string
 
 	// Execute extraction for each table
 	for i, plan := range plans {
+		if ctx.Err() != nil || stopRequested() {
+			fmt.Printf("\n⚠️  Extraction interrupted before %s.%s\n", plan.DatabaseName, plan.TableName)
+			break
+		}
+
 		tableKey := fmt.Sprintf("%s.%s", plan.DatabaseName, plan.TableName)
 		
 		// Skip if already completed
-		if completedTables[tableKey] {
+		if progress.Done(tableKey) {
 			fmt.Printf("[%d/%d] Skipping %s (already completed)\n", i+1, totalTables, tableKey)
+			dataProgressEvents.endTable(tableKey, "skipped", 0, nil, i+1)
 			continue
 		}
 
+		if err := throttleExtraction(ctx, db); err != nil {
+			fmt.Printf("\n⚠️  Extraction stopped before %s: %v\n", tableKey, err)
+			break
+		}
+
+		if dataSkipMetadataLocked > 0 {
+			lock, err := blockingMetadataLock(ctx, db, plan.DatabaseName, plan.TableName, dataSkipMetadataLocked)
+			if err != nil {
+				fmt.Printf("⚠️  Warning: %v\n", err)
+			} else if lock != nil {
+				fmt.Printf("[%d/%d] ⏭️  Skipping %s: %s\n", i+1, totalTables, tableKey, lock)
+				metadataLockSkipped = append(metadataLockSkipped, tableKey)
+				recordTableSkipped(tableKey, "metadata_locked", lock.String())
+				dataProgressEvents.endTable(tableKey, "skipped", 0, nil, i+1)
+				continue
+			}
+		}
+
+		if dataSkipLargerThanBytes > 0 {
+			size, err := getTableDataLength(ctx, db, plan.DatabaseName, plan.TableName)
+			if err != nil {
+				fmt.Printf("⚠️  Warning: failed to read the size of %s: %v\n", tableKey, err)
+			} else if size > dataSkipLargerThanBytes {
+				detail := fmt.Sprintf("%s of data exceeds --skip-larger-than %s", formatBytes(size), dataSkipLargerThan)
+				fmt.Printf("[%d/%d] ⚠️  Warning: skipping %s: %s\n", i+1, totalTables, tableKey, detail)
+				sizeSkipped = append(sizeSkipped, tableKey)
+				recordTableSkipped(tableKey, "too_large", detail)
+				dataProgressEvents.endTable(tableKey, "skipped", 0, nil, i+1)
+				continue
+			}
+		}
+
 		tableStartTime := time.Now()
+		dataMonitor.startTable(tableKey)
 		fmt.Printf("[%d/%d] Extracting %s.%s", i+1, totalTables, plan.DatabaseName, plan.TableName)
 
-		// Get actual row count
-		rowCount, err := getTableRowCount(db, plan.DatabaseName, plan.TableName)
+		// Continue a table interrupted after a checkpoint
+		if plan.resumeAfter = resumeKey(progress, tableKey); plan.resumeAfter != nil {
+			plan.resumeRows = progress.Partial.Rows
+			fmt.Printf(" (continuing after %d rows)", plan.resumeRows)
+		}
+		dataCheckpoints.begin(tableKey, plan.resumeRows)
+
+		// Tables continued from a checkpoint and split by tenant get no
+		// checksum, as this run does not write all their rows to one output
+		dataTableSum = nil
+		if plan.resumeAfter == nil && dataTenants == nil {
+			dataTableSum = &tableSum{}
+		}
+
+		// Restrict included children to the rows of the extracted parents
+		if plan.IncludedChild {
+			if condition := tracker.parentCondition(plan); condition != "" {
+				if plan.WhereClause != "" {
+					condition = "(" + plan.WhereClause + ") AND " + condition
+				}
+				plan.WhereClause = condition
+			}
+		}
+
+		// Get actual row count. With --fast-count only sampled tables are
+		// counted, since their sample size and strategy are worked out from it.
+		var rowCount int64
+		estimated := dataFastCount && plan.SampleSize == 0
+		if estimated {
+			rowCount, err = getTableRowEstimate(ctx, db, plan.DatabaseName, plan.TableName)
+		} else {
+			rowCount, err = getTableRowCount(ctx, db, plan.DatabaseName, plan.TableName, plan.WhereClause)
+		}
 		if err != nil {
 			log.Printf(" - Warning: Failed to get row count: %v", err)
 			rowCount = 0
@@ -555,31 +1551,113 @@ func executeExtractionPlan(db *sql.DB, plans []TableExtractionPlan) error {
 		if plan.SampleSize < 0 {
 			percentage := -plan.SampleSize
 			plan.SampleSize = (rowCount * int64(percentage)) / 100
+			plan.SampleSize = dataSampleCaps.apply(plan.DatabaseName, plan.TableName, rowCount, plan.SampleSize)
 		}
 
 		// Determine extraction size
 		extractSize := rowCount
+		entry := manifestTable{Table: tableKey, SourceRows: rowCount, Estimated: estimated, Sampling: "none"}
 		if plan.SampleSize > 0 && plan.SampleSize < rowCount {
 			extractSize = plan.SampleSize
-			fmt.Printf(" (sampling %d of %d rows)", extractSize, rowCount)
+			entry.Sampling = dataSampleStrategy
+			if dataSampleStrategy == sampleFirst {
+				fmt.Printf(" (sampling %d of %d rows)", extractSize, rowCount)
+			} else {
+				fmt.Printf(" (sampling %d of %d rows, %s)", extractSize, rowCount, dataSampleStrategy)
+			}
+		} else if estimated {
+			fmt.Printf(" (~%d rows)", rowCount)
 		} else {
 			fmt.Printf(" (%d rows)", rowCount)
 		}
+		dataProgressEvents.startTable(tableKey, i+1, extractSize)
+		dataProgressBar.startTable(extractSize)
+		dataManifest.startTable(out.Buffered())
 
 		// Extract table data
-		if err := extractTableData(db, file, plan); err != nil {
+		var bench *tableBenchmark
+		if dataBenchmark {
+			bench = &tableBenchmark{table: tableKey}
+			benchmarks = append(benchmarks, bench)
+		}
+		disk.bench = bench
+		spoolMark, err := spool.offset()
+		if flushErr := out.Flush(); err == nil {
+			err = flushErr
+		}
+		outputMark := offset + sum.Size()
+		var rows int64
+		if err == nil {
+			rows, err = extractTableData(ctx, db, out, plan, tracker, spool, budget/2, bench)
+		}
+		if flushErr := out.Flush(); err == nil {
+			err = flushErr
+		}
+		var spoolOffset int64
+		if err == nil {
+			spoolOffset, err = spool.offset()
+		}
+		if errors.Is(err, errStopped) {
+			dataProgressBar.end()
+			fmt.Printf(" - Stopped after %d rows\n", rows)
+			// The spool is kept: a resume cuts it back to the checkpoint
+			fmt.Fprintf(out, "\n-- Extraction interrupted during %s\n", tableKey)
+			dataProgressEvents.endTable(tableKey, "stopped", rows, nil, i+1)
+			dataManifest.endTable(entry, "stopped", rows, nil, tableStartTime, out.Buffered())
+			stoppedIn = tableKey
+			break
+		}
+		if err != nil {
+			dataProgressBar.end()
 			fmt.Printf(" - Failed: %v\n", err)
 			failCount++
+			recordTableFailure(ctx, tableKey, err)
+			dataProgressEvents.endTable(tableKey, "failed", rows, err, i+1)
+			dataManifest.endTable(entry, "failed", rows, err, tableStartTime, out.Buffered())
+			// The output cannot continue past a statement the target refused
+			if err := dataTarget.failure(); err != nil {
+				return err
+			}
+			if err := spool.truncate(spoolMark); err != nil {
+				return fmt.Errorf("failed to reset deferred key spool: %w", err)
+			}
+			// Drop the rows written before the failure, so a resume does not
+			// leave them in the middle of the file when later tables complete
+			out.Reset(disk)
+			if err := file.Truncate(outputMark); err != nil {
+				return fmt.Errorf("failed to reset output file: %w", err)
+			}
+			if _, err := file.Seek(outputMark, io.SeekStart); err != nil {
+				return fmt.Errorf("failed to reset output file: %w", err)
+			}
+			offset = outputMark - sum.Size()
+			dataCheckpoints.base = offset
+			rewound = true
+			// A failed table is extracted again on resume
+			if progress.Partial != nil {
+				progress.Partial = nil
+				if err := progress.Save(); err != nil {
+					log.Printf("Warning: failed to save extraction progress: %v", err)
+				}
+			}
 			// Continue with next table even if one fails
 			continue
 		}
 
 		// Mark as completed
 		successCount++
-		saveExtractionProgress(tableKey)
+		if err := progress.CompleteItem(state.Item{Name: tableKey, Offset: offset + sum.Size(), Spool: spoolOffset, Rows: rows}); err != nil {
+			log.Printf("Warning: failed to save extraction progress: %v", err)
+		}
 
 		duration := time.Since(tableStartTime)
+		dataProgressBar.end()
 		fmt.Printf(" - Completed in %v\n", duration.Round(time.Millisecond))
+		dataProgressEvents.endTable(tableKey, "completed", rows, nil, i+1)
+		if dataTableSum != nil {
+			entry.Checksum, entry.Columns = dataTableSum.String(), dataTableSum.columns
+		}
+		dataManifest.endTable(entry, "completed", rows, nil, tableStartTime, out.Buffered())
 
 		// Show overall progress
 		elapsed := time.Since(startTime)
@@ -590,139 +1668,1031 @@ func executeExtractionPlan(db *sql.DB, plans []TableExtractionPlan) error {
 			i+1, totalTables, elapsed.Round(time.Second), eta.Round(time.Second))
 	}
 
+	// Set the cyclic foreign keys now that every referenced row exists
+	if spool != nil {
+		fmt.Fprintf(out, "\n-- Set circular foreign keys written as NULL above\n")
+		written, skipped, err := spool.writeTo(out, tracker)
+		if err != nil {
+			spool.close()
+			return fmt.Errorf("failed to write deferred foreign keys: %w", err)
+		}
+		fmt.Printf("🔁 Wrote %d UPDATE statements for circular foreign keys", written)
+		if skipped > 0 {
+			fmt.Printf(" (%d skipped: referenced rows were not extracted)", skipped)
+		}
+		fmt.Printf("\n")
+		if failCount == 0 && ctx.Err() == nil && !stopRequested() {
+			spool.remove()
+		} else {
+			spool.close()
+		}
+	}
+
 	// Re-enable foreign key checks
-	fmt.Fprintf(file, "\n-- Re-enable foreign key checks\n")
-	fmt.Fprintf(file, "SET FOREIGN_KEY_CHECKS=1;\n")
+	if dataFormat != "clickhouse" {
+		writeSessionFooter(out)
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+	if err := dataTarget.finish(); err != nil {
+		return err
+	}
+	if err := dataTenants.finish(outputDir); err != nil {
+		return err
+	}
+
+	// A resumed extraction only saw the appended part, and a failed table
+	// was cut from what was hashed, so hash the whole file
+	if appending || rewound {
+		_, err = checksum.RecordFiles(outputDir, outputFile)
+	} else {
+		err = recordChecksum(outputFile, sum.Sum())
+	}
+	if err != nil {
+		return err
+	}
+
+	// Only a finished file is split; a resumed run appends to the whole file
+	if splitSize, _ := parseSplitSize(dataSplitSize); splitSize > 0 && ctx.Err() == nil && !stopRequested() {
+		file.Close()
+		parts, err := splitScript(outputFile, splitSize)
+		if err != nil {
+			return err
+		}
+		if len(parts) > 1 {
+			fmt.Printf("✂️  Split %s into %d files of at most %s\n", outputFile, len(parts), formatBytes(splitSize))
+		}
+	}
+	if dataSplitOut != "" && ctx.Err() == nil && !stopRequested() {
+		file.Close()
+		files, err := splitScriptByUnit(outputFile, dataSplitOut, fmt.Sprintf("%s:%d", dataHost, dataPort), progress.Rows())
+		if err != nil {
+			return err
+		}
+		if len(files) > 1 {
+			fmt.Printf("✂️  Split %s into %d %s scripts listed in %s\n", outputFile, len(files)-1, strings.TrimPrefix(dataSplitOut, "per-"), files[len(files)-1])
+		}
+	}
 
 	totalDuration := time.Since(startTime)
 	fmt.Printf("\nExtraction Summary:\n")
 	fmt.Printf("  Total tables: %d\n", totalTables)
 	fmt.Printf("  Successful: %d\n", successCount)
 	fmt.Printf("  Failed: %d\n", failCount)
+	printTrashSkipped("  ")
+	if dataSkipMetadataLocked > 0 {
+		printMetadataLockSkipped("  ")
+	}
+	if dataSkipLargerThanBytes > 0 {
+		fmt.Printf("  Skipped (larger than %s): %d", dataSkipLargerThan, len(sizeSkipped))
+		if len(sizeSkipped) > 0 {
+			fmt.Printf(" (%s)", strings.Join(sizeSkipped, ", "))
+		}
+		fmt.Println()
+	}
 	fmt.Printf("  Total time: %v\n", totalDuration.Round(time.Second))
 
+	if dataBenchmark {
+		printBenchmarkReport(benchmarks)
+	}
+
+	if err := writeFailures(); err != nil {
+		return err
+	}
+
+	status := "completed"
+	switch {
+	case stopRequested() || ctx.Err() != nil:
+		status = "interrupted"
+	case failCount > 0:
+		status = "failed"
+	}
+	if err := dataManifest.write(status); err != nil {
+		return err
+	}
+	dataProgressEvents.finish(status, successCount, failCount)
+
+	// Record where the run stopped, so the state shows it was interrupted
+	if stopRequested() {
+		if err := progress.Interrupt(stoppedIn); err != nil {
+			log.Printf("Warning: failed to save extraction progress: %v", err)
+		}
+		fmt.Printf("\n⏸️  Extraction stopped with %d of %d tables completed; resume with --resume %s\n", successCount, totalTables, progress.RunID)
+		if ctx.Err() == nil {
+			return fmt.Errorf("extraction interrupted: %w", errStopped)
+		}
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("extraction interrupted: %w", context.Cause(ctx))
+	}
+
 	return nil
 }
 
-// Progress tracking functions
-func loadExtractionProgress() map[string]bool {
-	progressFile := dataOutput + ".progress"
-	completedTables := make(map[string]bool)
+// writeSessionHeader writes the session settings a script loads its data
+// under: foreign key checks off, and the SQL mode and time zone the values
+// need
+func writeSessionHeader(w io.Writer) {
+	if dataForeignKeyChecks {
+		fmt.Fprintf(w, "-- Foreign key checks stay on: tables are in dependency order and\n")
+		fmt.Fprintf(w, "-- circular and self-referencing keys are set by UPDATEs at the end\n\n")
+	} else {
+		fmt.Fprintf(w, "-- Disable foreign key checks for data import\n")
+		fmt.Fprintf(w, "SET FOREIGN_KEY_CHECKS=0;\n\n")
+	}
+	if dataZeroDates == "keep" {
+		fmt.Fprintf(w, "-- Accept zero dates kept by --zero-dates keep under strict SQL modes\n")
+		fmt.Fprintf(w, "SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO';\n\n")
+	}
+	if header := timeZoneHeader(dataTimeZone); header != "" {
+		fmt.Fprintf(w, "-- Interpret TIMESTAMP values in the time zone they were read in\n")
+		fmt.Fprintf(w, "%s\n\n", header)
+	}
+}
 
-	if data, err := os.ReadFile(progressFile); err == nil {
-		lines := strings.Split(string(data), "\n")
-		for _, line := range lines {
-			if line = strings.TrimSpace(line); line != "" {
-				completedTables[line] = true
-			}
+// writeSessionFooter restores the session settings of writeSessionHeader
+func writeSessionFooter(w io.Writer) {
+	if dataZeroDates == "keep" {
+		fmt.Fprintf(w, "\nSET SQL_MODE=@OLD_SQL_MODE;\n")
+	}
+	if timeZoneHeader(dataTimeZone) != "" {
+		fmt.Fprintf(w, "\nSET TIME_ZONE=@OLD_TIME_ZONE;\n")
+	}
+	if !dataForeignKeyChecks {
+		fmt.Fprintf(w, "\n-- Re-enable foreign key checks\n")
+		fmt.Fprintf(w, "SET FOREIGN_KEY_CHECKS=1;\n")
+	}
+}
+
+// throttleExtraction is called between tables and every --chunk-size rows
+// and blocks while the source should not be loaded further
+func throttleExtraction(ctx context.Context, db *sql.DB) error {
+	if dataGalera {
+		if err := waitForGaleraFlowControl(ctx, db, int64(dataGaleraMaxQueue)); err != nil {
+			return err
 		}
 	}
+	if dataMaxReplicaLag > 0 {
+		return waitForReplicaLag(ctx, db, dataMaxReplicaLag)
+	}
+	return nil
+}
+
+// showCreateTable returns the CREATE TABLE statement of a table
+func showCreateTable(ctx context.Context, db *sql.DB, dbName, tableName string) (string, error) {
+	query := fmt.Sprintf("SHOW CREATE TABLE `%s`.`%s`", dbName, tableName)
+	ctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
 
-	return completedTables
+	var table, createTable string
+	if err := queryRowWithRetry(ctx, db, dataMaxRetries, query, nil, &table, &createTable); err != nil {
+		return "", fmt.Errorf("failed to get table definition: %w", err)
+	}
+	if dataStableOutput {
+		// The counter moves with deleted rows and failed inserts
+		createTable = autoIncrementOption.ReplaceAllString(createTable, "")
+	}
+	return createTable, nil
 }
 
-func saveExtractionProgress(tableKey string) {
-	progressFile := dataOutput + ".progress"
-	
-	// Read existing progress
-	completedTables := loadExtractionProgress()
-	completedTables[tableKey] = true
+// autoIncrementOption matches the AUTO_INCREMENT table option of SHOW CREATE
+// TABLE
+var autoIncrementOption = regexp.MustCompile(` AUTO_INCREMENT=\d+`)
 
-	// Write back to file
-	var lines []string
-	for table := range completedTables {
-		lines = append(lines, table)
+// getTableRowCount counts the rows of a table, or only those matching where
+// when it is not empty
+func getTableRowCount(ctx context.Context, db *sql.DB, dbName, tableName, where string) (int64, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`", dbName, tableName)
+	if where != "" {
+		query += " WHERE " + where
 	}
-	sort.Strings(lines)
+	ctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
 
-	data := strings.Join(lines, "\n") + "\n"
-	os.WriteFile(progressFile, []byte(data), 0644)
+	var count int64
+	err := queryRowWithRetry(ctx, db, dataMaxRetries, query, nil, &count)
+	return count, err
 }
 
-func getTableRowCount(db *sql.DB, dbName, tableName string) (int64, error) {
-	query := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`", dbName, tableName)
+// getTableRowEstimate returns information_schema's row count of a table,
+// which is exact for MyISAM but only an estimate for InnoDB
+func getTableRowEstimate(ctx context.Context, db *sql.DB, dbName, tableName string) (int64, error) {
+	ctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+
 	var count int64
-	err := db.QueryRow(query).Scan(&count)
+	err := queryRowWithRetry(ctx, db, dataMaxRetries,
+		"SELECT COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+		[]interface{}{dbName, tableName}, &count)
 	return count, err
 }
 
-func extractTableData(db *sql.DB, file *os.File, plan TableExtractionPlan) error {
-	// Write table header
-	fmt.Fprintf(file, "-- Table: %s.%s\n", plan.DatabaseName, plan.TableName)
-	fmt.Fprintf(file, "USE `%s`;\n", plan.DatabaseName)
+// getTableDataLength returns the size of a table's data as information_schema
+// reports it, an estimate for InnoDB that leaves out indexes
+func getTableDataLength(ctx context.Context, db *sql.DB, dbName, tableName string) (int64, error) {
+	ctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+
+	var size int64
+	err := queryRowWithRetry(ctx, db, dataMaxRetries,
+		"SELECT COALESCE(DATA_LENGTH, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+		[]interface{}{dbName, tableName}, &size)
+	return size, err
+}
+
+// extractTableData streams a table as INSERT statements to w and returns the
+// number of rows written. A statement is cut early once its text reaches
+// batchBudget bytes. Rows are filtered for foreign key consistency by tracker
+// and stage timings recorded in bench, both of which may be nil.
+func extractTableData(ctx context.Context, db *sql.DB, w io.Writer, plan TableExtractionPlan, tracker *fkTracker, spool *updateSpool, batchBudget int64, bench *tableBenchmark) (int64, error) {
+	// Fetch the table definition before writing anything for the table
+	var createTable string
+	if dataFormat == "clickhouse" {
+		var err error
+		if createTable, err = clickhouseCreateTable(ctx, db, plan); err != nil {
+			return 0, err
+		}
+	} else if dataWithSchema {
+		var err error
+		if createTable, err = showCreateTable(ctx, db, plan.DatabaseName, plan.TableName); err != nil {
+			return 0, err
+		}
+	}
+
+	// Write table header, which tenant exports repeat
+	var header bytes.Buffer
+	fmt.Fprintf(&header, "-- Table: %s.%s\n", plan.DatabaseName, plan.TableName)
+	if dataFormat == "clickhouse" {
+		// ClickHouse needs the table to load into, so it is always created
+		fmt.Fprintf(&header, "CREATE DATABASE IF NOT EXISTS `%s`;\n", plan.DatabaseName)
+		fmt.Fprintf(&header, "%s\n\n", createTable)
+	} else {
+		if dataWithSchema {
+			fmt.Fprintf(&header, "CREATE DATABASE IF NOT EXISTS `%s`;\n", plan.DatabaseName)
+		}
+		fmt.Fprintf(&header, "USE `%s`;\n", plan.DatabaseName)
+		if dataWithSchema {
+			fmt.Fprintf(&header, "DROP TABLE IF EXISTS `%s`;\n", plan.TableName)
+			fmt.Fprintf(&header, "%s;\n\n", strings.TrimSuffix(strings.TrimSpace(createTable), ";"))
+		}
+	}
+	// A table continued from a checkpoint already has its header
+	if plan.resumeAfter == nil {
+		w.Write(header.Bytes())
+	}
+
+	// Add LIMIT for sampling, unless filtered rows must not count towards it
+	filter := tracker.rowFilter(plan)
+	var limit int64
+	if plan.SampleSize > 0 && plan.SampleSize < plan.RowCount && !filter.filtering() {
+		limit = plan.SampleSize
+	}
 
-	// Build query
-	query := fmt.Sprintf("SELECT * FROM `%s`.`%s`", plan.DatabaseName, plan.TableName)
-	
-	// Add LIMIT for sampling
-	if plan.SampleSize > 0 && plan.SampleSize < plan.RowCount {
-		query += fmt.Sprintf(" LIMIT %d", plan.SampleSize)
+	// Split huge tables into primary key ranges read concurrently. Filtered
+	// and sampled tables are read in one piece, as are TSV files.
+	if dataFormat == "sql" && filter == nil && limit == 0 && len(plan.DeferredKeys) == 0 && dataTenants == nil && plan.resumeAfter == nil {
+		segments, err := planSegments(ctx, db, plan)
+		if err != nil {
+			return 0, err
+		}
+		if len(segments) > 1 {
+			rowCount, err := extractSegments(ctx, db, w, plan, segments, batchBudget)
+			if err != nil {
+				return rowCount, err
+			}
+			if bench != nil {
+				bench.rows = rowCount
+			}
+			fmt.Fprintf(w, "\n")
+			return rowCount, nil
+		}
 	}
 
 	// Execute query
-	rows, err := db.Query(query)
+	queryStart := bench.start()
+	reader, err := openTableReader(ctx, db, plan, limit)
+	bench.track(stageRead, queryStart)
 	if err != nil {
-		return fmt.Errorf("failed to query table data: %w", err)
+		return 0, err
 	}
-	defer rows.Close()
-
-	// Get column information
-	columns, err := rows.Columns()
+	defer reader.close()
+	filter.bind(reader.columns)
+	transforms := tableTransforms(plan, reader.columns)
+	deferred, err := newDeferredKeys(ctx, db, plan, reader, spool)
 	if err != nil {
-		return fmt.Errorf("failed to get columns: %w", err)
+		return 0, err
 	}
 
-	// Prepare scan destinations
-	values := make([]interface{}, len(columns))
-	valuePtrs := make([]interface{}, len(columns))
-	for i := range values {
-		valuePtrs[i] = &values[i]
+	// Tables without the --partition-by-column column are shared by all
+	// tenants
+	if dataTenants != nil && dataTenants.beginTable(header.Bytes(), reader.columns) < 0 && dataFormat == "sql" {
+		w = io.MultiWriter(w, dataTenants.shared())
+	}
+
+	if dataFormat != "sql" {
+		rowCount, err := writeLoadDataTable(ctx, db, w, reader, plan, filter, transforms, deferred, bench)
+		if err == nil {
+			err = dataTenants.endTable()
+		}
+		return rowCount, err
 	}
 
+	// Checkpoint tables whose output holds exactly the rows up to the last
+	// key read. Shuffled rows are held back and written out of order.
+	if filter == nil && limit == 0 && reader.sample == "" && reader.keyIndexes != nil && dataTenants == nil && !plan.IncludedChild && newShuffleWindow(plan, reader.columns) == nil {
+		reader.checkpoints = dataCheckpoints
+	}
+
+	rowCount, err := writeInsertRows(ctx, db, w, reader, plan, filter, transforms, deferred, batchBudget, bench)
+	rowCount += plan.resumeRows
+	if err == nil {
+		err = dataTenants.endTable()
+	}
+	if err != nil {
+		return rowCount, err
+	}
+	if bench != nil {
+		bench.rows = rowCount
+	}
+	filter.finish()
+	fmt.Print(filter.summary())
+
+	fmt.Fprintf(w, "\n")
+	return rowCount, nil
+}
+
+// writeInsertRows writes the rows of reader to w as batched INSERT
+// statements and returns how many were written
+func writeInsertRows(ctx context.Context, db *sql.DB, w io.Writer, reader *tableReader, plan TableExtractionPlan, filter *fkRowFilter, transforms transform.Row, deferred *deferredKeys, batchBudget int64, bench *tableBenchmark) (int64, error) {
+	columns, values := reader.columns, reader.values
+
 	// Process rows in batches
+	var batch bytes.Buffer
 	batchCount := 0
 	rowCount := 0
-	var batchValues []string
+	// oversized is set once a single row was too large for a statement
+	oversized := false
 
-	for rows.Next() {
-		if err := rows.Scan(valuePtrs...); err != nil {
-			return fmt.Errorf("failed to scan row: %w", err)
+	flushBatch := func() error {
+		if batchCount == 0 {
+			return nil
 		}
+		batch.WriteString(";\n")
+		_, err := w.Write(batch.Bytes())
+		batch.Reset()
+		batchCount = 0
+		return err
+	}
 
+	rowValues := make([]string, len(columns))
+	tenantColumn := dataTenants.tenantColumn()
+	window := newShuffleWindow(plan, columns)
+	dataTableSum.begin(columns)
+
+	// emit writes a row once it is transformed, and masked when shuffled
+	emit := func(values []interface{}, tenant string, routed bool) error {
 		// Convert row to SQL values
-		rowValues := make([]string, len(columns))
+		convertStart := bench.start()
+		dataTableSum.add(values, reader.precisions)
+		if err := deferred.capture(values); err != nil {
+			return err
+		}
 		for i, v := range values {
-			rowValues[i] = formatSQLValue(v)
+			rowValues[i] = formatSQLColumnValue(v, reader.kinds[i], reader.precisions[i])
 		}
-
-		batchValues = append(batchValues, fmt.Sprintf("(%s)", strings.Join(rowValues, ",")))
+		bench.track(stageConvert, convertStart)
+
+		// Start a new statement when the row would take this one past
+		// --max-statement-bytes, counting its parentheses, commas, the
+		// separator and the closing ";\n"
+		formatStart := bench.start()
+		rowSize := int64(len(rowValues) + 1)
+		for _, v := range rowValues {
+			rowSize += int64(len(v))
+		}
+		if batchCount > 0 && int64(batch.Len())+2+rowSize+2 > dataStatementLimit {
+			if err := flushBatch(); err != nil {
+				return fmt.Errorf("failed to write batch: %w", err)
+			}
+		}
+		if batchCount == 0 {
+			fmt.Fprintf(&batch, "INSERT INTO `%s`%s VALUES\n", plan.TableName, insertColumnList(reader))
+			if int64(batch.Len())+rowSize+2 > dataStatementLimit && !oversized {
+				oversized = true
+				fmt.Printf(" - Warning: a row of %s exceeds --max-statement-bytes", formatBytes(rowSize))
+			}
+		} else {
+			batch.WriteString(",\n")
+		}
+		batch.WriteByte('(')
+		for i, v := range rowValues {
+			if i > 0 {
+				batch.WriteByte(',')
+			}
+			batch.WriteString(v)
+		}
+		batch.WriteByte(')')
 		batchCount++
 		rowCount++
+		if routed {
+			if err := dataTenants.insertRow(tenant, plan.TableName, insertColumnList(reader), rowValues); err != nil {
+				return err
+			}
+		}
+		bench.track(stageFormat, formatStart)
 
 		// Write batch if full
-		if batchCount >= dataBatchSize {
-			fmt.Fprintf(file, "INSERT INTO `%s` VALUES\n%s;\n", 
-				plan.TableName, strings.Join(batchValues, ",\n"))
-			batchValues = nil
-			batchCount = 0
+		if batchCount >= dataBatchSize || int64(batch.Len()) >= batchBudget || int64(batch.Len())+2 >= dataStatementLimit {
+			if err := flushBatch(); err != nil {
+				return fmt.Errorf("failed to write batch: %w", err)
+			}
 		}
 
-		// Show progress
-		if rowCount%dataProgressInterval == 0 {
-			fmt.Printf(".")
+		if rowCount%dataChunkSize == 0 {
+			if reader.checkpoints != nil {
+				if err := flushBatch(); err != nil {
+					return fmt.Errorf("failed to write batch: %w", err)
+				}
+				if err := reader.checkpoints.save(reader, int64(rowCount)); err != nil {
+					return err
+				}
+			}
+			if err := throttleExtraction(ctx, db); err != nil {
+				return err
+			}
 		}
+		return nil
+	}
+
+	for !filter.full() {
+		// After an interrupt, end the statement being built and stop
+		if stopRequested() {
+			if err := window.flush(emit); err != nil {
+				return int64(rowCount), err
+			}
+			if err := flushBatch(); err != nil {
+				return int64(rowCount), fmt.Errorf("failed to write batch: %w", err)
+			}
+			if err := reader.checkpoints.save(reader, int64(rowCount)); err != nil {
+				return int64(rowCount), err
+			}
+			return int64(rowCount), errStopped
+		}
+
+		readStart := bench.start()
+		ok, err := reader.next()
+		bench.track(stageRead, readStart)
+		if err != nil {
+			return int64(rowCount), err
+		}
+		if !ok {
+			break
+		}
+		if !filter.accept(values) {
+			continue
+		}
+
+		// Route by the tenant value before it can be masked
+		var tenant string
+		routed := false
+		if tenantColumn >= 0 {
+			tenant, routed = dataTenants.tenant(values[tenantColumn])
+		}
+
+		convertStart := bench.start()
+		transforms.Apply(values)
+		bench.track(stageConvert, convertStart)
+		if err := window.add(values, tenant, routed, emit); err != nil {
+			return int64(rowCount), err
+		}
+	}
+	if err := window.flush(emit); err != nil {
+		return int64(rowCount), err
 	}
 
 	// Write remaining batch
-	if batchCount > 0 {
-		fmt.Fprintf(file, "INSERT INTO `%s` VALUES\n%s;\n", 
-			plan.TableName, strings.Join(batchValues, ",\n"))
+	if err := flushBatch(); err != nil {
+		return int64(rowCount), fmt.Errorf("failed to write batch: %w", err)
+	}
+	return int64(rowCount), nil
+}
+
+// writeLoadDataTable writes the rows to a per-table file and a LOAD DATA
+// LOCAL INFILE statement for it to w, and returns how many were written. The
+// loaddata format is a TSV file in LOAD DATA's default format: tab-separated,
+// backslash-escaped, \N for NULL. The csv format is RFC 4180 CSV with a
+// header row, see formatCSVValue. The clickhouse format writes the same TSV,
+// which ClickHouse reads as TabSeparated, and an INSERT ... FROM INFILE.
+func writeLoadDataTable(ctx context.Context, db *sql.DB, w io.Writer, reader *tableReader, plan TableExtractionPlan, filter *fkRowFilter, transforms transform.Row, deferred *deferredKeys, bench *tableBenchmark) (int64, error) {
+	columns, values := reader.columns, reader.values
+	outputDir := "output"
+	csv, jsonl := dataFormat == "csv", dataFormat == "jsonl"
+	relPath := filepath.ToSlash(filepath.Join(sink.Prefix(dataOutput), fmt.Sprintf("%s.%s.%s", plan.DatabaseName, plan.TableName, tableFileExtension())))
+	path := filepath.Join(outputDir, relPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create table file directory: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create table file: %w", err)
+	}
+	defer file.Close()
+	sum := checksum.NewWriter(file)
+	tsv := bufio.NewWriterSize(&timedWriter{w: sum, stage: stageWrite, bench: bench}, 1<<20)
+
+	if csv {
+		for i, col := range columns {
+			if i > 0 {
+				tsv.WriteByte(',')
+			}
+			tsv.WriteString(csvQuote(col))
+		}
+		tsv.WriteByte('\n')
+	}
+
+	rowCount := 0
+	tenantColumn := dataTenants.tenantColumn()
+	window := newShuffleWindow(plan, columns)
+	hexed := !jsonl && dataFormat != "clickhouse"
+	dataTableSum.begin(columns)
+
+	// emit writes a row once it is transformed, and masked when shuffled
+	emit := func(values []interface{}, tenant string, routed bool) error {
+		formatStart := bench.start()
+		dataTableSum.add(values, reader.precisions)
+		if err := deferred.capture(values); err != nil {
+			return err
+		}
+		if hexed {
+			hexLoadDataValues(values, reader.kinds)
+		}
+		for i, v := range values {
+			if jsonl {
+				writeJSONField(tsv, i, columns[i], v, reader.kinds[i])
+				continue
+			}
+			if csv {
+				if i > 0 {
+					tsv.WriteByte(',')
+				}
+				tsv.WriteString(formatCSVValue(v))
+				continue
+			}
+			if i > 0 {
+				tsv.WriteByte('\t')
+			}
+			tsv.WriteString(formatTSVValue(v))
+		}
+		if jsonl {
+			tsv.WriteByte('}')
+		}
+		if err := tsv.WriteByte('\n'); err != nil {
+			return fmt.Errorf("failed to write table file: %w", err)
+		}
+		if routed {
+			if err := dataTenants.writeCSVRow(tenant, filepath.Base(path), columns, values); err != nil {
+				return err
+			}
+		}
+		rowCount++
+		bench.track(stageFormat, formatStart)
+
+		if rowCount%dataChunkSize == 0 {
+			if err := throttleExtraction(ctx, db); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var stopped error
+	for !filter.full() {
+		// After an interrupt, finish the file with the rows read so far
+		if stopRequested() {
+			stopped = errStopped
+			break
+		}
+
+		readStart := bench.start()
+		ok, err := reader.next()
+		bench.track(stageRead, readStart)
+		if err != nil {
+			return int64(rowCount), err
+		}
+		if !ok {
+			break
+		}
+		if !filter.accept(values) {
+			continue
+		}
+
+		var tenant string
+		routed := false
+		if tenantColumn >= 0 {
+			tenant, routed = dataTenants.tenant(values[tenantColumn])
+		}
+
+		convertStart := bench.start()
+		transforms.Apply(values)
+		bench.track(stageConvert, convertStart)
+		if err := window.add(values, tenant, routed, emit); err != nil {
+			return int64(rowCount), err
+		}
+	}
+	if err := window.flush(emit); err != nil {
+		return int64(rowCount), err
+	}
+	if err := tsv.Flush(); err != nil {
+		return int64(rowCount), fmt.Errorf("failed to write table file: %w", err)
+	}
+	if bench != nil {
+		bench.rows = int64(rowCount)
+	}
+	if err := checksum.Record(outputDir, map[string]string{path: sum.Sum()}); err != nil {
+		return int64(rowCount), fmt.Errorf("failed to record checksum: %w", err)
+	}
+	filter.finish()
+	fmt.Print(filter.summary())
+
+	if jsonl {
+		fmt.Fprintf(w, "-- Data: %s (%d rows)\n\n", relPath, rowCount)
+		return int64(rowCount), stopped
+	}
+	if dataFormat == "clickhouse" {
+		fmt.Fprintf(w, "INSERT INTO `%s`.`%s`%s FROM INFILE '%s' FORMAT TabSeparated;\n\n",
+			plan.DatabaseName, plan.TableName, insertColumnList(reader), strings.ReplaceAll(relPath, "'", "\\'"))
+		return int64(rowCount), stopped
+	}
+
+	writeLoadDataStatement(w, relPath, plan.TableName, columns, reader.kinds)
+	if err := dataTenants.loadTableFiles(plan.TableName, filepath.Base(path), columns, reader.kinds); err != nil {
+		return int64(rowCount), err
+	}
+	return int64(rowCount), stopped
+}
+
+// writeLoadDataStatement writes the LOAD DATA LOCAL INFILE statement loading
+// the loaddata, tsv or csv table file at relPath into table. Binary and
+// spatial columns, which the file holds hex-encoded, are read into variables
+// and decoded by the SET clause.
+func writeLoadDataStatement(w io.Writer, relPath, table string, columns []string, kinds []columnKind) {
+	fields := make([]string, len(columns))
+	var set []string
+	for i, col := range columns {
+		variable := fmt.Sprintf("@hex%d", i+1)
+		switch kinds[i] {
+		case binaryColumn:
+			fields[i] = variable
+			set = append(set, fmt.Sprintf("`%s` = UNHEX(%s)", col, variable))
+		case spatialColumn:
+			// The server's format is a 4-byte little-endian SRID and the WKB
+			fields[i] = variable
+			srid := fmt.Sprintf("CONV(CONCAT(SUBSTRING(%[1]s, 7, 2), SUBSTRING(%[1]s, 5, 2), SUBSTRING(%[1]s, 3, 2), SUBSTRING(%[1]s, 1, 2)), 16, 10)", variable)
+			set = append(set, fmt.Sprintf("`%s` = ST_GeomFromWKB(UNHEX(SUBSTRING(%s, 9)), %s)", col, variable, srid))
+		default:
+			fields[i] = "`" + col + "`"
+		}
 	}
+	fmt.Fprintf(w, "LOAD DATA LOCAL INFILE '%s' INTO TABLE `%s`\n", strings.ReplaceAll(relPath, "'", "\\'"), table)
+	fmt.Fprintf(w, "  CHARACTER SET utf8mb4\n")
+	if dataFormat == "csv" {
+		fmt.Fprintf(w, "  FIELDS TERMINATED BY ',' OPTIONALLY ENCLOSED BY '\"' ESCAPED BY ''\n")
+		fmt.Fprintf(w, "  LINES TERMINATED BY '\\n'\n")
+		fmt.Fprintf(w, "  IGNORE 1 LINES\n")
+	} else {
+		fmt.Fprintf(w, "  FIELDS TERMINATED BY '\\t' ESCAPED BY '\\\\'\n")
+		fmt.Fprintf(w, "  LINES TERMINATED BY '\\n'\n")
+	}
+	fmt.Fprintf(w, "  (%s)", strings.Join(fields, ", "))
+	if len(set) > 0 {
+		fmt.Fprintf(w, "\n  SET %s", strings.Join(set, ", "))
+	}
+	fmt.Fprintf(w, ";\n\n")
+}
+
+// hexLoadDataValues hex-encodes the values of binary and spatial columns in
+// place. LOAD DATA reads the file as utf8mb4 text, which would corrupt their
+// bytes; writeLoadDataStatement decodes them again.
+func hexLoadDataValues(values []interface{}, kinds []columnKind) {
+	for i, v := range values {
+		if b, ok := v.([]byte); ok && (kinds[i] == binaryColumn || kinds[i] == spatialColumn) {
+			values[i] = hex.EncodeToString(b)
+		}
+	}
+}
 
-	fmt.Fprintf(file, "\n")
+// tableTransforms returns the --transforms of a table followed by its
+// --mask-config masks, warning about named columns the table does not have.
+// --encrypted-columns are left untouched.
+func tableTransforms(plan TableExtractionPlan, columns []string) transform.Row {
+	row, missing := dataTransforms.Row(plan.DatabaseName, plan.TableName, columns)
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		fmt.Printf(" - Warning: transformed columns not found: %s", strings.Join(missing, ", "))
+	}
+	masks, missing := dataMasks.Row(plan.DatabaseName, plan.TableName, columns)
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		fmt.Printf(" - Warning: masked columns not found: %s", strings.Join(missing, ", "))
+	}
+	row = row.Then(masks)
+
+	// Ciphertext would only be corrupted
+	var encrypted []string
+	for i, col := range columns {
+		if row != nil && row[i] != nil && encryptedColumn(dataEncryptedColumns, plan.DatabaseName, plan.TableName, col) {
+			row[i] = nil
+			encrypted = append(encrypted, col)
+		}
+	}
+	if len(encrypted) > 0 {
+		fmt.Printf(" - Warning: not transforming or masking encrypted columns: %s", strings.Join(encrypted, ", "))
+	}
+	return row
+}
+
+// shuffleWindow holds up to mask.ShufflePool transformed rows of a table,
+// so that --mask-config shuffle rules can permute column values among them
+// before they are written. A nil window passes rows straight through.
+type shuffleWindow struct {
+	columns []int
+	rows    [][]interface{}
+	tenants []string
+	routed  []bool
+}
+
+// newShuffleWindow returns the window of a table, or nil when none of its
+// columns is shuffled. Like tableTransforms, it leaves --encrypted-columns
+// untouched.
+func newShuffleWindow(plan TableExtractionPlan, columns []string) *shuffleWindow {
+	var shuffled []int
+	for _, idx := range dataMasks.Shuffled(plan.DatabaseName, plan.TableName, columns) {
+		if !encryptedColumn(dataEncryptedColumns, plan.DatabaseName, plan.TableName, columns[idx]) {
+			shuffled = append(shuffled, idx)
+		}
+	}
+	if len(shuffled) == 0 {
+		return nil
+	}
+	return &shuffleWindow{columns: shuffled}
+}
+
+// add passes a row with its tenant to emit, holding it until the window is
+// full when columns are shuffled
+func (w *shuffleWindow) add(values []interface{}, tenant string, routed bool, emit func([]interface{}, string, bool) error) error {
+	if w == nil {
+		return emit(values, tenant, routed)
+	}
+	w.rows = append(w.rows, append([]interface{}(nil), values...))
+	w.tenants = append(w.tenants, tenant)
+	w.routed = append(w.routed, routed)
+	if len(w.rows) < mask.ShufflePool {
+		return nil
+	}
+	return w.flush(emit)
+}
+
+// flush shuffles the rows held and passes them to emit
+func (w *shuffleWindow) flush(emit func([]interface{}, string, bool) error) error {
+	if w == nil || len(w.rows) == 0 {
+		return nil
+	}
+	mask.Shuffle(w.rows, w.columns)
+	for i, row := range w.rows {
+		if err := emit(row, w.tenants[i], w.routed[i]); err != nil {
+			return err
+		}
+	}
+	w.rows, w.tenants, w.routed = w.rows[:0], w.tenants[:0], w.routed[:0]
 	return nil
 }
 
+// tsvEscaper escapes the characters LOAD DATA treats specially
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r", "\x00", "\\0")
+
+func formatTSVValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "\\N"
+	case []byte:
+		return tsvEscaper.Replace(string(val))
+	case string:
+		return tsvEscaper.Replace(val)
+	case time.Time:
+		return formatDateTime(val, -1)
+	case int64:
+		return fmt.Sprintf("%d", val)
+	case float64:
+		return formatFloat(val)
+	case float32:
+		return formatFloat(float64(val))
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	default:
+		return tsvEscaper.Replace(fmt.Sprintf("%v", val))
+	}
+}
+
+// tableFileExtension is the extension of the per-table files of --format
+func tableFileExtension() string {
+	switch dataFormat {
+	case "csv", "jsonl":
+		return dataFormat
+	}
+	return "tsv"
+}
+
+// writeJSONField writes column col of the i-th field of a JSON line, opening
+// the object at the first. Values of binary and spatial columns are always
+// written base64 encoded, whatever their bytes, so consumers can decode them
+// by column; DECIMAL stays a string to keep its precision.
+func writeJSONField(w *bufio.Writer, i int, col string, v interface{}, kind columnKind) {
+	if i == 0 {
+		w.WriteByte('{')
+	} else {
+		w.WriteByte(',')
+	}
+	name, _ := json.Marshal(col)
+	w.Write(name)
+	w.WriteByte(':')
+
+	var value []byte
+	switch val := v.(type) {
+	case nil:
+		value = []byte("null")
+	case []byte:
+		if kind == binaryColumn || kind == spatialColumn {
+			value, _ = json.Marshal(base64.StdEncoding.EncodeToString(val))
+		} else {
+			value, _ = json.Marshal(string(val))
+		}
+	case time.Time:
+		value, _ = json.Marshal(formatDateTime(val, -1))
+	case float64:
+		if math.IsInf(val, 0) || math.IsNaN(val) {
+			value = []byte("null")
+		} else {
+			value, _ = json.Marshal(val)
+		}
+	case string, int64, bool:
+		value, _ = json.Marshal(val)
+	default:
+		value, _ = json.Marshal(fmt.Sprintf("%v", val))
+	}
+	w.Write(value)
+}
+
+// formatCSVValue formats a value for a CSV file. NULL is written as an
+// unquoted NULL, which LOAD DATA with ENCLOSED BY reads back as NULL, while
+// the string "NULL" is quoted to keep it apart.
+func formatCSVValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return csvQuote(string(val))
+	case string:
+		return csvQuote(val)
+	case time.Time:
+		return formatDateTime(val, -1)
+	case int64:
+		return fmt.Sprintf("%d", val)
+	case float64:
+		return formatFloat(val)
+	case float32:
+		return formatFloat(float64(val))
+	case bool:
+		if val {
+			return "1"
+		}
+		return "0"
+	default:
+		return csvQuote(fmt.Sprintf("%v", val))
+	}
+}
+
+// csvQuote quotes a CSV field when it contains a delimiter, quote or line
+// break, is empty or reads as NULL, doubling embedded quotes
+func csvQuote(s string) string {
+	if s != "" && s != "NULL" && !strings.ContainsAny(s, ",\"\r\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// columnKind tells how values of a column are written as SQL literals
+type columnKind int
+
+const (
+	textColumn columnKind = iota
+	// binaryColumn holds binary strings: BINARY, VARBINARY, the BLOB types
+	// and BIT
+	binaryColumn
+	// spatialColumn holds geometries, read in the server's internal format:
+	// a 4-byte little-endian SRID followed by the WKB
+	spatialColumn
+	// dateColumn and datetimeColumn hold dates and dates with times, which
+	// may be zero dates
+	dateColumn
+	datetimeColumn
+)
+
+// columnKinds returns the kind of each column of rows
+func columnKinds(rows *sql.Rows) ([]columnKind, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column types: %w", err)
+	}
+	kinds := make([]columnKind, len(types))
+	for i, t := range types {
+		switch t.DatabaseTypeName() {
+		case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT":
+			kinds[i] = binaryColumn
+		case "GEOMETRY":
+			kinds[i] = spatialColumn
+		case "DATE":
+			kinds[i] = dateColumn
+		case "DATETIME", "TIMESTAMP":
+			kinds[i] = datetimeColumn
+		}
+	}
+	return kinds, nil
+}
+
+// columnPrecisions returns the fractional second digits of each DATETIME
+// and TIMESTAMP column of rows, and -1 for the other columns
+func columnPrecisions(rows *sql.Rows) ([]int, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column types: %w", err)
+	}
+	precisions := make([]int, len(types))
+	for i, t := range types {
+		precisions[i] = -1
+		switch t.DatabaseTypeName() {
+		case "DATETIME", "TIMESTAMP":
+			if digits, _, ok := t.DecimalSize(); ok {
+				precisions[i] = int(digits)
+			}
+		}
+	}
+	return precisions, nil
+}
+
+// formatDateTime formats a date and time with precision fractional second
+// digits, or with the digits t has when precision is negative
+func formatDateTime(t time.Time, precision int) string {
+	switch {
+	case precision < 0:
+		return t.Format("2006-01-02 15:04:05.999999")
+	case precision == 0:
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return t.Format("2006-01-02 15:04:05." + strings.Repeat("0", min(precision, 6)))
+}
+
+// formatSQLColumnValue formats a value of a column. The bytes of binary
+// columns are written as a hex literal so they are not reinterpreted in the
+// connection character set on import, and geometries as their WKB with
+// their SRID, which every spatial type round-trips through. Dates and times
+// keep the column's fractional second digits.
+func formatSQLColumnValue(v interface{}, kind columnKind, precision int) string {
+	if t, ok := v.(time.Time); ok && !t.IsZero() && precision >= 0 {
+		return "'" + formatDateTime(t, precision) + "'"
+	}
+	val, ok := v.([]byte)
+	if !ok {
+		return formatSQLValue(v)
+	}
+	switch kind {
+	case binaryColumn:
+		return hexLiteral(val)
+	case spatialColumn:
+		if len(val) < 4 {
+			return hexLiteral(val)
+		}
+		return fmt.Sprintf("ST_GeomFromWKB(%s, %d)", hexLiteral(val[4:]), binary.LittleEndian.Uint32(val[:4]))
+	}
+	return formatSQLValue(v)
+}
+
+// hexLiteral returns b as an X'...' literal
+func hexLiteral(b []byte) string {
+	return "X'" + hex.EncodeToString(b) + "'"
+}
+
+// formatFloat writes a FLOAT or DOUBLE value exactly, so that it compares
+// equal to the column it was read from in key conditions. FLOAT values are
+// widened to the double the server compares them as.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
 func formatSQLValue(v interface{}) string {
 	if v == nil {
 		return "NULL"
@@ -730,6 +2700,11 @@ func formatSQLValue(v interface{}) string {
 
 	switch val := v.(type) {
 	case []byte:
+		// Bytes that are no valid text, such as binary keys in conditions,
+		// only survive as a hex literal
+		if !utf8.Valid(val) {
+			return hexLiteral(val)
+		}
 		// Escape string values
 		str := string(val)
 		str = strings.ReplaceAll(str, "\\", "\\\\")
@@ -747,12 +2722,19 @@ func formatSQLValue(v interface{}) string {
 		str = strings.ReplaceAll(str, "\r", "\\r")
 		str = strings.ReplaceAll(str, "\t", "\\t")
 		return fmt.Sprintf("'%s'", str)
+	case sqlLiteral:
+		return string(val)
 	case time.Time:
-		return fmt.Sprintf("'%s'", val.Format("2006-01-02 15:04:05"))
+		if val.IsZero() {
+			return "'0000-00-00 00:00:00'"
+		}
+		return fmt.Sprintf("'%s'", formatDateTime(val, -1))
 	case int64:
 		return fmt.Sprintf("%d", val)
 	case float64:
-		return fmt.Sprintf("%f", val)
+		return formatFloat(val)
+	case float32:
+		return formatFloat(float64(val))
 	case bool:
 		if val {
 			return "1"
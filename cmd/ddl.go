@@ -4,6 +4,7 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
@@ -12,6 +13,11 @@ import (
 	"strings"
 	"time"
 
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/metacache"
+	"mariadb-extractor/internal/sink"
+	"mariadb-extractor/internal/snapshot"
+
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/spf13/cobra"
 )
@@ -31,7 +37,7 @@ var ddlCmd = &cobra.Command{
 Generates markdown output files with complete table definitions including
 columns, indexes, constraints, and other table properties.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		runDDL()
+		runDDL(cmd.Context())
 	},
 }
 
@@ -44,6 +50,21 @@ var (
 	ddlTimeout     int
 	ddlMaxRetries  int
 	ddlBatchSize   int
+	ddlSplitSize   string
+
+	ddlIncludeSystem bool
+	ddlPool          poolOptions
+
+	// Schema history in a git repository
+	ddlGitCommit string
+	ddlGitPR     bool
+
+	// Metadata cache
+	ddlMetadataCache   string
+	ddlRefreshMetadata []string
+
+	// ddlServer is the detected source server
+	ddlServer serverInfo
 )
 
 func init() {
@@ -64,12 +85,26 @@ func init() {
 	ddlCmd.Flags().IntVarP(&ddlPort, "port", "P", defaultPort, "MariaDB port (env: MARIADB_PORT)")
 	ddlCmd.Flags().StringVarP(&ddlUser, "user", "u", defaultUser, "MariaDB username (env: MARIADB_USER)")
 	ddlCmd.Flags().StringVarP(&ddlPassword, "password", "p", defaultPassword, "MariaDB password (env: MARIADB_PASSWORD)")
-	ddlCmd.Flags().StringVarP(&ddlOutput, "output", "o", defaultOutput, "Output file prefix (env: MARIADB_OUTPUT_PREFIX)")
+	ddlCmd.Flags().StringVarP(&ddlOutput, "output", "o", defaultOutput, "Output file prefix, or a URI such as s3://bucket/path/prefix, ssh://host/path/prefix or - for stdout (env: MARIADB_OUTPUT_PREFIX)")
 	
 	// Performance and timeout flags
 	ddlCmd.Flags().IntVarP(&ddlTimeout, "timeout", "t", defaultTimeout, "Query timeout in seconds (env: MARIADB_TIMEOUT)")
 	ddlCmd.Flags().IntVar(&ddlMaxRetries, "max-retries", defaultMaxRetries, "Maximum retry attempts for failed queries (env: MARIADB_MAX_RETRIES)")
 	ddlCmd.Flags().IntVar(&ddlBatchSize, "batch-size", defaultBatchSize, "Number of databases to process before saving intermediate results (env: MARIADB_BATCH_SIZE)")
+	addPoolFlags(ddlCmd, &ddlPool, 5, 2, defaultTimeout)
+	ddlCmd.Flags().StringVar(&ddlSplitSize, "split-size", os.Getenv("MARIADB_SPLIT_SIZE"), "Split the init script into numbered files of at most this size, e.g. 64MB (env: MARIADB_SPLIT_SIZE)")
+
+	// Database selection flags
+	ddlCmd.Flags().BoolVar(&ddlIncludeSystem, "include-system", false, "Include system databases (information_schema, mysql, performance_schema, sys)")
+	ddlCmd.Flags().BoolVar(&ddlIncludeSystem, "all-databases", false, "Alias for --include-system, matching data and dump")
+
+	// Metadata cache flags
+	ddlCmd.Flags().StringVar(&ddlMetadataCache, "metadata-cache", os.Getenv("MARIADB_METADATA_CACHE"), "JSON file caching information_schema lookups across runs against the same server (env: MARIADB_METADATA_CACHE)")
+	ddlCmd.Flags().StringSliceVar(&ddlRefreshMetadata, "refresh-metadata", []string{}, "Databases whose cached metadata is read again, or all")
+
+	// Schema history flags
+	ddlCmd.Flags().StringVar(&ddlGitCommit, "git-commit", os.Getenv("MARIADB_GIT_COMMIT"), "Copy the generated DDL and documentation into this directory of a git repository and commit them when they changed (env: MARIADB_GIT_COMMIT)")
+	ddlCmd.Flags().BoolVar(&ddlGitPR, "git-pr", os.Getenv("MARIADB_GIT_PR") == "true", "Commit --git-commit to a new branch, push it to origin and open a pull request with the gh CLI (env: MARIADB_GIT_PR)")
 
 	// Only mark as required if not set via environment
 	if defaultUser == "" {
@@ -80,7 +115,7 @@ func init() {
 	}
 }
 
-func runDDL() {
+func runDDL(ctx context.Context) {
 	// Build connection string with performance optimizations
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&parseTime=true&timeout=%ds&readTimeout=%ds&writeTimeout=%ds&maxAllowedPacket=1073741824",
 		ddlUser, ddlPassword, ddlHost, ddlPort, ddlTimeout, ddlTimeout, ddlTimeout)
@@ -92,53 +127,96 @@ func runDDL() {
 	defer db.Close()
 
 	// Configure connection pool for better performance
-	db.SetMaxOpenConns(5)
-	db.SetMaxIdleConns(2)
-	db.SetConnMaxLifetime(time.Duration(ddlTimeout) * time.Second)
+	ddlPool.apply(db)
 
-	if err := db.Ping(); err != nil {
+	if err := pingWithRetry(ctx, db, ddlMaxRetries); err != nil {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
 	fmt.Printf("Connected to MariaDB at %s:%d (timeout: %ds, batch size: %d)\n", 
 		ddlHost, ddlPort, ddlTimeout, ddlBatchSize)
 
+	if err := runDDLWithDB(ctx, db); err != nil {
+		log.Fatalf("DDL extraction failed: %v", err)
+	}
+}
+
+// runDDLWithDB extracts DDLs over an open connection and writes the outputs
+func runDDLWithDB(ctx context.Context, db *sql.DB) error {
+	splitSize, err := parseSplitSize(ddlSplitSize)
+	if err != nil {
+		return err
+	}
+
+	out, restoreStdout, err := openOutputSink(ddlOutput)
+	if err != nil {
+		return err
+	}
+	if splitSize > 0 && sink.IsStream(out) {
+		restoreStdout()
+		return fmt.Errorf("--split-size writes several files and cannot be streamed to stdout")
+	}
+	defer restoreStdout()
+	prefix := sink.Prefix(ddlOutput)
+
+	ddlServer = connectedServer(ctx, db)
+	if err := openMetadataCache(ddlMetadataCache, fmt.Sprintf("%s:%d", ddlHost, ddlPort), ddlRefreshMetadata); err != nil {
+		return err
+	}
+	defer saveMetadataCache(ddlMetadataCache)
+
 	// Extract DDL information
-	ddlStatements, err := extractDDLs(db)
+	ddlStatements, err := extractDDLs(ctx, db)
 	if err != nil {
-		log.Fatalf("Failed to extract DDLs: %v", err)
+		return fmt.Errorf("failed to extract DDLs: %w", err)
 	}
 
 	// Generate markdown output
 	fmt.Printf("\n📝 Generating markdown documentation...\n")
-	if err := generateDDLMarkdownOutput(ddlStatements, ddlOutput); err != nil {
-		log.Fatalf("Failed to generate DDL markdown output: %v", err)
+	if err := generateDDLMarkdownOutput(ddlStatements, prefix); err != nil {
+		return fmt.Errorf("failed to generate DDL markdown output: %w", err)
 	}
-	fmt.Printf("✅ Created: %s.md\n", ddlOutput)
+	fmt.Printf("✅ Created: %s.md\n", prefix)
 
 	// Generate init script for Docker
 	fmt.Printf("🔧 Generating SQL init script...\n")
-	if err := generateDDLInitScript(ddlStatements); err != nil {
-		log.Fatalf("Failed to generate DDL init script: %v", err)
+	scripts, err := generateDDLInitScript(ddlStatements, splitSize)
+	if err != nil {
+		return fmt.Errorf("failed to generate DDL init script: %w", err)
+	}
+
+	markdown := filepath.Join("output", prefix+".md")
+	files := append(append([]string{}, scripts...), markdown, filepath.Join("output", "SHA256SUMS"))
+	if err := publishOutputs(ctx, out, "output", files...); err != nil {
+		return err
+	}
+
+	if ddlGitCommit != "" {
+		snap := schemaSnapshot{
+			runID:   snapshot.NewRunID(time.Now()),
+			server:  fmt.Sprintf("%s:%d (%s)", ddlHost, ddlPort, ddlServer),
+			baseDir: "output",
+			files:   append(append([]string{}, scripts...), markdown),
+		}
+		if err := commitSchemaSnapshot(ctx, ddlGitCommit, snap, ddlGitPR); err != nil {
+			return fmt.Errorf("failed to commit schema snapshot: %w", err)
+		}
 	}
-	fmt.Printf("✅ Created: init-scripts/01-extracted-schema.sql\n")
 
 	fmt.Printf("\n🎉 DDL extraction completed successfully!\n")
 	fmt.Printf("📁 Files generated:\n")
-	fmt.Printf("   - %s.md (documentation)\n", ddlOutput)
-	fmt.Printf("   - init-scripts/01-extracted-schema.sql (database setup)\n")
+	fmt.Printf("   - %s.md (documentation)\n", prefix)
+	for _, script := range scripts {
+		rel, _ := filepath.Rel("output", script)
+		fmt.Printf("   - %s (database setup)\n", rel)
+	}
+	printTrashSkipped("📋 ")
+	return nil
 }
 
-func extractDDLs(db *sql.DB) ([]DDLInfo, error) {
-	// Get all databases (excluding system databases)
-	query := `
-		SELECT SCHEMA_NAME
-		FROM information_schema.SCHEMATA
-		WHERE SCHEMA_NAME NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
-		ORDER BY SCHEMA_NAME
-	`
-
-	rows, err := db.Query(query)
+func extractDDLs(ctx context.Context, db *sql.DB) ([]DDLInfo, error) {
+	// Get all databases (excluding system databases unless requested)
+	rows, err := queryWithRetry(ctx, db, ddlMaxRetries, schemataQuery(ddlIncludeSystem))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query databases: %w", err)
 	}
@@ -157,53 +235,71 @@ func extractDDLs(db *sql.DB) ([]DDLInfo, error) {
 	}
 
 	totalDBs := len(dbNames)
-	fmt.Printf("Found %d user databases to process\n\n", totalDBs)
+	if ddlIncludeSystem {
+		fmt.Printf("Found %d databases to process (including system databases)\n\n", totalDBs)
+	} else {
+		fmt.Printf("Found %d user databases to process\n\n", totalDBs)
+	}
 
 	// Process each database with progress tracking
 	for i, dbName := range dbNames {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("DDL extraction interrupted: %w", err)
+		}
+
 		// Check if this is a "trash" database to skip
-		if isTrashDatabase(dbName) {
-			fmt.Printf("[%d/%d] ⏭️  Skipping trash database: %s\n", i+1, totalDBs, dbName)
+		pattern, err := trashPattern(dbName)
+		if err != nil {
+			return nil, err
+		}
+		if pattern != "" {
+			skipTrash(fmt.Sprintf("[%d/%d] ", i+1, totalDBs), dbName, pattern)
 			continue
 		}
 
 		fmt.Printf("[%d/%d] 📦 Extracting DDLs from database: %s\n", i+1, totalDBs, dbName)
 
-		// Get all tables for this database
+		// Get all tables for this database, plus sequences where the server
+		// has them; sequences sort first so tables can default to NEXTVAL
+		tableTypes := "'BASE TABLE'"
+		if ddlServer.supportsSequences() {
+			tableTypes = "'BASE TABLE', 'SEQUENCE'"
+		}
 		tableQuery := `
-			SELECT TABLE_NAME
+			SELECT TABLE_NAME, TABLE_TYPE
 			FROM information_schema.TABLES
-			WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
-			ORDER BY TABLE_NAME
+			WHERE TABLE_SCHEMA = ? AND TABLE_TYPE IN (` + tableTypes + `)
+			ORDER BY TABLE_TYPE DESC, TABLE_NAME
 		`
 
-		tableRows, err := db.Query(tableQuery, dbName)
+		tableRows, err := cachedRows(ctx, db, ddlMaxRetries, metacache.Key{Kind: metaTableTypes, Database: dbName}, tableQuery, dbName)
 		if err != nil {
 			log.Printf("Warning: failed to query tables for %s: %v", dbName, err)
 			continue
 		}
 
-		for tableRows.Next() {
-			var tableName string
-			if err := tableRows.Scan(&tableName); err != nil {
-				tableRows.Close()
-				return nil, fmt.Errorf("failed to scan table name: %w", err)
-			}
+		for _, row := range tableRows {
+			tableName, tableType := row[0], row[1]
 
-			// Get CREATE TABLE statement with retry logic
+			// Get CREATE TABLE (or CREATE SEQUENCE) statement with retry logic
 			createTableQuery := fmt.Sprintf("SHOW CREATE TABLE `%s`.`%s`", dbName, tableName)
-			row, err := executeWithRetry(db, createTableQuery)
-			if err != nil {
-				log.Printf("Warning: failed to get DDL for %s.%s after %d retries: %v", dbName, tableName, ddlMaxRetries, err)
-				continue
+			if tableType == "SEQUENCE" {
+				createTableQuery = fmt.Sprintf("SHOW CREATE SEQUENCE `%s`.`%s`", dbName, tableName)
 			}
-			
 			var table, createTable string
-			if err := row.Scan(&table, &createTable); err != nil {
-				log.Printf("Warning: failed to scan DDL for %s.%s: %v", dbName, tableName, err)
+			queryCtx, cancel := withTimeout(ctx, ddlTimeout)
+			err := queryRowWithRetry(queryCtx, db, ddlMaxRetries, createTableQuery, nil, &table, &createTable)
+			cancel()
+			if err != nil {
+				log.Printf("Warning: failed to get DDL for %s.%s: %v", dbName, tableName, err)
 				continue
 			}
 
+			// Schema history only changes with the schema, not the counter
+			if ddlGitCommit != "" {
+				createTable = autoIncrementOption.ReplaceAllString(createTable, "")
+			}
+
 			ddlInfo := DDLInfo{
 				DatabaseName: dbName,
 				TableName:    tableName,
@@ -212,82 +308,79 @@ func extractDDLs(db *sql.DB) ([]DDLInfo, error) {
 
 			allDDLs = append(allDDLs, ddlInfo)
 		}
-		tableRows.Close()
 
 		fmt.Printf("✅ Completed database: %s\n", dbName)
 
 		// Write intermediate results every N databases to prevent data loss
 		if (i+1)%ddlBatchSize == 0 {
 			fmt.Printf("💾 Saving intermediate results... (%d/%d databases)\n", i+1, totalDBs)
-			if err := generateDDLMarkdownOutput(allDDLs, ddlOutput+".partial"); err != nil {
+			if err := generateDDLMarkdownOutput(allDDLs, sink.Prefix(ddlOutput)+".partial"); err != nil {
 				fmt.Printf("⚠️  Warning: Failed to save intermediate markdown: %v\n", err)
 			}
-			if err := generateDDLInitScript(allDDLs); err != nil {
+			if _, err := generateDDLInitScript(allDDLs, 0); err != nil {
 				fmt.Printf("⚠️  Warning: Failed to save intermediate SQL: %v\n", err)
 			}
 		}
 	}
 
 	fmt.Printf("\n🎉 DDL extraction completed! Processed %d databases\n", totalDBs)
+	if note := ddlServer.collationNote(createStatements(allDDLs)); note != "" {
+		fmt.Printf("⚠️  Note: %s\n", note)
+	}
 	return allDDLs, nil
 }
 
-// executeWithRetry executes a database query with retry logic and exponential backoff
-func executeWithRetry(db *sql.DB, query string, args ...interface{}) (*sql.Row, error) {
-	var row *sql.Row
-	var err error
-	
-	for attempt := 0; attempt < ddlMaxRetries; attempt++ {
-		row = db.QueryRow(query, args...)
-		// Test the row by attempting to scan into temporary variables
-		var test1, test2 string
-		if scanErr := row.Scan(&test1, &test2); scanErr != nil {
-			err = scanErr
-			if attempt < ddlMaxRetries-1 {
-				backoffDuration := time.Duration(attempt+1) * time.Second
-				fmt.Printf("⚠️  Query failed (attempt %d/%d), retrying in %v: %v\n", 
-					attempt+1, ddlMaxRetries, backoffDuration, scanErr)
-				time.Sleep(backoffDuration)
-				continue
-			}
-		} else {
-			// Query succeeded, return a fresh row for actual use
-			return db.QueryRow(query, args...), nil
-		}
+// createStatements returns the CREATE statements of the DDLs
+func createStatements(ddls []DDLInfo) []string {
+	statements := make([]string, len(ddls))
+	for i, ddl := range ddls {
+		statements[i] = ddl.CreateTable
 	}
-	
-	return nil, fmt.Errorf("query failed after %d attempts: %w", ddlMaxRetries, err)
+	return statements
 }
 
-func generateDDLInitScript(ddlStatements []DDLInfo) error {
+// generateDDLInitScript writes the Docker init script and returns its files,
+// several when splitSize is positive and the script is larger
+func generateDDLInitScript(ddlStatements []DDLInfo, splitSize int64) ([]string, error) {
 	// Create output/init-scripts directory if it doesn't exist
 	outputDir := "output"
 	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
 	// Create init-scripts subdirectory in output
 	initScriptsDir := filepath.Join(outputDir, "init-scripts")
 	if err := os.MkdirAll(initScriptsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create init-scripts directory: %w", err)
+		return nil, fmt.Errorf("failed to create init-scripts directory: %w", err)
 	}
 
 	filename := filepath.Join(initScriptsDir, "01-extracted-schema.sql")
+	if err := removeScriptParts(filename); err != nil {
+		return nil, err
+	}
 	file, err := os.Create(filename)
 	if err != nil {
-		return fmt.Errorf("failed to create DDL init script: %w", err)
+		return nil, fmt.Errorf("failed to create DDL init script: %w", err)
 	}
 	defer file.Close()
+	sum := checksum.NewWriter(file)
 
 	// Write header
-	fmt.Fprintf(file, "-- MariaDB DDL Init Script\n")
-	fmt.Fprintf(file, "-- Auto-generated from production database\n")
-	fmt.Fprintf(file, "-- Generated on: %s\n", time.Now().Format("2006-01-02 15:04:05"))
-	fmt.Fprintf(file, "-- Source: %s:%d\n\n", ddlHost, ddlPort)
+	fmt.Fprintf(sum, "-- MariaDB DDL Init Script\n")
+	fmt.Fprintf(sum, "-- Auto-generated from production database\n")
+	if ddlGitCommit == "" {
+		fmt.Fprintf(sum, "-- Generated on: %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(sum, "-- Source: %s:%d\n", ddlHost, ddlPort)
+	fmt.Fprintf(sum, "-- Source flavor: %s (%s)\n", ddlServer, ddlServer.version)
+	if note := ddlServer.collationNote(createStatements(ddlStatements)); note != "" {
+		fmt.Fprintf(sum, "-- Note: %s\n", note)
+	}
+	fmt.Fprintf(sum, "\n")
 
 	// Disable foreign key checks to allow table creation in any order
-	fmt.Fprintf(file, "-- Disable foreign key checks to avoid constraint errors during import\n")
-	fmt.Fprintf(file, "SET FOREIGN_KEY_CHECKS=0;\n\n")
+	fmt.Fprintf(sum, "-- Disable foreign key checks to avoid constraint errors during import\n")
+	fmt.Fprintf(sum, "SET FOREIGN_KEY_CHECKS=0;\n\n")
 
 	// Group DDLs by database
 	dbGroups := make(map[string][]DDLInfo)
@@ -297,9 +390,9 @@ func generateDDLInitScript(ddlStatements []DDLInfo) error {
 
 	// Write DDLs grouped by database
 	for dbName, ddls := range dbGroups {
-		fmt.Fprintf(file, "-- Database: %s (%d tables)\n", dbName, len(ddls))
-		fmt.Fprintf(file, "CREATE DATABASE IF NOT EXISTS `%s`;\n", dbName)
-		fmt.Fprintf(file, "USE `%s`;\n\n", dbName)
+		fmt.Fprintf(sum, "-- Database: %s (%d tables)\n", dbName, len(ddls))
+		fmt.Fprintf(sum, "CREATE DATABASE IF NOT EXISTS `%s`;\n", dbName)
+		fmt.Fprintf(sum, "USE `%s`;\n\n", dbName)
 
 		for _, ddl := range ddls {
 			// Ensure DDL statement ends with semicolon for proper SQL syntax
@@ -307,18 +400,37 @@ func generateDDLInitScript(ddlStatements []DDLInfo) error {
 			if !strings.HasSuffix(strings.TrimSpace(createTableSQL), ";") {
 				createTableSQL += ";"
 			}
-			fmt.Fprintf(file, "%s\n\n", createTableSQL)
+			fmt.Fprintf(sum, "%s\n\n", createTableSQL)
 		}
 
-		fmt.Fprintf(file, "-- End of database: %s\n\n", dbName)
+		fmt.Fprintf(sum, "-- End of database: %s\n\n", dbName)
 	}
 
 	// Re-enable foreign key checks after all tables are created
-	fmt.Fprintf(file, "-- Re-enable foreign key checks\n")
-	fmt.Fprintf(file, "SET FOREIGN_KEY_CHECKS=1;\n")
+	fmt.Fprintf(sum, "-- Re-enable foreign key checks\n")
+	fmt.Fprintf(sum, "SET FOREIGN_KEY_CHECKS=1;\n")
+
+	// Recorded in output/SHA256SUMS alongside the other generated files
+	if err := checksum.Record(outputDir, map[string]string{filename: sum.Sum()}); err != nil {
+		return nil, fmt.Errorf("failed to record checksum: %w", err)
+	}
 
 	fmt.Printf("✅ DDL init script created: %s\n", filename)
-	return nil
+
+	if splitSize <= 0 {
+		return []string{filename}, nil
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write DDL init script: %w", err)
+	}
+	parts, err := splitScript(filename, splitSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(parts) > 1 {
+		fmt.Printf("✂️  Split into %d files of at most %s\n", len(parts), formatBytes(splitSize))
+	}
+	return parts, nil
 }
 
 func generateDDLMarkdownOutput(ddlStatements []DDLInfo, outputPrefix string) error {
@@ -334,13 +446,16 @@ func generateDDLMarkdownOutput(ddlStatements []DDLInfo, outputPrefix string) err
 		return fmt.Errorf("failed to create DDL markdown file: %w", err)
 	}
 	defer file.Close()
+	sum := checksum.NewWriter(file)
 
 	// Write header
-	fmt.Fprintf(file, "# MariaDB DDL Extraction Report\n\n")
-	fmt.Fprintf(file, "**Generated on:** %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
-	fmt.Fprintf(file, "**Server:** %s:%d\n\n", ddlHost, ddlPort)
-	fmt.Fprintf(file, "**Total DDL Statements:** %d\n\n", len(ddlStatements))
-	fmt.Fprintf(file, "---\n\n")
+	fmt.Fprintf(sum, "# MariaDB DDL Extraction Report\n\n")
+	if ddlGitCommit == "" {
+		fmt.Fprintf(sum, "**Generated on:** %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(sum, "**Server:** %s:%d (%s)\n\n", ddlHost, ddlPort, ddlServer)
+	fmt.Fprintf(sum, "**Total DDL Statements:** %d\n\n", len(ddlStatements))
+	fmt.Fprintf(sum, "---\n\n")
 
 	// Group DDLs by database
 	dbGroups := make(map[string][]DDLInfo)
@@ -350,18 +465,18 @@ func generateDDLMarkdownOutput(ddlStatements []DDLInfo, outputPrefix string) err
 
 	// Write DDLs grouped by database
 	for dbName, ddls := range dbGroups {
-		fmt.Fprintf(file, "## Database: `%s`\n\n", dbName)
-		fmt.Fprintf(file, "**Tables:** %d\n\n", len(ddls))
+		fmt.Fprintf(sum, "## Database: `%s`\n\n", dbName)
+		fmt.Fprintf(sum, "**Tables:** %d\n\n", len(ddls))
 
 		for _, ddl := range ddls {
-			fmt.Fprintf(file, "### Table: `%s`\n\n", ddl.TableName)
-			fmt.Fprintf(file, "```sql\n")
-			fmt.Fprintf(file, "%s\n", ddl.CreateTable)
-			fmt.Fprintf(file, "```\n\n")
+			fmt.Fprintf(sum, "### Table: `%s`\n\n", ddl.TableName)
+			fmt.Fprintf(sum, "```sql\n")
+			fmt.Fprintf(sum, "%s\n", ddl.CreateTable)
+			fmt.Fprintf(sum, "```\n\n")
 		}
 
-		fmt.Fprintf(file, "---\n\n")
+		fmt.Fprintf(sum, "---\n\n")
 	}
 
-	return nil
+	return recordChecksum(filename, sum.Sum())
 }
@@ -4,17 +4,30 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/sink"
+	"mariadb-extractor/internal/snapshot"
+	"mariadb-extractor/internal/state"
+
 	_ "github.com/go-sql-driver/mysql"
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
 	"github.com/spf13/cobra"
 )
 
@@ -26,7 +39,7 @@ var dumpCmd = &cobra.Command{
 Supports dumping schema only, data only, or both. Can dump all databases or specific ones.
 Generated dumps can be used to recreate databases locally with 'mysql < dump.sql'.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		runDump()
+		runDump(cmd.Context())
 	},
 }
 
@@ -42,8 +55,25 @@ var (
 	dumpAllDatabases     bool
 	dumpAllUserDatabases bool
 	dumpCompress         bool
+	dumpCompressFormat   string
+	dumpCompressThreads  int
+	dumpNoRoutines       bool
+	dumpNoTriggers       bool
+	dumpEvents           bool
+	dumpNoTablespaces    bool
+	dumpPartitions       []string
+	dumpMaxRetries       int
+	dumpPool             poolOptions
+	dumpResume           string
+	dumpMysqldump        string
+
+	// dumpState is the progress of the current run
+	dumpState *state.Progress
 )
 
+// tablespaceClausePattern matches DATA DIRECTORY / INDEX DIRECTORY table options
+var tablespaceClausePattern = regexp.MustCompile(`(?i)\s*(DATA|INDEX) DIRECTORY\s*=\s*'[^']*'`)
+
 func init() {
 	rootCmd.AddCommand(dumpCmd)
 
@@ -59,7 +89,7 @@ func init() {
 	dumpCmd.Flags().IntVarP(&dumpPort, "port", "P", defaultPort, "MariaDB port (env: MARIADB_PORT)")
 	dumpCmd.Flags().StringVarP(&dumpUser, "user", "u", defaultUser, "MariaDB username (env: MARIADB_USER)")
 	dumpCmd.Flags().StringVarP(&dumpPassword, "password", "p", defaultPassword, "MariaDB password (env: MARIADB_PASSWORD)")
-	dumpCmd.Flags().StringVarP(&dumpOutput, "output", "o", defaultOutput, "Output file prefix (env: MARIADB_OUTPUT_PREFIX)")
+	dumpCmd.Flags().StringVarP(&dumpOutput, "output", "o", defaultOutput, "Output file prefix, or a URI such as s3://bucket/path/prefix, ssh://host/path/prefix or - for stdout (env: MARIADB_OUTPUT_PREFIX)")
 
 	// Dump-specific flags
 	dumpCmd.Flags().StringSliceVarP(&dumpDatabases, "databases", "d", []string{}, "Specific databases to dump (comma-separated)")
@@ -68,6 +98,21 @@ func init() {
 	dumpCmd.Flags().BoolVar(&dumpSchemaOnly, "schema-only", false, "Dump only schema (no data)")
 	dumpCmd.Flags().BoolVar(&dumpDataOnly, "data-only", false, "Dump only data (no schema)")
 	dumpCmd.Flags().BoolVarP(&dumpCompress, "compress", "c", false, "Compress output with gzip")
+	dumpCmd.Flags().StringVar(&dumpCompressFormat, "compress-format", "gzip", "Compression format when --compress is set: gzip or zstd")
+	dumpCmd.Flags().IntVar(&dumpCompressThreads, "compress-threads", getEnvIntWithDefault("MARIADB_COMPRESS_THREADS", runtime.NumCPU()), "Compression worker threads (env: MARIADB_COMPRESS_THREADS)")
+	dumpCmd.Flags().IntVar(&dumpMaxRetries, "max-retries", getEnvIntWithDefault("MARIADB_MAX_RETRIES", 3), "Maximum retry attempts for failed metadata queries (env: MARIADB_MAX_RETRIES)")
+	addPoolFlags(dumpCmd, &dumpPool, 2, 1, 0)
+	dumpCmd.Flags().StringVar(&dumpResume, "resume", "", "Resume an interrupted dump run by its run ID")
+	dumpCmd.Flags().StringVar(&dumpMysqldump, "mysqldump", os.Getenv("MARIADB_MYSQLDUMP"), "Path of the mysqldump or mariadb-dump binary; found in PATH and, on Windows, in the MariaDB and MySQL install directories by default (env: MARIADB_MYSQLDUMP)")
+
+	// Object class toggles
+	dumpCmd.Flags().BoolVar(&dumpNoRoutines, "no-routines", false, "Exclude stored procedures and functions")
+	dumpCmd.Flags().BoolVar(&dumpNoTriggers, "no-triggers", false, "Exclude triggers")
+	dumpCmd.Flags().BoolVar(&dumpEvents, "events", false, "Include scheduled events")
+
+	// Tablespace and partition handling
+	dumpCmd.Flags().BoolVar(&dumpNoTablespaces, "no-tablespaces", false, "Omit CREATE TABLESPACE statements and DATA/INDEX DIRECTORY clauses")
+	dumpCmd.Flags().StringArrayVar(&dumpPartitions, "partitions", []string{}, "Dump only the given partitions of a table (format: db.table:p1,p2; repeatable)")
 
 	// Only mark as required if not set via environment
 	if defaultUser == "" {
@@ -78,7 +123,7 @@ func init() {
 	}
 }
 
-func runDump() {
+func runDump(ctx context.Context) {
 	// Validate dump options
 	if dumpSchemaOnly && dumpDataOnly {
 		log.Fatal("Cannot specify both --schema-only and --data-only")
@@ -96,20 +141,82 @@ func runDump() {
 		log.Fatal("Cannot specify both --all-* flags and --databases")
 	}
 
+	if dumpCompressFormat != "gzip" && dumpCompressFormat != "zstd" {
+		log.Fatalf("Invalid --compress-format %q: must be gzip or zstd", dumpCompressFormat)
+	}
+
+	if _, err := parsePartitionSpecs(dumpPartitions); err != nil {
+		log.Fatalf("Invalid --partitions value: %v", err)
+	}
+
+	out, restoreStdout, err := openOutputSink(dumpOutput)
+	if err != nil {
+		log.Fatalf("Invalid --output: %v", err)
+	}
+	defer restoreStdout()
+	publish := func() {
+		outputFile := dumpOutputFile()
+		checksums := filepath.Join(filepath.Dir(outputFile), "SHA256SUMS")
+		if err := publishOutputs(ctx, out, filepath.Dir(outputFile), outputFile, checksums); err != nil {
+			log.Fatalf("Failed to publish dump: %v", err)
+		}
+	}
+
+	if dumpResume != "" {
+		progress, err := state.Load("dump", dumpResume)
+		if err != nil {
+			log.Fatalf("Cannot resume dump: %v", err)
+		}
+		if progress.Output != dumpOutputFile() {
+			log.Fatalf("Dump run %s wrote to %s; resume it with the same --output and --compress options", dumpResume, progress.Output)
+		}
+		dumpState = progress
+		fmt.Printf("Resuming dump run %s\n", dumpResume)
+	} else {
+		runID := snapshot.NewRunID(time.Now())
+		dumpState = state.New("dump", runID, fmt.Sprintf("%s:%d", dumpHost, dumpPort), dumpOutputFile())
+		if err := dumpState.Save(); err != nil {
+			log.Fatalf("Failed to save dump state: %v", err)
+		}
+		fmt.Printf("Dump run ID: %s (resume with --resume %s)\n", runID, runID)
+	}
+
+	binary, err := findMysqldump()
+	if err != nil {
+		log.Fatal(err)
+	}
+	dumpMysqldump = binary
+
 	fmt.Printf("Starting database dump from %s:%d\n", dumpHost, dumpPort)
 
-	// Build mysqldump command
-	args := buildMysqldumpArgs()
+	// Build mysqldump command. Multi-database dumps are run one database at a
+	// time while building the arguments and return no arguments.
+	args := buildMysqldumpArgs(ctx)
+	if args == nil {
+		publish()
+		fmt.Printf("Database dump completed successfully!\n")
+		return
+	}
+
+	// A single mysqldump invocation cannot be continued part-way, so resuming
+	// it either skips a completed run or starts the invocation over
+	key := dumpInvocationKey(args)
+	if dumpState.Done(key) {
+		fmt.Printf("✅ Dump run %s already completed\n", dumpState.RunID)
+		return
+	}
 
 	// Execute mysqldump
-	if err := executeMysqldump(args); err != nil {
+	if err := executeMysqldump(ctx, args); err != nil {
 		log.Fatalf("Failed to execute mysqldump: %v", err)
 	}
+	markDatabaseCompleted(key)
+	publish()
 
 	fmt.Printf("Database dump completed successfully!\n")
 }
 
-func buildMysqldumpArgs() []string {
+func buildMysqldumpArgs(ctx context.Context) []string {
 	var args []string
 
 	// Connection parameters
@@ -131,16 +238,19 @@ func buildMysqldumpArgs() []string {
 	args = append(args, "--single-transaction") // Consistent snapshot
 	args = append(args, "--quick")              // Don't buffer entire result sets
 	args = append(args, "--lock-tables=false")  // Don't lock tables
-	args = append(args, "--routines")           // Include stored procedures and functions
-	args = append(args, "--triggers")           // Include triggers
+	args = append(args, objectClassArgs()...)   // Routines, triggers and events
+	if dumpNoTablespaces {
+		args = append(args, "--no-tablespaces")
+	}
 
 	// Database selection
 	if dumpAllDatabases {
+		args = append(args, partitionIgnoreArgs("")...)
 		args = append(args, "--all-databases")
 		fmt.Printf("Dumping ALL databases (including system databases)...\n")
 	} else if dumpAllUserDatabases {
 		// Get list of user databases (excluding system databases)
-		userDBs, err := getUserDatabases()
+		userDBs, err := getUserDatabases(ctx)
 		if err != nil {
 			log.Fatalf("Failed to get user databases: %v", err)
 		}
@@ -150,7 +260,7 @@ func buildMysqldumpArgs() []string {
 
 		// Process databases individually for progress tracking
 		fmt.Printf("Found %d user databases to dump\n", len(userDBs))
-		if err := dumpDatabasesWithProgress(userDBs); err != nil {
+		if err := dumpDatabasesWithProgress(ctx, userDBs); err != nil {
 			log.Fatalf("Failed to dump databases: %v", err)
 		}
 		return nil // Early return since we handled the dump
@@ -158,13 +268,14 @@ func buildMysqldumpArgs() []string {
 		// If multiple databases specified, use progress mode
 		if len(dumpDatabases) > 1 {
 			fmt.Printf("Dumping %d specified databases with progress tracking\n", len(dumpDatabases))
-			if err := dumpDatabasesWithProgress(dumpDatabases); err != nil {
+			if err := dumpDatabasesWithProgress(ctx, dumpDatabases); err != nil {
 				log.Fatalf("Failed to dump databases: %v", err)
 			}
 			return nil // Early return since we handled the dump
 		} else {
 			// Single database - use regular mode
 			fmt.Printf("Dumping database: %s\n", dumpDatabases[0])
+			args = append(args, partitionIgnoreArgs(dumpDatabases[0])...)
 			args = append(args, dumpDatabases[0])
 		}
 	}
@@ -172,7 +283,34 @@ func buildMysqldumpArgs() []string {
 	return args
 }
 
-func getUserDatabases() ([]string, error) {
+// objectClassArgs returns the mysqldump flags selecting which non-table objects
+// (routines, triggers, events) are included in the dump
+func objectClassArgs() []string {
+	var args []string
+
+	if dumpNoRoutines {
+		args = append(args, "--skip-routines")
+	} else {
+		args = append(args, "--routines")
+	}
+
+	if dumpNoTriggers {
+		args = append(args, "--skip-triggers")
+	} else {
+		args = append(args, "--triggers")
+	}
+
+	if dumpEvents {
+		args = append(args, "--events")
+	} else {
+		args = append(args, "--skip-events")
+	}
+
+	return args
+}
+
+// openDumpDB opens a connection to the dump source for metadata queries
+func openDumpDB(ctx context.Context) (*sql.DB, error) {
 	// Build connection string
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&parseTime=true",
 		dumpUser, dumpPassword, dumpHost, dumpPort)
@@ -181,21 +319,25 @@ func getUserDatabases() ([]string, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
-	defer db.Close()
+	dumpPool.apply(db)
 
-	if err := db.Ping(); err != nil {
+	if err := pingWithRetry(ctx, db, dumpMaxRetries); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
+	return db, nil
+}
+
+func getUserDatabases(ctx context.Context) ([]string, error) {
+	db, err := openDumpDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
 	// Get all user databases (excluding system databases)
-	query := `
-		SELECT SCHEMA_NAME
-		FROM information_schema.SCHEMATA
-		WHERE SCHEMA_NAME NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
-		ORDER BY SCHEMA_NAME
-	`
-
-	rows, err := db.Query(query)
+	rows, err := queryWithRetry(ctx, db, dumpMaxRetries, schemataQuery(false))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query databases: %w", err)
 	}
@@ -213,7 +355,7 @@ func getUserDatabases() ([]string, error) {
 	return databases, nil
 }
 
-func dumpDatabasesWithProgress(databases []string) error {
+func dumpDatabasesWithProgress(ctx context.Context, databases []string) error {
 	totalDBs := len(databases)
 	fmt.Printf("Starting dump of %d databases...\n\n", totalDBs)
 
@@ -221,13 +363,19 @@ func dumpDatabasesWithProgress(databases []string) error {
 	var successfulDumps, failedDumps, skippedDumps int
 
 	// Load progress from previous run if exists
-	completedDBs := loadProgress()
-	fmt.Printf("Found %d previously completed databases\n", len(completedDBs))
+	previouslyCompleted := len(dumpState.Completed)
+	fmt.Printf("Found %d previously completed databases\n", previouslyCompleted)
+
+	// Databases are appended to a single file; drop anything written after
+	// the last completed database so a failed one is not left half-dumped
+	if err := truncateDumpOutput(dumpState.Offset()); err != nil {
+		return err
+	}
 
 	// Filter out already completed databases
 	var remainingDBs []string
 	for _, dbName := range databases {
-		if !isDatabaseCompleted(completedDBs, dbName) {
+		if !dumpState.Done(dbName) {
 			remainingDBs = append(remainingDBs, dbName)
 		}
 	}
@@ -240,9 +388,17 @@ func dumpDatabasesWithProgress(databases []string) error {
 	fmt.Printf("Remaining databases to dump: %d\n\n", len(remainingDBs))
 
 	for i, dbName := range remainingDBs {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("dump interrupted after %d databases: %w", successfulDumps, err)
+		}
+
 		// Check if this is a "trash" database to skip
-		if isTrashDatabase(dbName) {
-			fmt.Printf("[%d/%d] ⏭️  Skipping trash database: %s\n", i+1, len(remainingDBs), dbName)
+		pattern, err := trashPattern(dbName)
+		if err != nil {
+			return err
+		}
+		if pattern != "" {
+			skipTrash(fmt.Sprintf("[%d/%d] ", i+1, len(remainingDBs)), dbName, pattern)
 			skippedDumps++
 			markDatabaseCompleted(dbName)
 			continue
@@ -259,8 +415,10 @@ func dumpDatabasesWithProgress(databases []string) error {
 			"--single-transaction",
 			"--quick",
 			"--lock-tables=false",
-			"--routines",
-			"--triggers",
+		}
+		args = append(args, objectClassArgs()...)
+		if dumpNoTablespaces {
+			args = append(args, "--no-tablespaces")
 		}
 
 		// Add schema/data options
@@ -271,12 +429,22 @@ func dumpDatabasesWithProgress(databases []string) error {
 		}
 
 		// Add the database name
+		args = append(args, partitionIgnoreArgs(dbName)...)
 		args = append(args, dbName)
 
 		// Execute mysqldump for this database
-		if err := executeMysqldumpForDB(args, dbName, dumpPassword, i+1, len(remainingDBs)); err != nil {
+		mark, err := dumpOutputSize()
+		if err != nil {
+			return err
+		}
+		if err := executeMysqldumpForDB(ctx, args, dbName, dumpPassword, i+1, len(remainingDBs)); err != nil {
 			fmt.Printf("❌ Failed to dump %s: %v\n", dbName, err)
 			failedDumps++
+			// Drop the half-written database, including its unfinished
+			// compressed frame, so the databases after it stay readable
+			if err := truncateDumpOutput(mark); err != nil {
+				return err
+			}
 			// Continue with next database even if this one fails
 		} else {
 			dbDuration := time.Since(dbStartTime)
@@ -287,7 +455,7 @@ func dumpDatabasesWithProgress(databases []string) error {
 
 		// Show progress
 		elapsed := time.Since(startTime)
-		completedCount := len(completedDBs) + successfulDumps
+		completedCount := previouslyCompleted + successfulDumps
 		totalProgress := completedCount + skippedDumps
 		avgTimePerDB := elapsed / time.Duration(totalProgress)
 		remainingCount := totalDBs - totalProgress
@@ -296,14 +464,22 @@ func dumpDatabasesWithProgress(databases []string) error {
 			totalProgress, totalDBs, skippedDumps, elapsed.Round(time.Second), remaining.Round(time.Second))
 	}
 
+	// Databases are appended across runs, so the file is hashed as a whole
+	if successfulDumps > 0 {
+		outputFile := dumpOutputFile()
+		if _, err := checksum.RecordFiles(filepath.Dir(outputFile), outputFile); err != nil {
+			fmt.Printf("⚠️  Warning: failed to record checksum: %v\n", err)
+		}
+	}
+
 	// Final summary
 	totalDuration := time.Since(startTime)
 	fmt.Printf("🎉 Dump Summary:\n")
 	fmt.Printf("   Total databases: %d\n", totalDBs)
 	fmt.Printf("   Successful: %d\n", successfulDumps)
 	fmt.Printf("   Failed: %d\n", failedDumps)
-	fmt.Printf("   Skipped (trash): %d\n", skippedDumps)
-	fmt.Printf("   Previously completed: %d\n", len(completedDBs))
+	printTrashSkipped("   ")
+	fmt.Printf("   Previously completed: %d\n", previouslyCompleted)
 	fmt.Printf("   Total time: %v\n", totalDuration.Round(time.Second))
 	if successfulDumps > 0 {
 		fmt.Printf("   Average per database: %v\n", (totalDuration / time.Duration(successfulDumps)).Round(time.Second))
@@ -317,110 +493,55 @@ func dumpDatabasesWithProgress(databases []string) error {
 	return nil
 }
 
-// Progress tracking functions
-func loadProgress() map[string]bool {
-	progressFile := dumpOutput + ".progress"
-	completedDBs := make(map[string]bool)
-
-	if data, err := os.ReadFile(progressFile); err == nil {
-		lines := strings.Split(string(data), "\n")
-		for _, line := range lines {
-			if line = strings.TrimSpace(line); line != "" {
-				completedDBs[line] = true
-			}
-		}
+// markDatabaseCompleted records a finished database (or single-invocation
+// dump) at the current end of the output file
+func markDatabaseCompleted(dbName string) {
+	var size int64
+	if info, err := os.Stat(dumpOutputFile()); err == nil {
+		size = info.Size()
 	}
-
-	return completedDBs
-}
-
-func saveProgress(completedDBs map[string]bool) {
-	progressFile := dumpOutput + ".progress"
-
-	var lines []string
-	for dbName := range completedDBs {
-		lines = append(lines, dbName)
+	if err := dumpState.Complete(dbName, size); err != nil {
+		fmt.Printf("⚠️  Warning: failed to save dump progress: %v\n", err)
 	}
-
-	data := strings.Join(lines, "\n") + "\n"
-	os.WriteFile(progressFile, []byte(data), 0644)
 }
 
-func isDatabaseCompleted(completedDBs map[string]bool, dbName string) bool {
-	return completedDBs[dbName]
+// dumpOutputSize returns the current size of the dump file, 0 if missing
+func dumpOutputSize() (int64, error) {
+	info, err := os.Stat(dumpOutputFile())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat output file: %w", err)
+	}
+	return info.Size(), nil
 }
 
-func markDatabaseCompleted(dbName string) {
-	// Read existing progress
-	completedDBs := loadProgress()
-
-	// Mark as completed
-	completedDBs[dbName] = true
+// truncateDumpOutput cuts the dump file back to size, creating it if needed
+func truncateDumpOutput(size int64) error {
+	file, err := os.OpenFile(dumpOutputFile(), os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+	defer file.Close()
 
-	// Save back to file
-	saveProgress(completedDBs)
+	if err := file.Truncate(size); err != nil {
+		return fmt.Errorf("failed to truncate output file: %w", err)
+	}
+	return nil
 }
 
-// Trash database detection
-func isTrashDatabase(dbName string) bool {
-	// Define patterns for trash/backup databases
-	trashPatterns := []string{
-		"backup",
-		"bak",
-		"old",
-		"temp",
-		"test",
-		"tmp",
-		"copy",
-		"archive",
-		"dump",
-		"bkp",
-		"save",
-		"restore",
-		"backup_",
-		"_backup",
-		"_old",
-		"_temp",
-		"_bak",
-		"_copy",
-		"_archive",
-		"_dump",
-		"_bkp",
-		"_save",
-		"_restore",
-	}
-
-	dbLower := strings.ToLower(dbName)
-
-	// Check if database name contains any trash patterns
-	for _, pattern := range trashPatterns {
-		if strings.Contains(dbLower, pattern) {
-			return true
-		}
-	}
-
-	// Check for databases with dates (likely backups)
-	// Pattern: name_YYYYMMDD or name_YYYY-MM-DD
-	if matched, _ := regexp.MatchString(`_\d{8}$|_\d{4}-\d{2}-\d{2}$`, dbName); matched {
-		return true
-	}
-
-	// Check for databases with timestamps
-	if matched, _ := regexp.MatchString(`_\d{10,}$`, dbName); matched {
-		return true
-	}
-
-	return false
+// dumpInvocationKey identifies a single-invocation dump in the progress file
+func dumpInvocationKey(args []string) string {
+	if dumpAllDatabases {
+		return "--all-databases"
+	}
+	return strings.Join(dumpedDatabases(args), ",")
 }
 
-func executeMysqldumpForDB(args []string, dbName string, password string, current, total int) error {
+func executeMysqldumpForDB(ctx context.Context, args []string, dbName string, password string, current, total int) error {
 	// Determine output file
-	outputFile := dumpOutput
-	if dumpCompress {
-		outputFile += ".sql.gz"
-	} else {
-		outputFile += ".sql"
-	}
+	outputFile := dumpOutputFile()
 
 	// For multiple databases, append to the same file
 	file, err := os.OpenFile(outputFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
@@ -429,9 +550,16 @@ func executeMysqldumpForDB(args []string, dbName string, password string, curren
 	}
 	defer file.Close()
 
+	// Each database is appended as its own compressed member/frame
+	out, err := newDumpWriter(file)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
 	// Add database header to the dump file
 	header := fmt.Sprintf("\n-- Database: %s\n-- Dumped at: %s\n\n", dbName, time.Now().Format("2006-01-02 15:04:05"))
-	if _, err := file.WriteString(header); err != nil {
+	if _, err := io.WriteString(out, header); err != nil {
 		return fmt.Errorf("failed to write header: %w", err)
 	}
 
@@ -460,37 +588,90 @@ password=%s
 	secureArgs := append([]string{"--defaults-file=" + tmpFile.Name()}, args...)
 
 	// Create the mysqldump command
-	cmd := exec.Command("mysqldump", secureArgs...)
+	cmd := exec.CommandContext(ctx, dumpMysqldump, secureArgs...)
 
 	// Set up output
-	cmd.Stdout = file
+	filter := newTablespaceFilter(out)
+	cmd.Stdout = filter
 	cmd.Stderr = os.Stderr
 
+	// Partition-filtered tables are read in a snapshot taken alongside
+	// mysqldump's
+	partitions, err := openPartitionSnapshot(ctx, []string{dbName})
+	if err != nil {
+		return err
+	}
+	defer partitions.close()
+
 	// Execute the command
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("mysqldump failed: %w", err)
 	}
+	if err := filter.Flush(); err != nil {
+		return fmt.Errorf("failed to write dump output: %w", err)
+	}
+
+	// Append the selected partitions of any partition-filtered tables
+	if err := partitions.write(ctx, out); err != nil {
+		return fmt.Errorf("failed to dump selected partitions: %w", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to finish compressed output: %w", err)
+	}
 
 	return nil
 }
 
-func executeMysqldump(args []string) error {
-	// Check if mysqldump is available
-	if _, err := exec.LookPath("mysqldump"); err != nil {
-		return fmt.Errorf("mysqldump not found in PATH. Please install MariaDB/MySQL client tools:\n\n" +
-			"  Ubuntu/Debian: sudo apt-get install mariadb-client\n" +
-			"  CentOS/RHEL: sudo yum install mariadb\n" +
-			"  macOS: brew install mariadb\n" +
-			"  Or download from: https://mariadb.com/downloads/")
+// mysqldumpNames are the dump client's names, mariadb-dump for MariaDB 10.5
+// and later installs that no longer ship the mysqldump alias
+var mysqldumpNames = []string{"mysqldump", "mariadb-dump"}
+
+// findMysqldump returns the --mysqldump binary, or else the first dump
+// client in PATH. Windows installers do not add the client to PATH, so there
+// the MariaDB and MySQL directories under Program Files are searched too.
+func findMysqldump() (string, error) {
+	if dumpMysqldump != "" {
+		path, err := exec.LookPath(dumpMysqldump)
+		if err != nil {
+			return "", fmt.Errorf("invalid --mysqldump %q: %w", dumpMysqldump, err)
+		}
+		return path, nil
+	}
+	for _, name := range mysqldumpNames {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	if runtime.GOOS == "windows" {
+		for _, env := range []string{"ProgramFiles", "ProgramFiles(x86)"} {
+			root := os.Getenv(env)
+			if root == "" {
+				continue
+			}
+			for _, dir := range []string{"MariaDB*", filepath.Join("MySQL", "MySQL Server*")} {
+				for _, name := range mysqldumpNames {
+					matches, _ := filepath.Glob(filepath.Join(root, dir, "bin", name+".exe"))
+					if len(matches) > 0 {
+						// Versioned directories sort oldest first
+						sort.Strings(matches)
+						return matches[len(matches)-1], nil
+					}
+				}
+			}
+		}
 	}
+	return "", fmt.Errorf("mysqldump not found in PATH. Please install MariaDB/MySQL client tools:\n\n" +
+		"  Ubuntu/Debian: sudo apt-get install mariadb-client\n" +
+		"  CentOS/RHEL: sudo yum install mariadb\n" +
+		"  macOS: brew install mariadb\n" +
+		"  Windows: install MariaDB from https://mariadb.com/downloads/, or pass --mysqldump C:\\path\\to\\mysqldump.exe\n" +
+		"  Or download from: https://mariadb.com/downloads/")
+}
 
+func executeMysqldump(ctx context.Context, args []string) error {
 	// Determine output file
-	outputFile := dumpOutput
-	if dumpCompress {
-		outputFile += ".sql.gz"
-	} else {
-		outputFile += ".sql"
-	}
+	outputFile := dumpOutputFile()
 
 	// Create a temporary my.cnf file for secure password passing
 	tmpFile, err := os.CreateTemp("", "mariadb-extractor-*.cnf")
@@ -516,10 +697,10 @@ password=%s
 	// Add --defaults-file to use our secure config
 	secureArgs := append([]string{"--defaults-file=" + tmpFile.Name()}, args...)
 
-	fmt.Printf("Executing: mysqldump --defaults-file=**** %s > %s\n", strings.Join(args, " "), outputFile)
+	fmt.Printf("Executing: %s --defaults-file=**** %s > %s\n", filepath.Base(dumpMysqldump), strings.Join(args, " "), outputFile)
 
 	// Create the mysqldump command
-	cmd := exec.Command("mysqldump", secureArgs...)
+	cmd := exec.CommandContext(ctx, dumpMysqldump, secureArgs...)
 
 	// Set up output file
 	file, err := os.Create(outputFile)
@@ -528,53 +709,356 @@ password=%s
 	}
 	defer file.Close()
 
-	// If compression is requested, pipe through gzip
-	if dumpCompress {
-		// Check if gzip is available
-		if _, err := exec.LookPath("gzip"); err != nil {
-			return fmt.Errorf("gzip not found in PATH. Please install gzip compression:\n\n" +
-				"  Ubuntu/Debian: sudo apt-get install gzip\n" +
-				"  CentOS/RHEL: sudo yum install gzip\n" +
-				"  macOS: gzip is usually pre-installed")
-		}
+	// If compression is requested, mysqldump output is compressed on the fly.
+	// The checksum covers the bytes on disk, i.e. the compressed stream.
+	sum := checksum.NewWriter(file)
+	out, err := newDumpWriter(sum)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	filter := newTablespaceFilter(out)
+	cmd.Stdout = filter
+
+	// Partition-filtered tables are read in a snapshot taken alongside
+	// mysqldump's
+	partitions, err := openPartitionSnapshot(ctx, dumpedDatabases(args))
+	if err != nil {
+		return err
+	}
+	defer partitions.close()
+
+	// Execute mysqldump
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("mysqldump failed: %w", err)
+	}
+	if err := filter.Flush(); err != nil {
+		return fmt.Errorf("failed to write dump output: %w", err)
+	}
+
+	if err := partitions.write(ctx, out); err != nil {
+		return fmt.Errorf("failed to dump selected partitions: %w", err)
+	}
 
-		gzipCmd := exec.Command("gzip")
-		gzipCmd.Stdout = file
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to finish compressed output: %w", err)
+	}
+
+	return recordChecksum(outputFile, sum.Sum())
+}
 
-		// Pipe mysqldump output to gzip
-		gzipCmd.Stdin, err = cmd.StdoutPipe()
+// dumpOutputFile returns the dump file name for the selected compression
+func dumpOutputFile() string {
+	prefix := sink.Prefix(dumpOutput)
+	switch {
+	case !dumpCompress:
+		return prefix + ".sql"
+	case dumpCompressFormat == "zstd":
+		return prefix + ".sql.zst"
+	default:
+		return prefix + ".sql.gz"
+	}
+}
+
+// newDumpWriter wraps w with the configured compressor. Closing the returned
+// writer finishes the compressed stream but leaves w open; closing it twice
+// is harmless.
+func newDumpWriter(w io.Writer) (io.WriteCloser, error) {
+	if !dumpCompress {
+		return nopWriteCloser{w}, nil
+	}
+
+	if dumpCompressFormat == "zstd" {
+		z, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(max(dumpCompressThreads, 1)))
 		if err != nil {
-			return fmt.Errorf("failed to create pipe: %w", err)
+			return nil, fmt.Errorf("failed to start zstd compression: %w", err)
 		}
+		return z, nil
+	}
+
+	z := pgzip.NewWriter(w)
+	if err := z.SetConcurrency(1<<20, max(dumpCompressThreads, 1)); err != nil {
+		return nil, fmt.Errorf("failed to start gzip compression: %w", err)
+	}
+	return z, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// tablespaceFilter is a line-oriented writer that strips DATA/INDEX DIRECTORY
+// clauses from mysqldump output when --no-tablespaces is set
+type tablespaceFilter struct {
+	w       io.Writer
+	pending []byte
+}
+
+func newTablespaceFilter(w io.Writer) *tablespaceFilter {
+	return &tablespaceFilter{w: w}
+}
 
-		// Start gzip first
-		if err := gzipCmd.Start(); err != nil {
-			return fmt.Errorf("failed to start gzip: %w", err)
+func (f *tablespaceFilter) Write(p []byte) (int, error) {
+	if !dumpNoTablespaces {
+		return f.w.Write(p)
+	}
+
+	f.pending = append(f.pending, p...)
+	for {
+		idx := bytes.IndexByte(f.pending, '\n')
+		if idx < 0 {
+			break
 		}
+		line := stripTablespaceClauses(string(f.pending[:idx+1]))
+		if _, err := io.WriteString(f.w, line); err != nil {
+			return 0, err
+		}
+		f.pending = f.pending[idx+1:]
+	}
 
-		// Start mysqldump
-		if err := cmd.Start(); err != nil {
-			return fmt.Errorf("failed to start mysqldump: %w", err)
+	return len(p), nil
+}
+
+// Flush writes any trailing partial line
+func (f *tablespaceFilter) Flush() error {
+	if len(f.pending) == 0 {
+		return nil
+	}
+	_, err := io.WriteString(f.w, stripTablespaceClauses(string(f.pending)))
+	f.pending = nil
+	return err
+}
+
+func stripTablespaceClauses(sql string) string {
+	if !dumpNoTablespaces {
+		return sql
+	}
+	return tablespaceClausePattern.ReplaceAllString(sql, "")
+}
+
+// parsePartitionSpecs parses --partitions values of the form db.table:p1,p2
+// into a map keyed by "db.table"
+func parsePartitionSpecs(specs []string) (map[string][]string, error) {
+	result := make(map[string][]string)
+
+	for _, spec := range specs {
+		tableKey, partList, ok := strings.Cut(spec, ":")
+		if !ok || partList == "" {
+			return nil, fmt.Errorf("%q: expected db.table:partition[,partition...]", spec)
+		}
+		if dbName, tableName, ok := strings.Cut(tableKey, "."); !ok || dbName == "" || tableName == "" {
+			return nil, fmt.Errorf("%q: table must be qualified as db.table", spec)
 		}
 
-		// Wait for mysqldump to complete
-		if err := cmd.Wait(); err != nil {
-			return fmt.Errorf("mysqldump failed: %w", err)
+		for _, part := range strings.Split(partList, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result[tableKey] = append(result[tableKey], part)
+			}
 		}
+	}
+
+	return result, nil
+}
 
-		// Wait for gzip to complete
-		if err := gzipCmd.Wait(); err != nil {
-			return fmt.Errorf("gzip failed: %w", err)
+// partitionIgnoreArgs excludes partition-filtered tables of dbName (or of every
+// database when dbName is empty) from the regular mysqldump run; their selected
+// partitions are appended afterwards
+func partitionIgnoreArgs(dbName string) []string {
+	if dumpSchemaOnly {
+		return nil
+	}
+
+	specs, _ := parsePartitionSpecs(dumpPartitions)
+	var args []string
+	for tableKey := range specs {
+		if dbName == "" || strings.HasPrefix(tableKey, dbName+".") {
+			args = append(args, "--ignore-table="+tableKey)
 		}
-	} else {
-		// Direct output to file
-		cmd.Stdout = file
+	}
+	sort.Strings(args)
+
+	return args
+}
+
+// dumpedDatabases returns the database names a single mysqldump invocation
+// covers, or nil when it dumps every database
+func dumpedDatabases(args []string) []string {
+	if len(args) == 0 || dumpAllDatabases {
+		return nil
+	}
+	return []string{args[len(args)-1]}
+}
+
+// partitionSnapshot reads the partitions requested via --partitions in one
+// read-only transaction WITH CONSISTENT SNAPSHOT. It is opened right before
+// mysqldump starts its own --single-transaction snapshot, so the partition
+// rows are consistent with each other and read at nearly the same point in
+// time as the rest of the dump. Methods are safe on nil, which dumps nothing.
+type partitionSnapshot struct {
+	db        *sql.DB
+	conn      *sql.Conn
+	specs     map[string][]string
+	tableKeys []string
+}
+
+// openPartitionSnapshot starts the snapshot for the --partitions tables in
+// databases (nil means all), or returns nil when there are none
+func openPartitionSnapshot(ctx context.Context, databases []string) (*partitionSnapshot, error) {
+	if len(dumpPartitions) == 0 || dumpSchemaOnly {
+		return nil, nil
+	}
+
+	specs, err := parsePartitionSpecs(dumpPartitions)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	for _, dbName := range databases {
+		wanted[dbName] = true
+	}
+
+	var tableKeys []string
+	for tableKey := range specs {
+		dbName, _, _ := strings.Cut(tableKey, ".")
+		if databases == nil || wanted[dbName] {
+			tableKeys = append(tableKeys, tableKey)
+		}
+	}
+	if len(tableKeys) == 0 {
+		return nil, nil
+	}
+	sort.Strings(tableKeys)
+
+	db, err := openDumpDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := &partitionSnapshot{db: db, specs: specs, tableKeys: tableKeys}
+	if s.conn, err = db.Conn(ctx); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to start partition snapshot: %w", err)
+	}
+	for _, statement := range []string{
+		"SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+	} {
+		if _, err := s.conn.ExecContext(ctx, annotateQuery(statement)); err != nil {
+			s.close()
+			return nil, fmt.Errorf("failed to start partition snapshot: %w", err)
+		}
+	}
+	return s, nil
+}
+
+// write writes schema and data for the requested partitions to w
+func (s *partitionSnapshot) write(ctx context.Context, w io.Writer) error {
+	if s == nil {
+		return nil
+	}
+
+	for _, tableKey := range s.tableKeys {
+		dbName, tableName, _ := strings.Cut(tableKey, ".")
+		partitions := s.specs[tableKey]
+		fmt.Printf("📦 Dumping partitions %s of %s\n", strings.Join(partitions, ","), tableKey)
+
+		fmt.Fprintf(w, "\n--\n-- Partitions %s of table `%s`.`%s`\n--\n\n", strings.Join(partitions, ","), dbName, tableName)
+		fmt.Fprintf(w, "USE `%s`;\n", dbName)
 
-		// Execute mysqldump
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("mysqldump failed: %w", err)
+		if !dumpDataOnly {
+			var name, createTable string
+			query := fmt.Sprintf("SHOW CREATE TABLE `%s`.`%s`", dbName, tableName)
+			if err := s.conn.QueryRowContext(ctx, annotateQuery(query)).Scan(&name, &createTable); err != nil {
+				return fmt.Errorf("failed to get DDL for %s: %w", tableKey, err)
+			}
+			fmt.Fprintf(w, "DROP TABLE IF EXISTS `%s`;\n", tableName)
+			fmt.Fprintf(w, "%s;\n\n", stripTablespaceClauses(createTable))
+		}
+
+		quoted := make([]string, len(partitions))
+		for i, part := range partitions {
+			quoted[i] = fmt.Sprintf("`%s`", part)
+		}
+		query := fmt.Sprintf("SELECT * FROM `%s`.`%s` PARTITION (%s)", dbName, tableName, strings.Join(quoted, ","))
+
+		if err := writeInsertStatements(ctx, s.conn, w, tableName, query); err != nil {
+			return fmt.Errorf("failed to dump partitions of %s: %w", tableKey, err)
 		}
 	}
 
 	return nil
 }
+
+// close ends the snapshot transaction
+func (s *partitionSnapshot) close() {
+	if s == nil {
+		return
+	}
+	if s.conn != nil {
+		s.conn.ExecContext(context.Background(), "ROLLBACK")
+		s.conn.Close()
+	}
+	s.db.Close()
+}
+
+// writeInsertStatements streams the result of query, read on conn so it sees
+// the conn's snapshot, as batched INSERT statements for tableName. Values are
+// formatted by column kind, like the data command writes them.
+func writeInsertStatements(ctx context.Context, conn *sql.Conn, w io.Writer, tableName, query string) error {
+	const batchSize = 100
+
+	rows, err := conn.QueryContext(ctx, annotateQuery(query))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	kinds, err := columnKinds(rows)
+	if err != nil {
+		return err
+	}
+	precisions, err := columnPrecisions(rows)
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	var batchValues []string
+	flush := func() {
+		if len(batchValues) > 0 {
+			fmt.Fprintf(w, "INSERT INTO `%s` VALUES\n%s;\n", tableName, strings.Join(batchValues, ",\n"))
+			batchValues = nil
+		}
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(valuePtrs...); err != nil {
+			return err
+		}
+
+		rowValues := make([]string, len(columns))
+		for i, v := range values {
+			rowValues[i] = formatSQLColumnValue(v, kinds[i], precisions[i])
+		}
+		batchValues = append(batchValues, fmt.Sprintf("(%s)", strings.Join(rowValues, ",")))
+
+		if len(batchValues) >= batchSize {
+			flush()
+		}
+	}
+	flush()
+
+	return rows.Err()
+}
@@ -0,0 +1,47 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// envList returns the comma-separated values of environment variable key
+func envList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// checkEncryptedColumns validates --encrypted-columns patterns
+func checkEncryptedColumns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.Contains(pattern, ".") {
+			return fmt.Errorf("invalid --encrypted-columns pattern %q: use table.column or db.table.column", pattern)
+		}
+	}
+	return nil
+}
+
+// encryptedColumn reports whether patterns tag column col of dbName.table as
+// holding application-encrypted ciphertext. Patterns match db.table.column
+// or table.column.
+func encryptedColumn(patterns []string, dbName, table, col string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, dbName+"."+table+"."+col); matched {
+			return true
+		}
+		if matched, _ := path.Match(pattern, table+"."+col); matched {
+			return true
+		}
+	}
+	return false
+}
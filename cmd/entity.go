@@ -0,0 +1,519 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/yaml"
+
+	"github.com/spf13/cobra"
+)
+
+// entityCmd represents the entity command
+var entityCmd = &cobra.Command{
+	Use:   "entity <definition.yaml>",
+	Short: "Export logical entities as JSON documents with nested related records",
+	Long: `Export one JSON document per root row of an entity definition, with the
+related rows of other tables nested inside it along the definition's join
+paths, e.g. a customer with its addresses, orders and order items. All rows
+are read in one consistent snapshot, which makes the documents suitable for
+moving individual entities between environments.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runEntity(cmd.Context(), args[0])
+	},
+}
+
+var (
+	entityHost       string
+	entityPort       int
+	entityUser       string
+	entityPassword   string
+	entityOutput     string
+	entityTimeout    int
+	entityMaxRetries int
+	entityBatchSize  int
+	entityWhere      string
+	entityPool       poolOptions
+)
+
+// entityDefinition is the entity definition file format
+type entityDefinition struct {
+	Name      string           `json:"name"`
+	Database  string           `json:"database"`
+	Root      entityRoot       `json:"root"`
+	Relations []entityRelation `json:"relations"`
+}
+
+// entityRoot selects the rows that become documents
+type entityRoot struct {
+	Table string   `json:"table"`
+	Key   []string `json:"key"`
+	Where string   `json:"where"`
+}
+
+// entityRelation nests the rows of Table whose On columns (keys) equal the
+// enclosing record's columns (values). Single nests one object instead of a
+// list, for relations pointing to a parent row.
+type entityRelation struct {
+	Table     string            `json:"table"`
+	Database  string            `json:"database"`
+	As        string            `json:"as"`
+	On        map[string]string `json:"on"`
+	Single    bool              `json:"single"`
+	Relations []entityRelation  `json:"relations"`
+}
+
+func init() {
+	rootCmd.AddCommand(entityCmd)
+
+	defaultTimeout := getEnvIntWithDefault("MARIADB_TIMEOUT", 300)
+	defaultUser := os.Getenv("MARIADB_USER")
+	defaultPassword := os.Getenv("MARIADB_PASSWORD")
+
+	entityCmd.Flags().StringVarP(&entityHost, "host", "H", getEnvWithDefault("MARIADB_HOST", "localhost"), "MariaDB host (env: MARIADB_HOST)")
+	entityCmd.Flags().IntVarP(&entityPort, "port", "P", getEnvIntWithDefault("MARIADB_PORT", 3306), "MariaDB port (env: MARIADB_PORT)")
+	entityCmd.Flags().StringVarP(&entityUser, "user", "u", defaultUser, "MariaDB username (env: MARIADB_USER)")
+	entityCmd.Flags().StringVarP(&entityPassword, "password", "p", defaultPassword, "MariaDB password (env: MARIADB_PASSWORD)")
+	entityCmd.Flags().StringVarP(&entityOutput, "output", "o", getEnvWithDefault("MARIADB_OUTPUT_PREFIX", "entity-extract"), "Output directory name under output/ (env: MARIADB_OUTPUT_PREFIX)")
+	entityCmd.Flags().IntVarP(&entityTimeout, "timeout", "t", defaultTimeout, "Query timeout in seconds (env: MARIADB_TIMEOUT)")
+	entityCmd.Flags().IntVar(&entityMaxRetries, "max-retries", getEnvIntWithDefault("MARIADB_MAX_RETRIES", 3), "Maximum retry attempts for failed queries (env: MARIADB_MAX_RETRIES)")
+	entityCmd.Flags().IntVar(&entityBatchSize, "batch-size", 100, "Root rows exported per batch of related-row queries")
+	entityCmd.Flags().StringVar(&entityWhere, "where", "", "Condition selecting root rows, overriding the definition's root.where")
+	addPoolFlags(entityCmd, &entityPool, 2, 2, defaultTimeout)
+
+	if defaultUser == "" {
+		entityCmd.MarkFlagRequired("user")
+	}
+	if defaultPassword == "" {
+		entityCmd.MarkFlagRequired("password")
+	}
+}
+
+func runEntity(ctx context.Context, path string) {
+	def, err := loadEntityDefinition(path)
+	if err != nil {
+		log.Fatalf("Failed to load entity definition: %v", err)
+	}
+	if entityWhere != "" {
+		def.Root.Where = entityWhere
+	}
+	if entityBatchSize <= 0 {
+		log.Fatalf("Invalid --batch-size %d: must be positive", entityBatchSize)
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&parseTime=true&timeout=%ds&readTimeout=%ds&writeTimeout=%ds",
+		entityUser, entityPassword, entityHost, entityPort, entityTimeout, entityTimeout, entityTimeout)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	entityPool.apply(db)
+
+	if err := pingWithRetry(ctx, db, entityMaxRetries); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+	fmt.Printf("Connected to MariaDB at %s:%d\n", entityHost, entityPort)
+
+	if err := exportEntities(ctx, db, def); err != nil {
+		log.Fatalf("Entity export failed: %v", err)
+	}
+}
+
+// loadEntityDefinition reads and validates an entity definition file
+func loadEntityDefinition(path string) (*entityDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var def entityDefinition
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("invalid entity definition %s: %w", path, err)
+	}
+
+	if def.Database == "" || def.Root.Table == "" || len(def.Root.Key) == 0 {
+		return nil, fmt.Errorf("%s: database, root.table and root.key are required", path)
+	}
+	if def.Name == "" {
+		def.Name = def.Root.Table
+	}
+	if err := validateEntityRelations(def.Relations, def.Database, def.Root.Table); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &def, nil
+}
+
+func validateEntityRelations(relations []entityRelation, database, parent string) error {
+	for i := range relations {
+		rel := &relations[i]
+		if rel.Table == "" || len(rel.On) == 0 {
+			return fmt.Errorf("relation of %s: table and on are required", parent)
+		}
+		if rel.Database == "" {
+			rel.Database = database
+		}
+		if rel.As == "" {
+			rel.As = rel.Table
+		}
+		if err := validateEntityRelations(rel.Relations, rel.Database, rel.Table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entityRecord is a row with its nested relations, marshalled with the
+// columns in table order followed by the relations in definition order
+type entityRecord struct {
+	columns []string
+	values  []interface{}
+	nested  []entityField
+}
+
+type entityField struct {
+	name  string
+	value interface{} // *entityRecord, []*entityRecord or nil
+}
+
+// MarshalJSON writes the record as an object with ordered fields
+func (r *entityRecord) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	write := func(i int, name string, value interface{}) error {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		b.Write(key)
+		b.WriteByte(':')
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+		return nil
+	}
+	for i, col := range r.columns {
+		if err := write(i, col, r.values[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i, field := range r.nested {
+		if err := write(len(r.columns)+i, field.name, field.value); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// get returns the value of a column, or nil when the record lacks it
+func (r *entityRecord) get(column string) interface{} {
+	for i, col := range r.columns {
+		if col == column {
+			return r.values[i]
+		}
+	}
+	return nil
+}
+
+// exportEntities writes one document per root row to output/<prefix>/
+func exportEntities(ctx context.Context, db *sql.DB, def *entityDefinition) error {
+	outputDir := filepath.Join("output", entityOutput)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	// A read-only repeatable-read transaction keeps every query of the export
+	// on the same snapshot, so documents are consistent across tables
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to start snapshot transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	keys, err := entityRootKeys(ctx, tx, def)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("📦 Exporting %d %s entities to %s\n", len(keys), def.Name, outputDir)
+
+	sums := make(map[string]string)
+	startTime := time.Now()
+	for start := 0; start < len(keys); start += entityBatchSize {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("export interrupted: %w", err)
+		}
+		end := min(start+entityBatchSize, len(keys))
+
+		roots, err := entityQuery(ctx, tx, def.Database, def.Root.Table, def.Root.Key, keys[start:end])
+		if err != nil {
+			return err
+		}
+		if err := attachRelations(ctx, tx, roots, def.Relations); err != nil {
+			return err
+		}
+
+		for _, root := range roots {
+			path, sum, err := writeEntityDocument(outputDir, def, root)
+			if err != nil {
+				return err
+			}
+			sums[path] = sum
+		}
+		fmt.Printf("   %d/%d entities\n", end, len(keys))
+	}
+
+	if err := checksum.Record(outputDir, sums); err != nil {
+		return fmt.Errorf("failed to record checksums: %w", err)
+	}
+	fmt.Printf("✅ Exported %d entities in %v\n", len(keys), time.Since(startTime).Round(time.Millisecond))
+	return nil
+}
+
+// entityRootKeys lists the key values of the root rows in key order
+func entityRootKeys(ctx context.Context, tx *sql.Tx, def *entityDefinition) ([][]interface{}, error) {
+	query := fmt.Sprintf("SELECT %s FROM `%s`.`%s`", quoteColumns(def.Root.Key), def.Database, def.Root.Table)
+	if def.Root.Where != "" {
+		query += " WHERE " + def.Root.Where
+	}
+	query += " ORDER BY " + quoteColumns(def.Root.Key)
+
+	records, err := entityRows(ctx, tx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select root rows: %w", err)
+	}
+	keys := make([][]interface{}, len(records))
+	for i, record := range records {
+		keys[i] = record.values
+	}
+	return keys, nil
+}
+
+// attachRelations nests the related rows of each relation into records,
+// querying each relation once for all records
+func attachRelations(ctx context.Context, tx *sql.Tx, records []*entityRecord, relations []entityRelation) error {
+	for _, rel := range relations {
+		var columns, parentColumns []string
+		for column, parentColumn := range rel.On {
+			columns = append(columns, column)
+			parentColumns = append(parentColumns, parentColumn)
+		}
+
+		// Collect the distinct, non-NULL values the records point at
+		seen := make(map[string]bool)
+		var tuples [][]interface{}
+		for _, record := range records {
+			tuple, key, ok := entityTuple(record, parentColumns)
+			if ok && !seen[key] {
+				seen[key] = true
+				tuples = append(tuples, tuple)
+			}
+		}
+
+		var related []*entityRecord
+		if len(tuples) > 0 {
+			var err error
+			if related, err = entityQuery(ctx, tx, rel.Database, rel.Table, columns, tuples); err != nil {
+				return err
+			}
+			if err := attachRelations(ctx, tx, related, rel.Relations); err != nil {
+				return err
+			}
+		}
+
+		byKey := make(map[string][]*entityRecord)
+		for _, child := range related {
+			if _, key, ok := entityTuple(child, columns); ok {
+				byKey[key] = append(byKey[key], child)
+			}
+		}
+		for _, record := range records {
+			_, key, ok := entityTuple(record, parentColumns)
+			matches := byKey[key]
+			if !ok {
+				matches = nil
+			}
+
+			field := entityField{name: rel.As}
+			if rel.Single {
+				if len(matches) > 0 {
+					field.value = matches[0]
+				}
+			} else {
+				if matches == nil {
+					matches = []*entityRecord{}
+				}
+				field.value = matches
+			}
+			record.nested = append(record.nested, field)
+		}
+	}
+	return nil
+}
+
+// entityTuple returns the values of columns in record and a string key for
+// them; ok is false when any is NULL
+func entityTuple(record *entityRecord, columns []string) ([]interface{}, string, bool) {
+	tuple := make([]interface{}, len(columns))
+	parts := make([]string, len(columns))
+	for i, col := range columns {
+		v := record.get(col)
+		if v == nil {
+			return nil, "", false
+		}
+		tuple[i] = v
+		parts[i] = fmt.Sprint(v)
+	}
+	return tuple, strings.Join(parts, "\x00"), true
+}
+
+// entityQueryChunk bounds the tuples per query, keeping placeholders well
+// below the server's limit of 65535 per statement
+const entityQueryChunk = 1000
+
+// entityQuery selects the rows of a table whose columns match any of tuples
+func entityQuery(ctx context.Context, tx *sql.Tx, dbName, table string, columns []string, tuples [][]interface{}) ([]*entityRecord, error) {
+	placeholder := "(" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+
+	var records []*entityRecord
+	for start := 0; start < len(tuples); start += entityQueryChunk {
+		chunk := tuples[start:min(start+entityQueryChunk, len(tuples))]
+		placeholders := make([]string, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*len(columns))
+		for i, tuple := range chunk {
+			placeholders[i] = placeholder
+			for _, v := range tuple {
+				args = append(args, jsonArg(v))
+			}
+		}
+
+		query := fmt.Sprintf("SELECT * FROM `%s`.`%s` WHERE (%s) IN (%s) ORDER BY %s",
+			dbName, table, quoteColumns(columns), strings.Join(placeholders, ","), quoteColumns(columns))
+		chunkRecords, err := entityRows(ctx, tx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query %s.%s: %w", dbName, table, err)
+		}
+		records = append(records, chunkRecords...)
+	}
+	return records, nil
+}
+
+// jsonArg turns a value converted for JSON back into a query argument
+func jsonArg(v interface{}) interface{} {
+	if n, ok := v.(json.Number); ok {
+		return string(n)
+	}
+	return v
+}
+
+// entityRows runs a query in the snapshot and reads all rows as records
+func entityRows(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]*entityRecord, error) {
+	ctx, cancel := withTimeout(ctx, entityTimeout)
+	defer cancel()
+
+	rows, err := tx.QueryContext(ctx, annotateQuery(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []*entityRecord
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			values[i] = jsonValue(v, types[i].DatabaseTypeName())
+		}
+		records = append(records, &entityRecord{columns: columns, values: values})
+	}
+	return records, rows.Err()
+}
+
+// jsonValue converts a driver value to its JSON form: numbers stay numbers,
+// binary data is base64-encoded by encoding/json, everything else is text
+func jsonValue(v interface{}, typeName string) interface{} {
+	switch val := v.(type) {
+	case []byte:
+		typeName = strings.TrimPrefix(typeName, "UNSIGNED ")
+		switch typeName {
+		case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "DECIMAL", "FLOAT", "DOUBLE", "YEAR":
+			return json.Number(val)
+		case "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB", "BIT", "GEOMETRY":
+			return val
+		}
+		return string(val)
+	case time.Time:
+		return formatDateTime(val, -1)
+	}
+	return v
+}
+
+// unsafeFileChars are replaced in key values used as file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeEntityDocument writes a root record to <name>-<key>.json and returns
+// the path and its SHA-256
+func writeEntityDocument(outputDir string, def *entityDefinition, root *entityRecord) (string, string, error) {
+	parts := []string{def.Name}
+	for _, col := range def.Root.Key {
+		parts = append(parts, unsafeFileChars.ReplaceAllString(fmt.Sprint(root.get(col)), "_"))
+	}
+	path := filepath.Join(outputDir, strings.Join(parts, "-")+".json")
+
+	data, err := json.MarshalIndent(root, "", "  ")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	data = append(data, '\n')
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+	sum := checksum.NewWriter(file)
+	if _, err := sum.Write(data); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, sum.Sum(), nil
+}
+
+// quoteColumns returns a comma-separated list of backtick-quoted columns
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = "`" + col + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -4,18 +4,51 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/sink"
+	"mariadb-extractor/internal/snapshot"
+
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/spf13/cobra"
 )
 
+// Extract JSON schema versions. Version 1 is the original layout; version 2
+// adds per-table column and index detail. Fields are only ever added within a
+// version; renames or removals require a new version.
+const (
+	extractSchemaVersionV1 = 1
+	extractSchemaVersionV2 = 2
+)
+
+// ExtractOutput is the top-level document written to <prefix>.json
+type ExtractOutput struct {
+	SchemaVersion int             `json:"schema_version"`
+	Metadata      ExtractMetadata `json:"metadata"`
+	Databases     []DatabaseInfo  `json:"databases"`
+}
+
+// ExtractMetadata describes the extraction run
+type ExtractMetadata struct {
+	Server         string `json:"server"`
+	Flavor         string `json:"flavor,omitempty"`
+	ServerVersion  string `json:"server_version,omitempty"`
+	User           string `json:"user"`
+	ExtractedAt    string `json:"extracted_at"`
+	TotalDatabases int    `json:"total_databases"`
+}
+
 // DatabaseInfo represents database information
 type DatabaseInfo struct {
 	Name        string      `json:"name"`
@@ -34,6 +67,47 @@ type TableInfo struct {
 	IndexLength int64  `json:"index_length,omitempty"`
 	Collation   string `json:"collation,omitempty"`
 	Comment     string `json:"comment,omitempty"`
+
+	// Populated only for --format json-v2
+	Columns     []ColumnInfo     `json:"columns,omitempty"`
+	Indexes     []IndexInfo      `json:"indexes,omitempty"`
+	ForeignKeys []ForeignKeyDecl `json:"foreign_keys,omitempty"`
+	Lineage     []ColumnLineage  `json:"lineage,omitempty"`
+}
+
+// ColumnInfo represents a table column (json-v2)
+type ColumnInfo struct {
+	Name       string  `json:"name"`
+	Position   int     `json:"position"`
+	DataType   string  `json:"data_type"`
+	ColumnType string  `json:"column_type"`
+	Nullable   bool    `json:"nullable"`
+	Default    *string `json:"default"`
+	Key        string  `json:"key,omitempty"`
+	Extra      string  `json:"extra,omitempty"`
+	Collation  string  `json:"collation,omitempty"`
+	Comment    string  `json:"comment,omitempty"`
+	// Encrypted marks columns tagged by --encrypted-columns as holding
+	// application-encrypted ciphertext
+	Encrypted bool `json:"encrypted,omitempty"`
+}
+
+// IndexInfo represents a table index (json-v2)
+type IndexInfo struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique"`
+	Type    string   `json:"type"`
+	Columns []string `json:"columns"`
+}
+
+// ForeignKeyDecl represents a foreign key constraint declared on a table (json-v2)
+type ForeignKeyDecl struct {
+	Name              string   `json:"name"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referenced_schema"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	Inferred          bool     `json:"inferred,omitempty"`
 }
 
 // extractCmd represents the extract command
@@ -44,7 +118,7 @@ var extractCmd = &cobra.Command{
 Generates both markdown (.md) and JSON (.json) output files with
 structured information about databases and their tables.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		runExtract()
+		runExtract(cmd.Context())
 	},
 }
 
@@ -54,8 +128,47 @@ var (
 	user     string
 	password string
 	output   string
+	format   string
+
+	includeSystem bool
+
+	inferRelations bool
+	inferSample    int
+
+	encryptedColumns []string
+
+	historyFile string
+	noHistory   bool
+
+	maxRetries int
+	pool       poolOptions
+
+	// extractServer is the detected source server
+	extractServer serverInfo
 )
 
+// systemDatabases are the server's own schemas, skipped unless explicitly requested
+var systemDatabases = []string{"information_schema", "mysql", "performance_schema", "sys"}
+
+// schemataQuery returns the query listing database names, excluding system
+// databases unless includeSystem is set
+func schemataQuery(includeSystem bool) string {
+	if includeSystem {
+		return `
+		SELECT SCHEMA_NAME
+		FROM information_schema.SCHEMATA
+		ORDER BY SCHEMA_NAME
+	`
+	}
+
+	return fmt.Sprintf(`
+		SELECT SCHEMA_NAME
+		FROM information_schema.SCHEMATA
+		WHERE SCHEMA_NAME NOT IN ('%s')
+		ORDER BY SCHEMA_NAME
+	`, strings.Join(systemDatabases, "', '"))
+}
+
 // getEnvWithDefault returns environment variable value or default if not set
 func getEnvWithDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -89,7 +202,17 @@ func init() {
 	extractCmd.Flags().IntVarP(&port, "port", "P", defaultPort, "MariaDB port (env: MARIADB_PORT)")
 	extractCmd.Flags().StringVarP(&user, "user", "u", defaultUser, "MariaDB username (env: MARIADB_USER)")
 	extractCmd.Flags().StringVarP(&password, "password", "p", defaultPassword, "MariaDB password (env: MARIADB_PASSWORD)")
-	extractCmd.Flags().StringVarP(&output, "output", "o", defaultOutput, "Output file prefix (env: MARIADB_OUTPUT_PREFIX)")
+	extractCmd.Flags().StringVarP(&output, "output", "o", defaultOutput, "Output file prefix, or a URI such as s3://bucket/path/prefix, ssh://host/path/prefix or - for stdout (env: MARIADB_OUTPUT_PREFIX)")
+	extractCmd.Flags().BoolVar(&includeSystem, "include-system", false, "Include system databases (information_schema, mysql, performance_schema, sys)")
+	extractCmd.Flags().BoolVar(&includeSystem, "all-databases", false, "Alias for --include-system, matching data and dump")
+	extractCmd.Flags().IntVar(&maxRetries, "max-retries", getEnvIntWithDefault("MARIADB_MAX_RETRIES", 3), "Maximum retry attempts for failed queries (env: MARIADB_MAX_RETRIES)")
+	addPoolFlags(extractCmd, &pool, 0, 2, 0)
+	extractCmd.Flags().StringVar(&historyFile, "history-file", defaultHistoryFile(), "Snapshot catalog each run is recorded in (env: MARIADB_HISTORY_FILE)")
+	extractCmd.Flags().BoolVar(&noHistory, "no-history", false, "Do not record this run in the snapshot catalog")
+	extractCmd.Flags().StringVar(&format, "format", "json", "JSON output format: json (schema v1) or json-v2 (adds columns and indexes)")
+	extractCmd.Flags().BoolVar(&inferRelations, "infer-relationships", os.Getenv("MARIADB_INFER_RELATIONSHIPS") == "true", "Add undeclared relationships of <table>_id columns to json-v2 foreign keys, marked inferred (env: MARIADB_INFER_RELATIONSHIPS)")
+	extractCmd.Flags().StringSliceVar(&encryptedColumns, "encrypted-columns", envList("MARIADB_ENCRYPTED_COLUMNS"), "Columns holding application-encrypted ciphertext, marked encrypted in json-v2 (table.column or db.table.column, supports wildcards) (env: MARIADB_ENCRYPTED_COLUMNS)")
+	extractCmd.Flags().IntVar(&inferSample, "infer-sample", 0, "Check up to N distinct values of each inferred relationship against the parent table (0=names and types only)")
 
 	// Only mark as required if not set via environment
 	if defaultUser == "" {
@@ -100,7 +223,17 @@ func init() {
 	}
 }
 
-func runExtract() {
+func runExtract(ctx context.Context) {
+	if format != "json" && format != "json-v2" {
+		log.Fatalf("Invalid --format %q: must be json or json-v2", format)
+	}
+	if inferRelations && format != "json-v2" {
+		log.Fatalf("--infer-relationships adds to the foreign keys of --format json-v2")
+	}
+	if err := checkEncryptedColumns(encryptedColumns); err != nil {
+		log.Fatal(err)
+	}
+
 	// Build connection string
 	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&parseTime=true",
 		user, password, host, port)
@@ -111,40 +244,86 @@ func runExtract() {
 	}
 	defer db.Close()
 
-	if err := db.Ping(); err != nil {
+	pool.apply(db)
+
+	if err := pingWithRetry(ctx, db, maxRetries); err != nil {
 		log.Fatalf("Failed to ping database: %v", err)
 	}
 
+	out, restoreStdout, err := openOutputSink(output)
+	if err != nil {
+		log.Fatalf("Invalid --output: %v", err)
+	}
+	defer restoreStdout()
+	prefix := sink.Prefix(output)
+
 	fmt.Printf("Connected to MariaDB at %s:%d\n", host, port)
+	extractServer = connectedServer(ctx, db)
 
 	// Extract database information
-	databases, err := extractDatabases(db)
+	databases, err := extractDatabases(ctx, db)
 	if err != nil {
 		log.Fatalf("Failed to extract databases: %v", err)
 	}
+	if format == "json-v2" {
+		if err := addViewLineage(ctx, db, databases); err != nil {
+			log.Printf("Warning: failed to trace view column lineage: %v", err)
+		}
+	}
 
 	// Generate outputs
-	if err := generateMarkdownOutput(databases, output); err != nil {
+	if err := generateMarkdownOutput(databases, prefix); err != nil {
 		log.Fatalf("Failed to generate markdown output: %v", err)
 	}
 
-	if err := generateJSONOutput(databases, output); err != nil {
+	if err := generateJSONOutput(databases, prefix); err != nil {
 		log.Fatalf("Failed to generate JSON output: %v", err)
 	}
 
-	fmt.Printf("Extraction completed! Generated %s.md and %s.json\n", output, output)
+	if err := publishOutputs(ctx, out, ".", prefix+".json", prefix+".md", "SHA256SUMS"); err != nil {
+		log.Fatalf("Failed to publish output: %v", err)
+	}
+
+	if !noHistory {
+		if err := recordSnapshot(databases, historyFile); err != nil {
+			log.Printf("Warning: failed to record run in snapshot catalog: %v", err)
+		} else {
+			fmt.Printf("Recorded run in snapshot catalog %s\n", historyFile)
+		}
+	}
+
+	fmt.Printf("Extraction completed! Generated %s.md and %s.json\n", prefix, prefix)
 }
 
-func extractDatabases(db *sql.DB) ([]DatabaseInfo, error) {
-	// Get all databases (excluding system databases)
-	query := `
-		SELECT SCHEMA_NAME
-		FROM information_schema.SCHEMATA
-		WHERE SCHEMA_NAME NOT IN ('information_schema', 'mysql', 'performance_schema', 'sys')
-		ORDER BY SCHEMA_NAME
-	`
+// recordSnapshot appends the extracted metadata to the snapshot catalog
+func recordSnapshot(databases []DatabaseInfo, path string) error {
+	now := time.Now()
+	run := snapshot.Run{
+		ID:          snapshot.NewRunID(now),
+		Server:      fmt.Sprintf("%s:%d", host, port),
+		ExtractedAt: now,
+	}
+
+	for _, db := range databases {
+		snapDB := snapshot.Database{Name: db.Name}
+		for _, table := range db.Tables {
+			snapDB.Tables = append(snapDB.Tables, snapshot.Table{
+				Name:        table.Name,
+				Engine:      table.Engine,
+				RowCount:    table.RowCount,
+				DataLength:  table.DataLength,
+				IndexLength: table.IndexLength,
+			})
+		}
+		run.Databases = append(run.Databases, snapDB)
+	}
+
+	return snapshot.Open(path).Append(run)
+}
 
-	rows, err := db.Query(query)
+func extractDatabases(ctx context.Context, db *sql.DB) ([]DatabaseInfo, error) {
+	// Get all databases (excluding system databases unless requested)
+	rows, err := queryWithRetry(ctx, db, maxRetries, schemataQuery(includeSystem))
 	if err != nil {
 		return nil, fmt.Errorf("failed to query databases: %w", err)
 	}
@@ -158,9 +337,13 @@ func extractDatabases(db *sql.DB) ([]DatabaseInfo, error) {
 			return nil, fmt.Errorf("failed to scan database name: %w", err)
 		}
 
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("extraction interrupted: %w", err)
+		}
+
 		fmt.Printf("Extracting database: %s\n", dbName)
 
-		tables, err := extractTables(db, dbName)
+		tables, err := extractTables(ctx, db, dbName)
 		if err != nil {
 			log.Printf("Warning: failed to extract tables for %s: %v", dbName, err)
 			tables = []TableInfo{}
@@ -179,7 +362,7 @@ func extractDatabases(db *sql.DB) ([]DatabaseInfo, error) {
 	return databases, nil
 }
 
-func extractTables(db *sql.DB, dbName string) ([]TableInfo, error) {
+func extractTables(ctx context.Context, db *sql.DB, dbName string) ([]TableInfo, error) {
 	query := `
 		SELECT
 			TABLE_NAME,
@@ -195,7 +378,7 @@ func extractTables(db *sql.DB, dbName string) ([]TableInfo, error) {
 		ORDER BY TABLE_NAME
 	`
 
-	rows, err := db.Query(query, dbName)
+	rows, err := queryWithRetry(ctx, db, maxRetries, query, dbName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query tables: %w", err)
 	}
@@ -243,10 +426,186 @@ func extractTables(db *sql.DB, dbName string) ([]TableInfo, error) {
 
 		tables = append(tables, table)
 	}
+	rows.Close()
+
+	if format == "json-v2" {
+		for i := range tables {
+			columns, err := extractColumns(ctx, db, dbName, tables[i].Name)
+			if err != nil {
+				return nil, err
+			}
+			tables[i].Columns = columns
+
+			indexes, err := extractIndexes(ctx, db, dbName, tables[i].Name)
+			if err != nil {
+				return nil, err
+			}
+			tables[i].Indexes = indexes
+
+			foreignKeys, err := extractForeignKeys(ctx, db, dbName, tables[i].Name)
+			if err != nil {
+				return nil, err
+			}
+			tables[i].ForeignKeys = foreignKeys
+		}
+
+		if inferRelations {
+			inferred, err := inferRelationships(ctx, db, maxRetries, dbName, inferSample)
+			if err != nil {
+				return nil, err
+			}
+			for i := range tables {
+				for _, fk := range inferred[tables[i].Name] {
+					tables[i].ForeignKeys = append(tables[i].ForeignKeys, ForeignKeyDecl{
+						Name:              fk.ConstraintName,
+						Columns:           []string{fk.ColumnName},
+						ReferencedSchema:  dbName,
+						ReferencedTable:   fk.RefTableName,
+						ReferencedColumns: []string{fk.RefColumnName},
+						Inferred:          true,
+					})
+				}
+			}
+		}
+	}
 
 	return tables, nil
 }
 
+func extractColumns(ctx context.Context, db *sql.DB, dbName, tableName string) ([]ColumnInfo, error) {
+	query := `
+		SELECT
+			COLUMN_NAME,
+			ORDINAL_POSITION,
+			DATA_TYPE,
+			COLUMN_TYPE,
+			IS_NULLABLE,
+			COLUMN_DEFAULT,
+			COLUMN_KEY,
+			EXTRA,
+			COLLATION_NAME,
+			COLUMN_COMMENT
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`
+
+	rows, err := queryWithRetry(ctx, db, maxRetries, query, dbName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var column ColumnInfo
+		var nullable string
+		var defaultValue, collation sql.NullString
+
+		err := rows.Scan(
+			&column.Name,
+			&column.Position,
+			&column.DataType,
+			&column.ColumnType,
+			&nullable,
+			&defaultValue,
+			&column.Key,
+			&column.Extra,
+			&collation,
+			&column.Comment,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan column info: %w", err)
+		}
+
+		column.Nullable = nullable == "YES"
+		if defaultValue.Valid {
+			column.Default = &defaultValue.String
+		}
+		if collation.Valid {
+			column.Collation = collation.String
+		}
+		column.Encrypted = encryptedColumn(encryptedColumns, dbName, tableName, column.Name)
+
+		columns = append(columns, column)
+	}
+
+	return columns, nil
+}
+
+func extractIndexes(ctx context.Context, db *sql.DB, dbName, tableName string) ([]IndexInfo, error) {
+	query := `
+		SELECT INDEX_NAME, NON_UNIQUE, INDEX_TYPE, COLUMN_NAME
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME, SEQ_IN_INDEX
+	`
+
+	rows, err := queryWithRetry(ctx, db, maxRetries, query, dbName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []IndexInfo
+	for rows.Next() {
+		var name, indexType, columnName string
+		var nonUnique int
+
+		if err := rows.Scan(&name, &nonUnique, &indexType, &columnName); err != nil {
+			return nil, fmt.Errorf("failed to scan index info: %w", err)
+		}
+
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != name {
+			indexes = append(indexes, IndexInfo{
+				Name:   name,
+				Unique: nonUnique == 0,
+				Type:   indexType,
+			})
+		}
+		last := &indexes[len(indexes)-1]
+		last.Columns = append(last.Columns, columnName)
+	}
+
+	return indexes, nil
+}
+
+func extractForeignKeys(ctx context.Context, db *sql.DB, dbName, tableName string) ([]ForeignKeyDecl, error) {
+	query := `
+		SELECT CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_SCHEMA, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY CONSTRAINT_NAME, ORDINAL_POSITION
+	`
+
+	rows, err := queryWithRetry(ctx, db, maxRetries, query, dbName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var foreignKeys []ForeignKeyDecl
+	for rows.Next() {
+		var name, column, refSchema, refTable, refColumn string
+		if err := rows.Scan(&name, &column, &refSchema, &refTable, &refColumn); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key info: %w", err)
+		}
+
+		if len(foreignKeys) == 0 || foreignKeys[len(foreignKeys)-1].Name != name {
+			foreignKeys = append(foreignKeys, ForeignKeyDecl{
+				Name:             name,
+				ReferencedSchema: refSchema,
+				ReferencedTable:  refTable,
+			})
+		}
+		last := &foreignKeys[len(foreignKeys)-1]
+		last.Columns = append(last.Columns, column)
+		last.ReferencedColumns = append(last.ReferencedColumns, refColumn)
+	}
+
+	return foreignKeys, nil
+}
+
 func generateMarkdownOutput(databases []DatabaseInfo, outputPrefix string) error {
 	filename := fmt.Sprintf("%s.md", outputPrefix)
 	file, err := os.Create(filename)
@@ -254,49 +613,76 @@ func generateMarkdownOutput(databases []DatabaseInfo, outputPrefix string) error
 		return fmt.Errorf("failed to create markdown file: %w", err)
 	}
 	defer file.Close()
+	sum := checksum.NewWriter(file)
 
 	// Write header
-	fmt.Fprintf(file, "# MariaDB Database Extraction Report\n\n")
-	fmt.Fprintf(file, "**Generated on:** %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
-	fmt.Fprintf(file, "**Server:** %s:%d\n\n", host, port)
-	fmt.Fprintf(file, "**Total Databases:** %d\n\n", len(databases))
-	fmt.Fprintf(file, "---\n\n")
+	fmt.Fprintf(sum, "# MariaDB Database Extraction Report\n\n")
+	fmt.Fprintf(sum, "**Generated on:** %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(sum, "**Server:** %s:%d (%s)\n\n", host, port, extractServer)
+	fmt.Fprintf(sum, "**Total Databases:** %d\n\n", len(databases))
+	fmt.Fprintf(sum, "---\n\n")
 
 	totalTables := 0
 	for _, db := range databases {
 		totalTables += db.TableCount
 	}
 
-	fmt.Fprintf(file, "## Summary\n\n")
-	fmt.Fprintf(file, "- **Databases:** %d\n", len(databases))
-	fmt.Fprintf(file, "- **Total Tables:** %d\n\n", totalTables)
-	fmt.Fprintf(file, "---\n\n")
+	fmt.Fprintf(sum, "## Summary\n\n")
+	fmt.Fprintf(sum, "- **Databases:** %d\n", len(databases))
+	fmt.Fprintf(sum, "- **Total Tables:** %d\n\n", totalTables)
+	fmt.Fprintf(sum, "---\n\n")
 
 	// Write database details
 	for _, db := range databases {
-		fmt.Fprintf(file, "## Database: `%s`\n\n", db.Name)
-		fmt.Fprintf(file, "**Tables:** %d\n\n", db.TableCount)
+		fmt.Fprintf(sum, "## Database: `%s`\n\n", db.Name)
+		fmt.Fprintf(sum, "**Tables:** %d\n\n", db.TableCount)
 
 		if len(db.Tables) > 0 {
-			fmt.Fprintf(file, "### Tables\n\n")
-			fmt.Fprintf(file, "| Table Name | Type | Engine | Rows | Data Size | Index Size | Collation |\n")
-			fmt.Fprintf(file, "|-----------|------|--------|------|-----------|------------|-----------|\n")
+			fmt.Fprintf(sum, "### Tables\n\n")
+			fmt.Fprintf(sum, "| Table Name | Type | Engine | Rows | Data Size | Index Size | Collation |\n")
+			fmt.Fprintf(sum, "|-----------|------|--------|------|-----------|------------|-----------|\n")
 
 			for _, table := range db.Tables {
 				dataSize := formatBytes(table.DataLength)
 				indexSize := formatBytes(table.IndexLength)
-				fmt.Fprintf(file, "| `%s` | %s | %s | %d | %s | %s | %s |\n",
+				fmt.Fprintf(sum, "| `%s` | %s | %s | %d | %s | %s | %s |\n",
 					table.Name, table.Type, table.Engine,
 					table.RowCount, dataSize, indexSize, table.Collation)
 			}
 		} else {
-			fmt.Fprintf(file, "*No tables found*\n")
+			fmt.Fprintf(sum, "*No tables found*\n")
 		}
 
-		fmt.Fprintf(file, "\n---\n\n")
+		writeLineageMarkdown(sum, db.Tables)
+
+		fmt.Fprintf(sum, "\n---\n\n")
 	}
 
-	return nil
+	return recordChecksum(filename, sum.Sum())
+}
+
+// writeLineageMarkdown lists where the columns of views come from
+func writeLineageMarkdown(w io.Writer, tables []TableInfo) {
+	header := false
+	for _, table := range tables {
+		for _, col := range table.Lineage {
+			if !header {
+				fmt.Fprintf(w, "\n### View Lineage\n\n")
+				fmt.Fprintf(w, "| View | Column | Source Columns | Derived |\n")
+				fmt.Fprintf(w, "|------|--------|----------------|---------|\n")
+				header = true
+			}
+			sources := "-"
+			if len(col.Sources) > 0 {
+				sources = "`" + strings.Join(col.Sources, "`, `") + "`"
+			}
+			derived := ""
+			if col.Derived {
+				derived = "yes"
+			}
+			fmt.Fprintf(w, "| `%s` | `%s` | %s | %s |\n", table.Name, col.Column, sources, derived)
+		}
+	}
 }
 
 func generateJSONOutput(databases []DatabaseInfo, outputPrefix string) error {
@@ -307,20 +693,40 @@ func generateJSONOutput(databases []DatabaseInfo, outputPrefix string) error {
 	}
 	defer file.Close()
 
-	encoder := json.NewEncoder(file)
+	sum := checksum.NewWriter(file)
+	encoder := json.NewEncoder(sum)
 	encoder.SetIndent("", "  ")
 
-	output := map[string]any{
-		"metadata": map[string]any{
-			"server":          fmt.Sprintf("%s:%d", host, port),
-			"user":            user,
-			"extracted_at":    time.Now().Format(time.RFC3339),
-			"total_databases": len(databases),
+	schemaVersion := extractSchemaVersionV1
+	if format == "json-v2" {
+		schemaVersion = extractSchemaVersionV2
+	}
+
+	output := ExtractOutput{
+		SchemaVersion: schemaVersion,
+		Metadata: ExtractMetadata{
+			Server:         fmt.Sprintf("%s:%d", host, port),
+			Flavor:         extractServer.flavor,
+			ServerVersion:  extractServer.version,
+			User:           user,
+			ExtractedAt:    time.Now().Format(time.RFC3339),
+			TotalDatabases: len(databases),
 		},
-		"databases": databases,
+		Databases: databases,
+	}
+
+	if err := encoder.Encode(output); err != nil {
+		return err
 	}
+	return recordChecksum(filename, sum.Sum())
+}
 
-	return encoder.Encode(output)
+// recordChecksum adds a generated file's SHA-256 to the SHA256SUMS next to it
+func recordChecksum(path, sum string) error {
+	if err := checksum.Record(filepath.Dir(path), map[string]string{path: sum}); err != nil {
+		return fmt.Errorf("failed to record checksum: %w", err)
+	}
+	return nil
 }
 
 func formatBytes(bytes int64) string {
@@ -339,3 +745,26 @@ func formatBytes(bytes int64) string {
 
 	return fmt.Sprintf("%.1f %s", size, units[unitIndex])
 }
+
+// parseByteSize parses sizes such as "512", "64KB", "10MB" or "2GB"
+// (binary multiples, case-insensitive, optional "iB" suffix)
+func parseByteSize(value string) (int64, error) {
+	str := strings.ToUpper(strings.TrimSpace(value))
+	str = strings.TrimSuffix(str, "IB")
+	str = strings.TrimSuffix(str, "B")
+
+	multiplier := int64(1)
+	for i, unit := range []string{"K", "M", "G", "T"} {
+		if strings.HasSuffix(str, unit) {
+			multiplier = int64(1) << (10 * (i + 1))
+			str = strings.TrimSuffix(str, unit)
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return int64(number * float64(multiplier)), nil
+}
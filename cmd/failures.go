@@ -0,0 +1,123 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/pipeline"
+	"mariadb-extractor/internal/retry"
+	"mariadb-extractor/internal/sink"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// tableFailures lists the tables the current data extraction failed or
+// skipped, written to <prefix>.failures.json and the pipeline manifest
+var tableFailures []pipeline.TableFailure
+
+// conversionError is a value that could not be read or written as its
+// column type, such as a zero date with --zero-dates error
+type conversionError struct {
+	err error
+}
+
+func (e conversionError) Error() string { return e.err.Error() }
+func (e conversionError) Unwrap() error { return e.err }
+
+// timeoutErrors are server errors for a statement or lock wait that took too
+// long
+var timeoutErrors = map[uint16]bool{
+	1205: true, // ER_LOCK_WAIT_TIMEOUT
+	1317: true, // ER_QUERY_INTERRUPTED, e.g. killed by --timeout
+	1969: true, // ER_STATEMENT_TIMEOUT (MariaDB max_statement_time)
+	3024: true, // ER_QUERY_TIMEOUT (MySQL max_execution_time)
+}
+
+// permissionErrors are server errors for missing privileges
+var permissionErrors = map[uint16]bool{
+	1044: true, // ER_DBACCESS_DENIED_ERROR
+	1045: true, // ER_ACCESS_DENIED_ERROR
+	1142: true, // ER_TABLEACCESS_DENIED_ERROR
+	1143: true, // ER_COLUMNACCESS_DENIED_ERROR
+	1227: true, // ER_SPECIFIC_ACCESS_DENIED_ERROR
+}
+
+// classifyFailure returns the reason recorded for a table that failed with err
+func classifyFailure(ctx context.Context, err error) string {
+	var mysqlErr *mysql.MySQLError
+	var convErr conversionError
+	switch {
+	case errors.As(err, &convErr):
+		return "conversion"
+	case errors.As(err, &mysqlErr) && timeoutErrors[mysqlErr.Number]:
+		return "timeout"
+	case errors.As(err, &mysqlErr) && permissionErrors[mysqlErr.Number]:
+		return "permission"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case ctx.Err() != nil:
+		return "interrupted"
+	case retry.IsTransient(err):
+		return "connection"
+	}
+	return "other"
+}
+
+// recordTableFailure adds a failed table with the reason for err
+func recordTableFailure(ctx context.Context, table string, err error) {
+	tableFailures = append(tableFailures, pipeline.TableFailure{
+		Table:  table,
+		Status: pipeline.StatusFailed,
+		Reason: classifyFailure(ctx, err),
+		Error:  err.Error(),
+	})
+}
+
+// recordTableSkipped adds a table skipped for reason
+func recordTableSkipped(table, reason, detail string) {
+	tableFailures = append(tableFailures, pipeline.TableFailure{
+		Table:  table,
+		Status: pipeline.StatusSkipped,
+		Reason: reason,
+		Error:  detail,
+	})
+}
+
+// failuresFile is the report of failed and skipped tables next to the SQL
+// script
+func failuresFile() string {
+	return filepath.Join("output", sink.Prefix(dataOutput)+".failures.json")
+}
+
+// writeFailures writes tableFailures to failuresFile, or removes a report
+// left by an earlier run when every table was extracted
+func writeFailures() error {
+	path := failuresFile()
+	if len(tableFailures) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove old failure report: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(tableFailures, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write failure report: %w", err)
+	}
+	if _, err := checksum.RecordFiles(filepath.Dir(path), path); err != nil {
+		return fmt.Errorf("failed to record checksum: %w", err)
+	}
+	fmt.Printf("📝 %d failed or skipped tables listed in %s\n", len(tableFailures), path)
+	return nil
+}
@@ -0,0 +1,275 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/mask"
+	"mariadb-extractor/internal/pipeline"
+	"mariadb-extractor/internal/yaml"
+
+	"github.com/spf13/cobra"
+)
+
+// fixtureCmd represents the fixture command
+var fixtureCmd = &cobra.Command{
+	Use:   "fixture <config.yaml>",
+	Short: "Build a small, masked, deterministic seed data set for CI",
+	Long: `Build a seed data set small enough to commit to a repository, for
+seeding per-PR CI databases, from one config file listing tables and the
+rows to keep of each.
+
+Only the listed tables are extracted, each limited to its row budget, with
+the rows of child tables kept consistent with their sampled parents. The
+output is stable, so the same source data gives the same files on every
+run, and a listed table referencing an unlisted one is an error. The run
+fails when the files are larger than the config's max_size.
+
+The config's data options are data flags keyed by flag name and must
+configure masking (mask-config or transforms).
+
+Example:
+  mariadb-extractor fixture ci-fixture.yaml`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runFixture(cmd.Context(), args[0])
+	},
+}
+
+var fixturePool poolOptions
+
+// dataFixtureTables is the row budget of each db.table of a fixture run,
+// nil outside of fixtures
+var dataFixtureTables map[string]int64
+
+// defaultFixtureMaxSize is the size budget of a fixture without max_size
+const defaultFixtureMaxSize = "1MB"
+
+// fixtureConfig is the fixture config file format. Tables maps db.table to
+// the rows kept of it, and Data holds data flags keyed by flag name, like
+// the options of a pipeline's data step.
+type fixtureConfig struct {
+	Name       string                 `json:"name"`
+	Connection pipeline.Connection    `json:"connection"`
+	Tables     map[string]int64       `json:"tables"`
+	Data       map[string]interface{} `json:"data"`
+	MaxSize    string                 `json:"max_size"`
+	Output     string                 `json:"output"`
+
+	maxSize int64
+}
+
+// fixtureReserved are the data options a fixture sets itself
+var fixtureReserved = []string{
+	"databases", "all-databases", "all-user-databases", "exclude-databases",
+	"include-tables", "exclude-tables", "sample-tables", "sample-percent",
+	"sample-caps", "max-rows", "full-tables", "stable-output", "fk-consistent",
+	"no-foreign-key-check", "output", "resume", "plan",
+}
+
+func init() {
+	rootCmd.AddCommand(fixtureCmd)
+
+	addPoolFlags(fixtureCmd, &fixturePool, 5, 2, getEnvIntWithDefault("MARIADB_TIMEOUT", 300))
+}
+
+func loadFixtureConfig(path string) (*fixtureConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg fixtureConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid fixture config %s: %w", path, err)
+	}
+
+	if cfg.Name == "" || len(cfg.Tables) == 0 {
+		return nil, fmt.Errorf("%s: name and tables are required", path)
+	}
+	if strings.ContainsAny(cfg.Name, `/\`) {
+		return nil, fmt.Errorf("%s: name %q must not contain path separators", path, cfg.Name)
+	}
+	for table, rows := range cfg.Tables {
+		if dbName, tableName, ok := strings.Cut(table, "."); !ok || dbName == "" || tableName == "" {
+			return nil, fmt.Errorf("%s: table %q must be db.table", path, table)
+		}
+		if rows < 1 {
+			return nil, fmt.Errorf("%s: table %s must keep at least 1 row", path, table)
+		}
+	}
+	for _, reserved := range fixtureReserved {
+		if _, ok := cfg.Data[reserved]; ok {
+			return nil, fmt.Errorf("%s: data option %q is set by the fixture", path, reserved)
+		}
+	}
+	if cfg.MaxSize == "" {
+		cfg.MaxSize = defaultFixtureMaxSize
+	}
+	if cfg.maxSize, err = parseByteSize(cfg.MaxSize); err != nil || cfg.maxSize <= 0 {
+		return nil, fmt.Errorf("%s: invalid max_size %q", path, cfg.MaxSize)
+	}
+	if cfg.Output == "" {
+		cfg.Output = "fixture-" + cfg.Name
+	}
+	return &cfg, nil
+}
+
+func runFixture(ctx context.Context, path string) {
+	cfg, err := loadFixtureConfig(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conn := cfg.Connection
+	db, password := connectPipeline(ctx, &conn, fixturePool)
+	defer db.Close()
+
+	fmt.Printf("🧪 Building fixture %s (%d tables, max %s)\n", cfg.Name, len(cfg.Tables), formatBytes(cfg.maxSize))
+	files, err := buildFixtureData(ctx, db, conn, password, cfg)
+	if err != nil {
+		log.Fatalf("Failed to extract fixture data: %v", err)
+	}
+
+	var total int64
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Fatalf("Failed to read fixture file: %v", err)
+		}
+		total += info.Size()
+		fmt.Printf("   %s (%s)\n", path, formatBytes(info.Size()))
+	}
+	if total > cfg.maxSize {
+		log.Fatalf("Fixture %s is %s, over its max_size of %s: lower the row budgets of its largest tables",
+			cfg.Name, formatBytes(total), formatBytes(cfg.maxSize))
+	}
+	fmt.Printf("✅ Fixture %s: %s of %s\n", cfg.Name, formatBytes(total), formatBytes(cfg.maxSize))
+}
+
+// buildFixtureData extracts the fixture's tables and returns the files
+// written, without the checksum file and the manifest, which holds the
+// run's timings
+func buildFixtureData(ctx context.Context, db *sql.DB, conn pipeline.Connection, password string, cfg *fixtureConfig) ([]string, error) {
+	options := make(map[string]interface{}, len(cfg.Data)+4)
+	for name, value := range cfg.Data {
+		options[name] = value
+	}
+	var databases []interface{}
+	seen := make(map[string]bool)
+	for table := range cfg.Tables {
+		dbName, _, _ := strings.Cut(table, ".")
+		if !seen[dbName] {
+			seen[dbName] = true
+			databases = append(databases, dbName)
+		}
+	}
+	sort.Slice(databases, func(i, j int) bool { return databases[i].(string) < databases[j].(string) })
+	options["databases"] = databases
+	options["output"] = cfg.Output
+	options["stable-output"] = true
+	options["fk-consistent"] = true
+
+	step := pipeline.Step{Name: "data", Type: pipeline.StepData, Options: options}
+	if err := applyStepOptions(dataCmd.Flags(), conn, password, step); err != nil {
+		return nil, err
+	}
+	if err := validateDataOptions(); err != nil {
+		return nil, err
+	}
+	if err := checkFixtureDeterministic(); err != nil {
+		return nil, err
+	}
+
+	dataFixtureTables = cfg.Tables
+	defer func() { dataFixtureTables = nil }()
+	if err := runDataWithDB(ctx, db); err != nil {
+		return nil, err
+	}
+	if len(tableFailures) > 0 {
+		return nil, fmt.Errorf("%d tables failed, see %s", len(tableFailures), failuresFile())
+	}
+
+	var files []string
+	for _, path := range dataOutputFiles() {
+		if filepath.Base(path) != checksum.SumsFile && path != manifestFile() {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// checkFixtureDeterministic rejects data options that give different
+// output from the same source data
+func checkFixtureDeterministic() error {
+	if dataMaskConfig == "" && dataTransformsFile == "" {
+		return fmt.Errorf("a fixture must mask its data: set mask-config or transforms in the data options")
+	}
+	if dataTempUser {
+		return fmt.Errorf("option \"temp-user\" is not supported in fixtures")
+	}
+	if dataSampleStrategy == sampleRandom && dataSampleSeed == "" {
+		return fmt.Errorf("random samples of a fixture need a sample-seed to pick the same rows on every run")
+	}
+	if dataMaskConfig != "" {
+		masks, err := mask.Load(dataMaskConfig, dataMaskKey)
+		if err != nil {
+			return err
+		}
+		if masks.Uses("shuffle") {
+			return fmt.Errorf("shuffle masking rules are random and not supported in fixtures: use hash, pseudonymize or faker rules")
+		}
+	}
+	return nil
+}
+
+// fixturePlans keeps the plans of the tables of a fixture run and limits
+// each to its row budget. A table referencing a table left out of the
+// fixture is an error, as its rows would reference missing parents.
+func fixturePlans(plans []TableExtractionPlan) ([]TableExtractionPlan, error) {
+	var kept []TableExtractionPlan
+	found := make(map[string]bool)
+	for _, plan := range plans {
+		tableKey := plan.DatabaseName + "." + plan.TableName
+		rows, ok := dataFixtureTables[tableKey]
+		if !ok {
+			continue
+		}
+		found[tableKey] = true
+		plan.SampleSize = rows
+		kept = append(kept, plan)
+	}
+
+	problems := make(map[string]bool)
+	for table := range dataFixtureTables {
+		if !found[table] {
+			problems[fmt.Sprintf("table %s not found", table)] = true
+		}
+	}
+	for _, plan := range kept {
+		for _, fk := range plan.ForeignKeys {
+			parent := plan.DatabaseName + "." + fk.RefTableName
+			if _, ok := dataFixtureTables[parent]; !ok {
+				problems[fmt.Sprintf("%s.%s references %s, which is not in the fixture", plan.DatabaseName, plan.TableName, parent)] = true
+			}
+		}
+	}
+	if len(problems) > 0 {
+		list := make([]string, 0, len(problems))
+		for problem := range problems {
+			list = append(list, problem)
+		}
+		sort.Strings(list)
+		return nil, fmt.Errorf("invalid fixture tables:\n  %s", strings.Join(list, "\n  "))
+	}
+	return kept, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// planWarnings collects the warnings raised while planning the current data
+// extraction, for the pipeline manifest
+var planWarnings []string
+
+// warnUnindexedForeignKeys checks that both sides of every foreign key of
+// the plans of database dbName start an index. Without one, the parent key
+// lookups of sampled children and any orphan check scan the whole table.
+// Each missing index is printed with a suggested CREATE INDEX.
+func warnUnindexedForeignKeys(ctx context.Context, db *sql.DB, dbName string, plans []TableExtractionPlan) error {
+	rows, err := queryWithRetry(ctx, db, dataMaxRetries, `
+		SELECT TABLE_NAME, INDEX_NAME, COLUMN_NAME
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ?
+		ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX`, dbName)
+	if err != nil {
+		return fmt.Errorf("failed to query indexes: %w", err)
+	}
+	indexes := make(map[string][]IndexInfo)
+	for rows.Next() {
+		var table, name, column string
+		if err := rows.Scan(&table, &name, &column); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan index info: %w", err)
+		}
+		tableIndexes := indexes[table]
+		if len(tableIndexes) == 0 || tableIndexes[len(tableIndexes)-1].Name != name {
+			tableIndexes = append(tableIndexes, IndexInfo{Name: name})
+		}
+		last := &tableIndexes[len(tableIndexes)-1]
+		last.Columns = append(last.Columns, column)
+		indexes[table] = tableIndexes
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query indexes: %w", err)
+	}
+
+	suggested := make(map[string]bool)
+	suggest := func(c fkConstraint, side, table string, columns []string) {
+		if hasIndexPrefix(indexes[table], columns) {
+			return
+		}
+		statement := fmt.Sprintf("CREATE INDEX `idx_%s_%s` ON `%s`.`%s` (%s);",
+			table, strings.Join(columns, "_"), dbName, table, quoteColumns(columns))
+		if suggested[statement] {
+			return
+		}
+		suggested[statement] = true
+
+		warning := fmt.Sprintf("foreign key %s.%s has no index on the %s side %s(%s)",
+			dbName, c.name, side, table, strings.Join(columns, ", "))
+		planWarnings = append(planWarnings, warning)
+		fmt.Printf("⚠️  Warning: %s; suggested: %s\n", warning, statement)
+	}
+
+	for _, plan := range plans {
+		if plan.DatabaseName != dbName {
+			continue
+		}
+		for _, c := range planConstraints(plan) {
+			suggest(c, "child", plan.TableName, c.columns)
+			suggest(c, "parent", strings.TrimPrefix(c.parent, dbName+"."), c.parentColumns)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,330 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// fkConstraint is a foreign key of a planned table, with its columns in order
+type fkConstraint struct {
+	name          string
+	columns       []string
+	parent        string // db.table
+	parentColumns []string
+}
+
+// planConstraints groups the per-column foreign key rows of a plan into
+// constraints, in the order they were declared
+func planConstraints(plan TableExtractionPlan) []fkConstraint {
+	byName := make(map[string]*fkConstraint)
+	var names []string
+	for _, fk := range plan.ForeignKeys {
+		c, ok := byName[fk.ConstraintName]
+		if !ok {
+			c = &fkConstraint{name: fk.ConstraintName, parent: plan.DatabaseName + "." + fk.RefTableName}
+			byName[fk.ConstraintName] = c
+			names = append(names, fk.ConstraintName)
+		}
+		c.columns = append(c.columns, fk.ColumnName)
+		c.parentColumns = append(c.parentColumns, fk.RefColumnName)
+	}
+
+	constraints := make([]fkConstraint, len(names))
+	for i, name := range names {
+		constraints[i] = *byName[name]
+	}
+	return constraints
+}
+
+// fkTracker keeps sampled extracts importable with foreign key checks on. It
+// records the referenced key values written for partially extracted tables,
+// and rows of child tables are only kept when their parents were extracted.
+// Tables are extracted parents first, so a parent's keys are complete by the
+// time its children are read.
+type fkTracker struct {
+	constraints map[string][]fkConstraint
+	// recorded holds the key values written per parent table and column list;
+	// a table is present once it has been extracted partially
+	recorded map[string]map[string]map[string]struct{}
+	// referenced lists the column lists of each table that children reference
+	referenced map[string][][]string
+	// partial marks tables whose rows may not all be in the extract
+	partial map[string]bool
+}
+
+// applyIncludeChildren stops sampling the tables up to depth foreign key
+// levels below a sampled table and marks them to take every row referencing
+// extracted parent rows instead. Plans must be in dependency order.
+func applyIncludeChildren(plans []TableExtractionPlan, depth int) {
+	sampledRoots := make(map[string]bool)
+	levels := make(map[string]int)
+	for i := range plans {
+		plan := &plans[i]
+		tableKey := plan.DatabaseName + "." + plan.TableName
+
+		level := 0
+		for _, c := range planConstraints(*plan) {
+			candidate := 0
+			if sampledRoots[c.parent] {
+				candidate = 1
+			} else if levels[c.parent] > 0 {
+				candidate = levels[c.parent] + 1
+			}
+			if candidate > 0 && (level == 0 || candidate < level) {
+				level = candidate
+			}
+		}
+
+		if level > 0 && level <= depth {
+			plan.SampleSize = 0
+			plan.IncludedChild = true
+			levels[tableKey] = level
+		} else if plan.SampleSize != 0 {
+			sampledRoots[tableKey] = true
+		}
+	}
+}
+
+// newFKTracker prepares tracking for plans, which must be in dependency
+// order. It returns nil when no table is sampled, as every row is extracted.
+func newFKTracker(plans []TableExtractionPlan) *fkTracker {
+	t := &fkTracker{
+		constraints: make(map[string][]fkConstraint),
+		recorded:    make(map[string]map[string]map[string]struct{}),
+		referenced:  make(map[string][][]string),
+		partial:     make(map[string]bool),
+	}
+
+	sampled := false
+	for _, plan := range plans {
+		tableKey := plan.DatabaseName + "." + plan.TableName
+		if plan.SampleSize != 0 {
+			t.partial[tableKey] = true
+			sampled = true
+		}
+
+		for _, c := range planConstraints(plan) {
+			// A self reference cannot be checked before the table is
+			// complete; its keys are recorded for the deferred UPDATEs
+			if c.parent == tableKey {
+				t.referenced[tableKey] = append(t.referenced[tableKey], c.parentColumns)
+				continue
+			}
+			t.constraints[tableKey] = append(t.constraints[tableKey], c)
+			t.referenced[c.parent] = append(t.referenced[c.parent], c.parentColumns)
+			// Rows filtered against a partial parent make the child partial too
+			if t.partial[c.parent] {
+				t.partial[tableKey] = true
+			}
+		}
+	}
+
+	if !sampled {
+		return nil
+	}
+	return t
+}
+
+// rowFilter returns the filter for one table, or nil when the table is
+// neither checked against a partial parent nor referenced by a later table.
+// The filter must be bound to the result columns before use.
+func (t *fkTracker) rowFilter(plan TableExtractionPlan) *fkRowFilter {
+	if t == nil {
+		return nil
+	}
+	tableKey := plan.DatabaseName + "." + plan.TableName
+
+	f := &fkRowFilter{tracker: t, table: tableKey}
+	for _, c := range t.constraints[tableKey] {
+		keys, ok := t.recorded[c.parent][strings.Join(c.parentColumns, ",")]
+		if !ok {
+			// The parent is extracted in full, not part of this run, or
+			// was completed before a resume
+			continue
+		}
+		f.checks = append(f.checks, fkCheck{names: c.columns, keys: keys})
+	}
+
+	if t.partial[tableKey] {
+		for _, cols := range t.referenced[tableKey] {
+			name := strings.Join(cols, ",")
+			if _, ok := f.records[name]; ok {
+				continue
+			}
+			if f.records == nil {
+				f.records = make(map[string]*fkRecord)
+			}
+			f.records[name] = &fkRecord{names: cols, keys: make(map[string]struct{})}
+		}
+	}
+
+	if len(f.checks) == 0 && len(f.records) == 0 {
+		return nil
+	}
+	if len(f.checks) > 0 && plan.SampleSize > 0 && plan.SampleSize < plan.RowCount {
+		f.limit = plan.SampleSize
+	}
+	return f
+}
+
+// parentCondition returns an SQL condition selecting the rows of an
+// included child table that reference extracted rows of its partial parents,
+// so the table is not scanned in full. It returns "" when no parent of the
+// table was extracted partially.
+func (t *fkTracker) parentCondition(plan TableExtractionPlan) string {
+	if t == nil {
+		return ""
+	}
+	var conditions []string
+	for _, c := range t.constraints[plan.DatabaseName+"."+plan.TableName] {
+		keys, ok := t.recorded[c.parent][strings.Join(c.parentColumns, ",")]
+		if !ok {
+			continue
+		}
+
+		condition := "FALSE"
+		if len(keys) > 0 {
+			tuples := make([]string, 0, len(keys))
+			for key := range keys {
+				tuples = append(tuples, key)
+			}
+			sort.Strings(tuples)
+			condition = inCondition(c.columns, tuples)
+		}
+		for _, col := range c.columns {
+			condition += " OR `" + col + "` IS NULL"
+		}
+		conditions = append(conditions, "("+condition+")")
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+// bind resolves the filter's column names against the result columns
+func (f *fkRowFilter) bind(columns []string) {
+	if f == nil {
+		return
+	}
+	index := make(map[string]int, len(columns))
+	for i, col := range columns {
+		index[col] = i
+	}
+	for i := range f.checks {
+		f.checks[i].columns = columnIndexes(index, f.checks[i].names)
+	}
+	for _, record := range f.records {
+		record.columns = columnIndexes(index, record.names)
+	}
+}
+
+func columnIndexes(index map[string]int, columns []string) []int {
+	indexes := make([]int, len(columns))
+	for i, col := range columns {
+		if idx, ok := index[col]; ok {
+			indexes[i] = idx
+		} else {
+			indexes[i] = -1
+		}
+	}
+	return indexes
+}
+
+// fkCheck is one foreign key whose parent was extracted partially
+type fkCheck struct {
+	names   []string
+	columns []int
+	keys    map[string]struct{}
+}
+
+// fkRecord collects the values of a column list referenced by children
+type fkRecord struct {
+	names   []string
+	columns []int
+	keys    map[string]struct{}
+}
+
+// fkRowFilter decides which rows of a table to keep. Methods are safe on a
+// nil filter, which keeps every row.
+type fkRowFilter struct {
+	tracker *fkTracker
+	table   string
+	checks  []fkCheck
+	records map[string]*fkRecord
+	// limit replaces the SQL LIMIT of a sampled table, as filtered rows must
+	// not count towards the sample
+	limit   int64
+	kept    int64
+	skipped int64
+}
+
+// filtering reports whether the table's sample limit is applied by the filter
+func (f *fkRowFilter) filtering() bool {
+	return f != nil && f.limit > 0
+}
+
+// accept reports whether a row is kept and records its referenced keys
+func (f *fkRowFilter) accept(values []interface{}) bool {
+	if f == nil {
+		return true
+	}
+	for _, check := range f.checks {
+		key, ok := fkKey(values, check.columns)
+		if !ok {
+			// NULL references are not checked by the server either
+			continue
+		}
+		if _, found := check.keys[key]; !found {
+			f.skipped++
+			return false
+		}
+	}
+	for _, record := range f.records {
+		if key, ok := fkKey(values, record.columns); ok {
+			record.keys[key] = struct{}{}
+		}
+	}
+	f.kept++
+	return true
+}
+
+// full reports whether the sample limit has been reached
+func (f *fkRowFilter) full() bool {
+	return f != nil && f.limit > 0 && f.kept >= f.limit
+}
+
+// finish makes the recorded keys available to the table's children. It must
+// only be called once the table has been extracted successfully.
+func (f *fkRowFilter) finish() {
+	if f == nil || f.records == nil {
+		return
+	}
+	recorded := make(map[string]map[string]struct{}, len(f.records))
+	for name, record := range f.records {
+		recorded[name] = record.keys
+	}
+	f.tracker.recorded[f.table] = recorded
+}
+
+// summary describes skipped rows for the progress line, or returns ""
+func (f *fkRowFilter) summary() string {
+	if f == nil || f.skipped == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d rows without extracted parents skipped)", f.skipped)
+}
+
+// fkKey returns the values of the given columns as an SQL tuple literal;
+// ok is false when any is NULL
+func fkKey(values []interface{}, columns []int) (string, bool) {
+	literals := make([]string, len(columns))
+	for i, idx := range columns {
+		if idx < 0 || values[idx] == nil {
+			return "", false
+		}
+		literals[i] = formatSQLValue(values[idx])
+	}
+	return "(" + strings.Join(literals, ",") + ")", true
+}
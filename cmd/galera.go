@@ -0,0 +1,163 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// galeraPollInterval is how often a paused extraction rechecks the cluster
+const galeraPollInterval = 5 * time.Second
+
+// Galera node states (wsrep_local_state_comment) that can serve reads
+const (
+	galeraStateSynced = "Synced"
+	galeraStateDonor  = "Donor/Desynced"
+)
+
+// galeraStatus is the subset of wsrep status variables the extractor uses
+type galeraStatus struct {
+	ready             bool
+	state             string
+	flowControlActive bool
+	recvQueue         int64
+}
+
+// readGaleraStatus reads the wsrep status of the connected node
+func readGaleraStatus(ctx context.Context, db *sql.DB) (galeraStatus, error) {
+	rows, err := db.QueryContext(ctx, annotateQuery("SHOW GLOBAL STATUS LIKE 'wsrep_%'"))
+	if err != nil {
+		return galeraStatus{}, fmt.Errorf("failed to read wsrep status: %w", err)
+	}
+	defer rows.Close()
+
+	vars := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return galeraStatus{}, fmt.Errorf("failed to read wsrep status: %w", err)
+		}
+		vars[strings.ToLower(name)] = value
+	}
+	if err := rows.Err(); err != nil {
+		return galeraStatus{}, fmt.Errorf("failed to read wsrep status: %w", err)
+	}
+
+	ready, ok := vars["wsrep_ready"]
+	if !ok {
+		return galeraStatus{}, fmt.Errorf("server is not a Galera node (no wsrep status variables)")
+	}
+
+	status := galeraStatus{
+		ready: strings.EqualFold(ready, "ON"),
+		state: vars["wsrep_local_state_comment"],
+	}
+	switch strings.ToLower(vars["wsrep_flow_control_active"]) {
+	case "true", "on", "1":
+		status.flowControlActive = true
+	}
+	status.recvQueue, _ = strconv.ParseInt(vars["wsrep_local_recv_queue"], 10, 64)
+	return status, nil
+}
+
+// serving reports whether the node can be read from without harming the cluster
+func (s galeraStatus) serving() bool {
+	return s.ready && (s.state == galeraStateSynced || s.state == galeraStateDonor)
+}
+
+// congested reports whether the extraction should back off
+func (s galeraStatus) congested(maxQueue int64) bool {
+	return s.flowControlActive || (maxQueue > 0 && s.recvQueue > maxQueue)
+}
+
+// checkGaleraReady fails unless the node is ready and synced (or desynced
+// as a donor)
+func checkGaleraReady(ctx context.Context, db *sql.DB) error {
+	status, err := readGaleraStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !status.serving() {
+		return fmt.Errorf("Galera node is not ready (wsrep_ready=%t, state=%q)", status.ready, status.state)
+	}
+	fmt.Printf("Galera node ready (state: %s)\n", status.state)
+	return nil
+}
+
+// waitForGaleraFlowControl blocks while the cluster is under flow control or
+// the node's receive queue exceeds maxQueue
+func waitForGaleraFlowControl(ctx context.Context, db *sql.DB, maxQueue int64) error {
+	paused := false
+	for {
+		status, err := readGaleraStatus(ctx, db)
+		if err != nil {
+			return err
+		}
+		if !status.congested(maxQueue) {
+			if paused {
+				fmt.Printf("\n▶️  Galera flow control cleared, resuming\n")
+			}
+			return nil
+		}
+		if !paused {
+			fmt.Printf("\n⏸️  Galera cluster under flow control (recv queue: %d), pausing extraction\n", status.recvQueue)
+			paused = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(galeraPollInterval):
+		}
+	}
+}
+
+// selectGaleraNode probes the candidate nodes (host:port) and returns the
+// preferred one: a node desynced as a donor, else the first synced node
+func selectGaleraNode(ctx context.Context, nodes []string, user, password string, timeout int) (string, int, error) {
+	var synced string
+	for _, node := range nodes {
+		host, portStr, err := net.SplitHostPort(node)
+		if err != nil {
+			host, portStr = node, "3306"
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid Galera node %q", node)
+		}
+
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?timeout=%ds", user, password, host, port, timeout)
+		db, err := sql.Open("mysql", dsn)
+		if err != nil {
+			return "", 0, err
+		}
+		status, err := readGaleraStatus(ctx, db)
+		db.Close()
+		if err != nil {
+			fmt.Printf("   %s: unavailable (%v)\n", node, err)
+			continue
+		}
+		fmt.Printf("   %s: %s (ready: %t)\n", node, status.state, status.ready)
+
+		if status.ready && status.state == galeraStateDonor {
+			return host, port, nil
+		}
+		if status.serving() && synced == "" {
+			synced = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+	}
+
+	if synced == "" {
+		return "", 0, fmt.Errorf("no ready Galera node among %s", strings.Join(nodes, ", "))
+	}
+	host, portStr, _ := net.SplitHostPort(synced)
+	port, _ := strconv.Atoi(portStr)
+	return host, port, nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// schemaSnapshot is a set of generated files to commit to a git repository
+// for --git-commit
+type schemaSnapshot struct {
+	runID  string
+	server string
+	// baseDir is the directory files are relative to in the repository
+	baseDir string
+	files   []string
+}
+
+// commitSchemaSnapshot copies the snapshot's files into dir, a directory in
+// a git working tree, and commits them when they changed. With openPR the
+// commit goes to a new branch that is pushed to origin and proposed with the
+// GitHub CLI, and the working tree is switched back to its branch.
+func commitSchemaSnapshot(ctx context.Context, dir string, snap schemaSnapshot, openPR bool) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if _, err := git(ctx, dir, "rev-parse", "--show-toplevel"); err != nil {
+		return fmt.Errorf("--git-commit %s is not in a git repository: %w", dir, err)
+	}
+
+	for _, file := range snap.files {
+		rel, err := filepath.Rel(snap.baseDir, file)
+		if err != nil {
+			return err
+		}
+		if err := copyFile(file, filepath.Join(dir, rel)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := git(ctx, dir, "add", "-A", "--", "."); err != nil {
+		return err
+	}
+	if _, err := git(ctx, dir, "diff", "--cached", "--quiet", "--", "."); err == nil {
+		fmt.Printf("📚 Schema unchanged, nothing to commit in %s\n", dir)
+		return nil
+	}
+
+	var previous, branch string
+	if openPR {
+		out, err := git(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return err
+		}
+		previous = out
+		branch = "schema-snapshot/" + snap.runID
+		if _, err := git(ctx, dir, "checkout", "-b", branch); err != nil {
+			return err
+		}
+	}
+
+	subject := fmt.Sprintf("Schema snapshot %s from %s", snap.runID, snap.server)
+	body := fmt.Sprintf("Run ID: %s\nSource server: %s", snap.runID, snap.server)
+	if _, err := git(ctx, dir, "commit", "-m", subject, "-m", body, "--", "."); err != nil {
+		return err
+	}
+	commit, _ := git(ctx, dir, "rev-parse", "--short", "HEAD")
+	fmt.Printf("📚 Committed schema snapshot %s in %s\n", commit, dir)
+	if !openPR {
+		return nil
+	}
+
+	pushErr := func() error {
+		if _, err := git(ctx, dir, "push", "-u", "origin", branch); err != nil {
+			return err
+		}
+		cmd := exec.CommandContext(ctx, "gh", "pr", "create", "--head", branch, "--title", subject, "--body", body)
+		cmd.Dir = dir
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to open pull request: %w", err)
+		}
+		return nil
+	}()
+	if _, err := git(ctx, dir, "checkout", previous); err != nil && pushErr == nil {
+		return err
+	}
+	return pushErr
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(dst), err)
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s: %w", src, err)
+	}
+	return out.Close()
+}
@@ -0,0 +1,54 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// waitForGTID blocks until the connected replica has applied the GTID set
+// gtid, for at most timeout (0 waits as long as it takes). Each wait on the
+// server is kept below the connection's read timeout of readTimeout
+// seconds, so a long wait is made of several.
+func waitForGTID(ctx context.Context, db *sql.DB, gtid string, timeout time.Duration, readTimeout int) error {
+	// MASTER_GTID_WAIT returns 0 once the position is reached and -1 on
+	// timeout; MySQL's WAIT_FOR_EXECUTED_GTID_SET returns 0 and 1
+	query, timedOut := "SELECT MASTER_GTID_WAIT(?, ?)", int64(-1)
+	if dataServer.flavor == flavorMySQL {
+		query, timedOut = "SELECT WAIT_FOR_EXECUTED_GTID_SET(?, ?)", 1
+	}
+
+	slice := 30 * time.Second
+	if readTimeout > 0 {
+		slice = max(time.Duration(readTimeout)*time.Second/2, time.Second)
+	}
+
+	fmt.Printf("⏳ Waiting for the replica to apply GTID %s...\n", gtid)
+	start := time.Now()
+	for {
+		wait := slice
+		if timeout > 0 {
+			remaining := timeout - time.Since(start)
+			if remaining <= 0 {
+				return fmt.Errorf("replica did not apply GTID %s within %v", gtid, timeout)
+			}
+			wait = min(wait, remaining)
+		}
+
+		var result sql.NullInt64
+		if err := queryRowWithRetry(ctx, db, dataMaxRetries, query, []interface{}{gtid, wait.Seconds()}, &result); err != nil {
+			return fmt.Errorf("failed to wait for GTID %s: %w", gtid, err)
+		}
+		if !result.Valid {
+			return fmt.Errorf("failed to wait for GTID %s: the server returned NULL", gtid)
+		}
+		if result.Int64 != timedOut {
+			fmt.Printf("✅ Replica applied GTID %s (waited %v)\n", gtid, time.Since(start).Round(time.Second))
+			return nil
+		}
+	}
+}
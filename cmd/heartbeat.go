@@ -0,0 +1,128 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// extractionMonitor follows the progress of a data extraction for
+// --heartbeat and --stall-timeout. Methods are safe on nil and for
+// concurrent use, as segments of a table are read in parallel.
+type extractionMonitor struct {
+	rows      atomic.Int64
+	tableRows atomic.Int64
+	chunks    atomic.Int64
+	tables    atomic.Int64
+
+	mu    sync.Mutex
+	table string
+}
+
+// dataMonitor is the monitor of the running extraction, nil when neither
+// heartbeats nor stall detection are enabled
+var dataMonitor *extractionMonitor
+
+// startTable marks the start of a table, which counts as progress
+func (m *extractionMonitor) startTable(name string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	m.table = name
+	m.mu.Unlock()
+	m.tableRows.Store(0)
+	m.chunks.Store(1)
+	m.tables.Add(1)
+}
+
+func (m *extractionMonitor) row() {
+	if m != nil {
+		m.rows.Add(1)
+		m.tableRows.Add(1)
+	}
+}
+
+func (m *extractionMonitor) chunk() {
+	if m != nil {
+		m.chunks.Add(1)
+	}
+}
+
+// progress is a counter that changes whenever the extraction moves on
+func (m *extractionMonitor) progress() int64 {
+	return m.rows.Load() + m.tables.Load()
+}
+
+func (m *extractionMonitor) currentTable() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.table
+}
+
+// startMonitor sets dataMonitor and watches it until the returned stop is
+// called. Every heartbeat it logs the current table, chunk and read rate.
+// When no row was read and no table started for stallTimeout, it logs an
+// alert and, with abort, cancels the extraction with the stall as the cause;
+// cancelling also kills the running query, such as one waiting on a lock.
+func startMonitor(cancel context.CancelCauseFunc, heartbeat, stallTimeout time.Duration, abort bool) (stop func()) {
+	if heartbeat <= 0 && stallTimeout <= 0 {
+		return func() {}
+	}
+	m := &extractionMonitor{}
+	dataMonitor = m
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		lastBeat, lastBeatRows := time.Now(), int64(0)
+		lastProgress, lastMove := m.progress(), time.Now()
+		alerted := false
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if p := m.progress(); p != lastProgress {
+					lastProgress, lastMove, alerted = p, now, false
+				}
+
+				if heartbeat > 0 && now.Sub(lastBeat) >= heartbeat {
+					rows := m.rows.Load()
+					rate := float64(rows-lastBeatRows) / now.Sub(lastBeat).Seconds()
+					log.Printf("💓 Heartbeat: %s, chunk %d, %d rows of the table, %d rows total, %.0f rows/s",
+						m.currentTable(), m.chunks.Load(), m.tableRows.Load(), rows, rate)
+					lastBeat, lastBeatRows = now, rows
+				}
+
+				if stallTimeout > 0 && !alerted && now.Sub(lastMove) >= stallTimeout {
+					alerted = true
+					stalled := fmt.Errorf("no progress on %s for %v", m.currentTable(), now.Sub(lastMove).Round(time.Second))
+					if abort {
+						log.Printf("🛑 Stalled: %v; aborting the extraction", stalled)
+						cancel(fmt.Errorf("extraction stalled: %w", stalled))
+						return
+					}
+					log.Printf("⚠️  Stalled: %v", stalled)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+		dataMonitor = nil
+	}
+}
@@ -0,0 +1,45 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"os"
+	"strings"
+)
+
+// queryHints are comments prepended to every query the extractor sends, so
+// proxies such as MaxScale or ProxySQL can route extraction traffic
+var queryHints []string
+
+func init() {
+	var defaultHints []string
+	if hint := os.Getenv("MARIADB_QUERY_HINT"); hint != "" {
+		defaultHints = []string{hint}
+	}
+	rootCmd.PersistentFlags().StringArrayVar(&queryHints, "query-hint", defaultHints,
+		"Comment prepended to every query for proxy routing, e.g. \"maxscale route to server replica1\"; repeatable (env: MARIADB_QUERY_HINT)")
+}
+
+// annotateQuery prepends the configured hints to query. Hints not already
+// written as comments are wrapped in /* */.
+func annotateQuery(query string) string {
+	if len(queryHints) == 0 {
+		return query
+	}
+
+	var b strings.Builder
+	for _, hint := range queryHints {
+		hint = strings.TrimSpace(hint)
+		if hint == "" {
+			continue
+		}
+		if !strings.HasPrefix(hint, "/*") {
+			hint = "/* " + strings.ReplaceAll(hint, "*/", "* /") + " */"
+		}
+		b.WriteString(hint)
+		b.WriteByte(' ')
+	}
+	b.WriteString(query)
+	return b.String()
+}
@@ -0,0 +1,159 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"mariadb-extractor/internal/snapshot"
+
+	"github.com/spf13/cobra"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Query the local snapshot catalog of previous extract runs",
+	Long: `Every extract run is recorded in a local snapshot catalog (runs, databases,
+tables, row counts and sizes). The history subcommands query that catalog,
+e.g. to follow the row count of a table over the last runs.`,
+}
+
+var historyRunsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "List recorded extract runs",
+	Run: func(cmd *cobra.Command, args []string) {
+		runHistoryRuns()
+	},
+}
+
+var historyTableCmd = &cobra.Command{
+	Use:   "table <db.table>",
+	Short: "Show row count and size of a table across runs",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runHistoryTable(args[0])
+	},
+}
+
+var historyDatabaseCmd = &cobra.Command{
+	Use:   "database <db>",
+	Short: "Show table count, rows and size of a database across runs",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runHistoryDatabase(args[0])
+	},
+}
+
+var (
+	historyCatalog string
+	historyLast    int
+	historyServer  string
+)
+
+// historyServerFilter returns the --server value as a catalog filter, where
+// "" matches every server
+func historyServerFilter() string {
+	if historyServer == "all" {
+		return ""
+	}
+	return historyServer
+}
+
+// defaultHistoryFile returns the snapshot catalog location (env: MARIADB_HISTORY_FILE)
+func defaultHistoryFile() string {
+	return getEnvWithDefault("MARIADB_HISTORY_FILE", "mariadb-history.jsonl")
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyRunsCmd)
+	historyCmd.AddCommand(historyTableCmd)
+	historyCmd.AddCommand(historyDatabaseCmd)
+
+	historyCmd.PersistentFlags().StringVar(&historyCatalog, "history-file", defaultHistoryFile(), "Snapshot catalog to query (env: MARIADB_HISTORY_FILE)")
+	historyCmd.PersistentFlags().IntVarP(&historyLast, "last", "n", 10, "Only consider the last N runs (0=all)")
+	defaultServer := fmt.Sprintf("%s:%d", getEnvWithDefault("MARIADB_HOST", "localhost"), getEnvIntWithDefault("MARIADB_PORT", 3306))
+	historyCmd.PersistentFlags().StringVar(&historyServer, "server", defaultServer, "Only consider runs of this host:port, or \"all\" (default from MARIADB_HOST and MARIADB_PORT)")
+}
+
+func runHistoryRuns() {
+	store := snapshot.Open(historyCatalog)
+	runs, err := store.ServerRuns(historyServerFilter())
+	if err != nil {
+		log.Fatalf("Failed to read snapshot catalog: %v", err)
+	}
+	runs = snapshot.LastRuns(runs, historyLast)
+	if len(runs) == 0 {
+		fmt.Printf("No runs of %s recorded in %s (see --server)\n", historyServer, store.Path())
+		return
+	}
+
+	fmt.Printf("%-18s  %-19s  %-24s  %9s  %8s\n", "RUN", "EXTRACTED AT", "SERVER", "DATABASES", "TABLES")
+	for _, run := range runs {
+		tables := 0
+		for _, db := range run.Databases {
+			tables += len(db.Tables)
+		}
+		fmt.Printf("%-18s  %-19s  %-24s  %9d  %8d\n",
+			run.ID, run.ExtractedAt.Local().Format("2006-01-02 15:04:05"), run.Server, len(run.Databases), tables)
+	}
+}
+
+func runHistoryTable(name string) {
+	dbName, tableName, ok := strings.Cut(name, ".")
+	if !ok || dbName == "" || tableName == "" {
+		log.Fatalf("Table must be qualified as db.table, got %q", name)
+	}
+
+	points, err := snapshot.Open(historyCatalog).TableHistory(historyServerFilter(), dbName, tableName, historyLast)
+	if err != nil {
+		log.Fatalf("Failed to read snapshot catalog: %v", err)
+	}
+	if len(points) == 0 {
+		fmt.Printf("No history for %s.%s on %s (see --server)\n", dbName, tableName, historyServer)
+		return
+	}
+
+	fmt.Printf("History of `%s`.`%s`\n\n", dbName, tableName)
+	fmt.Printf("%-18s  %-19s  %-24s  %12s  %12s  %10s  %10s\n", "RUN", "EXTRACTED AT", "SERVER", "ROWS", "CHANGE", "DATA", "INDEX")
+	// With --server all, each run is compared to the previous run of its own server
+	previous := make(map[string]int64)
+	for _, point := range points {
+		change := ""
+		if rows, ok := previous[point.Server]; ok {
+			change = fmt.Sprintf("%+d", point.RowCount-rows)
+		}
+		previous[point.Server] = point.RowCount
+		fmt.Printf("%-18s  %-19s  %-24s  %12d  %12s  %10s  %10s\n",
+			point.RunID, point.ExtractedAt.Local().Format("2006-01-02 15:04:05"), point.Server, point.RowCount, change,
+			formatBytes(point.DataLength), formatBytes(point.IndexLength))
+	}
+}
+
+func runHistoryDatabase(dbName string) {
+	runs, err := snapshot.Open(historyCatalog).DatabaseHistory(historyServerFilter(), dbName, historyLast)
+	if err != nil {
+		log.Fatalf("Failed to read snapshot catalog: %v", err)
+	}
+	if len(runs) == 0 {
+		fmt.Printf("No history for database %s on %s (see --server)\n", dbName, historyServer)
+		return
+	}
+
+	fmt.Printf("History of database `%s`\n\n", dbName)
+	fmt.Printf("%-18s  %-19s  %-24s  %8s  %14s  %10s\n", "RUN", "EXTRACTED AT", "SERVER", "TABLES", "ROWS", "SIZE")
+	for _, run := range runs {
+		db := run.Databases[0]
+		var rows, size int64
+		for _, table := range db.Tables {
+			rows += table.RowCount
+			size += table.DataLength + table.IndexLength
+		}
+		fmt.Printf("%-18s  %-19s  %-24s  %8d  %14d  %10s\n",
+			run.ID, run.ExtractedAt.Local().Format("2006-01-02 15:04:05"), run.Server, len(db.Tables), rows, formatBytes(size))
+	}
+}
@@ -0,0 +1,412 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// impactCmd represents the impact command
+var impactCmd = &cobra.Command{
+	Use:   "impact <db.table>",
+	Short: "Report everything that references or is referenced by a table",
+	Long: `Report the blast radius of a table before extracting or altering it:
+foreign keys in and out, views using it (directly or through other views),
+triggers on it or mentioning it, and stored routines and events whose body
+mentions it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runImpact(cmd.Context(), args[0])
+	},
+}
+
+var (
+	impactHost       string
+	impactPort       int
+	impactUser       string
+	impactPassword   string
+	impactTimeout    int
+	impactMaxRetries int
+	impactFormat     string
+)
+
+// impactForeignKey is a foreign key from or to the analyzed table
+type impactForeignKey struct {
+	Name              string   `json:"name"`
+	Table             string   `json:"table"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnUpdate          string   `json:"on_update"`
+	OnDelete          string   `json:"on_delete"`
+}
+
+// impactObject is a view, trigger, routine or event depending on the table
+type impactObject struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// impactReport lists the dependencies of one table
+type impactReport struct {
+	Table        string             `json:"table"`
+	References   []impactForeignKey `json:"references"`
+	ReferencedBy []impactForeignKey `json:"referenced_by"`
+	Views        []impactObject     `json:"views"`
+	Triggers     []impactObject     `json:"triggers"`
+	Routines     []impactObject     `json:"routines"`
+	Events       []impactObject     `json:"events"`
+}
+
+func init() {
+	rootCmd.AddCommand(impactCmd)
+
+	defaultUser := os.Getenv("MARIADB_USER")
+	defaultPassword := os.Getenv("MARIADB_PASSWORD")
+
+	impactCmd.Flags().StringVarP(&impactHost, "host", "H", getEnvWithDefault("MARIADB_HOST", "localhost"), "MariaDB host (env: MARIADB_HOST)")
+	impactCmd.Flags().IntVarP(&impactPort, "port", "P", getEnvIntWithDefault("MARIADB_PORT", 3306), "MariaDB port (env: MARIADB_PORT)")
+	impactCmd.Flags().StringVarP(&impactUser, "user", "u", defaultUser, "MariaDB username (env: MARIADB_USER)")
+	impactCmd.Flags().StringVarP(&impactPassword, "password", "p", defaultPassword, "MariaDB password (env: MARIADB_PASSWORD)")
+	impactCmd.Flags().IntVarP(&impactTimeout, "timeout", "t", getEnvIntWithDefault("MARIADB_TIMEOUT", 300), "Query timeout in seconds (env: MARIADB_TIMEOUT)")
+	impactCmd.Flags().IntVar(&impactMaxRetries, "max-retries", getEnvIntWithDefault("MARIADB_MAX_RETRIES", 3), "Maximum retry attempts for failed queries (env: MARIADB_MAX_RETRIES)")
+	impactCmd.Flags().StringVar(&impactFormat, "format", "text", "Report format: text or json")
+
+	if defaultUser == "" {
+		impactCmd.MarkFlagRequired("user")
+	}
+	if defaultPassword == "" {
+		impactCmd.MarkFlagRequired("password")
+	}
+}
+
+func runImpact(ctx context.Context, target string) {
+	dbName, tableName, ok := strings.Cut(target, ".")
+	if !ok || dbName == "" || tableName == "" {
+		log.Fatalf("Invalid table %q: use db.table", target)
+	}
+	if impactFormat != "text" && impactFormat != "json" {
+		log.Fatalf("Invalid --format %q: must be text or json", impactFormat)
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&timeout=%ds&readTimeout=%ds",
+		impactUser, impactPassword, impactHost, impactPort, impactTimeout, impactTimeout)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if err := pingWithRetry(ctx, db, impactMaxRetries); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+
+	var exists int
+	if err := queryRowWithRetry(ctx, db, impactMaxRetries,
+		"SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+		[]interface{}{dbName, tableName}, &exists); err != nil {
+		log.Fatalf("Failed to look up %s: %v", target, err)
+	}
+	if exists == 0 {
+		log.Fatalf("Table %s does not exist", target)
+	}
+
+	report, err := analyzeImpact(ctx, db, dbName, tableName)
+	if err != nil {
+		log.Fatalf("Impact analysis failed: %v", err)
+	}
+
+	if impactFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+		return
+	}
+	printImpactReport(report)
+}
+
+// analyzeImpact collects the dependencies of dbName.tableName
+func analyzeImpact(ctx context.Context, db *sql.DB, dbName, tableName string) (*impactReport, error) {
+	report := &impactReport{
+		Table:        dbName + "." + tableName,
+		References:   []impactForeignKey{},
+		ReferencedBy: []impactForeignKey{},
+		Views:        []impactObject{},
+		Triggers:     []impactObject{},
+		Routines:     []impactObject{},
+		Events:       []impactObject{},
+	}
+
+	var err error
+	if report.References, report.ReferencedBy, err = impactForeignKeys(ctx, db, dbName, tableName); err != nil {
+		return nil, err
+	}
+	if report.Views, err = impactViews(ctx, db, dbName, tableName); err != nil {
+		return nil, err
+	}
+	if report.Triggers, err = impactTriggers(ctx, db, dbName, tableName); err != nil {
+		return nil, err
+	}
+
+	mentions := tableMentionPattern(tableName)
+	routines, err := impactDefinitions(ctx, db, `
+		SELECT ROUTINE_SCHEMA, ROUTINE_NAME, ROUTINE_TYPE, ROUTINE_DEFINITION
+		FROM information_schema.ROUTINES
+		WHERE ROUTINE_DEFINITION LIKE ?
+		ORDER BY ROUTINE_SCHEMA, ROUTINE_NAME`, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query routines: %w", err)
+	}
+	for _, r := range routines {
+		if mentionsTable(mentions, r.definition, r.schema, dbName) {
+			report.Routines = append(report.Routines, impactObject{Name: r.schema + "." + r.name, Type: r.kind, Reason: "body mentions the table"})
+		}
+	}
+
+	events, err := impactDefinitions(ctx, db, `
+		SELECT EVENT_SCHEMA, EVENT_NAME, 'EVENT', EVENT_DEFINITION
+		FROM information_schema.EVENTS
+		WHERE EVENT_DEFINITION LIKE ?
+		ORDER BY EVENT_SCHEMA, EVENT_NAME`, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	for _, e := range events {
+		if mentionsTable(mentions, e.definition, e.schema, dbName) {
+			report.Events = append(report.Events, impactObject{Name: e.schema + "." + e.name, Type: e.kind, Reason: "body mentions the table"})
+		}
+	}
+
+	return report, nil
+}
+
+// impactForeignKeys returns the foreign keys declared on the table and those
+// of other tables referencing it
+func impactForeignKeys(ctx context.Context, db *sql.DB, dbName, tableName string) ([]impactForeignKey, []impactForeignKey, error) {
+	query := `
+		SELECT k.CONSTRAINT_NAME, k.TABLE_SCHEMA, k.TABLE_NAME, k.COLUMN_NAME,
+			k.REFERENCED_TABLE_SCHEMA, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME,
+			r.UPDATE_RULE, r.DELETE_RULE
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+			ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA
+			AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+			AND r.TABLE_NAME = k.TABLE_NAME
+		WHERE k.REFERENCED_TABLE_NAME IS NOT NULL
+			AND ((k.TABLE_SCHEMA = ? AND k.TABLE_NAME = ?)
+				OR (k.REFERENCED_TABLE_SCHEMA = ? AND k.REFERENCED_TABLE_NAME = ?))
+		ORDER BY k.TABLE_SCHEMA, k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION
+	`
+
+	rows, err := queryWithRetry(ctx, db, impactMaxRetries, query, dbName, tableName, dbName, tableName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var all []impactForeignKey
+	for rows.Next() {
+		var name, schema, table, column, refSchema, refTable, refColumn, onUpdate, onDelete string
+		if err := rows.Scan(&name, &schema, &table, &column, &refSchema, &refTable, &refColumn, &onUpdate, &onDelete); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan foreign key: %w", err)
+		}
+		owner := schema + "." + table
+		if len(all) == 0 || all[len(all)-1].Name != name || all[len(all)-1].Table != owner {
+			all = append(all, impactForeignKey{
+				Name:            name,
+				Table:           owner,
+				ReferencedTable: refSchema + "." + refTable,
+				OnUpdate:        onUpdate,
+				OnDelete:        onDelete,
+			})
+		}
+		last := &all[len(all)-1]
+		last.Columns = append(last.Columns, column)
+		last.ReferencedColumns = append(last.ReferencedColumns, refColumn)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	target := dbName + "." + tableName
+	references, referencedBy := []impactForeignKey{}, []impactForeignKey{}
+	for _, fk := range all {
+		if fk.Table == target {
+			references = append(references, fk)
+		}
+		// A self reference is listed in both directions
+		if fk.ReferencedTable == target {
+			referencedBy = append(referencedBy, fk)
+		}
+	}
+	return references, referencedBy, nil
+}
+
+// impactViews returns the views selecting from the table, directly or
+// through other views
+func impactViews(ctx context.Context, db *sql.DB, dbName, tableName string) ([]impactObject, error) {
+	views, err := impactDefinitions(ctx, db, `
+		SELECT TABLE_SCHEMA, TABLE_NAME, 'VIEW', VIEW_DEFINITION
+		FROM information_schema.VIEWS
+		WHERE VIEW_DEFINITION LIKE ?
+		ORDER BY TABLE_SCHEMA, TABLE_NAME`, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query views: %w", err)
+	}
+
+	found := []impactObject{}
+	seen := map[string]bool{dbName + "." + tableName: true}
+	type source struct{ schema, name, label string }
+	queue := []source{{dbName, tableName, "the table"}}
+	for len(queue) > 0 {
+		src := queue[0]
+		queue = queue[1:]
+		pattern := tableMentionPattern(src.name)
+		for _, v := range views {
+			key := v.schema + "." + v.name
+			if seen[key] || !mentionsTable(pattern, v.definition, v.schema, src.schema) {
+				continue
+			}
+			seen[key] = true
+			reason := "selects from the table"
+			if src.label != "the table" {
+				reason = "selects from view " + src.label
+			}
+			found = append(found, impactObject{Name: key, Type: "VIEW", Reason: reason})
+			queue = append(queue, source{v.schema, v.name, key})
+		}
+	}
+	return found, nil
+}
+
+// impactTriggers returns the triggers defined on the table and those whose
+// body mentions it
+func impactTriggers(ctx context.Context, db *sql.DB, dbName, tableName string) ([]impactObject, error) {
+	query := `
+		SELECT TRIGGER_SCHEMA, TRIGGER_NAME, EVENT_OBJECT_SCHEMA, EVENT_OBJECT_TABLE,
+			ACTION_TIMING, EVENT_MANIPULATION, ACTION_STATEMENT
+		FROM information_schema.TRIGGERS
+		WHERE (EVENT_OBJECT_SCHEMA = ? AND EVENT_OBJECT_TABLE = ?) OR ACTION_STATEMENT LIKE ?
+		ORDER BY TRIGGER_SCHEMA, TRIGGER_NAME
+	`
+	rows, err := queryWithRetry(ctx, db, impactMaxRetries, query, dbName, tableName, "%"+tableName+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query triggers: %w", err)
+	}
+	defer rows.Close()
+
+	pattern := tableMentionPattern(tableName)
+	found := []impactObject{}
+	for rows.Next() {
+		var schema, name, onSchema, onTable, timing, event, statement string
+		if err := rows.Scan(&schema, &name, &onSchema, &onTable, &timing, &event, &statement); err != nil {
+			return nil, fmt.Errorf("failed to scan trigger: %w", err)
+		}
+		trigger := impactObject{Name: schema + "." + name, Type: "TRIGGER"}
+		switch {
+		case onSchema == dbName && onTable == tableName:
+			trigger.Reason = fmt.Sprintf("%s %s on the table", timing, event)
+		case mentionsTable(pattern, statement, schema, dbName):
+			trigger.Reason = fmt.Sprintf("body mentions the table (%s %s on %s.%s)", timing, event, onSchema, onTable)
+		default:
+			continue
+		}
+		found = append(found, trigger)
+	}
+	return found, rows.Err()
+}
+
+// impactDefinition is a named object with a SQL body
+type impactDefinition struct {
+	schema, name, kind, definition string
+}
+
+// impactDefinitions runs a query returning schema, name, type and body for
+// objects whose body contains name; an empty name returns all objects
+func impactDefinitions(ctx context.Context, db *sql.DB, query, name string) ([]impactDefinition, error) {
+	rows, err := queryWithRetry(ctx, db, impactMaxRetries, query, "%"+name+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var definitions []impactDefinition
+	for rows.Next() {
+		var d impactDefinition
+		var body sql.NullString
+		if err := rows.Scan(&d.schema, &d.name, &d.kind, &body); err != nil {
+			return nil, err
+		}
+		// Bodies are NULL for objects the user may not see the source of
+		d.definition = body.String
+		definitions = append(definitions, d)
+	}
+	return definitions, rows.Err()
+}
+
+// tableMentionPattern matches a table name as an identifier, optionally
+// qualified with a schema, capturing the schema
+func tableMentionPattern(tableName string) *regexp.Regexp {
+	name := regexp.QuoteMeta(tableName)
+	return regexp.MustCompile("(?i)(?:^|[^\\w$`.])(?:`?([\\w$]+)`?\\s*\\.\\s*)?`?" + name + "`?(?:$|[^\\w$])")
+}
+
+// mentionsTable reports whether body, defined in objectSchema, refers to the
+// table in tableSchema: qualified with that schema, or unqualified within it
+func mentionsTable(pattern *regexp.Regexp, body, objectSchema, tableSchema string) bool {
+	for _, m := range pattern.FindAllStringSubmatch(body, -1) {
+		qualifier := m[1]
+		if qualifier == "" && strings.EqualFold(objectSchema, tableSchema) {
+			return true
+		}
+		if qualifier != "" && strings.EqualFold(qualifier, tableSchema) {
+			return true
+		}
+	}
+	return false
+}
+
+func printImpactReport(report *impactReport) {
+	fmt.Printf("💥 Impact of %s\n\n", report.Table)
+
+	printKeys := func(title string, keys []impactForeignKey) {
+		fmt.Printf("%s (%d)\n", title, len(keys))
+		for _, fk := range keys {
+			fmt.Printf("  %s: %s (%s) -> %s (%s) [ON UPDATE %s, ON DELETE %s]\n",
+				fk.Name, fk.Table, strings.Join(fk.Columns, ", "), fk.ReferencedTable,
+				strings.Join(fk.ReferencedColumns, ", "), fk.OnUpdate, fk.OnDelete)
+		}
+		fmt.Println()
+	}
+	printObjects := func(title string, objects []impactObject) {
+		fmt.Printf("%s (%d)\n", title, len(objects))
+		for _, o := range objects {
+			fmt.Printf("  %s %s: %s\n", o.Type, o.Name, o.Reason)
+		}
+		fmt.Println()
+	}
+
+	printKeys("Foreign keys out", report.References)
+	printKeys("Foreign keys in", report.ReferencedBy)
+	printObjects("Views", report.Views)
+	printObjects("Triggers", report.Triggers)
+	printObjects("Routines", report.Routines)
+	printObjects("Events", report.Events)
+
+	total := len(report.References) + len(report.ReferencedBy) + len(report.Views) +
+		len(report.Triggers) + len(report.Routines) + len(report.Events)
+	fmt.Printf("Total dependencies: %d\n", total)
+}
@@ -0,0 +1,190 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// inferMinOverlap is the share of sampled child values that must exist in
+// the parent table for an inferred relationship to be kept
+const inferMinOverlap = 0.9
+
+// inferColumn is a column considered by inferRelationships
+type inferColumn struct {
+	table, name, dataType string
+}
+
+// inferRelationships proposes foreign keys that database dbName does not
+// declare, from column naming conventions: a column <name>_id of a type
+// compatible with the single-column primary key of a table called <name>,
+// or its plural, is taken to reference it. With sample > 0, up to sample
+// distinct values of the column are looked up in the parent, and the
+// relationship is dropped if fewer than inferMinOverlap of them are found.
+// Inferred keys are returned by table, named inferred_<column>.
+func inferRelationships(ctx context.Context, db *sql.DB, retries int, dbName string, sample int) (map[string][]ForeignKeyInfo, error) {
+	rows, err := queryWithRetry(ctx, db, retries, `
+		SELECT c.TABLE_NAME, c.COLUMN_NAME, c.DATA_TYPE
+		FROM information_schema.COLUMNS c
+		JOIN information_schema.TABLES t
+			ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME
+		WHERE c.TABLE_SCHEMA = ? AND t.TABLE_TYPE = 'BASE TABLE'
+		ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION`, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+	var columns []inferColumn
+	for rows.Next() {
+		var col inferColumn
+		if err := rows.Scan(&col.table, &col.name, &col.dataType); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan column: %w", err)
+		}
+		columns = append(columns, col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query columns: %w", err)
+	}
+
+	// Single-column primary keys by lowercased table name, and the columns
+	// already covered by declared foreign keys
+	rows, err = queryWithRetry(ctx, db, retries, `
+		SELECT TABLE_NAME, COLUMN_NAME, CONSTRAINT_NAME, REFERENCED_TABLE_NAME IS NOT NULL
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = ?
+			AND (CONSTRAINT_NAME = 'PRIMARY' OR REFERENCED_TABLE_NAME IS NOT NULL)
+		ORDER BY TABLE_NAME, ORDINAL_POSITION`, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query keys: %w", err)
+	}
+	keyColumns := make(map[string][]string)
+	declared := make(map[string]bool)
+	for rows.Next() {
+		var table, column, constraint string
+		var foreign bool
+		if err := rows.Scan(&table, &column, &constraint, &foreign); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan key: %w", err)
+		}
+		if foreign {
+			declared[table+"."+column] = true
+		} else {
+			keyColumns[table] = append(keyColumns[table], column)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query keys: %w", err)
+	}
+
+	types := make(map[string]string, len(columns))
+	for _, col := range columns {
+		types[col.table+"."+col.name] = col.dataType
+	}
+	parents := make(map[string]string)
+	for table, key := range keyColumns {
+		if len(key) == 1 {
+			parents[strings.ToLower(table)] = table
+		}
+	}
+
+	inferred := make(map[string][]ForeignKeyInfo)
+	for _, col := range columns {
+		lower := strings.ToLower(col.name)
+		if declared[col.table+"."+col.name] || !strings.HasSuffix(lower, "_id") || lower == "_id" {
+			continue
+		}
+		parent, ok := inferParentTable(parents, col.table, strings.TrimSuffix(lower, "_id"))
+		if !ok {
+			continue
+		}
+		parentKey := keyColumns[parent][0]
+		if !compatibleKeyTypes(col.dataType, types[parent+"."+parentKey]) {
+			continue
+		}
+
+		evidence := "by name"
+		if sample > 0 {
+			found, total, err := sampleOverlap(ctx, db, retries, dbName, col, parent, parentKey, sample)
+			if err != nil {
+				return nil, err
+			}
+			if total > 0 && float64(found) < inferMinOverlap*float64(total) {
+				fmt.Printf("   Not inferring %s.%s.%s -> %s.%s: only %d of %d sampled values match\n",
+					dbName, col.table, col.name, parent, parentKey, found, total)
+				continue
+			}
+			evidence = fmt.Sprintf("%d of %d sampled values match", found, total)
+		}
+
+		fmt.Printf("🔗 Inferred relationship %s.%s.%s -> %s.%s (%s)\n", dbName, col.table, col.name, parent, parentKey, evidence)
+		inferred[col.table] = append(inferred[col.table], ForeignKeyInfo{
+			ConstraintName: "inferred_" + col.name,
+			TableName:      col.table,
+			ColumnName:     col.name,
+			RefTableName:   parent,
+			RefColumnName:  parentKey,
+			Inferred:       true,
+		})
+	}
+	return inferred, nil
+}
+
+// inferParentTable finds the table a column named <stem>_id of table refers
+// to: stem itself or a plural of it, also under the table's own name prefix,
+// e.g. shop_customers for customer_id in shop_orders. A table does not
+// reference itself by name.
+func inferParentTable(parents map[string]string, table, stem string) (string, bool) {
+	candidates := []string{stem, stem + "s", stem + "es"}
+	if strings.HasSuffix(stem, "y") {
+		candidates = append(candidates, strings.TrimSuffix(stem, "y")+"ies")
+	}
+	if prefix, _, ok := strings.Cut(strings.ToLower(table), "_"); ok {
+		for _, c := range candidates {
+			candidates = append(candidates, prefix+"_"+c)
+		}
+	}
+
+	for _, c := range candidates {
+		if parent, ok := parents[c]; ok && parent != table {
+			return parent, true
+		}
+	}
+	return "", false
+}
+
+// compatibleKeyTypes reports whether values of column types a and b can be
+// equal: integers of any size, character strings of either kind, or else the
+// same type
+func compatibleKeyTypes(a, b string) bool {
+	class := func(t string) string {
+		switch t {
+		case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+			return "integer"
+		case "char", "varchar":
+			return "string"
+		case "binary", "varbinary":
+			return "binary"
+		}
+		return t
+	}
+	return a != "" && class(a) == class(b)
+}
+
+// sampleOverlap counts how many of up to sample distinct non-NULL values of
+// col exist as parentKey in parent
+func sampleOverlap(ctx context.Context, db *sql.DB, retries int, dbName string, col inferColumn, parent, parentKey string, sample int) (found, total int64, err error) {
+	subquery := fmt.Sprintf("SELECT DISTINCT `%s` AS v FROM `%s`.`%s` WHERE `%s` IS NOT NULL LIMIT ?",
+		col.name, dbName, col.table, col.name)
+	query := fmt.Sprintf("SELECT COUNT(*), COALESCE(SUM(EXISTS (SELECT 1 FROM `%s`.`%s` p WHERE p.`%s` = s.v)), 0) FROM (%s) s",
+		dbName, parent, parentKey, subquery)
+	if err := queryRowWithRetry(ctx, db, retries, query, []interface{}{sample}, &total, &found); err != nil {
+		return 0, 0, fmt.Errorf("failed to sample %s.%s: %w", col.table, col.name, err)
+	}
+	return found, total, nil
+}
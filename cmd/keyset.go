@@ -0,0 +1,343 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"mariadb-extractor/internal/metacache"
+	"mariadb-extractor/internal/retry"
+)
+
+// tableReader streams the rows of a planned table. Tables with a primary key
+// are read in key order, in chunks of --chunk-size rows each starting after
+// the last key of the previous one, so no single query has to walk a huge
+// table. The last key read also serves as a checkpoint: when the connection
+// drops mid-table the query is reopened after it and extraction continues
+// within the same run.
+type tableReader struct {
+	ctx   context.Context
+	db    *sql.DB
+	plan  TableExtractionPlan
+	limit int64
+
+	key        []string
+	keyIndexes []int
+	// order sorts tables without a primary key by all their columns, for
+	// --stable-output
+	order []string
+	// sample is the condition selecting the --sample-strategy sample, and
+	// descending reads it newest first
+	sample     string
+	descending bool
+	// selected lists the columns read when --exclude-columns drops some;
+	// nil reads them all
+	selected []string
+	// kinds tell how the values of each column are written
+	kinds []columnKind
+	// precisions are the fractional second digits of date and time columns
+	precisions []int
+	lastKey    []interface{}
+	read       int64
+	reconnects int
+	// checkpoints records the last key written for --resume, when the
+	// rows are written in key order as read
+	checkpoints *tableCheckpoints
+
+	// chunkLimit is the LIMIT of the current query, chunkRead the rows it
+	// returned so far
+	chunkLimit int64
+	chunkRead  int64
+
+	rows    *sql.Rows
+	cleanup func()
+	columns []string
+	values  []interface{}
+	ptrs    []interface{}
+}
+
+// openTableReader starts reading plan's table, at most limit rows when it is
+// positive
+func openTableReader(ctx context.Context, db *sql.DB, plan TableExtractionPlan, limit int64) (*tableReader, error) {
+	key, err := getPrimaryKey(ctx, db, plan.DatabaseName, plan.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &tableReader{ctx: ctx, db: db, plan: plan, limit: limit, key: key}
+	if plan.resumeAfter != nil {
+		if len(plan.resumeAfter) != len(key) {
+			return nil, fmt.Errorf("the primary key of %s.%s changed since the run was interrupted", plan.DatabaseName, plan.TableName)
+		}
+		r.lastKey = plan.resumeAfter
+	}
+	if r.selected, err = selectedColumns(ctx, db, plan, key); err != nil {
+		return nil, err
+	}
+	if len(key) == 0 && dataStableOutput {
+		if r.order, err = getTableColumns(ctx, db, plan.DatabaseName, plan.TableName); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.applySampleStrategy(ctx, db); err != nil {
+		return nil, err
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+
+	if r.columns, err = r.rows.Columns(); err != nil {
+		r.close()
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+	if r.kinds, err = columnKinds(r.rows); err != nil {
+		r.close()
+		return nil, err
+	}
+	if r.precisions, err = columnPrecisions(r.rows); err != nil {
+		r.close()
+		return nil, err
+	}
+	for i, col := range r.columns {
+		if encryptedColumn(dataEncryptedColumns, plan.DatabaseName, plan.TableName, col) {
+			r.kinds[i] = binaryColumn
+		}
+	}
+	r.values = make([]interface{}, len(r.columns))
+	r.ptrs = make([]interface{}, len(r.columns))
+	for i := range r.values {
+		r.ptrs[i] = &r.values[i]
+	}
+
+	index := make(map[string]int, len(r.columns))
+	for i, col := range r.columns {
+		index[col] = i
+	}
+	for _, col := range key {
+		idx, ok := index[col]
+		if !ok {
+			r.keyIndexes = nil
+			break
+		}
+		r.keyIndexes = append(r.keyIndexes, idx)
+	}
+	return r, nil
+}
+
+// query returns the SELECT continuing after the last key read
+func (r *tableReader) query() string {
+	list := "*"
+	if r.selected != nil {
+		list = quoteColumns(r.selected)
+	}
+	query := fmt.Sprintf("SELECT %s FROM `%s`.`%s`", list, r.plan.DatabaseName, r.plan.TableName)
+
+	var conditions []string
+	if r.plan.WhereClause != "" {
+		conditions = append(conditions, "("+r.plan.WhereClause+")")
+	}
+	if r.sample != "" {
+		conditions = append(conditions, r.sample)
+	}
+	if r.lastKey != nil {
+		conditions = append(conditions, keysetCondition(r.key, r.lastKey, r.descending))
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if r.descending {
+		order := make([]string, len(r.key))
+		for i, col := range r.key {
+			order[i] = "`" + col + "` DESC"
+		}
+		query += " ORDER BY " + strings.Join(order, ", ")
+	} else if len(r.key) > 0 {
+		query += " ORDER BY " + quoteColumns(r.key)
+	} else if len(r.order) > 0 {
+		query += " ORDER BY " + quoteColumns(r.order)
+	}
+	if r.chunkLimit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", r.chunkLimit)
+	}
+	return query
+}
+
+func (r *tableReader) open() error {
+	r.chunkLimit, r.chunkRead = 0, 0
+	if r.limit > 0 {
+		r.chunkLimit = r.limit - r.read
+	}
+	if len(r.key) > 0 && (r.chunkLimit == 0 || r.chunkLimit > int64(dataChunkSize)) {
+		r.chunkLimit = int64(dataChunkSize)
+	}
+
+	rows, cleanup, err := queryWithKill(r.ctx, r.db, dataMaxRetries, r.query())
+	if err != nil {
+		return fmt.Errorf("failed to query table data: %w", err)
+	}
+	r.rows, r.cleanup = rows, cleanup
+	return nil
+}
+
+// nextChunk reports whether the current chunk ended at its LIMIT while more
+// rows may follow, and if so opens the next one
+func (r *tableReader) nextChunk() (bool, error) {
+	if len(r.key) == 0 || r.lastKey == nil || r.chunkRead < r.chunkLimit {
+		return false, nil
+	}
+	if r.limit > 0 && r.read >= r.limit {
+		return false, nil
+	}
+
+	r.cleanup()
+	r.cleanup = func() {}
+	if err := r.open(); err != nil {
+		return false, err
+	}
+	dataMonitor.chunk()
+	return true, nil
+}
+
+// next scans the next row into values. It reconnects and continues after the
+// last key read when the connection is lost, up to --max-retries times.
+func (r *tableReader) next() (bool, error) {
+	for {
+		if r.rows.Next() {
+			if err := r.rows.Scan(r.ptrs...); err != nil {
+				return false, conversionError{fmt.Errorf("failed to scan row: %w", err)}
+			}
+			r.read++
+			r.chunkRead++
+			dataMonitor.row()
+			dataProgressEvents.row()
+			dataProgressBar.row()
+			if err := dataRateLimiter.wait(r.ctx, rowBytes(r.values)); err != nil {
+				return false, err
+			}
+			if err := dataRowLimiter.wait(r.ctx, 1); err != nil {
+				return false, err
+			}
+			if r.keyIndexes != nil {
+				if r.lastKey == nil {
+					r.lastKey = make([]interface{}, len(r.keyIndexes))
+				}
+				for i, idx := range r.keyIndexes {
+					r.lastKey[i] = r.values[idx]
+				}
+			}
+			// Only after the key is taken, so --zero-dates null keeps it intact
+			if err := applyZeroDates(r.plan, r.columns, r.kinds, r.values); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+
+		err := r.rows.Err()
+		if err == nil {
+			more, err := r.nextChunk()
+			if !more || err != nil {
+				return false, err
+			}
+			continue
+		}
+		if !r.canReconnect(err) {
+			return false, fmt.Errorf("failed to read rows: %w", err)
+		}
+
+		r.reconnects++
+		fmt.Printf("\n⚠️  Connection lost after %d rows of %s.%s, continuing after the last row read (%d/%d): %v\n",
+			r.read, r.plan.DatabaseName, r.plan.TableName, r.reconnects, dataMaxRetries, err)
+		r.cleanup()
+		r.cleanup = func() {}
+		if err := r.open(); err != nil {
+			return false, err
+		}
+	}
+}
+
+// canReconnect reports whether reading may continue on a new connection: the
+// error is transient and the rows read so far can be skipped by key
+func (r *tableReader) canReconnect(err error) bool {
+	if r.ctx.Err() != nil || !retry.IsTransient(err) || r.reconnects >= dataMaxRetries {
+		return false
+	}
+	return r.read == 0 || r.lastKey != nil
+}
+
+func (r *tableReader) close() {
+	r.cleanup()
+}
+
+// getPrimaryKey returns the primary key columns of a table in index order, or
+// nil when it has none
+func getPrimaryKey(ctx context.Context, db *sql.DB, dbName, tableName string) ([]string, error) {
+	query := `
+		SELECT COLUMN_NAME
+		FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER BY ORDINAL_POSITION
+	`
+	ctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+
+	rows, err := cachedRows(ctx, db, dataMaxRetries, metacache.Key{Kind: metaPrimaryKey, Database: dbName, Table: tableName}, query, dbName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get primary key: %w", err)
+	}
+
+	var key []string
+	for _, row := range rows {
+		key = append(key, row[0])
+	}
+	return key, nil
+}
+
+// getTableColumns returns the columns of a table in definition order
+func getTableColumns(ctx context.Context, db *sql.DB, dbName, tableName string) ([]string, error) {
+	query := `
+		SELECT COLUMN_NAME
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`
+	ctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+
+	rows, err := cachedRows(ctx, db, dataMaxRetries, metacache.Key{Kind: metaColumns, Database: dbName, Table: tableName}, query, dbName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	var columns []string
+	for _, row := range rows {
+		columns = append(columns, row[0])
+	}
+	return columns, nil
+}
+
+// keysetCondition matches the rows ordered after values on columns, or
+// before them when descending. It is spelled out column by column instead of
+// as a row comparison so the server can use the primary key index.
+func keysetCondition(columns []string, values []interface{}, descending bool) string {
+	op := ">"
+	if descending {
+		op = "<"
+	}
+	var alternatives []string
+	for i := range columns {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("`%s` = %s", columns[j], formatSQLValue(values[j])))
+		}
+		terms = append(terms, fmt.Sprintf("`%s` %s %s", columns[i], op, formatSQLValue(values[i])))
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	if len(alternatives) == 1 {
+		return alternatives[0]
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")"
+}
@@ -0,0 +1,496 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ColumnLineage maps a view column to the base table columns its values come
+// from (json-v2). Derived is set when the value is computed from them rather
+// than copied; a column without sources is a constant or could not be traced.
+type ColumnLineage struct {
+	Column  string   `json:"column"`
+	Sources []string `json:"sources"`
+	Derived bool     `json:"derived,omitempty"`
+}
+
+// viewDefinition is a view as read from information_schema
+type viewDefinition struct {
+	schema, name, definition string
+	columns                  []string
+}
+
+// addViewLineage sets the column lineage of the views among databases. All
+// views on the server are parsed, so columns selected through views in other
+// databases are traced down to their base tables.
+func addViewLineage(ctx context.Context, db *sql.DB, databases []DatabaseInfo) error {
+	views, err := readViewDefinitions(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	parsed := make(map[string][]ColumnLineage, len(views))
+	for _, v := range views {
+		parsed[v.schema+"."+v.name] = parseViewLineage(v)
+	}
+
+	resolver := lineageResolver{views: parsed, done: map[string]ColumnLineage{}, visiting: map[string]bool{}}
+	for i := range databases {
+		for j := range databases[i].Tables {
+			table := &databases[i].Tables[j]
+			columns, ok := parsed[databases[i].Name+"."+table.Name]
+			if !ok {
+				continue
+			}
+			table.Lineage = make([]ColumnLineage, len(columns))
+			for k, col := range columns {
+				table.Lineage[k] = resolver.resolve(databases[i].Name+"."+table.Name, col)
+			}
+		}
+	}
+	return nil
+}
+
+// readViewDefinitions returns the views on the server with their columns.
+// Views whose definition the user may not see are left out.
+func readViewDefinitions(ctx context.Context, db *sql.DB) ([]viewDefinition, error) {
+	rows, err := queryWithRetry(ctx, db, maxRetries, `
+		SELECT v.TABLE_SCHEMA, v.TABLE_NAME, v.VIEW_DEFINITION, c.COLUMN_NAME
+		FROM information_schema.VIEWS v
+		JOIN information_schema.COLUMNS c
+			ON c.TABLE_SCHEMA = v.TABLE_SCHEMA AND c.TABLE_NAME = v.TABLE_NAME
+		WHERE v.VIEW_DEFINITION <> ''
+		ORDER BY v.TABLE_SCHEMA, v.TABLE_NAME, c.ORDINAL_POSITION`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []viewDefinition
+	for rows.Next() {
+		var schema, name, definition, column string
+		if err := rows.Scan(&schema, &name, &definition, &column); err != nil {
+			return nil, fmt.Errorf("failed to scan view: %w", err)
+		}
+		if n := len(views); n == 0 || views[n-1].schema != schema || views[n-1].name != name {
+			views = append(views, viewDefinition{schema: schema, name: name, definition: definition})
+		}
+		views[len(views)-1].columns = append(views[len(views)-1].columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query views: %w", err)
+	}
+	return views, nil
+}
+
+// lineageResolver follows view column sources through other views
+type lineageResolver struct {
+	views    map[string][]ColumnLineage
+	done     map[string]ColumnLineage
+	visiting map[string]bool
+}
+
+// resolve replaces the sources of col, a column of view, that are columns of
+// other views by their base table sources
+func (r *lineageResolver) resolve(view string, col ColumnLineage) ColumnLineage {
+	key := view + "." + col.Column
+	if resolved, ok := r.done[key]; ok {
+		return resolved
+	}
+	r.visiting[key] = true
+	defer delete(r.visiting, key)
+
+	resolved := ColumnLineage{Column: col.Column, Sources: []string{}, Derived: col.Derived}
+	seen := map[string]bool{}
+	for _, source := range col.Sources {
+		table, column := cutLast(source)
+		sources := []string{source}
+		if columns, ok := r.views[table]; ok {
+			sources = nil
+			for _, c := range columns {
+				if c.Column != column || r.visiting[table+"."+c.Column] {
+					continue
+				}
+				inner := r.resolve(table, c)
+				sources = inner.Sources
+				resolved.Derived = resolved.Derived || inner.Derived
+			}
+		}
+		for _, s := range sources {
+			if !seen[s] {
+				seen[s] = true
+				resolved.Sources = append(resolved.Sources, s)
+			}
+		}
+	}
+	sort.Strings(resolved.Sources)
+	r.done[key] = resolved
+	return resolved
+}
+
+// cutLast splits db.table.column into db.table and column
+func cutLast(column string) (string, string) {
+	i := strings.LastIndex(column, ".")
+	if i < 0 {
+		return "", column
+	}
+	return column[:i], column[i+1:]
+}
+
+// parseViewLineage maps the columns of a view to the db.table.column
+// references of its select list. The server stores view definitions in a
+// canonical form with backquoted, qualified names, which is what this parses;
+// columns of a UNION take the sources of every branch.
+func parseViewLineage(v viewDefinition) []ColumnLineage {
+	var lineage []ColumnLineage
+	for _, branch := range splitUnion(tokenizeSQL(v.definition)) {
+		items := parseSelect(branch, v.schema)
+		for i, item := range items {
+			if i == len(lineage) {
+				name := item.alias
+				if i < len(v.columns) {
+					name = v.columns[i]
+				}
+				lineage = append(lineage, ColumnLineage{Column: name, Sources: []string{}})
+			}
+			lineage[i].Sources = append(lineage[i].Sources, item.sources...)
+			lineage[i].Derived = lineage[i].Derived || item.derived
+		}
+	}
+	return lineage
+}
+
+// sqlToken is a token of a view definition: a backquoted identifier, a
+// string literal, a word or a single punctuation character
+type sqlToken struct {
+	text  string
+	ident bool
+	str   bool
+}
+
+// is reports whether t is the keyword or punctuation s
+func (t sqlToken) is(s string) bool {
+	return !t.ident && !t.str && strings.EqualFold(t.text, s)
+}
+
+func tokenizeSQL(s string) []sqlToken {
+	var tokens []sqlToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '`' || c == '\'' || c == '"':
+			var b strings.Builder
+			j := i + 1
+			for j < len(s) {
+				if s[j] == '\\' && c != '`' && j+1 < len(s) {
+					b.WriteByte(s[j+1])
+					j += 2
+					continue
+				}
+				if s[j] == c {
+					if j+1 < len(s) && s[j+1] == c {
+						b.WriteByte(c)
+						j += 2
+						continue
+					}
+					break
+				}
+				b.WriteByte(s[j])
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: b.String(), ident: c == '`', str: c != '`'})
+			i = j + 1
+		case isWordByte(c):
+			j := i
+			for j < len(s) && isWordByte(s[j]) {
+				j++
+			}
+			tokens = append(tokens, sqlToken{text: s[i:j]})
+			i = j
+		default:
+			tokens = append(tokens, sqlToken{text: s[i : i+1]})
+			i++
+		}
+	}
+	return tokens
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '$' || c == '@' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// splitTopLevel splits tokens before each token outside parentheses for
+// which split returns true
+func splitTopLevel(tokens []sqlToken, split func(i int) bool) [][]sqlToken {
+	var parts [][]sqlToken
+	depth, start := 0, 0
+	for i, t := range tokens {
+		switch {
+		case t.is("("):
+			depth++
+		case t.is(")"):
+			depth--
+		case depth == 0 && split(i):
+			parts = append(parts, tokens[start:i])
+			start = i
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// splitUnion returns the SELECT branches of a UNION, without the UNION
+// keywords; a definition wrapped in parentheses is unwrapped first
+func splitUnion(tokens []sqlToken) [][]sqlToken {
+	for len(tokens) > 2 && tokens[0].is("(") && tokens[len(tokens)-1].is(")") && closingParen(tokens, 0) == len(tokens)-1 {
+		tokens = tokens[1 : len(tokens)-1]
+	}
+
+	var branches [][]sqlToken
+	for _, part := range splitTopLevel(tokens, func(i int) bool { return tokens[i].is("union") }) {
+		for len(part) > 0 && (part[0].is("union") || part[0].is("all") || part[0].is("distinct")) {
+			part = part[1:]
+		}
+		for len(part) > 2 && part[0].is("(") && closingParen(part, 0) == len(part)-1 {
+			part = part[1 : len(part)-1]
+		}
+		branches = append(branches, part)
+	}
+	return branches
+}
+
+// closingParen returns the index of the parenthesis closing tokens[open]
+func closingParen(tokens []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(tokens); i++ {
+		if tokens[i].is("(") {
+			depth++
+		} else if tokens[i].is(")") {
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// selectItem is a parsed select list entry
+type selectItem struct {
+	alias   string
+	sources []string
+	derived bool
+}
+
+// selectClauseEnd are the keywords ending the FROM clause of a SELECT
+var selectClauseEnd = map[string]bool{
+	"where": true, "group": true, "having": true, "order": true, "limit": true,
+	"window": true, "procedure": true, "into": true, "for": true, "lock": true,
+}
+
+// parseSelect returns the select list of a single SELECT, with column
+// references resolved against its FROM clause. schema is the view's database,
+// used for unqualified table names.
+func parseSelect(tokens []sqlToken, schema string) []selectItem {
+	if len(tokens) == 0 || !tokens[0].is("select") {
+		return nil
+	}
+	tokens = tokens[1:]
+	for len(tokens) > 0 && !tokens[0].ident && isSelectModifier(tokens[0].text) {
+		tokens = tokens[1:]
+	}
+
+	var list, from []sqlToken
+	parts := splitTopLevel(tokens, func(i int) bool { return tokens[i].is("from") })
+	list = parts[0]
+	if len(parts) > 1 {
+		from = parts[1][1:]
+		isEnd := func(i int) bool {
+			return !from[i].ident && !from[i].str && selectClauseEnd[strings.ToLower(from[i].text)]
+		}
+		from = splitTopLevel(from, isEnd)[0]
+	}
+
+	tables := fromTables(from, schema)
+	var items []selectItem
+	for _, expr := range splitTopLevel(list, func(i int) bool { return list[i].is(",") }) {
+		if len(expr) > 0 && expr[0].is(",") {
+			expr = expr[1:]
+		}
+		items = append(items, parseSelectItem(expr, tables))
+	}
+	return items
+}
+
+func isSelectModifier(word string) bool {
+	switch strings.ToLower(word) {
+	case "all", "distinct", "distinctrow", "high_priority", "straight_join", "sql_small_result",
+		"sql_big_result", "sql_buffer_result", "sql_cache", "sql_no_cache", "sql_calc_found_rows":
+		return true
+	}
+	return false
+}
+
+// fromSource is a table or derived table of a FROM clause. Derived tables
+// carry the lineage of their own select list instead of a table name.
+type fromSource struct {
+	table   string
+	derived map[string]selectItem
+}
+
+// fromTables maps the names a FROM clause makes available, table names and
+// aliases, to their sources
+func fromTables(from []sqlToken, schema string) map[string]fromSource {
+	tables := map[string]fromSource{}
+	for i := 0; i < len(from); i++ {
+		if !startsTableReference(from, i) {
+			continue
+		}
+
+		var name string
+		var source fromSource
+		end := i
+		switch {
+		case from[i].is("("):
+			end = closingParen(from, i)
+			if end < 0 || i+1 >= end || !from[i+1].is("select") {
+				continue
+			}
+			source.derived = map[string]selectItem{}
+			for _, item := range parseSelect(from[i+1:end], schema) {
+				source.derived[item.alias] = item
+			}
+		case from[i].ident && i+2 < len(from) && from[i+1].is(".") && from[i+2].ident:
+			name = from[i+2].text
+			source.table = from[i].text + "." + name
+			end = i + 2
+		case from[i].ident:
+			name = from[i].text
+			source.table = schema + "." + name
+		default:
+			continue
+		}
+
+		// An alias follows the table, optionally after AS
+		next := end + 1
+		if next < len(from) && from[next].is("as") {
+			next++
+		}
+		if next < len(from) && from[next].ident {
+			name = from[next].text
+			end = next
+		}
+		if name != "" {
+			tables[name] = source
+		}
+		i = end
+	}
+	return tables
+}
+
+// startsTableReference reports whether from[i] starts a table reference:
+// the first token of the clause or one following a join or a separator
+func startsTableReference(from []sqlToken, i int) bool {
+	if i == 0 {
+		return true
+	}
+	prev := from[i-1]
+	if prev.is("join") || prev.is("straight_join") || prev.is(",") {
+		return true
+	}
+	// A parenthesised join, but not the condition of ON(...) or USING(...)
+	return prev.is("(") && startsTableReference(from, i-1) && (i < 2 || !from[i-2].is("on") && !from[i-2].is("using"))
+}
+
+// parseSelectItem resolves the column references of one select list entry
+func parseSelectItem(expr []sqlToken, tables map[string]fromSource) selectItem {
+	var item selectItem
+	if n := len(expr); n >= 2 && expr[n-1].ident && expr[n-2].is("as") {
+		item.alias = expr[n-1].text
+		expr = expr[:n-2]
+	}
+
+	refs := 0
+	for i := 0; i < len(expr); i++ {
+		if expr[i].is("select") {
+			// Scalar subqueries are computed from tables of their own
+			item.derived = true
+			end := closingParen(expr, i-1)
+			if i == 0 || end < 0 {
+				break
+			}
+			i = end
+			continue
+		}
+		if !expr[i].ident || i > 0 && expr[i-1].is(".") {
+			continue
+		}
+
+		var parts []string
+		j := i
+		for j < len(expr) && expr[j].ident {
+			parts = append(parts, expr[j].text)
+			if j+2 < len(expr) && expr[j+1].is(".") && expr[j+2].ident {
+				j += 2
+				continue
+			}
+			break
+		}
+		i = j
+		refs++
+
+		switch len(parts) {
+		case 3:
+			item.sources = append(item.sources, strings.Join(parts, "."))
+		case 2:
+			source, ok := tables[parts[0]]
+			if !ok {
+				continue
+			}
+			if source.derived != nil {
+				inner := source.derived[parts[1]]
+				item.sources = append(item.sources, inner.sources...)
+				item.derived = item.derived || inner.derived
+			} else {
+				item.sources = append(item.sources, source.table+"."+parts[1])
+			}
+		case 1:
+			// Only a sole table leaves a column unqualified unambiguous
+			for _, source := range tables {
+				if len(tables) == 1 && source.derived == nil {
+					item.sources = append(item.sources, source.table+"."+parts[0])
+				}
+				break
+			}
+		}
+		if item.alias == "" {
+			item.alias = parts[len(parts)-1]
+		}
+	}
+
+	// Anything but a bare column reference computes the value
+	if refs != 1 || !isColumnReference(expr) {
+		item.derived = true
+	}
+	return item
+}
+
+// isColumnReference reports whether expr is a single, possibly qualified,
+// column name
+func isColumnReference(expr []sqlToken) bool {
+	if len(expr) == 0 || len(expr)%2 == 0 {
+		return false
+	}
+	for i, t := range expr {
+		if i%2 == 0 && !t.ident || i%2 == 1 && !t.is(".") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,401 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"mariadb-extractor/internal/yaml"
+
+	"github.com/spf13/cobra"
+)
+
+// lintCmd represents the lint command
+var lintCmd = &cobra.Command{
+	Use:   "lint [extract.json]",
+	Short: "Check an extracted schema against design rules and score it",
+	Long: `Check the JSON written by 'extract --format json-v2' against schema design
+rules (missing primary keys, foreign keys without indexes, nullable foreign
+keys, naming conventions, utf8mb3 character sets, TEXT/BLOB columns in hot
+tables) and print a scored report. Rules are configured with a YAML file;
+--min-score makes the command fail, e.g. in CI against a weekly snapshot.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		input := getEnvWithDefault("MARIADB_OUTPUT_PREFIX", "mariadb-extract") + ".json"
+		if len(args) == 1 {
+			input = args[0]
+		}
+		runLint(input)
+	},
+}
+
+var (
+	lintRulesFile string
+	lintFormat    string
+	lintMinScore  float64
+)
+
+// Lint rule names
+const (
+	lintMissingPrimaryKey = "missing-primary-key"
+	lintFKWithoutIndex    = "fk-without-index"
+	lintNullableFK        = "nullable-foreign-key"
+	lintNaming            = "naming"
+	lintUTF8MB3           = "utf8mb3-charset"
+	lintTextInHotTable    = "text-in-hot-table"
+)
+
+// Finding severities, from most to least severe
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+// severityPenalty is the score a table loses per finding of each severity
+var severityPenalty = map[string]float64{
+	severityError:   25,
+	severityWarning: 10,
+	severityInfo:    2,
+}
+
+// defaultLintSeverities lists every rule with its default severity
+var defaultLintSeverities = map[string]string{
+	lintMissingPrimaryKey: severityError,
+	lintFKWithoutIndex:    severityWarning,
+	lintNullableFK:        severityWarning,
+	lintNaming:            severityInfo,
+	lintUTF8MB3:           severityWarning,
+	lintTextInHotTable:    severityWarning,
+}
+
+// namingPatterns are the supported identifier conventions
+var namingPatterns = map[string]*regexp.Regexp{
+	"snake_case": regexp.MustCompile(`^[a-z][a-z0-9]*(_[a-z0-9]+)*$`),
+	"camelCase":  regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`),
+	"PascalCase": regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`),
+}
+
+// lintConfig is the rules file format
+type lintConfig struct {
+	Rules        map[string]lintRuleConfig `json:"rules"`
+	Naming       string                    `json:"naming"`
+	HotTableRows int64                     `json:"hot_table_rows"`
+	HotTables    []string                  `json:"hot_tables"`
+	Ignore       []string                  `json:"ignore"`
+}
+
+// lintRuleConfig overrides a single rule
+type lintRuleConfig struct {
+	Enabled  *bool  `json:"enabled"`
+	Severity string `json:"severity"`
+}
+
+// lintFinding is a single rule violation
+type lintFinding struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Column   string `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// lintReport is the result of linting one extract
+type lintReport struct {
+	Source   string         `json:"source"`
+	Tables   int            `json:"tables"`
+	Score    float64        `json:"score"`
+	Counts   map[string]int `json:"counts"`
+	Findings []lintFinding  `json:"findings"`
+}
+
+func init() {
+	rootCmd.AddCommand(lintCmd)
+
+	lintCmd.Flags().StringVar(&lintRulesFile, "rules", os.Getenv("MARIADB_LINT_RULES"), "YAML rules file (env: MARIADB_LINT_RULES)")
+	lintCmd.Flags().StringVar(&lintFormat, "format", "text", "Report format: text or json")
+	lintCmd.Flags().Float64Var(&lintMinScore, "min-score", 0, "Fail when the score (0-100) is below this value")
+}
+
+func runLint(input string) {
+	if lintFormat != "text" && lintFormat != "json" {
+		log.Fatalf("Invalid --format %q: must be text or json", lintFormat)
+	}
+
+	config, err := loadLintConfig(lintRulesFile)
+	if err != nil {
+		log.Fatalf("Failed to load lint rules: %v", err)
+	}
+
+	data, err := os.ReadFile(input)
+	if err != nil {
+		log.Fatalf("Failed to read extract: %v", err)
+	}
+	var extract ExtractOutput
+	if err := json.Unmarshal(data, &extract); err != nil {
+		log.Fatalf("Failed to parse extract %s: %v", input, err)
+	}
+	if extract.SchemaVersion < extractSchemaVersionV2 {
+		log.Fatalf("%s has schema version %d; lint needs columns and indexes, run 'extract --format json-v2'", input, extract.SchemaVersion)
+	}
+
+	report := lintSchema(extract, config)
+	report.Source = input
+
+	if lintFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	} else {
+		printLintReport(report)
+	}
+
+	if report.Score < lintMinScore {
+		log.Fatalf("Lint score %.1f is below --min-score %.1f", report.Score, lintMinScore)
+	}
+}
+
+// loadLintConfig reads the rules file, or returns the defaults when path is empty
+func loadLintConfig(path string) (lintConfig, error) {
+	config := lintConfig{Naming: "snake_case", HotTableRows: 1000000}
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+
+	for name, rule := range config.Rules {
+		if _, ok := defaultLintSeverities[name]; !ok {
+			return config, fmt.Errorf("unknown rule %q", name)
+		}
+		if rule.Severity != "" {
+			if _, ok := severityPenalty[rule.Severity]; !ok {
+				return config, fmt.Errorf("rule %s: invalid severity %q (error, warning or info)", name, rule.Severity)
+			}
+		}
+	}
+	if config.Naming == "" {
+		config.Naming = "snake_case"
+	}
+	if _, ok := namingPatterns[config.Naming]; !ok {
+		return config, fmt.Errorf("invalid naming %q (snake_case, camelCase or PascalCase)", config.Naming)
+	}
+	return config, nil
+}
+
+// severity returns the configured severity of rule, or "" when it is disabled
+func (c lintConfig) severity(rule string) string {
+	override := c.Rules[rule]
+	if override.Enabled != nil && !*override.Enabled {
+		return ""
+	}
+	if override.Severity != "" {
+		return override.Severity
+	}
+	return defaultLintSeverities[rule]
+}
+
+// lintMatches reports whether db.table matches any of the patterns
+func lintMatches(patterns []string, dbName, tableName string) bool {
+	name := dbName + "." + tableName
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// lintSchema applies the rules to every base table. Each table starts at 100
+// and loses points per finding; the overall score is the mean over tables.
+func lintSchema(extract ExtractOutput, config lintConfig) lintReport {
+	report := lintReport{Counts: make(map[string]int), Findings: []lintFinding{}}
+	naming := namingPatterns[config.Naming]
+
+	var total float64
+	for _, database := range extract.Databases {
+		for _, table := range database.Tables {
+			if table.Type != "BASE TABLE" || lintMatches(config.Ignore, database.Name, table.Name) {
+				continue
+			}
+
+			var findings []lintFinding
+			add := func(rule, column, message string) {
+				severity := config.severity(rule)
+				if severity == "" {
+					return
+				}
+				findings = append(findings, lintFinding{
+					Rule:     rule,
+					Severity: severity,
+					Database: database.Name,
+					Table:    table.Name,
+					Column:   column,
+					Message:  message,
+				})
+			}
+
+			lintTable(database.Name, table, config, naming, add)
+
+			score := 100.0
+			for _, f := range findings {
+				score -= severityPenalty[f.Severity]
+				report.Counts[f.Severity]++
+			}
+			if score < 0 {
+				score = 0
+			}
+			total += score
+			report.Tables++
+			report.Findings = append(report.Findings, findings...)
+		}
+	}
+
+	report.Score = 100
+	if report.Tables > 0 {
+		report.Score = float64(int(total/float64(report.Tables)*10+0.5)) / 10
+	}
+	return report
+}
+
+// lintTable reports the rule violations of a single table through add
+func lintTable(dbName string, table TableInfo, config lintConfig, naming *regexp.Regexp, add func(rule, column, message string)) {
+	columns := make(map[string]ColumnInfo, len(table.Columns))
+	for _, column := range table.Columns {
+		columns[column.Name] = column
+	}
+
+	hasPrimary := false
+	for _, index := range table.Indexes {
+		if index.Name == "PRIMARY" {
+			hasPrimary = true
+		}
+	}
+	if !hasPrimary {
+		add(lintMissingPrimaryKey, "", "table has no primary key")
+	}
+
+	for _, fk := range table.ForeignKeys {
+		// Inferred relationships are not constraints of the schema
+		if fk.Inferred {
+			continue
+		}
+		if !hasIndexPrefix(table.Indexes, fk.Columns) {
+			add(lintFKWithoutIndex, strings.Join(fk.Columns, ","),
+				fmt.Sprintf("foreign key %s has no index starting with (%s)", fk.Name, strings.Join(fk.Columns, ", ")))
+		}
+		for _, name := range fk.Columns {
+			if columns[name].Nullable {
+				add(lintNullableFK, name, fmt.Sprintf("column of foreign key %s is nullable", fk.Name))
+			}
+		}
+	}
+
+	if !naming.MatchString(table.Name) {
+		add(lintNaming, "", fmt.Sprintf("table name is not %s", config.Naming))
+	}
+	for _, column := range table.Columns {
+		if !naming.MatchString(column.Name) {
+			add(lintNaming, column.Name, fmt.Sprintf("column name is not %s", config.Naming))
+		}
+	}
+
+	if isUTF8MB3(table.Collation) {
+		add(lintUTF8MB3, "", fmt.Sprintf("table collation %s is utf8mb3; use utf8mb4", table.Collation))
+	}
+	for _, column := range table.Columns {
+		if isUTF8MB3(column.Collation) {
+			add(lintUTF8MB3, column.Name, fmt.Sprintf("column collation %s is utf8mb3; use utf8mb4", column.Collation))
+		}
+	}
+
+	hot := (config.HotTableRows > 0 && table.RowCount >= config.HotTableRows) ||
+		lintMatches(config.HotTables, dbName, table.Name)
+	if hot {
+		for _, column := range table.Columns {
+			if strings.HasSuffix(column.DataType, "text") || strings.HasSuffix(column.DataType, "blob") {
+				add(lintTextInHotTable, column.Name,
+					fmt.Sprintf("%s column in a hot table (%d rows)", strings.ToUpper(column.DataType), table.RowCount))
+			}
+		}
+	}
+}
+
+// hasIndexPrefix reports whether some index starts with exactly columns
+func hasIndexPrefix(indexes []IndexInfo, columns []string) bool {
+	for _, index := range indexes {
+		if len(index.Columns) < len(columns) {
+			continue
+		}
+		match := true
+		for i, column := range columns {
+			if index.Columns[i] != column {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// isUTF8MB3 reports whether a collation belongs to the 3-byte utf8 charset
+func isUTF8MB3(collation string) bool {
+	return strings.HasPrefix(collation, "utf8_") || strings.HasPrefix(collation, "utf8mb3_")
+}
+
+func printLintReport(report lintReport) {
+	fmt.Printf("🔍 Linted %s (%d tables)\n\n", report.Source, report.Tables)
+
+	icons := map[string]string{severityError: "❌", severityWarning: "⚠️ ", severityInfo: "ℹ️ "}
+	findings := append([]lintFinding(nil), report.Findings...)
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Database != b.Database {
+			return a.Database < b.Database
+		}
+		return a.Table < b.Table
+	})
+
+	current := ""
+	for _, f := range findings {
+		if name := f.Database + "." + f.Table; name != current {
+			if current != "" {
+				fmt.Println()
+			}
+			fmt.Println(name)
+			current = name
+		}
+		subject := ""
+		if f.Column != "" {
+			subject = f.Column + ": "
+		}
+		fmt.Printf("  %s %-7s %-21s %s%s\n", icons[f.Severity], f.Severity, f.Rule, subject, f.Message)
+	}
+	if current != "" {
+		fmt.Println()
+	}
+
+	fmt.Printf("Findings: %d errors, %d warnings, %d info\n",
+		report.Counts[severityError], report.Counts[severityWarning], report.Counts[severityInfo])
+	fmt.Printf("Score: %.1f/100\n", report.Score)
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// outputLock keeps two runs writing the same output prefix from interleaving:
+// a lock file next to the output and, optionally, a named lock held on the
+// server by a dedicated connection. Methods are safe on nil.
+type outputLock struct {
+	path string
+	conn *sql.Conn
+	name string
+}
+
+// acquireOutputLock locks the output file path, e.g. output/data-extract.sql.
+// A lock file left by a process that no longer runs on this host is taken
+// over. With serverLock, GET_LOCK also guards the prefix against runs on other
+// hosts sharing the output directory.
+func acquireOutputLock(ctx context.Context, db *sql.DB, path string, serverLock bool) (*outputLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	l := &outputLock{path: filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock")}
+	if err := l.createFile(); err != nil {
+		return nil, err
+	}
+
+	if serverLock {
+		if err := l.lockServer(ctx, db, filepath.Base(path)); err != nil {
+			l.release()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// createFile creates the lock file, holding the owner's pid, host and start
+func (l *outputLock) createFile() error {
+	host, _ := os.Hostname()
+	owner := fmt.Sprintf("%d %s %s\n", os.Getpid(), host, time.Now().Format(time.RFC3339))
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(l.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = file.WriteString(owner)
+			if cerr := file.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(l.path)
+				return fmt.Errorf("failed to write lock file: %w", err)
+			}
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		data, _ := os.ReadFile(l.path)
+		pid, lockHost, started := parseLockOwner(string(data))
+		if attempt == 0 && pid > 0 && lockHost == host && !processRunning(pid) {
+			fmt.Printf("⚠️  Removing stale lock %s of process %d, which is no longer running\n", l.path, pid)
+			os.Remove(l.path)
+			continue
+		}
+		return fmt.Errorf("output is locked by process %d on %s since %s; wait for that run to finish, or remove %s if it is no longer running",
+			pid, lockHost, started, l.path)
+	}
+	return fmt.Errorf("failed to create lock file %s", l.path)
+}
+
+// parseLockOwner reads the pid, host and start time of a lock file
+func parseLockOwner(data string) (pid int, host, started string) {
+	fields := strings.Fields(data)
+	host, started = "an unknown host", "an unknown time"
+	if len(fields) > 0 {
+		pid, _ = strconv.Atoi(fields[0])
+	}
+	if len(fields) > 1 {
+		host = fields[1]
+	}
+	if len(fields) > 2 {
+		started = fields[2]
+	}
+	return pid, host, started
+}
+
+// processRunning reports whether a local process exists. Where that cannot
+// be told, the process is assumed to run, so the lock is kept.
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		// FindProcess opens the process there, which fails once it exited
+		process.Release()
+		return true
+	}
+	err = process.Signal(syscall.Signal(0))
+	return !errors.Is(err, os.ErrProcessDone) && !errors.Is(err, syscall.ESRCH)
+}
+
+// lockServer takes the named lock mariadb-extractor:<name> without waiting
+func (l *outputLock) lockServer(ctx context.Context, db *sql.DB, name string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open lock connection: %w", err)
+	}
+
+	l.name = "mariadb-extractor:" + name
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 0)", l.name).Scan(&acquired); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to acquire server lock: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		var holder sql.NullInt64
+		conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?)", l.name).Scan(&holder)
+		conn.Close()
+		return fmt.Errorf("server lock %q is held by connection %d; another extraction with the same output is running", l.name, holder.Int64)
+	}
+	l.conn = conn
+	return nil
+}
+
+// release frees the server lock and removes the lock file
+func (l *outputLock) release() {
+	if l == nil {
+		return
+	}
+	if l.conn != nil {
+		l.conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", l.name)
+		l.conn.Close()
+	}
+	os.Remove(l.path)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/pipeline"
+	"mariadb-extractor/internal/sink"
+	"mariadb-extractor/internal/state"
+)
+
+// dataManifest collects the statistics of the running data extraction, nil
+// outside of data extractions
+var dataManifest *extractManifest
+
+// extractManifest is <prefix>.manifest.json, the per-table statistics of a
+// data run for tooling that verifies and audits extracts
+type extractManifest struct {
+	RunID      string                  `json:"run_id"`
+	Source     string                  `json:"source"`
+	Format     string                  `json:"format"`
+	TimeZone   string                  `json:"time_zone,omitempty"`
+	Status     string                  `json:"status"`
+	StartedAt  time.Time               `json:"started_at"`
+	FinishedAt time.Time               `json:"finished_at"`
+	Tables     []manifestTable         `json:"tables"`
+	Failures   []pipeline.TableFailure `json:"failures"`
+
+	// bytes counts the bytes written to the output files, and tableBytes
+	// the count when the current table started
+	bytes      atomic.Int64
+	tableBytes int64
+}
+
+// manifestTable is a table the run extracted, or failed or stopped in.
+// Sampling is none when every row was read, else the --sample-strategy.
+// Completed tables have the tableSum of Columns as Checksum.
+type manifestTable struct {
+	Table           string   `json:"table"`
+	Status          string   `json:"status"`
+	SourceRows      int64    `json:"source_rows"`
+	Estimated       bool     `json:"source_rows_estimated,omitempty"`
+	Rows            int64    `json:"rows"`
+	Sampling        string   `json:"sampling"`
+	DurationSeconds float64  `json:"duration_seconds"`
+	Bytes           int64    `json:"bytes"`
+	Error           string   `json:"error,omitempty"`
+	Checksum        string   `json:"checksum,omitempty"`
+	Columns         []string `json:"checksum_columns,omitempty"`
+}
+
+// manifestFile is the manifest next to the SQL script
+func manifestFile() string {
+	return filepath.Join("output", sink.Prefix(dataOutput)+".manifest.json")
+}
+
+// newExtractManifest starts the manifest of a run. A resumed run keeps the
+// entries of the tables it completed before.
+func newExtractManifest(progress *state.Progress, source string) *extractManifest {
+	m := &extractManifest{RunID: progress.RunID, Source: source, Format: dataFormat, StartedAt: time.Now().UTC(), Tables: []manifestTable{}}
+	if len(progress.Completed) == 0 {
+		return m
+	}
+
+	data, err := os.ReadFile(manifestFile())
+	if err != nil {
+		return m
+	}
+	var previous extractManifest
+	if json.Unmarshal(data, &previous) != nil || previous.RunID != progress.RunID {
+		return m
+	}
+	m.StartedAt = previous.StartedAt
+	for _, table := range previous.Tables {
+		if table.Status == "completed" && progress.Done(table.Table) {
+			m.Tables = append(m.Tables, table)
+		}
+	}
+	return m
+}
+
+// wrote counts bytes written to an output file
+func (m *extractManifest) wrote(n int) {
+	if m != nil {
+		m.bytes.Add(int64(n))
+	}
+}
+
+// startTable starts counting the bytes of a table, with buffered bytes of
+// earlier output not yet written
+func (m *extractManifest) startTable(buffered int) {
+	if m != nil {
+		m.tableBytes = m.bytes.Load() + int64(buffered)
+	}
+}
+
+// endTable records a table with its status: completed, failed or stopped.
+// buffered are the bytes of its output not yet written.
+func (m *extractManifest) endTable(table manifestTable, status string, rows int64, err error, started time.Time, buffered int) {
+	if m == nil {
+		return
+	}
+	table.Status, table.Rows = status, rows
+	table.DurationSeconds = roundMillis(time.Since(started).Seconds())
+	table.Bytes = m.bytes.Load() + int64(buffered) - m.tableBytes
+	if err != nil {
+		table.Error = err.Error()
+	}
+	m.Tables = append(m.Tables, table)
+}
+
+// write saves the manifest with the run's status: completed, failed or
+// interrupted
+func (m *extractManifest) write(status string) error {
+	if m == nil {
+		return nil
+	}
+	m.Status, m.FinishedAt = status, time.Now().UTC()
+	m.TimeZone = importTimeZone(dataTimeZone)
+	m.Failures = tableFailures
+	if m.Failures == nil {
+		m.Failures = []pipeline.TableFailure{}
+	}
+
+	path := manifestFile()
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if _, err := checksum.RecordFiles(filepath.Dir(path), path); err != nil {
+		return fmt.Errorf("failed to record checksum: %w", err)
+	}
+	fmt.Printf("📋 Manifest of %d tables written to %s\n", len(m.Tables), path)
+	return nil
+}
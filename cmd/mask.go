@@ -0,0 +1,239 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+
+	"mariadb-extractor/internal/mask"
+
+	"github.com/spf13/cobra"
+)
+
+// maskCmd groups the masking rule tools
+var maskCmd = &cobra.Command{
+	Use:   "mask",
+	Short: "Work with masking rules",
+}
+
+// maskPreviewCmd represents the mask preview command
+var maskPreviewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Show masking rules applied to live rows of a table",
+	Long: `Read a few rows of a table and show the values of its masked columns
+before and after the --mask-config rules, so rules can be checked before a
+full masked extraction. Nothing is written: the values are only printed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runMaskPreview(cmd.Context()); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var (
+	maskHost       string
+	maskPort       int
+	maskUser       string
+	maskPassword   string
+	maskTimeout    int
+	maskMaxRetries int
+	maskConfig     string
+	maskKey        string
+	maskDatabase   string
+	maskTable      string
+	maskRows       int
+)
+
+// maskPreviewWidth is the widest value shown, in characters
+const maskPreviewWidth = 40
+
+func init() {
+	rootCmd.AddCommand(maskCmd)
+	maskCmd.AddCommand(maskPreviewCmd)
+
+	defaultUser := os.Getenv("MARIADB_USER")
+	defaultPassword := os.Getenv("MARIADB_PASSWORD")
+
+	maskPreviewCmd.Flags().StringVarP(&maskHost, "host", "H", getEnvWithDefault("MARIADB_HOST", "localhost"), "MariaDB host (env: MARIADB_HOST)")
+	maskPreviewCmd.Flags().IntVarP(&maskPort, "port", "P", getEnvIntWithDefault("MARIADB_PORT", 3306), "MariaDB port (env: MARIADB_PORT)")
+	maskPreviewCmd.Flags().StringVarP(&maskUser, "user", "u", defaultUser, "MariaDB username (env: MARIADB_USER)")
+	maskPreviewCmd.Flags().StringVarP(&maskPassword, "password", "p", defaultPassword, "MariaDB password (env: MARIADB_PASSWORD)")
+	maskPreviewCmd.Flags().IntVarP(&maskTimeout, "timeout", "t", getEnvIntWithDefault("MARIADB_TIMEOUT", 300), "Query timeout in seconds (env: MARIADB_TIMEOUT)")
+	maskPreviewCmd.Flags().IntVar(&maskMaxRetries, "max-retries", getEnvIntWithDefault("MARIADB_MAX_RETRIES", 3), "Maximum retry attempts for failed queries (env: MARIADB_MAX_RETRIES)")
+	maskPreviewCmd.Flags().StringVar(&maskConfig, "mask-config", os.Getenv("MARIADB_MASK_CONFIG"), "YAML file of per-column masking rules, as used by data (env: MARIADB_MASK_CONFIG)")
+	maskPreviewCmd.Flags().StringVar(&maskKey, "mask-key", os.Getenv("MARIADB_MASK_KEY"), "Secret key of pseudonymize masking rules; prefer the environment variable (env: MARIADB_MASK_KEY)")
+	maskPreviewCmd.Flags().StringVarP(&maskDatabase, "database", "d", "", "Database of --table when it is not given as db.table")
+	maskPreviewCmd.Flags().StringVar(&maskTable, "table", "", "Table to preview, as db.table or table with --database")
+	maskPreviewCmd.Flags().IntVar(&maskRows, "rows", 20, "Rows to preview")
+	maskPreviewCmd.MarkFlagRequired("table")
+
+	if defaultUser == "" {
+		maskPreviewCmd.MarkFlagRequired("user")
+	}
+	if defaultPassword == "" {
+		maskPreviewCmd.MarkFlagRequired("password")
+	}
+}
+
+func runMaskPreview(ctx context.Context) error {
+	dbName, tableName := maskDatabase, maskTable
+	if db, table, ok := strings.Cut(maskTable, "."); ok {
+		dbName, tableName = db, table
+	}
+	if dbName == "" || tableName == "" {
+		return fmt.Errorf("invalid --table %q: use db.table, or table with --database", maskTable)
+	}
+	if maskConfig == "" {
+		return fmt.Errorf("--mask-config is required")
+	}
+	if maskRows < 1 {
+		return fmt.Errorf("--rows must be at least 1")
+	}
+	masks, err := mask.Load(maskConfig, maskKey)
+	if err != nil {
+		return err
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&parseTime=true&timeout=%ds&readTimeout=%ds",
+		maskUser, maskPassword, maskHost, maskPort, maskTimeout, maskTimeout)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+	if err := pingWithRetry(ctx, db, maskMaxRetries); err != nil {
+		return fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	// Read in key order, so the same rows are shown while rules are edited
+	key, err := getPrimaryKey(ctx, db, dbName, tableName)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("SELECT * FROM `%s`.`%s`", dbName, tableName)
+	if len(key) > 0 {
+		query += " ORDER BY " + quoteColumns(key)
+	}
+	query += fmt.Sprintf(" LIMIT %d", maskRows)
+
+	queryCtx, cancel := withTimeout(ctx, maskTimeout)
+	defer cancel()
+	rows, err := queryWithRetry(queryCtx, db, maskMaxRetries, query)
+	if err != nil {
+		return fmt.Errorf("failed to read %s.%s: %w", dbName, tableName, err)
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to get columns: %w", err)
+	}
+
+	row, missing := masks.Row(dbName, tableName, columns)
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		fmt.Printf("⚠️  Warning: masked columns not found: %s\n", strings.Join(missing, ", "))
+	}
+	var masked, keyIndexes []int
+	for i, col := range columns {
+		if row != nil && row[i] != nil {
+			masked = append(masked, i)
+		}
+		for _, k := range key {
+			if col == k {
+				keyIndexes = append(keyIndexes, i)
+			}
+		}
+	}
+	if len(masked) == 0 {
+		fmt.Printf("No masking rule in %s matches a column of %s.%s\n", maskConfig, dbName, tableName)
+		return nil
+	}
+
+	rules := make([]string, len(columns))
+	shuffled := false
+	for _, idx := range masked {
+		rules[idx] = masks.RuleFor(dbName, tableName, columns[idx])
+		shuffled = shuffled || rules[idx] == "shuffle"
+	}
+	fmt.Printf("🔍 Masking preview of %s.%s: %d masked columns, nothing is written\n", dbName, tableName, len(masked))
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	var before, after [][]interface{}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("failed to scan row: %w", err)
+		}
+		before = append(before, append([]interface{}(nil), values...))
+		row.Apply(values)
+		after = append(after, append([]interface{}(nil), values...))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+	mask.Shuffle(after, masks.Shuffled(dbName, tableName, columns))
+
+	for n := range before {
+		label := fmt.Sprintf("Row %d", n+1)
+		if len(keyIndexes) > 0 {
+			parts := make([]string, len(keyIndexes))
+			for i, idx := range keyIndexes {
+				parts[i] = columns[idx] + "=" + previewValue(before[n][idx])
+			}
+			label += " (" + strings.Join(parts, ", ") + ")"
+		}
+		fmt.Printf("\n%s\n", label)
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, idx := range masked {
+			fmt.Fprintf(tw, "  %s (%s)\t%s\t→ %s\n", columns[idx], rules[idx], previewValue(before[n][idx]), previewValue(after[n][idx]))
+		}
+		tw.Flush()
+	}
+
+	if len(before) == 0 {
+		fmt.Printf("%s.%s has no rows\n", dbName, tableName)
+		return nil
+	}
+	if shuffled {
+		fmt.Printf("\nℹ️  shuffle permutes values among the rows shown, so a short preview draws from few values\n")
+	}
+	return nil
+}
+
+// previewValue shows a value on one line, cut to maskPreviewWidth characters
+func previewValue(v interface{}) string {
+	var s string
+	switch val := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		if !utf8.Valid(val) {
+			s = hexLiteral(val)
+		} else {
+			s = string(val)
+		}
+	case time.Time:
+		s = formatDateTime(val, -1)
+	default:
+		s = fmt.Sprint(val)
+	}
+	s = strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s)
+	if utf8.RuneCountInString(s) > maskPreviewWidth {
+		s = string([]rune(s)[:maskPreviewWidth-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,122 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// metadataLockQueries find the longest-running connection holding or
+// awaiting a metadata lock that blocks reading a table, such as an ALTER
+// TABLE or LOCK TABLES ... WRITE, from performance_schema or else from
+// MariaDB's METADATA_LOCK_INFO plugin, which only shows granted locks
+var metadataLockQueries = []string{`
+	SELECT t.PROCESSLIST_ID, m.LOCK_TYPE, m.LOCK_STATUS, COALESCE(t.PROCESSLIST_INFO, ''), t.PROCESSLIST_TIME
+	FROM performance_schema.metadata_locks m
+	JOIN performance_schema.threads t ON t.THREAD_ID = m.OWNER_THREAD_ID
+	WHERE m.OBJECT_TYPE = 'TABLE' AND m.OBJECT_SCHEMA = ? AND m.OBJECT_NAME = ?
+		AND m.LOCK_TYPE IN ('EXCLUSIVE', 'SHARED_NO_READ_WRITE')
+		AND t.PROCESSLIST_ID <> CONNECTION_ID() AND t.PROCESSLIST_TIME >= ?
+	ORDER BY t.PROCESSLIST_TIME DESC
+	LIMIT 1`, `
+	SELECT m.THREAD_ID, m.LOCK_MODE, 'GRANTED', COALESCE(p.INFO, ''), p.TIME
+	FROM information_schema.METADATA_LOCK_INFO m
+	JOIN information_schema.PROCESSLIST p ON p.ID = m.THREAD_ID
+	WHERE m.TABLE_SCHEMA = ? AND m.TABLE_NAME = ?
+		AND m.LOCK_MODE IN ('MDL_EXCLUSIVE', 'MDL_SHARED_NO_READ_WRITE')
+		AND p.ID <> CONNECTION_ID() AND p.TIME >= ?
+	ORDER BY p.TIME DESC
+	LIMIT 1`,
+}
+
+var (
+	// metadataLockQuery is the metadataLockQueries entry the server
+	// supports, "" when metadata locks cannot be seen
+	metadataLockQuery string
+
+	// metadataLockSkipped lists the tables skipped by the current command
+	// for --skip-metadata-locked
+	metadataLockSkipped []string
+)
+
+// metadataLock is a connection's lock on a table that blocks reading it
+type metadataLock struct {
+	connection int64
+	mode       string
+	status     string
+	info       string
+	seconds    int64
+}
+
+func (l metadataLock) String() string {
+	held := "held"
+	if l.status == "PENDING" {
+		held = "awaited"
+	}
+	s := fmt.Sprintf("%s metadata lock %s by connection %d for %ds", strings.TrimPrefix(l.mode, "MDL_"), held, l.connection, l.seconds)
+	if info := strings.Join(strings.Fields(l.info), " "); info != "" {
+		if len(info) > 60 {
+			info = info[:57] + "..."
+		}
+		s += " (" + info + ")"
+	}
+	return s
+}
+
+// detectMetadataLockSource sets metadataLockQuery to the first query that
+// runs on the server, warning when there is none. performance_schema only
+// counts when it is enabled.
+func detectMetadataLockSource(ctx context.Context, db *sql.DB) {
+	metadataLockQuery = ""
+	var enabled bool
+	if err := queryRowWithRetry(ctx, db, dataMaxRetries, "SELECT @@performance_schema", nil, &enabled); err != nil {
+		enabled = false
+	}
+	for i, query := range metadataLockQueries {
+		if i == 0 && !enabled {
+			continue
+		}
+		if _, err := findMetadataLock(ctx, db, query, "", "", 0); err == nil {
+			metadataLockQuery = query
+			return
+		}
+	}
+	fmt.Printf("⚠️  Warning: --skip-metadata-locked needs performance_schema or the METADATA_LOCK_INFO plugin; metadata locks are not checked\n")
+}
+
+// blockingMetadataLock returns the lock that has blocked reading dbName.table
+// for at least minSeconds, or nil when there is none or it cannot be seen
+func blockingMetadataLock(ctx context.Context, db *sql.DB, dbName, table string, minSeconds int) (*metadataLock, error) {
+	if metadataLockQuery == "" {
+		return nil, nil
+	}
+	return findMetadataLock(ctx, db, metadataLockQuery, dbName, table, minSeconds)
+}
+
+func findMetadataLock(ctx context.Context, db *sql.DB, query, dbName, table string, minSeconds int) (*metadataLock, error) {
+	var l metadataLock
+	err := queryRowWithRetry(ctx, db, dataMaxRetries, query, []interface{}{dbName, table, minSeconds},
+		&l.connection, &l.mode, &l.status, &l.info, &l.seconds)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to check metadata locks: %w", err)
+	}
+	return &l, nil
+}
+
+// printMetadataLockSkipped lists the tables skipped for metadata locks in a
+// summary
+func printMetadataLockSkipped(indent string) {
+	if len(metadataLockSkipped) == 0 {
+		fmt.Printf("%sSkipped (metadata locked): 0\n", indent)
+		return
+	}
+	fmt.Printf("%sSkipped (metadata locked): %d (%s)\n", indent, len(metadataLockSkipped), strings.Join(metadataLockSkipped, ", "))
+}
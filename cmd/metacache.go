@@ -0,0 +1,113 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"mariadb-extractor/internal/metacache"
+)
+
+// metaCache caches the information_schema lookups of the running command,
+// and across the steps of a pipeline. --metadata-cache replaces it with one
+// loaded from a file.
+var metaCache = metacache.New("")
+
+// Kinds of cached metadata lookups
+const (
+	metaTables      = "tables"
+	metaTableTypes  = "table-types"
+	metaForeignKeys = "foreign-keys"
+	metaPrimaryKey  = "primary-key"
+	metaColumns     = "columns"
+	metaDateColumns = "date-columns"
+	metaColumnTypes = "column-types"
+)
+
+// cachedRows returns the rows of a metadata query as text, from metaCache
+// when it holds key and else from the server, caching them
+func cachedRows(ctx context.Context, db *sql.DB, maxRetries int, key metacache.Key, query string, args ...interface{}) ([][]string, error) {
+	if rows, ok := metaCache.Get(key); ok {
+		return rows, nil
+	}
+
+	rows, err := queryWithRetry(ctx, db, maxRetries, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result [][]string
+	values := make([]sql.NullString, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = v.String
+		}
+		result = append(result, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	metaCache.Put(key, result)
+	return result, nil
+}
+
+// openMetadataCache loads the --metadata-cache file for source and drops the
+// entries of the --refresh-metadata databases, or all of them for "all"
+func openMetadataCache(path, source string, refresh []string) error {
+	if path == "" {
+		if len(refresh) > 0 {
+			metaCache.Invalidate(refreshDatabases(refresh)...)
+		}
+		return nil
+	}
+	cache, err := metacache.Load(path, source)
+	if err != nil {
+		return err
+	}
+	metaCache = cache
+	if len(refresh) > 0 {
+		metaCache.Invalidate(refreshDatabases(refresh)...)
+		fmt.Printf("🗂️  Metadata cache %s, refreshing %v\n", path, refresh)
+	} else {
+		fmt.Printf("🗂️  Metadata cache %s\n", path)
+	}
+	return nil
+}
+
+func refreshDatabases(refresh []string) []string {
+	for _, name := range refresh {
+		if name == "all" {
+			return nil
+		}
+	}
+	return refresh
+}
+
+// saveMetadataCache writes metaCache back to the --metadata-cache file
+func saveMetadataCache(path string) {
+	if path == "" {
+		return
+	}
+	if err := metaCache.Save(path); err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+		return
+	}
+	hits, misses := metaCache.Stats()
+	fmt.Printf("🗂️  Metadata cache: %d lookups cached, %d read from the server\n", hits, misses)
+}
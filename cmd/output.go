@@ -0,0 +1,55 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"mariadb-extractor/internal/sink"
+)
+
+// openOutputSink parses an --output value into its sink, nil for a plain
+// prefix. While a sink streams to stdout, progress messages go to stderr
+// instead; the returned function restores stdout.
+func openOutputSink(output string) (sink.Sink, func(), error) {
+	s, _, err := sink.Parse(output)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !sink.IsStream(s) {
+		return s, func() {}, nil
+	}
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	return s, func() { os.Stdout = stdout }, nil
+}
+
+// publishOutputs sends finished files to s under their path relative to
+// baseDir, the primary file first. Stream sinks only take the primary file.
+// A nil sink publishes nothing.
+func publishOutputs(ctx context.Context, s sink.Sink, baseDir string, files ...string) error {
+	if s == nil {
+		return nil
+	}
+	if sink.IsStream(s) {
+		files = files[:1]
+	}
+
+	for _, file := range files {
+		rel, err := filepath.Rel(baseDir, file)
+		if err != nil {
+			return err
+		}
+		if err := sink.PutFile(ctx, s, filepath.ToSlash(rel), file); err != nil {
+			return err
+		}
+		if !sink.IsStream(s) {
+			fmt.Printf("📤 Uploaded %s to %s\n", filepath.ToSlash(rel), s)
+		}
+	}
+	return nil
+}
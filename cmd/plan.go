@@ -0,0 +1,48 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Export data extraction plans for review and approval",
+	Long: `Compute what the data command would extract and save it as a plan file:
+the tables in extraction order with their sampling, WHERE clauses, foreign
+keys and estimated sizes, and the options used. Once the plan is reviewed,
+edited or approved, data --plan runs exactly those tables, and --plan-sha256
+refuses a file that was changed since.`,
+}
+
+var planExportCmd = &cobra.Command{
+	Use:   "export <plan.json|plan.yaml>",
+	Short: "Write the data extraction plan for the given options to a file",
+	Long: `Plan a data extraction with the data command's options and write the plan
+to a file without extracting. Files ending in .json are written as JSON,
+others as YAML. Run the plan with: data --plan <file>.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dataFlags = cmd.Flags()
+		dataPlanOut = args[0]
+		runDataExtraction(cmd.Context())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.AddCommand(planExportCmd)
+
+	// plan export takes the options of the data command it plans for
+	dataCmd.Flags().VisitAll(func(f *pflag.Flag) {
+		switch f.Name {
+		case "plan", "plan-out", "plan-sha256":
+			return
+		}
+		planExportCmd.Flags().AddFlag(f)
+	})
+}
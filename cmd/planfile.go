@@ -0,0 +1,360 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/yaml"
+
+	"github.com/spf13/pflag"
+)
+
+// planFile is an extraction plan saved by --plan-out or plan export and
+// replayed by --plan: the options it was made with and the tables in
+// extraction order. Plans are YAML, or JSON when the file name ends in .json.
+type planFile struct {
+	Source    string `json:"source"`
+	CreatedAt string `json:"created_at"`
+	// EstimatedRows and EstimatedBytes add up the tables' estimates
+	EstimatedRows  int64                  `json:"estimated_rows"`
+	EstimatedBytes int64                  `json:"estimated_bytes"`
+	Options        map[string]interface{} `json:"options"`
+	Tables         []planTable            `json:"tables"`
+}
+
+// planTable is a planned table. A negative SampleSize is a percentage of the
+// rows at extraction time. The estimates come from information_schema's
+// approximate row count and data size when the plan was made; they are upper
+// bounds for tables with a WHERE clause.
+type planTable struct {
+	Database       string           `json:"database"`
+	Table          string           `json:"table"`
+	Order          int              `json:"order"`
+	RowCount       int64            `json:"row_count"`
+	SampleSize     int64            `json:"sample_size"`
+	EstimatedRows  int64            `json:"estimated_rows"`
+	EstimatedBytes int64            `json:"estimated_bytes"`
+	Where          string           `json:"where,omitempty"`
+	IncludedChild  bool             `json:"included_child,omitempty"`
+	Dependencies   []string         `json:"dependencies,omitempty"`
+	ForeignKeys    []planForeignKey `json:"foreign_keys,omitempty"`
+	DeferredKeys   []planForeignKey `json:"deferred_keys,omitempty"`
+}
+
+type planForeignKey struct {
+	Constraint string `json:"constraint"`
+	Table      string `json:"table"`
+	Column     string `json:"column"`
+	RefTable   string `json:"ref_table"`
+	RefColumn  string `json:"ref_column"`
+	Inferred   bool   `json:"inferred,omitempty"`
+}
+
+// planFileSkippedFlags are not recorded in a plan: the connection, which a
+// replay may point elsewhere, and the plan flags themselves
+var planFileSkippedFlags = append([]string{"plan", "plan-out", "plan-sha256", "resume"}, connectionFlags...)
+
+var (
+	// dataPlan is the plan loaded by --plan, nil when the plan is computed
+	dataPlan *planFile
+
+	// dataFlags are the data command's flags, recorded in and set from plans
+	dataFlags *pflag.FlagSet
+)
+
+// loadDataPlan reads --plan and sets the options it records on flags, except
+// those given explicitly, which take precedence
+func loadDataPlan(flags *pflag.FlagSet) error {
+	dataPlan = nil
+	if dataPlanFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(dataPlanFile)
+	if err != nil {
+		return fmt.Errorf("failed to read plan: %w", err)
+	}
+	if dataPlanChecksum != "" {
+		sum, err := checksum.File(dataPlanFile)
+		if err != nil {
+			return fmt.Errorf("failed to read plan: %w", err)
+		}
+		if !strings.EqualFold(sum, dataPlanChecksum) {
+			return fmt.Errorf("plan %s was changed after approval: its SHA-256 is %s, not %s", dataPlanFile, sum, dataPlanChecksum)
+		}
+	}
+	var plan planFile
+	if jsonPlan(dataPlanFile) {
+		err = json.Unmarshal(data, &plan)
+	} else {
+		err = yaml.Unmarshal(data, &plan)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid plan %s: %w", dataPlanFile, err)
+	}
+	if len(plan.Tables) == 0 {
+		return fmt.Errorf("plan %s has no tables", dataPlanFile)
+	}
+
+	for name, value := range plan.Options {
+		f := flags.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("plan %s has unknown option %q", dataPlanFile, name)
+		}
+		if f.Changed {
+			continue
+		}
+		values := []interface{}{value}
+		if list, ok := value.([]interface{}); ok {
+			values = list
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			items := make([]string, len(values))
+			for i, v := range values {
+				items[i] = fmt.Sprint(v)
+			}
+			err = sv.Replace(items)
+		} else {
+			err = f.Value.Set(fmt.Sprint(value))
+		}
+		if err != nil {
+			return fmt.Errorf("invalid option %q in plan %s: %w", name, dataPlanFile, err)
+		}
+	}
+	dataPlan = &plan
+	return nil
+}
+
+// planTables returns the extraction plans recorded in a plan file
+func (p *planFile) planTables() []TableExtractionPlan {
+	plans := make([]TableExtractionPlan, len(p.Tables))
+	for i, t := range p.Tables {
+		plans[i] = TableExtractionPlan{
+			DatabaseName:  t.Database,
+			TableName:     t.Table,
+			RowCount:      t.RowCount,
+			SampleSize:    t.SampleSize,
+			WhereClause:   t.Where,
+			Dependencies:  t.Dependencies,
+			ForeignKeys:   fromPlanForeignKeys(t.ForeignKeys),
+			IncludedChild: t.IncludedChild,
+			DeferredKeys:  fromPlanForeignKeys(t.DeferredKeys),
+			Order:         t.Order,
+		}
+	}
+	return plans
+}
+
+func fromPlanForeignKeys(keys []planForeignKey) []ForeignKeyInfo {
+	var fks []ForeignKeyInfo
+	for _, k := range keys {
+		fks = append(fks, ForeignKeyInfo{
+			ConstraintName: k.Constraint,
+			TableName:      k.Table,
+			ColumnName:     k.Column,
+			RefTableName:   k.RefTable,
+			RefColumnName:  k.RefColumn,
+			Inferred:       k.Inferred,
+		})
+	}
+	return fks
+}
+
+// jsonPlan reports whether a plan file is JSON rather than YAML
+func jsonPlan(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// newPlanFile records plans, with their estimated sizes, and the options
+// given to flags
+func newPlanFile(ctx context.Context, db *sql.DB, source string, flags *pflag.FlagSet, plans []TableExtractionPlan) (*planFile, error) {
+	file := &planFile{
+		Source:    source,
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Options:   make(map[string]interface{}),
+	}
+	flags.Visit(func(f *pflag.Flag) {
+		for _, skipped := range planFileSkippedFlags {
+			if f.Name == skipped {
+				return
+			}
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			file.Options[f.Name] = sv.GetSlice()
+		} else {
+			file.Options[f.Name] = f.Value.String()
+		}
+	})
+
+	for _, plan := range plans {
+		rows, bytes, err := estimateTable(ctx, db, plan)
+		if err != nil {
+			return nil, err
+		}
+		file.EstimatedRows += rows
+		file.EstimatedBytes += bytes
+		file.Tables = append(file.Tables, planTable{
+			Database:       plan.DatabaseName,
+			Table:          plan.TableName,
+			Order:          plan.Order,
+			RowCount:       plan.RowCount,
+			SampleSize:     plan.SampleSize,
+			EstimatedRows:  rows,
+			EstimatedBytes: bytes,
+			Where:          plan.WhereClause,
+			IncludedChild:  plan.IncludedChild,
+			Dependencies:   plan.Dependencies,
+			ForeignKeys:    toPlanForeignKeys(plan.ForeignKeys),
+			DeferredKeys:   toPlanForeignKeys(plan.DeferredKeys),
+		})
+	}
+	return file, nil
+}
+
+// estimateTable returns the rows and bytes a planned table is expected to
+// extract, from information_schema's approximate row count and data size
+func estimateTable(ctx context.Context, db *sql.DB, plan TableExtractionPlan) (int64, int64, error) {
+	ctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+
+	var tableRows, dataLength int64
+	if err := queryRowWithRetry(ctx, db, dataMaxRetries,
+		"SELECT COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+		[]interface{}{plan.DatabaseName, plan.TableName}, &tableRows, &dataLength); err != nil {
+		return 0, 0, fmt.Errorf("failed to estimate the size of %s.%s: %w", plan.DatabaseName, plan.TableName, err)
+	}
+
+	rows := tableRows
+	if plan.SampleSize < 0 {
+		rows = dataSampleCaps.apply(plan.DatabaseName, plan.TableName, tableRows, tableRows*-plan.SampleSize/100)
+	} else if plan.SampleSize > 0 && plan.SampleSize < tableRows {
+		rows = plan.SampleSize
+	}
+	if tableRows == 0 {
+		return rows, 0, nil
+	}
+	return rows, int64(float64(dataLength) * float64(rows) / float64(tableRows)), nil
+}
+
+func toPlanForeignKeys(fks []ForeignKeyInfo) []planForeignKey {
+	var keys []planForeignKey
+	for _, fk := range fks {
+		keys = append(keys, planForeignKey{
+			Constraint: fk.ConstraintName,
+			Table:      fk.TableName,
+			Column:     fk.ColumnName,
+			RefTable:   fk.RefTableName,
+			RefColumn:  fk.RefColumnName,
+			Inferred:   fk.Inferred,
+		})
+	}
+	return keys
+}
+
+// writePlanFile saves a plan as JSON or YAML, by the extension of path
+func writePlanFile(path string, plan *planFile) error {
+	var data []byte
+	if jsonPlan(path) {
+		encoded, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		data = append(encoded, '\n')
+	} else {
+		data = []byte(planYAML(path, plan))
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+	return nil
+}
+
+// planYAML writes a plan as commented YAML
+func planYAML(path string, plan *planFile) string {
+	var b strings.Builder
+	b.WriteString("# Extraction plan written by mariadb-extractor\n")
+	b.WriteString("# Review or edit it, then run it with: mariadb-extractor data --plan " + path + "\n")
+	fmt.Fprintf(&b, "source: %s\n", strconv.Quote(plan.Source))
+	fmt.Fprintf(&b, "created_at: %s\n", strconv.Quote(plan.CreatedAt))
+	fmt.Fprintf(&b, "estimated_rows: %d\n", plan.EstimatedRows)
+	fmt.Fprintf(&b, "estimated_bytes: %d\n", plan.EstimatedBytes)
+
+	names := make([]string, 0, len(plan.Options))
+	for name := range plan.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		b.WriteString("options: {}\n")
+	} else {
+		b.WriteString("options:\n")
+	}
+	for _, name := range names {
+		switch value := plan.Options[name].(type) {
+		case []string:
+			fmt.Fprintf(&b, "  %s: %s\n", name, quoteList(value))
+		default:
+			fmt.Fprintf(&b, "  %s: %s\n", name, strconv.Quote(fmt.Sprint(value)))
+		}
+	}
+
+	b.WriteString("tables:\n")
+	for _, t := range plan.Tables {
+		fmt.Fprintf(&b, "  - database: %s\n", strconv.Quote(t.Database))
+		fmt.Fprintf(&b, "    table: %s\n", strconv.Quote(t.Table))
+		fmt.Fprintf(&b, "    order: %d\n", t.Order)
+		fmt.Fprintf(&b, "    row_count: %d\n", t.RowCount)
+		fmt.Fprintf(&b, "    sample_size: %d\n", t.SampleSize)
+		fmt.Fprintf(&b, "    estimated_rows: %d\n", t.EstimatedRows)
+		fmt.Fprintf(&b, "    estimated_bytes: %d\n", t.EstimatedBytes)
+		if t.Where != "" {
+			fmt.Fprintf(&b, "    where: %s\n", strconv.Quote(t.Where))
+		}
+		if t.IncludedChild {
+			b.WriteString("    included_child: true\n")
+		}
+		if len(t.Dependencies) > 0 {
+			fmt.Fprintf(&b, "    dependencies: %s\n", quoteList(t.Dependencies))
+		}
+		writePlanForeignKeys(&b, "foreign_keys", t.ForeignKeys)
+		writePlanForeignKeys(&b, "deferred_keys", t.DeferredKeys)
+	}
+	return b.String()
+}
+
+func writePlanForeignKeys(b *strings.Builder, name string, fks []planForeignKey) {
+	if len(fks) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "    %s:\n", name)
+	for _, fk := range fks {
+		fmt.Fprintf(b, "      - constraint: %s\n", strconv.Quote(fk.Constraint))
+		fmt.Fprintf(b, "        table: %s\n", strconv.Quote(fk.Table))
+		fmt.Fprintf(b, "        column: %s\n", strconv.Quote(fk.Column))
+		fmt.Fprintf(b, "        ref_table: %s\n", strconv.Quote(fk.RefTable))
+		fmt.Fprintf(b, "        ref_column: %s\n", strconv.Quote(fk.RefColumn))
+		if fk.Inferred {
+			b.WriteString("        inferred: true\n")
+		}
+	}
+}
+
+// quoteList writes a YAML flow sequence of quoted strings
+func quoteList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = strconv.Quote(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,33 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// poolOptions holds the connection pool tuning flags of a command
+type poolOptions struct {
+	maxOpenConns    int
+	maxIdleConns    int
+	connMaxLifetime int // seconds
+}
+
+// addPoolFlags registers --max-open-conns, --max-idle-conns and
+// --conn-max-lifetime on cmd with environment variable defaults
+func addPoolFlags(cmd *cobra.Command, opts *poolOptions, defaultOpen, defaultIdle, defaultLifetime int) {
+	cmd.Flags().IntVar(&opts.maxOpenConns, "max-open-conns", getEnvIntWithDefault("MARIADB_MAX_OPEN_CONNS", defaultOpen), "Maximum open connections to the server, 0=unlimited (env: MARIADB_MAX_OPEN_CONNS)")
+	cmd.Flags().IntVar(&opts.maxIdleConns, "max-idle-conns", getEnvIntWithDefault("MARIADB_MAX_IDLE_CONNS", defaultIdle), "Maximum idle connections kept in the pool (env: MARIADB_MAX_IDLE_CONNS)")
+	cmd.Flags().IntVar(&opts.connMaxLifetime, "conn-max-lifetime", getEnvIntWithDefault("MARIADB_CONN_MAX_LIFETIME", defaultLifetime), "Maximum connection lifetime in seconds, 0=unlimited (env: MARIADB_CONN_MAX_LIFETIME)")
+}
+
+// apply configures the pool of db
+func (o poolOptions) apply(db *sql.DB) {
+	db.SetMaxOpenConns(o.maxOpenConns)
+	db.SetMaxIdleConns(o.maxIdleConns)
+	db.SetConnMaxLifetime(time.Duration(o.connMaxLifetime) * time.Second)
+}
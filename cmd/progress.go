@@ -0,0 +1,175 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dataProgressEvents writes the --progress-format json events of the
+// running extraction, nil for text progress
+var dataProgressEvents *progressEvents
+
+// progressEvent is one JSON line of --progress-format json. Fields that do
+// not apply to an event are left out.
+type progressEvent struct {
+	Event      string    `json:"event"`
+	Time       time.Time `json:"time"`
+	RunID      string    `json:"run_id,omitempty"`
+	Table      string    `json:"table,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Index      int       `json:"index,omitempty"`
+	Tables     int       `json:"tables,omitempty"`
+	TablesDone *int      `json:"tables_done,omitempty"`
+	Succeeded  *int      `json:"succeeded,omitempty"`
+	Failed     *int      `json:"failed,omitempty"`
+	Rows       *int64    `json:"rows,omitempty"`
+	RowsTotal  *int64    `json:"rows_total,omitempty"`
+	Bytes      *int64    `json:"bytes,omitempty"`
+	RowsPerSec *float64  `json:"rows_per_sec,omitempty"`
+	Elapsed    *float64  `json:"elapsed_seconds,omitempty"`
+	ETA        *float64  `json:"eta_seconds,omitempty"`
+}
+
+// progressEvents emits the progress of a data extraction as JSON lines:
+// run_start, table_start, progress every --progress-interval rows of a
+// table, table_done and run_done. Methods are safe on nil and for
+// concurrent use, as segments of a table are read in parallel.
+type progressEvents struct {
+	// rows counts the rows read of the current table, bytes the bytes
+	// written to the output files
+	rows  atomic.Int64
+	bytes atomic.Int64
+
+	mu         sync.Mutex
+	enc        *json.Encoder
+	runID      string
+	tables     int
+	start      time.Time
+	table      string
+	tableTotal int64
+	tableStart time.Time
+	totalRows  int64
+}
+
+func newProgressEvents(w io.Writer) *progressEvents {
+	return &progressEvents{enc: json.NewEncoder(w)}
+}
+
+// begin emits run_start
+func (p *progressEvents) begin(runID string, tables int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.runID, p.tables, p.start = runID, tables, time.Now()
+	p.emit(progressEvent{Event: "run_start", RunID: runID, Tables: tables})
+}
+
+// startTable emits table_start for the index-th table (from 1), which is
+// expected to yield total rows
+func (p *progressEvents) startTable(table string, index int, total int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.table, p.tableTotal, p.tableStart = table, total, time.Now()
+	p.rows.Store(0)
+	p.emit(progressEvent{Event: "table_start", Table: table, Index: index, Tables: p.tables, RowsTotal: &total})
+}
+
+// row counts a row read and emits a progress event every
+// --progress-interval rows
+func (p *progressEvents) row() {
+	if p == nil {
+		return
+	}
+	rows := p.rows.Add(1)
+	if dataProgressInterval <= 0 || rows%int64(dataProgressInterval) != 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	bytes := p.bytes.Load()
+	event := progressEvent{Event: "progress", Table: p.table, Rows: &rows, Bytes: &bytes}
+	if p.tableTotal > 0 {
+		event.RowsTotal = &p.tableTotal
+	}
+	if elapsed := time.Since(p.tableStart).Seconds(); elapsed > 0 {
+		rate := roundMillis(float64(rows) / elapsed)
+		event.RowsPerSec = &rate
+		if p.tableTotal > rows && rate > 0 {
+			eta := roundMillis(float64(p.tableTotal-rows) / rate)
+			event.ETA = &eta
+		}
+	}
+	p.emit(event)
+}
+
+// wrote counts bytes written to an output file
+func (p *progressEvents) wrote(n int) {
+	if p != nil {
+		p.bytes.Add(int64(n))
+	}
+}
+
+// endTable emits table_done with the table's status: completed, failed,
+// stopped or skipped. done is the number of tables finished so far, and
+// the ETA of the run assumes the remaining tables take as long as those.
+func (p *progressEvents) endTable(table, status string, rows int64, err error, done int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.totalRows += rows
+	bytes := p.bytes.Load()
+	event := progressEvent{Event: "table_done", Table: table, Status: status, Rows: &rows, Bytes: &bytes, TablesDone: &done, Tables: p.tables}
+	if err != nil {
+		event.Error = err.Error()
+	}
+	if status != "skipped" {
+		elapsed := roundMillis(time.Since(p.tableStart).Seconds())
+		event.Elapsed = &elapsed
+	}
+	if done > 0 {
+		eta := roundMillis(time.Since(p.start).Seconds() / float64(done) * float64(p.tables-done))
+		event.ETA = &eta
+	}
+	p.emit(event)
+}
+
+// finish emits run_done with the run's status: completed, failed or
+// interrupted
+func (p *progressEvents) finish(status string, succeeded, failed int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rows, bytes := p.totalRows, p.bytes.Load()
+	elapsed := roundMillis(time.Since(p.start).Seconds())
+	p.emit(progressEvent{Event: "run_done", RunID: p.runID, Status: status, Tables: p.tables,
+		Succeeded: &succeeded, Failed: &failed, Rows: &rows, Bytes: &bytes, Elapsed: &elapsed})
+}
+
+func (p *progressEvents) emit(event progressEvent) {
+	event.Time = time.Now().UTC()
+	// Progress is best effort and never fails the extraction
+	p.enc.Encode(event)
+}
+
+// roundMillis cuts a duration or rate to milliseconds of precision
+func roundMillis(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
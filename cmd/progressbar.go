@@ -0,0 +1,164 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dataProgressBar shows the progress of the table being extracted on stdout,
+// nil outside of data extractions
+var dataProgressBar *progressBar
+
+const (
+	// progressBarWidth is the width of the bar, in characters
+	progressBarWidth = 30
+	// progressBarRedraw is how often a terminal bar is redrawn at most, and
+	// progressLogInterval how often a line is logged when stdout is not a
+	// terminal
+	progressBarRedraw   = 100 * time.Millisecond
+	progressLogInterval = 10 * time.Second
+)
+
+// progressBar shows the rows read of a table with its row and byte rates and
+// ETA. On a terminal it is one bar below the table's line, redrawn in place;
+// otherwise a progress line is logged every progressLogInterval, so
+// redirected output stays readable. Tables read before the first update
+// keep their single line. Methods are safe on nil and for concurrent use, as
+// segments of a table are read in parallel.
+type progressBar struct {
+	rows  atomic.Int64
+	bytes atomic.Int64
+
+	mu    sync.Mutex
+	w     io.Writer
+	tty   bool
+	total int64
+	start time.Time
+	last  time.Time
+	// shown is set once the table's line was ended to show progress
+	shown bool
+}
+
+func newProgressBar(f *os.File) *progressBar {
+	return &progressBar{w: f, tty: isTerminal(f)}
+}
+
+// isTerminal reports whether f is a terminal that handles cursor controls
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("TERM") != "dumb"
+}
+
+// startTable starts the progress of a table expected to yield total rows,
+// 0 when unknown
+func (b *progressBar) startTable(total int64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.total, b.shown = total, false
+	b.start = time.Now()
+	b.last = b.start
+	b.rows.Store(0)
+	b.bytes.Store(0)
+}
+
+// row counts a row read, and updates the progress every --progress-interval
+// rows when it is due
+func (b *progressBar) row() {
+	if b == nil {
+		return
+	}
+	rows := b.rows.Add(1)
+	if dataProgressInterval <= 0 || rows%int64(dataProgressInterval) != 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	interval := progressLogInterval
+	if b.tty {
+		interval = progressBarRedraw
+	}
+	if now.Sub(b.last) < interval {
+		return
+	}
+	b.last = now
+
+	if !b.shown {
+		fmt.Fprint(b.w, "\n")
+		b.shown = true
+	}
+	if b.tty {
+		fmt.Fprintf(b.w, "\r  %s\x1b[K", b.status(rows, now, true))
+	} else {
+		fmt.Fprintf(b.w, "  %s\n", b.status(rows, now, false))
+	}
+}
+
+// wrote counts bytes written to an output file
+func (b *progressBar) wrote(n int) {
+	if b != nil {
+		b.bytes.Add(int64(n))
+	}
+}
+
+// end clears the bar of a table that showed progress and starts the line
+// its outcome is printed on with the table's totals
+func (b *progressBar) end() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.shown {
+		return
+	}
+	if b.tty {
+		fmt.Fprint(b.w, "\r\x1b[K")
+	}
+	rows := b.rows.Load()
+	fmt.Fprintf(b.w, "  %d rows%s", rows, b.rates(rows, time.Now()))
+}
+
+// status formats the progress after rows, with a bar on terminals
+func (b *progressBar) status(rows int64, now time.Time, bar bool) string {
+	var s strings.Builder
+	if b.total > 0 {
+		done := min(float64(rows)/float64(b.total), 1)
+		if bar {
+			filled := int(done * progressBarWidth)
+			fmt.Fprintf(&s, "[%s%s] ", strings.Repeat("█", filled), strings.Repeat("░", progressBarWidth-filled))
+		}
+		fmt.Fprintf(&s, "%3.0f%% %d/%d rows", done*100, rows, b.total)
+	} else {
+		fmt.Fprintf(&s, "%d rows", rows)
+	}
+	s.WriteString(b.rates(rows, now))
+
+	elapsed := now.Sub(b.start).Seconds()
+	if b.total > rows && elapsed > 0 && rows > 0 {
+		eta := time.Duration(float64(b.total-rows) / (float64(rows) / elapsed) * float64(time.Second))
+		fmt.Fprintf(&s, ", ETA %v", eta.Round(time.Second))
+	}
+	return s.String()
+}
+
+// rates formats the row and byte rates of the table so far
+func (b *progressBar) rates(rows int64, now time.Time) string {
+	elapsed := now.Sub(b.start).Seconds()
+	if elapsed <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %.0f rows/s, %s/s", float64(rows)/elapsed, formatBytes(int64(float64(b.bytes.Load())/elapsed)))
+}
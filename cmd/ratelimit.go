@@ -0,0 +1,98 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket over bytes, or rows, read from the server.
+// Reads may overdraw the bucket; the reader then sleeps until the debt is paid back, so
+// the average rate stays at the limit while bursts are bounded to a second.
+// It is shared by concurrent readers.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes or rows per second
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter for perSecond bytes or rows, or nil when
+// it is not positive. A nil limiter never waits.
+func newRateLimiter(perSecond int64) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{rate: float64(perSecond), tokens: float64(perSecond), last: time.Now()}
+}
+
+// parseRate parses a bandwidth such as 50MB/s, 512KB or 1G; an empty value
+// means unlimited
+func parseRate(value string) (int64, error) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return 0, nil
+	}
+	trimmed = strings.TrimSuffix(strings.TrimSuffix(trimmed, "/s"), "/S")
+	rate, err := parseByteSize(trimmed)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: use e.g. 50MB/s", value)
+	}
+	return rate, nil
+}
+
+// wait takes n bytes or rows from the bucket and sleeps while it is in debt
+func (l *rateLimiter) wait(ctx context.Context, n int64) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	debt := -l.tokens
+	l.mu.Unlock()
+
+	// Sleeping for tiny debts costs more than it saves
+	if debt <= l.rate/100 {
+		return nil
+	}
+	timer := time.NewTimer(time.Duration(debt / l.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// rowBytes estimates the bytes a scanned row took on the wire
+func rowBytes(values []interface{}) int64 {
+	var n int64
+	for _, v := range values {
+		switch val := v.(type) {
+		case nil:
+			n++
+		case []byte:
+			n += int64(len(val))
+		case string:
+			n += int64(len(val))
+		case time.Time:
+			n += int64(len("2006-01-02 15:04:05"))
+		default:
+			n += 8
+		}
+	}
+	return n
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"mariadb-extractor/internal/yaml"
+)
+
+// relationshipFile declares parent/child relationships the server does not
+// know, for schemas without foreign key constraints
+type relationshipFile struct {
+	Relationships []relationshipRule `json:"relationships"`
+}
+
+// relationshipRule is a relationship of Table's Columns to the
+// ReferencedColumns of ReferencedTable, like a foreign key. Tables are
+// db.table or table; a bare table applies in every extracted database that
+// has it. Both tables must be in the same database.
+type relationshipRule struct {
+	Table             string   `json:"table"`
+	Columns           []string `json:"columns"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+}
+
+// loadRelationships reads and validates a --relationships file
+func loadRelationships(file string) (*relationshipFile, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read relationships: %w", err)
+	}
+
+	var rels relationshipFile
+	if err := yaml.Unmarshal(data, &rels); err != nil {
+		return nil, fmt.Errorf("invalid relationships file %s: %w", file, err)
+	}
+
+	for i, rule := range rels.Relationships {
+		if rule.Table == "" || rule.ReferencedTable == "" {
+			return nil, fmt.Errorf("invalid relationships file %s: relationship %d needs table and referenced_table", file, i+1)
+		}
+		if len(rule.Columns) == 0 || len(rule.Columns) != len(rule.ReferencedColumns) {
+			return nil, fmt.Errorf("invalid relationships file %s: relationship %d needs as many columns as referenced_columns", file, i+1)
+		}
+		childDB, _, childQualified := strings.Cut(rule.Table, ".")
+		parentDB, _, parentQualified := strings.Cut(rule.ReferencedTable, ".")
+		if parentQualified && (!childQualified || parentDB != childDB) {
+			return nil, fmt.Errorf("invalid relationships file %s: relationship %d references %s in another database", file, i+1, rule.ReferencedTable)
+		}
+	}
+	return &rels, nil
+}
+
+// forDatabase returns the relationships of database dbName by child table,
+// in the form of the foreign keys read from the server. A nil file has none.
+func (f *relationshipFile) forDatabase(dbName string) map[string][]ForeignKeyInfo {
+	if f == nil {
+		return nil
+	}
+
+	relationships := make(map[string][]ForeignKeyInfo)
+	for i, rule := range f.Relationships {
+		table := rule.Table
+		if db, name, ok := strings.Cut(rule.Table, "."); ok {
+			if db != dbName {
+				continue
+			}
+			table = name
+		}
+		parent := rule.ReferencedTable
+		if _, name, ok := strings.Cut(parent, "."); ok {
+			parent = name
+		}
+
+		for j, col := range rule.Columns {
+			relationships[table] = append(relationships[table], ForeignKeyInfo{
+				ConstraintName: fmt.Sprintf("relationship_%d", i+1),
+				TableName:      table,
+				ColumnName:     col,
+				RefTableName:   parent,
+				RefColumnName:  rule.ReferencedColumns[j],
+			})
+		}
+	}
+	return relationships
+}
+
+// mergeRelationships adds the relationships of extra to foreignKeys, except
+// those on a column that a relationship already there covers
+func mergeRelationships(foreignKeys, extra map[string][]ForeignKeyInfo) map[string][]ForeignKeyInfo {
+	if len(extra) == 0 {
+		return foreignKeys
+	}
+	if foreignKeys == nil {
+		foreignKeys = make(map[string][]ForeignKeyInfo)
+	}
+
+	for table, fks := range extra {
+		covered := make(map[string]bool)
+		for _, fk := range foreignKeys[table] {
+			covered[fk.ColumnName] = true
+		}
+		plan := TableExtractionPlan{TableName: table, ForeignKeys: fks}
+		for _, c := range planConstraints(plan) {
+			skip := false
+			for _, col := range c.columns {
+				skip = skip || covered[col]
+			}
+			if skip {
+				continue
+			}
+			for _, fk := range fks {
+				if fk.ConstraintName == c.name {
+					foreignKeys[table] = append(foreignKeys[table], fk)
+				}
+			}
+		}
+	}
+	return foreignKeys
+}
+
+// warnUnmatched reports relationships whose table is not among the planned
+// tables, which usually means a typo in the file
+func (f *relationshipFile) warnUnmatched(plans []TableExtractionPlan) {
+	if f == nil {
+		return
+	}
+	for _, rule := range f.Relationships {
+		found := false
+		for _, plan := range plans {
+			found = found || rule.Table == plan.TableName || rule.Table == plan.DatabaseName+"."+plan.TableName
+		}
+		if !found {
+			fmt.Printf("⚠️  Warning: relationship table %s is not in the extraction\n", rule.Table)
+		}
+	}
+}
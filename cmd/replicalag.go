@@ -0,0 +1,143 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// replicaLagInterval is how often the replica's lag is checked, and how
+// often a paused extraction rechecks it
+const replicaLagInterval = 5 * time.Second
+
+// replicaLagChecked is when the lag was last checked during the extraction.
+// Segments of a table read concurrently wait together under replicaLagMu.
+var (
+	replicaLagMu      sync.Mutex
+	replicaLagChecked time.Time
+)
+
+// replicaLag is the replication delay of the connected server. known is
+// false while replication is stopped, as the server then reports no delay.
+type replicaLag struct {
+	known bool
+	delay time.Duration
+}
+
+// readReplicaLag reads Seconds_Behind_Master (Seconds_Behind_Source on newer
+// MySQL) of the connected replica; the largest delay counts when the replica
+// has several sources
+func readReplicaLag(ctx context.Context, db *sql.DB) (replicaLag, error) {
+	rows, err := db.QueryContext(ctx, annotateQuery(dataServer.replicaStatusQuery()))
+	if err != nil {
+		return replicaLag{}, fmt.Errorf("failed to read replica status: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return replicaLag{}, fmt.Errorf("failed to read replica status: %w", err)
+	}
+	index := -1
+	for i, col := range columns {
+		if strings.EqualFold(col, "Seconds_Behind_Master") || strings.EqualFold(col, "Seconds_Behind_Source") {
+			index = i
+		}
+	}
+	if index < 0 {
+		return replicaLag{}, fmt.Errorf("replica status has no Seconds_Behind_Master column")
+	}
+
+	values := make([]sql.NullString, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	var lag replicaLag
+	replica := false
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return replicaLag{}, fmt.Errorf("failed to read replica status: %w", err)
+		}
+		replica = true
+		if !values[index].Valid {
+			continue
+		}
+		seconds, err := strconv.ParseInt(values[index].String, 10, 64)
+		if err != nil {
+			continue
+		}
+		lag.known = true
+		if delay := time.Duration(seconds) * time.Second; delay > lag.delay {
+			lag.delay = delay
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return replicaLag{}, fmt.Errorf("failed to read replica status: %w", err)
+	}
+	if !replica {
+		return replicaLag{}, fmt.Errorf("server is not a replica (%s returned no rows)", dataServer.replicaStatusQuery())
+	}
+	return lag, nil
+}
+
+// checkReplicaLag fails unless the server is a replica whose lag can be read
+func checkReplicaLag(ctx context.Context, db *sql.DB, maxLag time.Duration) error {
+	lag, err := readReplicaLag(ctx, db)
+	if err != nil {
+		return fmt.Errorf("--max-replica-lag: %w", err)
+	}
+	replicaLagMu.Lock()
+	replicaLagChecked = time.Now()
+	replicaLagMu.Unlock()
+	if !lag.known {
+		fmt.Printf("⚠️  Warning: replication is not running, so its lag is unknown and the extraction is not paused for it\n")
+		return nil
+	}
+	fmt.Printf("Replica lag: %v (pausing above %v)\n", lag.delay, maxLag)
+	return nil
+}
+
+// waitForReplicaLag blocks while the replica is more than maxLag behind its
+// source. The lag is read at most every replicaLagInterval; while replication
+// is stopped the lag is unknown and the extraction continues.
+func waitForReplicaLag(ctx context.Context, db *sql.DB, maxLag time.Duration) error {
+	replicaLagMu.Lock()
+	defer replicaLagMu.Unlock()
+	if time.Since(replicaLagChecked) < replicaLagInterval {
+		return nil
+	}
+	paused := false
+	for {
+		lag, err := readReplicaLag(ctx, db)
+		if err != nil {
+			return err
+		}
+		replicaLagChecked = time.Now()
+		if !lag.known || lag.delay <= maxLag {
+			if paused && !lag.known {
+				fmt.Printf("\n▶️  Replication stopped, so its lag is unknown; resuming\n")
+			} else if paused {
+				fmt.Printf("\n▶️  Replica lag down to %v, resuming\n", lag.delay)
+			}
+			return nil
+		}
+		if !paused {
+			fmt.Printf("\n⏸️  Replica is %v behind its source (max %v), pausing extraction\n", lag.delay, maxLag)
+			paused = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(replicaLagInterval):
+		}
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"mariadb-extractor/internal/retry"
+)
+
+// newRetryPolicy returns the retry policy shared by all commands, reporting
+// each retry on stdout
+func newRetryPolicy(maxAttempts int) retry.Policy {
+	policy := retry.DefaultPolicy(maxAttempts)
+	policy.OnRetry = func(attempt int, delay time.Duration, err error) {
+		fmt.Printf("⚠️  Query failed (attempt %d/%d), retrying in %v: %v\n",
+			attempt, maxAttempts, delay.Round(time.Millisecond), err)
+	}
+	return policy
+}
+
+// queryWithRetry runs a query, retrying transient failures before any row is read
+func queryWithRetry(ctx context.Context, db *sql.DB, maxAttempts int, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := retry.Do(ctx, newRetryPolicy(maxAttempts), func(ctx context.Context) error {
+		var err error
+		rows, err = db.QueryContext(ctx, annotateQuery(query), args...)
+		return err
+	})
+	return rows, err
+}
+
+// queryRowWithRetry runs a single-row query and scans it into dest, retrying
+// transient failures
+func queryRowWithRetry(ctx context.Context, db *sql.DB, maxAttempts int, query string, args []interface{}, dest ...interface{}) error {
+	return retry.Do(ctx, newRetryPolicy(maxAttempts), func(ctx context.Context) error {
+		return db.QueryRowContext(ctx, annotateQuery(query), args...).Scan(dest...)
+	})
+}
+
+// pingWithRetry verifies the connection, retrying transient failures
+func pingWithRetry(ctx context.Context, db *sql.DB, maxAttempts int) error {
+	return retry.Do(ctx, newRetryPolicy(maxAttempts), func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	})
+}
+
+// queryWithKill runs a long-running query on a dedicated connection. If ctx is
+// cancelled while rows are being read, KILL QUERY is issued from another
+// connection so the server stops working on it too. The returned cleanup
+// function must be called once the rows are no longer needed.
+func queryWithKill(ctx context.Context, db *sql.DB, maxAttempts int, query string, args ...interface{}) (*sql.Rows, func(), error) {
+	var conn *sql.Conn
+	var rows *sql.Rows
+	var connID int64
+
+	err := retry.Do(ctx, newRetryPolicy(maxAttempts), func(ctx context.Context) error {
+		var err error
+		if conn, err = db.Conn(ctx); err != nil {
+			return err
+		}
+		if err = conn.QueryRowContext(ctx, annotateQuery("SELECT CONNECTION_ID()")).Scan(&connID); err != nil {
+			conn.Close()
+			return err
+		}
+		if rows, err = conn.QueryContext(ctx, annotateQuery(query), args...); err != nil {
+			conn.Close()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			killCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := db.ExecContext(killCtx, annotateQuery(fmt.Sprintf("KILL QUERY %d", connID))); err != nil {
+				log.Printf("Warning: failed to kill query on connection %d: %v", connID, err)
+			}
+		case <-done:
+		}
+	}()
+
+	cleanup := func() {
+		close(done)
+		rows.Close()
+		conn.Close()
+	}
+	return rows, cleanup, nil
+}
+
+// withTimeout bounds a single statement by the command's --timeout in seconds
+func withTimeout(ctx context.Context, seconds int) (context.Context, context.CancelFunc) {
+	if seconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+}
@@ -4,7 +4,15 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"mariadb-extractor/internal/config"
 
 	"github.com/spf13/cobra"
 )
@@ -31,13 +39,54 @@ Outputs are saved as .md and .json files for documentation and automation purpos
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
+// The command context is cancelled on SIGINT/SIGTERM so in-flight queries stop.
+// While gracefulStop is set, the first signal only closes interrupted so the
+// command can stop at a safe point, and a second one cancels the context.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		sig := <-signals
+		close(interrupted)
+		if gracefulStop.Load() {
+			fmt.Printf("\n⚠️  Received %v: stopping after the current batch (interrupt again to stop immediately)\n", sig)
+			sig = <-signals
+		}
+		cancel(fmt.Errorf("received %v", sig))
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+var (
+	// interrupted is closed on the first SIGINT or SIGTERM
+	interrupted = make(chan struct{})
+	// gracefulStop is set while the running command stops by itself once
+	// interrupted is closed
+	gracefulStop atomic.Bool
+)
+
+// errStopped is returned by work that stopped at a safe point after an
+// interrupt
+var errStopped = errors.New("stopped on interrupt")
+
+// stopRequested reports whether an interrupt asked the command to stop
+func stopRequested() bool {
+	select {
+	case <-interrupted:
+		return true
+	default:
+		return false
+	}
+}
+
 func init() {
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
@@ -45,6 +94,11 @@ func init() {
 
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.mariadb-extractor.yaml)")
 
+	// Environment files are loaded by internal/config before flags are parsed;
+	// these are registered so they are accepted and listed in help
+	rootCmd.PersistentFlags().String("env-file", config.DefaultEnvFile, "Environment file with connection settings")
+	rootCmd.PersistentFlags().String("profile", "", "Environment profile; loads <env-file>.<profile> on top of the env file (env: MARIADB_PROFILE)")
+
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
@@ -0,0 +1,543 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/pipeline"
+	"mariadb-extractor/internal/sink"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run <pipeline.yaml>",
+	Short: "Run a multi-step extraction pipeline",
+	Long: `Run a pipeline file describing a sequence of steps over one shared
+database connection:
+
+  ddl     - schema extraction (options are ddl flags)
+  data    - data extraction (options are data flags)
+  grants  - roles, SHOW GRANTS and default roles of every account into a SQL file
+  upload  - shell command, e.g. copying output to object storage
+  notify  - POST a JSON summary to a webhook and/or run a shell command
+
+A failing step stops the pipeline unless continue_on_error is set for it or
+for the whole pipeline. A combined manifest listing every step's status and
+artifacts (with SHA-256) is written after each step.
+
+Example:
+  mariadb-extractor run nightly.yaml`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runPipeline(cmd.Context(), args[0])
+	},
+}
+
+var runPool poolOptions
+
+// connectionFlags are taken from the pipeline's connection section and may
+// not be overridden per step, since all steps share one connection
+var connectionFlags = []string{"host", "port", "user", "password", "max-open-conns", "max-idle-conns", "conn-max-lifetime", "galera-nodes"}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	addPoolFlags(runCmd, &runPool, 5, 2, getEnvIntWithDefault("MARIADB_TIMEOUT", 300))
+}
+
+func runPipeline(ctx context.Context, path string) {
+	p, err := pipeline.Load(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conn := p.Connection
+	db, password := connectPipeline(ctx, &conn, runPool)
+	defer db.Close()
+
+	fmt.Printf("🚀 Running pipeline %s (%d steps)\n", p.Name, len(p.Steps))
+
+	manifest := &pipeline.Manifest{
+		Pipeline:  p.Name,
+		Server:    fmt.Sprintf("%s:%d", conn.Host, conn.Port),
+		Status:    pipeline.StatusRunning,
+		StartedAt: time.Now().UTC(),
+	}
+	for _, step := range p.Steps {
+		manifest.Steps = append(manifest.Steps, pipeline.StepResult{Name: step.Name, Type: step.Type, Status: pipeline.StatusSkipped})
+	}
+
+	failed := false
+	for i, step := range p.Steps {
+		if ctx.Err() != nil || stopRequested() {
+			fmt.Printf("\n⚠️  Pipeline interrupted before step %s\n", step.Name)
+			failed = true
+			break
+		}
+
+		fmt.Printf("\n[%d/%d] ▶️  Step %s (%s)\n", i+1, len(p.Steps), step.Name, step.Type)
+		result := &manifest.Steps[i]
+		started := time.Now().UTC()
+		result.StartedAt = &started
+		result.Status = pipeline.StatusRunning
+
+		trashSkipped, planWarnings, tableFailures = nil, nil, nil
+		artifacts, err := runPipelineStep(ctx, db, conn, password, p, step, manifest)
+
+		result.DurationMS = time.Since(started).Milliseconds()
+		result.Artifacts = describeArtifacts(artifacts)
+		result.SkippedDatabases = trashSkipped
+		result.Warnings = planWarnings
+		result.Failures = tableFailures
+		if err != nil {
+			result.Status = pipeline.StatusFailed
+			result.Error = err.Error()
+			fmt.Printf("❌ Step %s failed: %v\n", step.Name, err)
+		} else {
+			result.Status = pipeline.StatusSucceeded
+			fmt.Printf("✅ Step %s completed in %v\n", step.Name, time.Since(started).Round(time.Second))
+		}
+
+		if err := manifest.Write(p.Manifest); err != nil {
+			fmt.Printf("⚠️  Warning: %v\n", err)
+		}
+
+		if err != nil {
+			failed = true
+			if !p.ContinuesOnError(step) {
+				break
+			}
+		}
+	}
+
+	finished := time.Now().UTC()
+	manifest.FinishedAt = &finished
+	manifest.Status = pipeline.StatusSucceeded
+	if failed {
+		manifest.Status = pipeline.StatusFailed
+	}
+	if err := manifest.Write(p.Manifest); err != nil {
+		log.Fatalf("Failed to write pipeline manifest: %v", err)
+	}
+
+	fmt.Printf("\n📋 Manifest: %s\n", p.Manifest)
+	for _, result := range manifest.Steps {
+		fmt.Printf("   %-20s %-10s %s\n", result.Name, result.Status, result.Error)
+	}
+
+	if failed {
+		log.Fatalf("Pipeline %s failed", p.Name)
+	}
+	fmt.Printf("🎉 Pipeline %s completed successfully!\n", p.Name)
+}
+
+// connectPipeline connects with a pipeline file's connection section, filling
+// what it leaves out from the environment, and returns the connection and
+// password. Connection failures are fatal.
+func connectPipeline(ctx context.Context, conn *pipeline.Connection, pool poolOptions) (*sql.DB, string) {
+	if conn.Host == "" {
+		conn.Host = getEnvWithDefault("MARIADB_HOST", "localhost")
+	}
+	if conn.Port == 0 {
+		conn.Port = getEnvIntWithDefault("MARIADB_PORT", 3306)
+	}
+	if conn.User == "" {
+		conn.User = os.Getenv("MARIADB_USER")
+	}
+	if conn.Timeout == 0 {
+		conn.Timeout = getEnvIntWithDefault("MARIADB_TIMEOUT", 300)
+	}
+	password := os.Getenv("MARIADB_PASSWORD")
+	if conn.PasswordEnv != "" {
+		password = os.Getenv(conn.PasswordEnv)
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&parseTime=true&timeout=%ds&readTimeout=%ds&writeTimeout=%ds&maxAllowedPacket=1073741824",
+		conn.User, password, conn.Host, conn.Port, conn.Timeout, conn.Timeout, conn.Timeout)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	pool.apply(db)
+
+	if err := pingWithRetry(ctx, db, getEnvIntWithDefault("MARIADB_MAX_RETRIES", 3)); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+
+	fmt.Printf("Connected to MariaDB at %s:%d\n", conn.Host, conn.Port)
+	return db, password
+}
+
+// runPipelineStep runs one step and returns the files it produced
+func runPipelineStep(ctx context.Context, db *sql.DB, conn pipeline.Connection, password string, p *pipeline.Pipeline, step pipeline.Step, manifest *pipeline.Manifest) ([]string, error) {
+	switch step.Type {
+	case pipeline.StepDDL:
+		if err := applyStepOptions(ddlCmd.Flags(), conn, password, step); err != nil {
+			return nil, err
+		}
+		err := runDDLWithDB(ctx, db)
+		artifacts := []string{filepath.Join("output", sink.Prefix(ddlOutput)+".md")}
+		artifacts = append(artifacts, scriptFiles(filepath.Join("output", "init-scripts", "01-extracted-schema.sql"))...)
+		return artifacts, err
+
+	case pipeline.StepData:
+		if err := applyStepOptions(dataCmd.Flags(), conn, password, step); err != nil {
+			return nil, err
+		}
+		if err := validateDataOptions(); err != nil {
+			return nil, err
+		}
+		if dataTempUser {
+			return nil, fmt.Errorf("option \"temp-user\" is not supported in pipelines, whose steps share one connection")
+		}
+		err := runDataWithDB(ctx, db)
+		artifacts := scriptFiles(filepath.Join("output", sink.Prefix(dataOutput)+".sql"))
+		if len(tableFailures) > 0 {
+			artifacts = append(artifacts, failuresFile())
+		}
+		return artifacts, err
+
+	case pipeline.StepGrants:
+		output := step.Output
+		if output == "" {
+			output = filepath.Join("output", "grants.sql")
+		}
+		return []string{output}, extractGrants(ctx, db, output)
+
+	case pipeline.StepUpload:
+		return nil, runStepCommand(ctx, step.Command, p, manifest)
+
+	case pipeline.StepNotify:
+		if step.Webhook != "" {
+			if err := postNotification(ctx, step.Webhook, manifest); err != nil {
+				return nil, err
+			}
+		}
+		if step.Command != "" {
+			return nil, runStepCommand(ctx, step.Command, p, manifest)
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown step type %q", step.Type)
+}
+
+// applyStepOptions resets a command's flags to their defaults, applies the
+// pipeline connection and then the step's options, keyed by flag name
+func applyStepOptions(flags *pflag.FlagSet, conn pipeline.Connection, password string, step pipeline.Step) error {
+	flags.VisitAll(func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	})
+
+	settings := map[string]string{
+		"host":     conn.Host,
+		"port":     fmt.Sprint(conn.Port),
+		"user":     conn.User,
+		"password": password,
+		"timeout":  fmt.Sprint(conn.Timeout),
+	}
+	for name, value := range settings {
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("invalid connection %s: %w", name, err)
+		}
+	}
+
+	for name, value := range step.Options {
+		for _, reserved := range connectionFlags {
+			if name == reserved {
+				return fmt.Errorf("option %q must be set in the pipeline's connection section", name)
+			}
+		}
+		if flags.Lookup(name) == nil {
+			return fmt.Errorf("unknown %s option %q", step.Type, name)
+		}
+
+		// Lists set a slice flag once per element
+		values := []interface{}{value}
+		if list, ok := value.([]interface{}); ok {
+			values = list
+		}
+		for _, v := range values {
+			if err := flags.Set(name, fmt.Sprint(v)); err != nil {
+				return fmt.Errorf("invalid %s option %q: %w", step.Type, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// extractGrants writes the SHOW GRANTS output of every account to path.
+// MariaDB roles are created first and their grants written before the
+// users', so granting a role to a user finds it complete; default roles
+// follow each user's grants.
+func extractGrants(ctx context.Context, db *sql.DB, path string) error {
+	accounts, err := listGrantAccounts(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create grants file: %w", err)
+	}
+	defer file.Close()
+	sum := checksum.NewWriter(file)
+
+	fmt.Fprintf(sum, "-- MariaDB Grants\n")
+	fmt.Fprintf(sum, "-- Generated on: %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	roles := 0
+	for _, a := range accounts {
+		if a.role {
+			if roles == 0 {
+				fmt.Fprintf(sum, "-- Roles\n")
+			}
+			fmt.Fprintf(sum, "CREATE ROLE IF NOT EXISTS %s;\n", a.name())
+			roles++
+		}
+	}
+	if roles > 0 {
+		fmt.Fprintf(sum, "\n")
+	}
+
+	for _, a := range accounts {
+		name := a.name()
+		grants, err := db.QueryContext(ctx, annotateQuery("SHOW GRANTS FOR "+name))
+		if err != nil {
+			fmt.Printf("⚠️  Warning: failed to get grants for %s: %v\n", name, err)
+			continue
+		}
+		if a.role {
+			fmt.Fprintf(sum, "-- Role: %s\n", name)
+		} else {
+			fmt.Fprintf(sum, "-- Account: %s\n", name)
+			writeAccountReport(ctx, db, sum, a)
+		}
+		defaultRoleSet := false
+		for grants.Next() {
+			var grant string
+			if err := grants.Scan(&grant); err != nil {
+				grants.Close()
+				return fmt.Errorf("failed to scan grants for %s: %w", name, err)
+			}
+			defaultRoleSet = defaultRoleSet || strings.HasPrefix(grant, "SET DEFAULT ROLE")
+			fmt.Fprintf(sum, "%s;\n", grant)
+		}
+		grants.Close()
+		// Older servers leave the default role out of SHOW GRANTS
+		if a.defaultRole != "" && !defaultRoleSet {
+			fmt.Fprintf(sum, "SET DEFAULT ROLE %s FOR %s;\n", quoteRole(a.defaultRole), name)
+		}
+		fmt.Fprintf(sum, "\n")
+	}
+
+	fmt.Printf("✅ Grants for %d accounts and %d roles written to %s\n", len(accounts)-roles, roles, path)
+	return recordChecksum(path, sum.Sum())
+}
+
+// grantAccount is a user account or, on MariaDB, a role
+type grantAccount struct {
+	user, host  string
+	role        bool
+	defaultRole string
+
+	plugin  string
+	sslType string
+	// limits are MAX_QUERIES_PER_HOUR, MAX_UPDATES_PER_HOUR,
+	// MAX_CONNECTIONS_PER_HOUR and MAX_USER_CONNECTIONS; 0 is unlimited
+	limits [4]int64
+}
+
+var accountLimitNames = [4]string{"MAX_QUERIES_PER_HOUR", "MAX_UPDATES_PER_HOUR", "MAX_CONNECTIONS_PER_HOUR", "MAX_USER_CONNECTIONS"}
+
+// writeAccountReport writes comments on how account a authenticates and
+// what limits it: its authentication plugin, TLS requirement, resource
+// limits and whether it is locked, followed by its SHOW CREATE USER
+// statement, which reproduces all of them
+func writeAccountReport(ctx context.Context, db *sql.DB, w io.Writer, a grantAccount) {
+	plugin := a.plugin
+	if plugin == "" {
+		plugin = "mysql_native_password (server default)"
+	}
+	fmt.Fprintf(w, "-- Authentication: %s\n", plugin)
+
+	tls := "none"
+	switch a.sslType {
+	case "ANY":
+		tls = "REQUIRE SSL"
+	case "X509":
+		tls = "REQUIRE X509"
+	case "SPECIFIED":
+		tls = "REQUIRE cipher, issuer or subject"
+	}
+	fmt.Fprintf(w, "-- TLS: %s\n", tls)
+
+	var limits []string
+	for i, limit := range a.limits {
+		if limit > 0 {
+			limits = append(limits, fmt.Sprintf("%s %d", accountLimitNames[i], limit))
+		}
+	}
+	if len(limits) == 0 {
+		limits = []string{"none"}
+	}
+	fmt.Fprintf(w, "-- Resource limits: %s\n", strings.Join(limits, ", "))
+
+	var create string
+	if err := db.QueryRowContext(ctx, annotateQuery("SHOW CREATE USER "+a.name())).Scan(&create); err != nil {
+		fmt.Fprintf(w, "-- Locked: unknown (%v)\n", err)
+		return
+	}
+	locked := "no"
+	if strings.Contains(create, " ACCOUNT LOCK") {
+		locked = "yes"
+	}
+	fmt.Fprintf(w, "-- Locked: %s\n", locked)
+	fmt.Fprintf(w, "%s;\n", strings.Replace(create, "CREATE USER ", "CREATE USER IF NOT EXISTS ", 1))
+}
+
+// name returns the account as SHOW GRANTS and GRANT take it
+func (a grantAccount) name() string {
+	if a.role {
+		return quoteRole(a.user)
+	}
+	return fmt.Sprintf("'%s'@'%s'", strings.ReplaceAll(a.user, "'", "''"), strings.ReplaceAll(a.host, "'", "''"))
+}
+
+// quoteRole quotes a role name as an identifier
+func quoteRole(role string) string {
+	return "`" + strings.ReplaceAll(role, "`", "``") + "`"
+}
+
+// listGrantAccounts returns the accounts of mysql.user, roles first. Servers
+// without MariaDB's role columns only list users.
+func listGrantAccounts(ctx context.Context, db *sql.DB) ([]grantAccount, error) {
+	retries := getEnvIntWithDefault("MARIADB_MAX_RETRIES", 3)
+	const columns = "plugin, ssl_type, max_questions, max_updates, max_connections, max_user_connections"
+	rows, err := queryWithRetry(ctx, db, retries,
+		"SELECT User, Host, is_role = 'Y', COALESCE(default_role, ''), "+columns+" FROM mysql.user ORDER BY is_role = 'Y' DESC, User, Host")
+	if err != nil {
+		rows, err = queryWithRetry(ctx, db, retries, "SELECT User, Host, 0, '', "+columns+" FROM mysql.user ORDER BY User, Host")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var accounts []grantAccount
+	for rows.Next() {
+		var a grantAccount
+		if err := rows.Scan(&a.user, &a.host, &a.role, &a.defaultRole, &a.plugin, &a.sslType,
+			&a.limits[0], &a.limits[1], &a.limits[2], &a.limits[3]); err != nil {
+			return nil, fmt.Errorf("failed to scan account: %w", err)
+		}
+		accounts = append(accounts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list accounts: %w", err)
+	}
+	return accounts, nil
+}
+
+// runStepCommand runs a shell command with the manifest path and the
+// artifacts produced so far in its environment
+func runStepCommand(ctx context.Context, command string, p *pipeline.Pipeline, manifest *pipeline.Manifest) error {
+	var artifacts []string
+	for _, result := range manifest.Steps {
+		for _, a := range result.Artifacts {
+			artifacts = append(artifacts, a.Path)
+		}
+	}
+
+	cmd := shellCommand(ctx, command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"MARIADB_PIPELINE="+p.Name,
+		"MARIADB_PIPELINE_MANIFEST="+p.Manifest,
+		"MARIADB_PIPELINE_ARTIFACTS="+strings.Join(artifacts, "\n"),
+	)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("command failed: %w", err)
+	}
+	return nil
+}
+
+// shellCommand runs command with the system shell, cmd.exe on Windows
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// postNotification sends the manifest so far to a webhook as JSON
+func postNotification(ctx context.Context, url string, manifest *pipeline.Manifest) error {
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// describeArtifacts hashes the files a step produced, skipping missing ones
+func describeArtifacts(paths []string) []pipeline.Artifact {
+	var artifacts []pipeline.Artifact
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		sum, err := checksum.File(path)
+		if err != nil {
+			continue
+		}
+		artifacts = append(artifacts, pipeline.Artifact{Path: path, Size: info.Size(), SHA256: sum})
+	}
+	return artifacts
+}
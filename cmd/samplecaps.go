@@ -0,0 +1,115 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"mariadb-extractor/internal/yaml"
+)
+
+// sampleCaps bounds the per-table sample sizes computed from --sample-percent,
+// so one global percentage neither guts small reference tables nor still
+// extracts a billion rows from the largest ones
+type sampleCaps struct {
+	sampleCapLimits
+	// Tables override the limits for tables matching a db.table or table
+	// pattern; the first matching rule applies
+	Tables []sampleCapRule `json:"tables"`
+}
+
+// sampleCapLimits are the caps of a config or a rule. In a rule, unset
+// fields keep the config-wide value and 0 disables the cap.
+type sampleCapLimits struct {
+	// KeepFullBelow extracts tables with at most this many rows in full
+	KeepFullBelow *int64 `json:"keep_full_below"`
+	MinRows       *int64 `json:"min_rows"`
+	MaxRows       *int64 `json:"max_rows"`
+}
+
+type sampleCapRule struct {
+	Match string `json:"match"`
+	sampleCapLimits
+}
+
+// loadSampleCaps reads and validates a --sample-caps file
+func loadSampleCaps(file string) (*sampleCaps, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sample caps: %w", err)
+	}
+
+	var caps sampleCaps
+	if err := yaml.Unmarshal(data, &caps); err != nil {
+		return nil, fmt.Errorf("invalid sample caps file %s: %w", file, err)
+	}
+
+	if err := caps.sampleCapLimits.validate(); err != nil {
+		return nil, fmt.Errorf("invalid sample caps file %s: %w", file, err)
+	}
+	for i, rule := range caps.Tables {
+		if rule.Match == "" {
+			return nil, fmt.Errorf("invalid sample caps file %s: table rule %d has no match", file, i+1)
+		}
+		if _, err := path.Match(rule.Match, ""); err != nil {
+			return nil, fmt.Errorf("invalid sample caps file %s: bad pattern %q", file, rule.Match)
+		}
+		if err := rule.sampleCapLimits.validate(); err != nil {
+			return nil, fmt.Errorf("invalid sample caps file %s: rule %q: %w", file, rule.Match, err)
+		}
+	}
+	return &caps, nil
+}
+
+func (l sampleCapLimits) validate() error {
+	for name, value := range map[string]*int64{"keep_full_below": l.KeepFullBelow, "min_rows": l.MinRows, "max_rows": l.MaxRows} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if l.MinRows != nil && l.MaxRows != nil && *l.MaxRows > 0 && *l.MinRows > *l.MaxRows {
+		return fmt.Errorf("min_rows %d exceeds max_rows %d", *l.MinRows, *l.MaxRows)
+	}
+	return nil
+}
+
+// apply returns the sample size of a table with rowCount rows for which the
+// percentage gave size. A nil config leaves size unchanged.
+func (c *sampleCaps) apply(dbName, tableName string, rowCount, size int64) int64 {
+	if c == nil {
+		return size
+	}
+
+	limits := c.sampleCapLimits
+	for _, rule := range c.Tables {
+		if matched, _ := path.Match(rule.Match, dbName+"."+tableName); !matched {
+			if matched, _ = path.Match(rule.Match, tableName); !matched {
+				continue
+			}
+		}
+		if rule.KeepFullBelow != nil {
+			limits.KeepFullBelow = rule.KeepFullBelow
+		}
+		if rule.MinRows != nil {
+			limits.MinRows = rule.MinRows
+		}
+		if rule.MaxRows != nil {
+			limits.MaxRows = rule.MaxRows
+		}
+		break
+	}
+
+	if limits.KeepFullBelow != nil && rowCount <= *limits.KeepFullBelow {
+		return rowCount
+	}
+	if limits.MinRows != nil && size < *limits.MinRows {
+		size = min(*limits.MinRows, rowCount)
+	}
+	if limits.MaxRows != nil && *limits.MaxRows > 0 && size > *limits.MaxRows {
+		size = *limits.MaxRows
+	}
+	return size
+}
@@ -0,0 +1,114 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Sampling strategies of --sample-strategy
+const (
+	// sampleFirst reads the first rows in primary key order
+	sampleFirst = "first"
+	// sampleRandom keeps each row with the sample's share of the table
+	sampleRandom = "random"
+	// sampleModulo keeps every k-th row by primary key, the same rows on
+	// every run
+	sampleModulo = "modulo"
+	// sampleNewest reads the last rows in primary key order
+	sampleNewest = "newest"
+)
+
+var sampleStrategies = []string{sampleFirst, sampleRandom, sampleModulo, sampleNewest}
+
+// sampledTable reports whether only part of a planned table is extracted
+func sampledTable(plan TableExtractionPlan) bool {
+	return plan.SampleSize > 0 && plan.SampleSize < plan.RowCount
+}
+
+// applySampleStrategy sets up r to read the --sample-strategy sample of its
+// table. The random and modulo strategies add a condition selecting about
+// SampleSize rows spread over the table, which the LIMIT then cuts to size;
+// newest reverses the key order. Tables without a primary key cannot be read
+// newest first and are sampled from the start.
+//
+// With --sample-seed, random keeps the rows whose key hashed with the seed
+// falls below the sample's share instead of calling RAND(), so the same seed
+// picks the same rows from the same data. Tables without a primary key are
+// then read in column order, so the LIMIT cuts the same rows too.
+func (r *tableReader) applySampleStrategy(ctx context.Context, db *sql.DB) error {
+	if !sampledTable(r.plan) {
+		return nil
+	}
+	fraction := float64(r.plan.SampleSize) / float64(r.plan.RowCount)
+	switch dataSampleStrategy {
+	case sampleRandom:
+		if dataSampleSeed == "" {
+			r.sample = fmt.Sprintf("RAND() < %g", fraction)
+			return nil
+		}
+		columns, err := r.sampleColumns(ctx, db)
+		if err != nil {
+			return err
+		}
+		if len(r.key) == 0 {
+			r.order = columns
+		}
+		// CRC32 values are spread evenly over 32 bits
+		r.sample = fmt.Sprintf("CRC32(CONCAT_WS(',', %s, %s)) < %d",
+			formatSQLValue(dataSampleSeed), quoteColumns(columns), int64(fraction*(1<<32)))
+	case sampleModulo:
+		columns, err := r.sampleColumns(ctx, db)
+		if err != nil {
+			return err
+		}
+		step := r.plan.RowCount / r.plan.SampleSize
+		integer, err := integerColumn(ctx, db, r.plan, columns)
+		if err != nil {
+			return err
+		}
+		if integer {
+			r.sample = fmt.Sprintf("`%s` MOD %d = 0", columns[0], step)
+		} else {
+			// Other keys are hashed, which spreads them as evenly
+			r.sample = fmt.Sprintf("CRC32(CONCAT_WS(',', %s)) MOD %d = 0", quoteColumns(columns), step)
+		}
+	case sampleNewest:
+		if len(r.key) == 0 {
+			fmt.Printf(" (no primary key, sampling the first rows)")
+			return nil
+		}
+		r.descending = true
+	}
+	return nil
+}
+
+// sampleColumns returns the columns identifying a row of r's table: its
+// primary key, or all its columns without one
+func (r *tableReader) sampleColumns(ctx context.Context, db *sql.DB) ([]string, error) {
+	if len(r.key) > 0 {
+		return r.key, nil
+	}
+	return getTableColumns(ctx, db, r.plan.DatabaseName, r.plan.TableName)
+}
+
+// integerColumn reports whether columns is a single integer column
+func integerColumn(ctx context.Context, db *sql.DB, plan TableExtractionPlan, columns []string) (bool, error) {
+	if len(columns) != 1 {
+		return false, nil
+	}
+	ctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+
+	var dataType string
+	if err := queryRowWithRetry(ctx, db, dataMaxRetries,
+		"SELECT DATA_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+		[]interface{}{plan.DatabaseName, plan.TableName, columns[0]}, &dataType); err != nil {
+		return false, fmt.Errorf("failed to get column type: %w", err)
+	}
+	return integerTypes[strings.ToLower(dataType)], nil
+}
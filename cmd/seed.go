@@ -0,0 +1,202 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// seedSelection accumulates the rows of one table reached from the seeds:
+// the seed conditions on the table itself and the referenced key values per
+// column list
+type seedSelection struct {
+	conditions []string
+	keys       map[string]*seedKeys
+}
+
+// seedKeys are the distinct key tuples, as SQL literals, of a column list
+type seedKeys struct {
+	columns []string
+	values  map[string]bool
+	order   []string
+}
+
+// seedWork is a table whose newly selected rows still have to be followed
+type seedWork struct {
+	table     string
+	condition string
+}
+
+// parseSeed splits "table:condition" or "db.table:condition"
+func parseSeed(spec string) (string, string, error) {
+	table, condition, ok := strings.Cut(spec, ":")
+	table, condition = strings.TrimSpace(table), strings.TrimSpace(condition)
+	if !ok || table == "" || condition == "" {
+		return "", "", fmt.Errorf("invalid --seed %q: use table:condition, e.g. \"customers:id IN (1,2,3)\"", spec)
+	}
+	return table, condition, nil
+}
+
+// applySeeds restricts plans to the seed rows and every row they reference,
+// directly or through other referenced rows. Tables not reached are dropped;
+// the others get a WhereClause selecting exactly the reached rows, combined
+// with any WhereClause they already had.
+func applySeeds(ctx context.Context, db *sql.DB, plans []TableExtractionPlan, seeds []string) ([]TableExtractionPlan, error) {
+	ix := newPlanIndex(plans)
+	byKey := ix.byKey
+
+	selections := make(map[string]*seedSelection)
+	selection := func(table string) *seedSelection {
+		if selections[table] == nil {
+			selections[table] = &seedSelection{keys: make(map[string]*seedKeys)}
+		}
+		return selections[table]
+	}
+
+	var queue []seedWork
+	for _, spec := range seeds {
+		table, condition, err := parseSeed(spec)
+		if err != nil {
+			return nil, err
+		}
+		if table, err = ix.resolve(table, "--seed"); err != nil {
+			return nil, err
+		}
+		selection(table).conditions = append(selection(table).conditions, condition)
+		queue = append(queue, seedWork{table: table, condition: condition})
+	}
+
+	// Follow foreign keys from newly selected rows until no new parent rows
+	// turn up; key sets only grow, so cycles terminate
+	warned := make(map[string]bool)
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		work := queue[0]
+		queue = queue[1:]
+
+		for _, c := range planConstraints(*byKey[work.table]) {
+			if _, ok := byKey[c.parent]; !ok {
+				if !warned[c.parent] {
+					fmt.Printf("⚠️  Seed rows reference %s, which is not part of the extraction\n", c.parent)
+					warned[c.parent] = true
+				}
+				continue
+			}
+
+			tuples, err := seedReferences(ctx, db, work, c.columns)
+			if err != nil {
+				return nil, err
+			}
+
+			name := strings.Join(c.parentColumns, ",")
+			parent := selection(c.parent)
+			keys := parent.keys[name]
+			if keys == nil {
+				keys = &seedKeys{columns: c.parentColumns, values: make(map[string]bool)}
+				parent.keys[name] = keys
+			}
+			var added []string
+			for _, tuple := range tuples {
+				if !keys.values[tuple] {
+					keys.values[tuple] = true
+					keys.order = append(keys.order, tuple)
+					added = append(added, tuple)
+				}
+			}
+			for _, chunk := range seedChunks(added) {
+				queue = append(queue, seedWork{table: c.parent, condition: inCondition(c.parentColumns, chunk)})
+			}
+		}
+	}
+
+	var selected []TableExtractionPlan
+	for _, plan := range plans {
+		sel := selections[plan.DatabaseName+"."+plan.TableName]
+		if sel == nil {
+			continue
+		}
+		conditions := append([]string(nil), sel.conditions...)
+		names := make([]string, 0, len(sel.keys))
+		for name := range sel.keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if keys := sel.keys[name]; len(keys.order) > 0 {
+				conditions = append(conditions, inCondition(keys.columns, keys.order))
+			}
+		}
+		if len(conditions) == 0 {
+			continue
+		}
+		where := "(" + strings.Join(conditions, ") OR (") + ")"
+		if plan.WhereClause != "" {
+			// A --where condition still applies to the seeded rows
+			where = "(" + plan.WhereClause + ") AND " + where
+		}
+		plan.WhereClause = where
+		selected = append(selected, plan)
+	}
+	return selected, nil
+}
+
+// seedReferences returns the distinct non-NULL values of columns, as SQL
+// tuple literals, in the rows of work.table matching work.condition
+func seedReferences(ctx context.Context, db *sql.DB, work seedWork, columns []string) ([]string, error) {
+	dbName, table, _ := strings.Cut(work.table, ".")
+	notNull := make([]string, len(columns))
+	for i, col := range columns {
+		notNull[i] = "`" + col + "` IS NOT NULL"
+	}
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM `%s`.`%s` WHERE (%s) AND %s",
+		quoteColumns(columns), dbName, table, work.condition, strings.Join(notNull, " AND "))
+
+	ctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+	rows, err := queryWithRetry(ctx, db, dataMaxRetries, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to follow seed rows of %s: %w", work.table, err)
+	}
+	defer rows.Close()
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	var tuples []string
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("failed to scan seed reference: %w", err)
+		}
+		literals := make([]string, len(values))
+		for i, v := range values {
+			literals[i] = formatSQLValue(v)
+		}
+		tuples = append(tuples, "("+strings.Join(literals, ",")+")")
+	}
+	return tuples, rows.Err()
+}
+
+// seedChunkSize bounds the tuples followed per query
+const seedChunkSize = 1000
+
+func seedChunks(tuples []string) [][]string {
+	var chunks [][]string
+	for start := 0; start < len(tuples); start += seedChunkSize {
+		chunks = append(chunks, tuples[start:min(start+seedChunkSize, len(tuples))])
+	}
+	return chunks
+}
+
+// inCondition matches rows whose columns equal one of the tuple literals
+func inCondition(columns []string, tuples []string) string {
+	return fmt.Sprintf("(%s) IN (%s)", quoteColumns(columns), strings.Join(tuples, ","))
+}
@@ -0,0 +1,174 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// integerTypes are the column types whose ranges can be split numerically
+var integerTypes = map[string]bool{
+	"tinyint": true, "smallint": true, "mediumint": true, "int": true, "bigint": true,
+}
+
+// planSegments splits a table into --table-segments primary key ranges and
+// returns their conditions. It returns nil when the table is not split:
+// segmentation is off, the table is not one of --segment-tables or, without
+// them, has fewer than --segment-min-rows rows, or its primary key is not a
+// single integer column.
+func planSegments(ctx context.Context, db *sql.DB, plan TableExtractionPlan) ([]string, error) {
+	if dataTableSegments <= 1 || !segmentTable(plan) {
+		return nil, nil
+	}
+
+	key, err := getPrimaryKey(ctx, db, plan.DatabaseName, plan.TableName)
+	if err != nil || len(key) != 1 {
+		return nil, err
+	}
+
+	qctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+
+	var dataType string
+	if err := queryRowWithRetry(qctx, db, dataMaxRetries,
+		"SELECT DATA_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+		[]interface{}{plan.DatabaseName, plan.TableName, key[0]}, &dataType); err != nil {
+		return nil, fmt.Errorf("failed to get primary key type: %w", err)
+	}
+	if !integerTypes[dataType] {
+		return nil, nil
+	}
+
+	query := fmt.Sprintf("SELECT MIN(`%s`), MAX(`%s`) FROM `%s`.`%s`", key[0], key[0], plan.DatabaseName, plan.TableName)
+	if plan.WhereClause != "" {
+		query += " WHERE " + plan.WhereClause
+	}
+	var lo, hi sql.NullInt64
+	if err := queryRowWithRetry(qctx, db, dataMaxRetries, query, nil, &lo, &hi); err != nil {
+		// Unsigned keys beyond the int64 range are read in one piece
+		fmt.Printf(" (not segmented: %v)", err)
+		return nil, nil
+	}
+	span := hi.Int64 - lo.Int64
+	if !lo.Valid || !hi.Valid || span < 0 {
+		return nil, nil
+	}
+
+	n := int64(dataTableSegments)
+	width := span/n + 1
+	var segments []string
+	for i := int64(0); i < n; i++ {
+		start := lo.Int64 + i*width
+		if start > hi.Int64 {
+			break
+		}
+		end := start + width - 1
+		if i == n-1 || end > hi.Int64 {
+			end = hi.Int64
+		}
+		segments = append(segments, fmt.Sprintf("`%s` BETWEEN %d AND %d", key[0], start, end))
+	}
+	return segments, nil
+}
+
+// segmentTable reports whether a table qualifies for --table-segments by
+// name or size
+func segmentTable(plan TableExtractionPlan) bool {
+	if len(dataSegmentTables) == 0 {
+		return plan.RowCount >= dataSegmentMinRows
+	}
+	return matchTablePatterns(dataSegmentTables, plan.DatabaseName, plan.TableName)
+}
+
+// extractSegments reads the primary key ranges of a table concurrently, each
+// into a spool file next to the output, and appends the spools to w in key
+// order once all succeeded. It returns the number of rows written.
+func extractSegments(ctx context.Context, db *sql.DB, w io.Writer, plan TableExtractionPlan, segments []string, batchBudget int64) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	readers := make([]*tableReader, 0, len(segments))
+	defer func() {
+		for _, r := range readers {
+			r.close()
+		}
+	}()
+	for _, condition := range segments {
+		segment := plan
+		segment.WhereClause = condition
+		if plan.WhereClause != "" {
+			segment.WhereClause = "(" + plan.WhereClause + ") AND " + condition
+		}
+
+		r, err := openTableReader(ctx, db, segment, 0)
+		if err != nil {
+			return 0, err
+		}
+		readers = append(readers, r)
+	}
+	transforms := tableTransforms(plan, readers[0].columns)
+	fmt.Printf(" (%d segments)", len(segments))
+
+	spools := make([]*os.File, len(segments))
+	defer func() {
+		for _, spool := range spools {
+			if spool != nil {
+				spool.Close()
+				os.Remove(spool.Name())
+			}
+		}
+	}()
+	for i := range spools {
+		spool, err := os.CreateTemp("output", fmt.Sprintf(".%s.%s.segment-*", plan.DatabaseName, plan.TableName))
+		if err != nil {
+			return 0, fmt.Errorf("failed to create segment spool: %w", err)
+		}
+		spools[i] = spool
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	var total int64
+	segmentBudget := max(batchBudget/int64(len(segments)), 4096)
+	for i, r := range readers {
+		wg.Add(1)
+		go func(r *tableReader, spool *os.File) {
+			defer wg.Done()
+			out := bufio.NewWriterSize(spool, 1<<20)
+			rows, err := writeInsertRows(ctx, db, out, r, plan, nil, transforms, nil, segmentBudget, nil)
+			if err == nil {
+				err = out.Flush()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			total += rows
+			if err != nil && firstErr == nil {
+				firstErr = err
+				cancel()
+			}
+		}(r, spools[i])
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return total, firstErr
+	}
+
+	for _, spool := range spools {
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return total, fmt.Errorf("failed to read segment spool: %w", err)
+		}
+		if _, err := io.Copy(w, spool); err != nil {
+			return total, fmt.Errorf("failed to merge segment: %w", err)
+		}
+	}
+	return total, nil
+}
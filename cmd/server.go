@@ -0,0 +1,134 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Server flavors
+const (
+	flavorMariaDB = "MariaDB"
+	flavorMySQL   = "MySQL"
+)
+
+// serverInfo describes the source server, detected at connect time
+type serverInfo struct {
+	flavor  string
+	version string // full VERSION() string
+	major   int
+	minor   int
+	patch   int
+}
+
+var versionPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+
+// detectServer reads the flavor and version of the connected server
+func detectServer(ctx context.Context, db *sql.DB) (serverInfo, error) {
+	var version, comment string
+	if err := db.QueryRowContext(ctx, annotateQuery("SELECT VERSION(), @@version_comment")).Scan(&version, &comment); err != nil {
+		return serverInfo{}, fmt.Errorf("failed to detect server version: %w", err)
+	}
+	return parseServerVersion(version, comment), nil
+}
+
+// connectedServer detects the server and reports it; detection failures are
+// not fatal and leave the flavor unknown
+func connectedServer(ctx context.Context, db *sql.DB) serverInfo {
+	info, err := detectServer(ctx, db)
+	if err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+		return info
+	}
+	fmt.Printf("Server: %s (%s)\n", info, info.version)
+	return info
+}
+
+func parseServerVersion(version, comment string) serverInfo {
+	info := serverInfo{flavor: flavorMySQL, version: version}
+	if strings.Contains(strings.ToLower(version+" "+comment), "mariadb") {
+		info.flavor = flavorMariaDB
+	}
+
+	// Proxies may report the handshake form of MariaDB 10 versions, which
+	// carries a "5.5.5-" prefix for old replication clients
+	v := strings.TrimPrefix(version, "5.5.5-")
+	if m := versionPattern.FindStringSubmatch(v); m != nil {
+		info.major, _ = strconv.Atoi(m[1])
+		info.minor, _ = strconv.Atoi(m[2])
+		info.patch, _ = strconv.Atoi(m[3])
+	}
+	return info
+}
+
+// String returns e.g. "MariaDB 10.11.6"
+func (s serverInfo) String() string {
+	if s.flavor == "" {
+		return "unknown"
+	}
+	return fmt.Sprintf("%s %d.%d.%d", s.flavor, s.major, s.minor, s.patch)
+}
+
+// atLeast reports whether the server version is at least major.minor.patch
+func (s serverInfo) atLeast(major, minor, patch int) bool {
+	if s.major != major {
+		return s.major > major
+	}
+	if s.minor != minor {
+		return s.minor > minor
+	}
+	return s.patch >= patch
+}
+
+func (s serverInfo) isMariaDB() bool {
+	return s.flavor == flavorMariaDB
+}
+
+// supportsSequences reports whether CREATE SEQUENCE objects exist (MariaDB 10.3+)
+func (s serverInfo) supportsSequences() bool {
+	return s.isMariaDB() && s.atLeast(10, 3, 0)
+}
+
+// replicaStatusQuery returns the statement showing replication status;
+// the REPLICA form replaced SLAVE in MySQL 8.0.22 and MariaDB 10.5.1
+func (s serverInfo) replicaStatusQuery() string {
+	if (s.isMariaDB() && s.atLeast(10, 5, 1)) || (!s.isMariaDB() && s.atLeast(8, 0, 22)) {
+		return "SHOW REPLICA STATUS"
+	}
+	return "SHOW SLAVE STATUS"
+}
+
+// flavorSpecificCollations matches collations that only exist on one flavor:
+// MySQL 8's utf8mb4_0900_* and MariaDB 11's *_uca1400_*
+var flavorSpecificCollations = regexp.MustCompile(`\butf8mb[34]_(0900|uca1400)_\w+`)
+
+// collationNote describes collations in the statements that the other flavor
+// may not accept, or returns "" when there are none
+func (s serverInfo) collationNote(statements []string) string {
+	found := make(map[string]bool)
+	var names []string
+	for _, stmt := range statements {
+		for _, name := range flavorSpecificCollations.FindAllString(stmt, -1) {
+			if !found[name] {
+				found[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+
+	target := flavorMariaDB
+	if s.isMariaDB() {
+		target = flavorMySQL
+	}
+	return fmt.Sprintf("collations %s are specific to %s and may not exist on %s; restore onto a %s image",
+		strings.Join(names, ", "), s, target, s.flavor)
+}
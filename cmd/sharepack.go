@@ -0,0 +1,522 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/metacache"
+	"mariadb-extractor/internal/pipeline"
+	"mariadb-extractor/internal/sealed"
+	"mariadb-extractor/internal/sink"
+	"mariadb-extractor/internal/yaml"
+
+	"github.com/spf13/cobra"
+)
+
+// sharepackCmd represents the sharepack command
+var sharepackCmd = &cobra.Command{
+	Use:   "sharepack <config.yaml>",
+	Short: "Build an encrypted archive of schema, masked sample data and docs for sharing",
+	Long: `Build a share pack for an external vendor from one config file: a single
+compressed archive, encrypted with a passphrase, holding the schema of the
+configured databases, a masked sample of their data, ER diagrams and a
+README, plus any documents the config lists.
+
+The data is extracted with the config's data options, which are data flags
+keyed by flag name and must configure masking (mask-config or transforms).
+The passphrase is read from the environment variable named by
+passphrase_env (default SHAREPACK_PASSPHRASE).
+
+Example:
+  SHAREPACK_PASSPHRASE=... mariadb-extractor sharepack acme.yaml
+  SHAREPACK_PASSPHRASE=... mariadb-extractor sharepack open output/acme.sharepack`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runSharepack(cmd.Context(), args[0])
+	},
+}
+
+// sharepackOpenCmd decrypts a share pack
+var sharepackOpenCmd = &cobra.Command{
+	Use:   "open <file.sharepack>",
+	Short: "Decrypt a share pack into a .tar.gz archive",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := openSharepack(args[0]); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var (
+	sharepackPool          poolOptions
+	sharepackOpenOutput    string
+	sharepackPassphraseEnv string
+)
+
+// sharepackConfig is the share pack config file format. Data holds data
+// flags keyed by flag name, like the options of a pipeline's data step.
+type sharepackConfig struct {
+	Name          string                 `json:"name"`
+	Connection    pipeline.Connection    `json:"connection"`
+	Databases     []string               `json:"databases"`
+	Data          map[string]interface{} `json:"data"`
+	Docs          []string               `json:"docs"`
+	Output        string                 `json:"output"`
+	PassphraseEnv string                 `json:"passphrase_env"`
+}
+
+// sharepackFile is a file added to a share pack, from disk or from memory
+type sharepackFile struct {
+	name    string
+	path    string
+	content []byte
+}
+
+func init() {
+	rootCmd.AddCommand(sharepackCmd)
+	sharepackCmd.AddCommand(sharepackOpenCmd)
+
+	addPoolFlags(sharepackCmd, &sharepackPool, 5, 2, getEnvIntWithDefault("MARIADB_TIMEOUT", 300))
+	sharepackOpenCmd.Flags().StringVarP(&sharepackOpenOutput, "output", "o", "", "Archive to write (default: the pack's name with .tar.gz)")
+	sharepackOpenCmd.Flags().StringVar(&sharepackPassphraseEnv, "passphrase-env", "SHAREPACK_PASSPHRASE", "Environment variable holding the passphrase")
+}
+
+func loadSharepackConfig(path string) (*sharepackConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg sharepackConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid share pack config %s: %w", path, err)
+	}
+
+	if cfg.Name == "" || len(cfg.Databases) == 0 {
+		return nil, fmt.Errorf("%s: name and databases are required", path)
+	}
+	if strings.ContainsAny(cfg.Name, `/\`) {
+		return nil, fmt.Errorf("%s: name %q must not contain path separators", path, cfg.Name)
+	}
+	for _, reserved := range []string{"databases", "output"} {
+		if _, ok := cfg.Data[reserved]; ok {
+			return nil, fmt.Errorf("%s: data option %q is set by the share pack", path, reserved)
+		}
+	}
+	if cfg.Output == "" {
+		cfg.Output = filepath.Join("output", cfg.Name+".sharepack")
+	}
+	if cfg.PassphraseEnv == "" {
+		cfg.PassphraseEnv = "SHAREPACK_PASSPHRASE"
+	}
+	return &cfg, nil
+}
+
+func runSharepack(ctx context.Context, path string) {
+	cfg, err := loadSharepackConfig(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	passphrase := os.Getenv(cfg.PassphraseEnv)
+	if passphrase == "" {
+		log.Fatalf("Set the share pack passphrase in %s", cfg.PassphraseEnv)
+	}
+
+	conn := cfg.Connection
+	db, password := connectPipeline(ctx, &conn, sharepackPool)
+	defer db.Close()
+
+	fmt.Printf("📦 Building share pack %s\n", cfg.Name)
+	files, err := buildSharepackData(ctx, db, conn, password, cfg)
+	if err != nil {
+		log.Fatalf("Failed to extract share pack data: %v", err)
+	}
+	staged := make([]string, 0, len(files))
+	for _, f := range files {
+		staged = append(staged, f.path)
+	}
+
+	schema, err := sharepackSchema(ctx, db, cfg.Databases)
+	if err != nil {
+		log.Fatalf("Failed to extract share pack schema: %v", err)
+	}
+	files = append(files, sharepackFile{name: "schema.sql", content: schema})
+
+	diagrams := make(map[string]string)
+	for _, dbName := range cfg.Databases {
+		diagram, err := erDiagram(ctx, db, dbName)
+		if err != nil {
+			log.Fatalf("Failed to draw the ER diagram of %s: %v", dbName, err)
+		}
+		diagrams[dbName] = diagram
+		files = append(files, sharepackFile{name: "diagrams/" + dbName + ".mmd", content: []byte(diagram)})
+	}
+
+	for _, doc := range cfg.Docs {
+		files = append(files, sharepackFile{name: "docs/" + filepath.Base(doc), path: doc})
+	}
+	readme := sharepackReadme(cfg, files, diagrams)
+	files = append([]sharepackFile{{name: "README.md", content: readme}}, files...)
+
+	if err := writeSharepack(cfg.Output, cfg.Name, passphrase, files); err != nil {
+		log.Fatalf("Failed to write share pack: %v", err)
+	}
+
+	// Only the encrypted pack is kept
+	for _, path := range staged {
+		os.Remove(path)
+	}
+	os.Remove(filepath.Join("output", sink.Prefix(dataOutput)))
+	if err := checksum.Forget("output", staged...); err != nil {
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+
+	info, _ := os.Stat(cfg.Output)
+	fmt.Printf("🔒 Share pack: %s (%d files", cfg.Output, len(files))
+	if info != nil {
+		fmt.Printf(", %s", formatBytes(info.Size()))
+	}
+	fmt.Printf(")\n")
+	fmt.Printf("   Open with: %s=... mariadb-extractor sharepack open %s\n", cfg.PassphraseEnv, cfg.Output)
+}
+
+// buildSharepackData extracts the masked data of the pack's databases and
+// returns the files written, named as in the pack
+func buildSharepackData(ctx context.Context, db *sql.DB, conn pipeline.Connection, password string, cfg *sharepackConfig) ([]sharepackFile, error) {
+	options := make(map[string]interface{}, len(cfg.Data)+2)
+	for name, value := range cfg.Data {
+		options[name] = value
+	}
+	databases := make([]interface{}, len(cfg.Databases))
+	for i, name := range cfg.Databases {
+		databases[i] = name
+	}
+	options["databases"] = databases
+	options["output"] = "sharepack-" + cfg.Name
+
+	step := pipeline.Step{Name: "data", Type: pipeline.StepData, Options: options}
+	if err := applyStepOptions(dataCmd.Flags(), conn, password, step); err != nil {
+		return nil, err
+	}
+	if err := validateDataOptions(); err != nil {
+		return nil, err
+	}
+	if dataMaskConfig == "" && dataTransformsFile == "" {
+		return nil, fmt.Errorf("a share pack must mask its data: set mask-config or transforms in the data options")
+	}
+	if dataTempUser {
+		return nil, fmt.Errorf("option \"temp-user\" is not supported in share packs")
+	}
+	if err := runDataWithDB(ctx, db); err != nil {
+		return nil, err
+	}
+
+	var files []sharepackFile
+	for _, path := range dataOutputFiles() {
+		if filepath.Base(path) == checksum.SumsFile {
+			continue
+		}
+		rel, err := filepath.Rel("output", path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, sharepackFile{name: "data/" + filepath.ToSlash(rel), path: path})
+	}
+	return files, nil
+}
+
+// sharepackSchema returns the CREATE statements of the extracted tables of
+// databases
+func sharepackSchema(ctx context.Context, db *sql.DB, databases []string) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "-- Schema of %s\n\n", strings.Join(databases, ", "))
+	for _, dbName := range databases {
+		tables, err := getTablesForDatabase(ctx, db, dbName)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "CREATE DATABASE IF NOT EXISTS `%s`;\n", dbName)
+		fmt.Fprintf(&b, "USE `%s`;\n\n", dbName)
+		for _, table := range tables {
+			createTable, err := showCreateTable(ctx, db, dbName, table)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&b, "%s;\n\n", strings.TrimSuffix(strings.TrimSpace(createTable), ";"))
+		}
+	}
+	return b.Bytes(), nil
+}
+
+// erDiagram draws the extracted tables of a database and their foreign keys
+// as a Mermaid entity relationship diagram
+func erDiagram(ctx context.Context, db *sql.DB, dbName string) (string, error) {
+	tables, err := getTablesForDatabase(ctx, db, dbName)
+	if err != nil {
+		return "", err
+	}
+	foreignKeys, err := getForeignKeyRelationships(ctx, db, dbName)
+	if err != nil {
+		return "", err
+	}
+
+	query := `
+		SELECT TABLE_NAME, COLUMN_NAME, DATA_TYPE, COLUMN_KEY
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ?
+		ORDER BY TABLE_NAME, ORDINAL_POSITION
+	`
+	qctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+	rows, err := cachedRows(qctx, db, dataMaxRetries, metacache.Key{Kind: metaColumnTypes, Database: dbName}, query, dbName)
+	if err != nil {
+		return "", fmt.Errorf("failed to get columns: %w", err)
+	}
+	columns := make(map[string][][]string)
+	for _, row := range rows {
+		columns[row[0]] = append(columns[row[0]], row[1:])
+	}
+
+	included := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		included[table] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, table := range tables {
+		keys := make(map[string]bool)
+		for _, fk := range foreignKeys[table] {
+			keys[fk.ColumnName] = true
+		}
+		fmt.Fprintf(&b, "    %s {\n", mermaidName(table))
+		for _, col := range columns[table] {
+			var marks []string
+			if col[2] == "PRI" {
+				marks = append(marks, "PK")
+			}
+			if keys[col[0]] {
+				marks = append(marks, "FK")
+			}
+			fmt.Fprintf(&b, "        %s %s", mermaidName(col[1]), mermaidName(col[0]))
+			if len(marks) > 0 {
+				fmt.Fprintf(&b, " %s", strings.Join(marks, ","))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("    }\n")
+	}
+
+	// One relationship per constraint, from the referenced table
+	for _, table := range tables {
+		seen := make(map[string]bool)
+		for _, fk := range foreignKeys[table] {
+			if seen[fk.ConstraintName] || !included[fk.RefTableName] {
+				continue
+			}
+			seen[fk.ConstraintName] = true
+			fmt.Fprintf(&b, "    %s ||--o{ %s : %q\n", mermaidName(fk.RefTableName), mermaidName(table), fk.ColumnName)
+		}
+	}
+	return b.String(), nil
+}
+
+// mermaidName replaces the characters Mermaid does not accept in names
+func mermaidName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// sharepackReadme describes the pack's contents and how to load them
+func sharepackReadme(cfg *sharepackConfig, files []sharepackFile, diagrams map[string]string) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", cfg.Name)
+	fmt.Fprintf(&b, "**Generated on:** %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "**Source:** %s\n\n", dataServer)
+	fmt.Fprintf(&b, "**Databases:** %s\n\n", strings.Join(cfg.Databases, ", "))
+	fmt.Fprintf(&b, "The data is masked: personal and confidential values were replaced\n")
+	fmt.Fprintf(&b, "as the rows were extracted.\n\n")
+
+	fmt.Fprintf(&b, "## Contents\n\n")
+	fmt.Fprintf(&b, "| File | Description |\n")
+	fmt.Fprintf(&b, "|------|-------------|\n")
+	for _, f := range files {
+		description := ""
+		switch {
+		case f.name == "schema.sql":
+			description = "CREATE statements of the tables"
+		case strings.HasPrefix(f.name, "data/") && strings.HasSuffix(f.name, ".sql"):
+			description = "Data script"
+		case strings.HasPrefix(f.name, "data/"):
+			description = "Table data"
+		case strings.HasPrefix(f.name, "diagrams/"):
+			description = "ER diagram (Mermaid)"
+		case strings.HasPrefix(f.name, "docs/"):
+			description = "Documentation"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s |\n", f.name, description)
+	}
+	fmt.Fprintf(&b, "| `SHA256SUMS` | Checksums of the files, for `sha256sum -c` |\n\n")
+
+	fmt.Fprintf(&b, "## Loading\n\n")
+	fmt.Fprintf(&b, "```bash\n")
+	fmt.Fprintf(&b, "mysql < schema.sql\n")
+	fmt.Fprintf(&b, "cd data && mysql --local-infile=1 < %s.sql\n", sink.Prefix(dataOutput))
+	fmt.Fprintf(&b, "```\n\n")
+
+	fmt.Fprintf(&b, "## ER Diagrams\n\n")
+	for _, dbName := range cfg.Databases {
+		fmt.Fprintf(&b, "### %s\n\n", dbName)
+		fmt.Fprintf(&b, "```mermaid\n%s```\n\n", diagrams[dbName])
+	}
+	return b.Bytes()
+}
+
+// writeSharepack writes files as a tar.gz archive under the directory name,
+// followed by their SHA256SUMS, encrypted with passphrase to path
+func writeSharepack(path, name, passphrase string, files []sharepackFile) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create share pack: %w", err)
+	}
+	defer file.Close()
+
+	enc, err := sealed.NewWriter(file, passphrase)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(enc)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+
+	add := func(entry string, size int64, r io.Reader) (string, error) {
+		if err := tw.WriteHeader(&tar.Header{Name: name + "/" + entry, Mode: 0644, Size: size, ModTime: now}); err != nil {
+			return "", err
+		}
+		h := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(tw, h), r); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	sums := make(map[string]string, len(files))
+	for _, f := range files {
+		var sum string
+		if f.path == "" {
+			sum, err = add(f.name, int64(len(f.content)), bytes.NewReader(f.content))
+		} else {
+			sum, err = addSharepackFile(add, f)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", f.name, err)
+		}
+		sums[f.name] = sum
+	}
+
+	names := make([]string, 0, len(sums))
+	for entry := range sums {
+		names = append(names, entry)
+	}
+	sort.Strings(names)
+	var list bytes.Buffer
+	for _, entry := range names {
+		fmt.Fprintf(&list, "%s  %s\n", sums[entry], entry)
+	}
+	if _, err := add(checksum.SumsFile, int64(list.Len()), &list); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	sum, err := checksum.File(path)
+	if err != nil {
+		return err
+	}
+	return recordChecksum(path, sum)
+}
+
+// addSharepackFile adds a file from disk to a share pack
+func addSharepackFile(add func(string, int64, io.Reader) (string, error), f sharepackFile) (string, error) {
+	in, err := os.Open(f.path)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+	return add(f.name, info.Size(), in)
+}
+
+// openSharepack decrypts a share pack into a .tar.gz archive
+func openSharepack(path string) error {
+	passphrase := os.Getenv(sharepackPassphraseEnv)
+	if passphrase == "" {
+		return fmt.Errorf("set the share pack passphrase in %s", sharepackPassphraseEnv)
+	}
+	output := sharepackOpenOutput
+	if output == "" {
+		output = strings.TrimSuffix(path, filepath.Ext(path)) + ".tar.gz"
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	dec, err := sealed.NewReader(in, passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	out, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	if _, err := io.Copy(out, dec); err != nil {
+		out.Close()
+		os.Remove(output)
+		return fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("🔓 Decrypted %s to %s (extract with: tar xzf %s)\n", path, output, output)
+	return nil
+}
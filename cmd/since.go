@@ -0,0 +1,149 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"mariadb-extractor/internal/metacache"
+)
+
+// sinceColumnNames are the date columns --since limits tables by, in order of
+// preference when a table has several
+var sinceColumnNames = []string{
+	"created_at", "created_on", "creation_date", "inserted_at", "created",
+	"updated_at", "updated_on", "modified_at", "modified",
+}
+
+// parseSince parses a --since window: a day or week count such as 90d or 2w,
+// or a Go duration such as 12h
+func parseSince(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if count, ok := strings.CutSuffix(s, suffix); ok {
+			if n, err := strconv.Atoi(count); err == nil && n > 0 {
+				return time.Duration(n) * unit, nil
+			}
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid --since %q: use e.g. 90d, 2w or 12h", s)
+	}
+	return d, nil
+}
+
+// parseSinceColumn splits "table:column" or "db.table:column". The column "-"
+// leaves the table out of the window.
+func parseSinceColumn(spec string) (string, string, error) {
+	table, column, ok := strings.Cut(spec, ":")
+	table, column = strings.TrimSpace(table), strings.TrimSpace(column)
+	if !ok || table == "" || column == "" {
+		return "", "", fmt.Errorf("invalid --since-column %q: use table:column, or table:- to extract the table whole", spec)
+	}
+	return table, column, nil
+}
+
+// applySince limits planned tables to the rows of the last --since window by
+// their date column: the one named by --since-column, or else the first of
+// sinceColumnNames the table has as a DATE, DATETIME or TIMESTAMP column.
+// Tables without one are extracted as usual. The window starts at a fixed
+// server time computed now, so a saved plan keeps it.
+func applySince(ctx context.Context, db *sql.DB, plans []TableExtractionPlan) error {
+	window, err := parseSince(dataSince)
+	if err != nil {
+		return err
+	}
+	qctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+	var cutoff string
+	if err := queryRowWithRetry(qctx, db, dataMaxRetries,
+		"SELECT DATE_FORMAT(NOW() - INTERVAL ? SECOND, '%Y-%m-%d %H:%i:%s')",
+		[]interface{}{int64(window / time.Second)}, &cutoff); err != nil {
+		return fmt.Errorf("failed to compute the --since cutoff: %w", err)
+	}
+
+	ix := newPlanIndex(plans)
+	columns := make(map[string]string)
+	databases := make(map[string]bool)
+	for _, plan := range plans {
+		if databases[plan.DatabaseName] {
+			continue
+		}
+		databases[plan.DatabaseName] = true
+		detected, err := getSinceColumns(ctx, db, plan.DatabaseName)
+		if err != nil {
+			return err
+		}
+		for table, column := range detected {
+			columns[plan.DatabaseName+"."+table] = column
+		}
+	}
+	for _, spec := range dataSinceColumns {
+		table, column, err := parseSinceColumn(spec)
+		if err != nil {
+			return err
+		}
+		key, err := ix.resolve(table, "--since-column")
+		if err != nil {
+			return err
+		}
+		columns[key] = column
+	}
+
+	limited := 0
+	for i := range plans {
+		plan := &plans[i]
+		column := columns[plan.DatabaseName+"."+plan.TableName]
+		if column == "" || column == "-" {
+			continue
+		}
+		condition := fmt.Sprintf("`%s` >= '%s'", column, cutoff)
+		if plan.WhereClause != "" {
+			condition = "(" + plan.WhereClause + ") AND (" + condition + ")"
+		}
+		plan.WhereClause = condition
+		limited++
+	}
+	fmt.Printf("Limited %d of %d tables to rows since %s (--since %s)\n", limited, len(plans), cutoff, dataSince)
+	return nil
+}
+
+// getSinceColumns returns the preferred --since column of each table of a
+// database that has one
+func getSinceColumns(ctx context.Context, db *sql.DB, dbName string) (map[string]string, error) {
+	query := `
+		SELECT TABLE_NAME, COLUMN_NAME
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND DATA_TYPE IN ('date', 'datetime', 'timestamp')
+		ORDER BY TABLE_NAME, ORDINAL_POSITION
+	`
+	ctx, cancel := withTimeout(ctx, dataTimeout)
+	defer cancel()
+
+	rows, err := cachedRows(ctx, db, dataMaxRetries, metacache.Key{Kind: metaDateColumns, Database: dbName}, query, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get date columns: %w", err)
+	}
+
+	columns := make(map[string]string)
+	rank := make(map[string]int)
+	for _, row := range rows {
+		table, column := row[0], row[1]
+		for i, name := range sinceColumnNames {
+			if !strings.EqualFold(column, name) {
+				continue
+			}
+			if current, ok := rank[table]; !ok || i < current {
+				columns[table], rank[table] = column, i
+			}
+			break
+		}
+	}
+	return columns, nil
+}
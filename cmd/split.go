@@ -0,0 +1,458 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mariadb-extractor/internal/checksum"
+)
+
+// scriptParts returns the numbered parts splitScript wrote for path, in order
+func scriptParts(path string) []string {
+	base := strings.TrimSuffix(path, ".sql")
+	matches, _ := filepath.Glob(base + ".part*.sql")
+
+	var parts []string
+	for _, match := range matches {
+		number := strings.TrimSuffix(strings.TrimPrefix(match, base+".part"), ".sql")
+		if number != "" && strings.Trim(number, "0123456789") == "" {
+			parts = append(parts, match)
+		}
+	}
+	// Numbers are zero-padded to the same width, so names sort in order
+	sort.Strings(parts)
+	return parts
+}
+
+// unitScripts returns the per-table or per-database scripts splitScriptByUnit
+// wrote for path, in import order
+func unitScripts(path string) []string {
+	dir := strings.TrimSuffix(path, ".sql")
+	matches, _ := filepath.Glob(filepath.Join(dir, "*-*.sql"))
+
+	var units []string
+	for _, match := range matches {
+		number, _, _ := strings.Cut(filepath.Base(match), "-")
+		if number != "" && strings.Trim(number, "0123456789") == "" {
+			units = append(units, match)
+		}
+	}
+	sort.Strings(units)
+	return units
+}
+
+// scriptFiles returns the files holding the script at path: the parts when it
+// was split by size, the unit scripts and their manifest when it was split by
+// unit, otherwise path itself
+func scriptFiles(path string) []string {
+	if parts := scriptParts(path); len(parts) > 0 {
+		return parts
+	}
+	manifest := filepath.Join(strings.TrimSuffix(path, ".sql"), "manifest.json")
+	if units := unitScripts(path); len(units) > 0 {
+		if _, err := os.Stat(manifest); err == nil {
+			return append(units, manifest)
+		}
+	}
+	return []string{path}
+}
+
+// removeScriptParts deletes parts and unit scripts left by an earlier split
+// of path, so a Docker init directory does not run them next to the new
+// script
+func removeScriptParts(path string) error {
+	parts := append(scriptParts(path), unitScripts(path)...)
+	manifest := filepath.Join(strings.TrimSuffix(path, ".sql"), "manifest.json")
+	if _, err := os.Stat(manifest); err == nil {
+		parts = append(parts, manifest)
+	}
+	for _, part := range parts {
+		if err := os.Remove(part); err != nil {
+			return fmt.Errorf("failed to remove old script part: %w", err)
+		}
+	}
+	if len(parts) > 0 {
+		return checksum.Forget(filepath.Dir(path), parts...)
+	}
+	return nil
+}
+
+// splitScript replaces the SQL script at path with numbered parts of at most
+// maxBytes each, cut between statements. Every part repeats the SET and USE
+// statements in effect where it starts, so the parts can run as separate
+// sessions, e.g. from /docker-entrypoint-initdb.d. A statement larger than
+// maxBytes gets a part of its own. Scripts already small enough are kept.
+func splitScript(path string, maxBytes int64) ([]string, error) {
+	if err := removeScriptParts(path); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split script: %w", err)
+	}
+	if maxBytes <= 0 || info.Size() <= maxBytes {
+		return []string{path}, nil
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split script: %w", err)
+	}
+	defer in.Close()
+
+	// Pad part numbers to the widest the split can reach
+	width := max(3, len(fmt.Sprint(2*info.Size()/maxBytes+1)))
+	base := strings.TrimSuffix(path, ".sql")
+
+	s := &scriptSplitter{maxBytes: maxBytes, sums: make(map[string]string)}
+	reader := bufio.NewReaderSize(in, 1<<20)
+	var statement strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		statement.WriteString(line)
+
+		// Statements end at a line ending in a semicolon; comments and
+		// blank lines stay with the statement that follows them
+		trimmed := strings.TrimSpace(line)
+		complete := strings.HasSuffix(trimmed, ";") && !strings.HasPrefix(trimmed, "--")
+		if complete || (err == io.EOF && statement.Len() > 0) {
+			if werr := s.write(base, width, statement.String()); werr != nil {
+				s.close()
+				return nil, werr
+			}
+			statement.Reset()
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("failed to read script: %w", err)
+		}
+	}
+	if err := s.close(); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	if err := checksum.Record(dir, s.sums); err != nil {
+		return nil, fmt.Errorf("failed to record checksum: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, fmt.Errorf("failed to remove split script: %w", err)
+	}
+	if err := checksum.Forget(dir, path); err != nil {
+		return nil, fmt.Errorf("failed to record checksum: %w", err)
+	}
+	return s.parts, nil
+}
+
+// scriptSplitter writes statements to the current part of a split script
+type scriptSplitter struct {
+	maxBytes int64
+	parts    []string
+	sums     map[string]string
+
+	file *os.File
+	out  *bufio.Writer
+	sum  *checksum.Writer
+	// statements counts the statements written to the part after its preamble
+	statements int
+
+	// settings are the session SET statements so far, keyed by variable
+	settings     map[string]string
+	settingOrder []string
+	use          string
+}
+
+func (s *scriptSplitter) write(base string, width int, statement string) error {
+	if s.file == nil || (s.statements > 0 && s.sum.Size()+int64(s.out.Buffered())+int64(len(statement)) > s.maxBytes) {
+		if err := s.next(base, width); err != nil {
+			return err
+		}
+	}
+	if _, err := s.out.WriteString(statement); err != nil {
+		return fmt.Errorf("failed to write script part: %w", err)
+	}
+	s.statements++
+	if int64(len(statement)) > s.maxBytes {
+		fmt.Printf("⚠️  A statement of %s exceeds the split size; %s holds it alone\n", formatBytes(int64(len(statement))), s.parts[len(s.parts)-1])
+	}
+
+	// Remember the session state later parts must restore
+	last := statement[strings.LastIndex(strings.TrimRight(statement, "\n"), "\n")+1:]
+	last = strings.TrimSpace(last)
+	upper := strings.ToUpper(last)
+	switch {
+	case strings.HasPrefix(upper, "USE "):
+		s.use = last
+	case strings.HasPrefix(upper, "SET "):
+		name, _, _ := strings.Cut(upper[4:], "=")
+		name = strings.TrimSpace(name)
+		if s.settings == nil {
+			s.settings = make(map[string]string)
+		}
+		if _, ok := s.settings[name]; !ok {
+			s.settingOrder = append(s.settingOrder, name)
+		}
+		s.settings[name] = last
+	}
+	return nil
+}
+
+// next closes the current part and starts the following one
+func (s *scriptSplitter) next(base string, width int) error {
+	if err := s.close(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s.part%0*d.sql", base, width, len(s.parts)+1)
+	file, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create script part: %w", err)
+	}
+	s.file = file
+	s.sum = checksum.NewWriter(file)
+	s.out = bufio.NewWriterSize(s.sum, 1<<20)
+	s.parts = append(s.parts, name)
+	s.statements = 0
+
+	if len(s.parts) > 1 {
+		fmt.Fprintf(s.out, "-- Part %d of %s, continued from %s\n", len(s.parts), filepath.Base(base)+".sql", filepath.Base(s.parts[len(s.parts)-2]))
+		for _, name := range s.settingOrder {
+			fmt.Fprintf(s.out, "%s\n", s.settings[name])
+		}
+		if s.use != "" {
+			fmt.Fprintf(s.out, "%s\n", s.use)
+		}
+		fmt.Fprintf(s.out, "\n")
+	}
+	return nil
+}
+
+func (s *scriptSplitter) close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.out.Flush()
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write script part: %w", err)
+	}
+	s.sums[s.file.Name()] = s.sum.Sum()
+	s.file = nil
+	return nil
+}
+
+// splitManifest describes the scripts of a split extraction in import order
+type splitManifest struct {
+	Source string              `json:"source"`
+	Split  string              `json:"split"`
+	Format string              `json:"format"`
+	Files  []splitManifestFile `json:"files"`
+}
+
+// splitManifestFile is one script of a split extraction. DataFiles are the
+// per-table files it loads, relative to the script.
+type splitManifestFile struct {
+	Order     int      `json:"order"`
+	File      string   `json:"file"`
+	Database  string   `json:"database"`
+	Tables    []string `json:"tables"`
+	Rows      int64    `json:"rows"`
+	DataFiles []string `json:"data_files,omitempty"`
+}
+
+// scriptUnit is a run of table sections going to the same unit script
+type scriptUnit struct {
+	database string
+	tables   []string
+}
+
+// splitScriptByUnit replaces the data script at path with one script per
+// table or per database (unit), numbered in import order in the directory
+// named after the script, and a manifest.json listing them with the rows of
+// each, taken from rows by db.table. Every script repeats the session
+// settings that precede the first table; the circular foreign key updates
+// and closing statements go to the last one.
+func splitScriptByUnit(path, unit, source string, rows map[string]int64) ([]string, error) {
+	if err := removeScriptParts(path); err != nil {
+		return nil, err
+	}
+
+	// A first pass finds the units, so the scripts can be numbered
+	var units []scriptUnit
+	err := scanTableSections(path, func(dbName, table string) {
+		if n := len(units); n > 0 && unit == "per-database" && units[n-1].database == dbName {
+			units[n-1].tables = append(units[n-1].tables, table)
+			return
+		}
+		units = append(units, scriptUnit{database: dbName, tables: []string{table}})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(units) == 0 {
+		return []string{path}, nil
+	}
+
+	dir := strings.TrimSuffix(path, ".sql")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create split output directory: %w", err)
+	}
+	width := max(3, len(fmt.Sprint(len(units))))
+	manifest := splitManifest{Source: source, Split: unit, Format: dataFormat, Files: []splitManifestFile{}}
+	for i, u := range units {
+		name := fmt.Sprintf("%0*d-%s.sql", width, i+1, u.database)
+		if unit == "per-table" {
+			name = fmt.Sprintf("%0*d-%s.%s.sql", width, i+1, u.database, u.tables[0])
+		}
+		file := splitManifestFile{Order: i + 1, File: name, Database: u.database, Tables: u.tables}
+		for _, table := range u.tables {
+			file.Rows += rows[u.database+"."+table]
+			if dataFormat != "sql" {
+				file.DataFiles = append(file.DataFiles, fmt.Sprintf("%s.%s.%s", u.database, table, tableFileExtension()))
+			}
+		}
+		manifest.Files = append(manifest.Files, file)
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to split script: %w", err)
+	}
+	defer in.Close()
+
+	s := &scriptSplitter{sums: make(map[string]string)}
+	var preamble strings.Builder
+	current := -1
+	reader := bufio.NewReaderSize(in, 1<<20)
+	for {
+		line, err := reader.ReadString('\n')
+		// Units start at the same tables as in the first pass
+		if dbName, _, ok := tableSectionHeader(line); ok && (current < 0 || unit == "per-table" || units[current].database != dbName) {
+			current++
+			if err := s.nextUnit(filepath.Join(dir, manifest.Files[current].File), preamble.String()); err != nil {
+				s.close()
+				return nil, err
+			}
+		}
+		if current < 0 {
+			preamble.WriteString(line)
+		} else if _, werr := s.out.WriteString(line); werr != nil {
+			s.close()
+			return nil, fmt.Errorf("failed to write unit script: %w", werr)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("failed to read script: %w", err)
+		}
+	}
+	if err := s.close(); err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	manifestPath := filepath.Join(dir, "manifest.json")
+	if err := os.WriteFile(manifestPath, append(data, '\n'), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write split manifest: %w", err)
+	}
+
+	outputDir := filepath.Dir(path)
+	if err := checksum.Record(outputDir, s.sums); err != nil {
+		return nil, fmt.Errorf("failed to record checksum: %w", err)
+	}
+	if _, err := checksum.RecordFiles(outputDir, manifestPath); err != nil {
+		return nil, fmt.Errorf("failed to record checksum: %w", err)
+	}
+	if err := os.Remove(path); err != nil {
+		return nil, fmt.Errorf("failed to remove split script: %w", err)
+	}
+	if err := checksum.Forget(outputDir, path); err != nil {
+		return nil, fmt.Errorf("failed to record checksum: %w", err)
+	}
+	return append(s.parts, manifestPath), nil
+}
+
+// scanTableSections calls fn with the database and table of each table
+// section of the data script at path
+func scanTableSections(path string, fn func(dbName, table string)) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to split script: %w", err)
+	}
+	defer in.Close()
+
+	reader := bufio.NewReaderSize(in, 1<<20)
+	for {
+		line, err := reader.ReadString('\n')
+		if dbName, table, ok := tableSectionHeader(line); ok {
+			fn(dbName, table)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read script: %w", err)
+		}
+	}
+}
+
+// tableSectionHeader parses the "-- Table: db.table" line extractTableData
+// starts each table with
+func tableSectionHeader(line string) (dbName, table string, ok bool) {
+	name, found := strings.CutPrefix(strings.TrimRight(line, "\r\n"), "-- Table: ")
+	if !found {
+		return "", "", false
+	}
+	return strings.Cut(name, ".")
+}
+
+// nextUnit closes the current unit script and starts the one at name with
+// preamble
+func (s *scriptSplitter) nextUnit(name, preamble string) error {
+	if err := s.close(); err != nil {
+		return err
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to create unit script: %w", err)
+	}
+	s.file = file
+	s.sum = checksum.NewWriter(file)
+	s.out = bufio.NewWriterSize(s.sum, 1<<20)
+	s.parts = append(s.parts, name)
+	if _, err := s.out.WriteString(preamble); err != nil {
+		return fmt.Errorf("failed to write unit script: %w", err)
+	}
+	return nil
+}
+
+// parseSplitSize parses a --split-size value; empty means no splitting
+func parseSplitSize(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	size, err := parseByteSize(value)
+	if err != nil || size < 64*1024 {
+		return 0, fmt.Errorf("invalid --split-size %q: must be a size of at least 64KB, e.g. 64MB", value)
+	}
+	return size, nil
+}
@@ -0,0 +1,84 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"hash/crc64"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// tableSumAlgorithm names the checksum of tableSum in manifests
+const tableSumAlgorithm = "crc64sum"
+
+// dataTableSum is the checksum of the table being extracted, nil when it
+// gets none
+var dataTableSum *tableSum
+
+var crc64Table = crc64.MakeTable(crc64.ECMA)
+
+// tableSum is an order-independent checksum of the rows of a table: the sum,
+// wrapping at 64 bits, of the CRC-64 of each row's values as SQL literals.
+// Rows are summed after transforms and masks, as they are written, so the
+// checksum can be recomputed from the rows imported on a target whatever
+// order they are read in. It is safe for concurrent use by the segments of
+// a table and on nil.
+type tableSum struct {
+	sum atomic.Uint64
+
+	once    sync.Once
+	columns []string
+}
+
+// begin records the columns summed, the same for every segment
+func (s *tableSum) begin(columns []string) {
+	if s != nil {
+		s.once.Do(func() { s.columns = append([]string(nil), columns...) })
+	}
+}
+
+// add sums one row
+func (s *tableSum) add(values []interface{}, precisions []int) {
+	if s != nil {
+		s.sum.Add(rowSum(values, precisions))
+	}
+}
+
+// String formats the checksum as algorithm:hex
+func (s *tableSum) String() string {
+	return fmt.Sprintf("%s:%016x", tableSumAlgorithm, s.sum.Load())
+}
+
+// rowSum is the CRC-64 of a row's values as SQL text literals, separated by
+// commas. Binary and encrypted columns are summed as text too, so their sum
+// does not depend on how the column is declared on the target.
+func rowSum(values []interface{}, precisions []int) uint64 {
+	var crc uint64
+	for i, v := range values {
+		if i > 0 {
+			crc = crc64.Update(crc, crc64Table, []byte{','})
+		}
+		crc = crc64.Update(crc, crc64Table, []byte(formatSQLColumnValue(sumValue(v), textColumn, precisions[i])))
+	}
+	return crc
+}
+
+// sumValue returns numbers as the text a plain query reads them as. Queries
+// with arguments, such as keyset chunks after the first, read them as
+// numbers, which would otherwise sum differently.
+func sumValue(v interface{}) interface{} {
+	switch n := v.(type) {
+	case int64:
+		return []byte(strconv.FormatInt(n, 10))
+	case uint64:
+		return []byte(strconv.FormatUint(n, 10))
+	case float64:
+		return []byte(strconv.FormatFloat(n, 'g', -1, 64))
+	case float32:
+		return []byte(strconv.FormatFloat(float64(n), 'g', -1, 32))
+	}
+	return v
+}
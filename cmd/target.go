@@ -0,0 +1,192 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// dataTarget executes the extracted statements on --target-dsn, nil when
+// the output is only written to a file
+var dataTarget *targetWriter
+
+// targetWriter executes the SQL script written to it statement by statement
+// on one session of a target server, so rows reach a development database
+// as they are extracted, in the script's foreign key order and INSERT
+// batches. Comment lines between statements are skipped. Once a statement
+// fails, every later write returns its error.
+type targetWriter struct {
+	ctx  context.Context
+	db   *sql.DB
+	conn *sql.Conn
+	addr string
+
+	// buf holds the statement being written; scanned bytes of it are past
+	// the quote and comment state below
+	buf     []byte
+	scanned int
+	quote   byte
+	escaped bool
+	comment bool
+	blank   bool
+
+	statements int64
+	err        error
+}
+
+// parseTargetDSN parses a --target-dsn, a Go MySQL driver DSN such as
+// user:password@tcp(localhost:3306)/
+func parseTargetDSN(dsn string) (*mysql.Config, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --target-dsn: %w", err)
+	}
+	if cfg.Net != "tcp" && cfg.Net != "unix" {
+		return nil, fmt.Errorf("invalid --target-dsn: unsupported network %q", cfg.Net)
+	}
+	return cfg, nil
+}
+
+// openTarget connects to the target of --target-dsn, which must not be the
+// source server
+func openTarget(ctx context.Context, dsn string) (*targetWriter, error) {
+	cfg, err := parseTargetDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Net == "tcp" && cfg.Addr == fmt.Sprintf("%s:%d", dataHost, dataPort) {
+		return nil, fmt.Errorf("--target-dsn points at the source server %s", cfg.Addr)
+	}
+	if cfg.Params == nil {
+		cfg.Params = make(map[string]string)
+	}
+	if _, ok := cfg.Params["charset"]; !ok {
+		cfg.Params["charset"] = "utf8mb4"
+	}
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to target: %w", err)
+	}
+	// Session settings of the script apply to the statements after them, so
+	// everything runs on one connection
+	conn, err := db.Conn(ctx)
+	if err == nil {
+		err = conn.PingContext(ctx)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to target %s: %w", cfg.Addr, err)
+	}
+	return &targetWriter{ctx: ctx, db: db, conn: conn, addr: cfg.Addr, blank: true}, nil
+}
+
+func (t *targetWriter) Write(p []byte) (int, error) {
+	if t.err != nil {
+		return 0, t.err
+	}
+	t.buf = append(t.buf, p...)
+
+	start := 0
+	i := t.scanned
+scan:
+	for ; i < len(t.buf); i++ {
+		c := t.buf[i]
+		switch {
+		case t.comment:
+			if c == '\n' {
+				t.comment = false
+				start = i + 1
+			}
+		case t.escaped:
+			t.escaped = false
+		case t.quote != 0:
+			if c == '\\' && t.quote != '`' {
+				t.escaped = true
+			} else if c == t.quote {
+				t.quote = 0
+			}
+		case c == '-' && t.blank:
+			// A comment line, unless this starts a statement such as a
+			// negative number, which the script never does
+			if i+1 == len(t.buf) {
+				break scan
+			}
+			if t.buf[i+1] == '-' {
+				t.comment = true
+			} else {
+				t.blank = false
+			}
+		case c == ';':
+			if err := t.exec(t.buf[start:i]); err != nil {
+				t.err = err
+				return 0, err
+			}
+			start = i + 1
+			t.blank = true
+		case c == '\'' || c == '"' || c == '`':
+			t.quote = c
+			t.blank = false
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			t.blank = false
+		}
+		if t.blank && !t.comment && (c == ' ' || c == '\t' || c == '\n' || c == '\r') {
+			start = i + 1
+		}
+	}
+	t.buf = append(t.buf[:0], t.buf[start:]...)
+	t.scanned = i - start
+	return len(p), nil
+}
+
+// exec runs one statement on the target
+func (t *targetWriter) exec(statement []byte) error {
+	statement = bytes.TrimSpace(statement)
+	if len(statement) == 0 {
+		return nil
+	}
+	if _, err := t.conn.ExecContext(t.ctx, string(statement)); err != nil {
+		if len(statement) > 80 {
+			statement = append(statement[:77:77], "..."...)
+		}
+		return fmt.Errorf("target %s: %w (statement: %s)", t.addr, err, statement)
+	}
+	t.statements++
+	return nil
+}
+
+// failure returns the error that stopped the target, if any. It is safe
+// on nil.
+func (t *targetWriter) failure() error {
+	if t == nil {
+		return nil
+	}
+	return t.err
+}
+
+// finish fails when the script ended in the middle of a statement, and
+// otherwise reports the statements executed. It is safe on nil.
+func (t *targetWriter) finish() error {
+	if t == nil {
+		return nil
+	}
+	if t.err != nil {
+		return t.err
+	}
+	if !t.blank {
+		return fmt.Errorf("target %s: the script ends with an incomplete statement", t.addr)
+	}
+	fmt.Printf("🎯 Executed %d statements on %s\n", t.statements, t.addr)
+	return nil
+}
+
+func (t *targetWriter) close() {
+	t.conn.Close()
+	t.db.Close()
+}
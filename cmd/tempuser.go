@@ -0,0 +1,90 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"mariadb-extractor/internal/snapshot"
+)
+
+// temporaryUser is a read-only account created for one run by
+// --temp-user and dropped when it ends
+type temporaryUser struct {
+	user     string
+	host     string
+	password string
+}
+
+func (u temporaryUser) account() string {
+	return fmt.Sprintf("'%s'@'%s'", strings.ReplaceAll(u.user, "'", "''"), strings.ReplaceAll(u.host, "'", "''"))
+}
+
+// createTemporaryUser creates a user that can only read databases, or every
+// database when none are given, from the host the admin connection comes
+// from. Its password expires after ttlDays, so an account left behind by a
+// crashed run stops working on its own; servers without password expiry
+// get the account without it, with a warning.
+func createTemporaryUser(ctx context.Context, admin *sql.DB, databases []string, ttlDays int) (*temporaryUser, error) {
+	var current string
+	if err := admin.QueryRowContext(ctx, annotateQuery("SELECT USER()")).Scan(&current); err != nil {
+		return nil, fmt.Errorf("failed to read the connection's host: %w", err)
+	}
+	_, host, _ := strings.Cut(current, "@")
+
+	suffix := make([]byte, 2)
+	secret := make([]byte, 16)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	u := &temporaryUser{
+		user: "mxtmp_" + strings.ToLower(snapshot.NewRunID(time.Now())) + "_" + hex.EncodeToString(suffix),
+		host: host,
+		// Mixed case, digits and a symbol satisfy password validation plugins
+		password: hex.EncodeToString(secret) + "Aa1_",
+	}
+
+	create := fmt.Sprintf("CREATE USER %s IDENTIFIED BY '%s'", u.account(), u.password)
+	if _, err := admin.ExecContext(ctx, annotateQuery(fmt.Sprintf("%s PASSWORD EXPIRE INTERVAL %d DAY", create, ttlDays))); err != nil {
+		fmt.Printf("⚠️  Warning: the server does not support password expiry (%v); %s has no TTL\n", err, u.user)
+		if _, err := admin.ExecContext(ctx, annotateQuery(create)); err != nil {
+			return nil, fmt.Errorf("failed to create temporary user: %w", err)
+		}
+	}
+
+	scopes := []string{"*.*"}
+	if len(databases) > 0 {
+		scopes = nil
+		for _, dbName := range databases {
+			scopes = append(scopes, fmt.Sprintf("`%s`.*", dbName))
+		}
+	}
+	for _, scope := range scopes {
+		if _, err := admin.ExecContext(ctx, annotateQuery(fmt.Sprintf("GRANT SELECT, SHOW VIEW ON %s TO %s", scope, u.account()))); err != nil {
+			u.drop(admin)
+			return nil, fmt.Errorf("failed to grant read access to temporary user: %w", err)
+		}
+	}
+	return u, nil
+}
+
+// drop removes the user, also after the run was cancelled
+func (u *temporaryUser) drop(admin *sql.DB) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := admin.ExecContext(ctx, annotateQuery("DROP USER IF EXISTS "+u.account())); err != nil {
+		fmt.Printf("⚠️  Warning: failed to drop temporary user %s: %v\n", u.user, err)
+		return
+	}
+	fmt.Printf("🔑 Dropped temporary user %s\n", u.user)
+}
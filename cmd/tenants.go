@@ -0,0 +1,422 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"mariadb-extractor/internal/checksum"
+	"mariadb-extractor/internal/sink"
+)
+
+// tenantBufferSize is how much tenant output is buffered before it is
+// appended to the files. Files are opened for each append, so a run with
+// thousands of tenants does not run out of file descriptors.
+const tenantBufferSize = 4 << 20
+
+// sharedTenant names the shared script among the tenant scripts
+const sharedTenant = ""
+
+// tenantOutputs writes the --partition-by-column exports next to the full
+// extract: output/<prefix>/tenants/<value>/<prefix>.sql holds the rows of one
+// value of the column from every table that has it, and tenants/shared.sql
+// the tables that do not. With --format csv the tenant directories also hold
+// the tenant's table files.
+type tenantOutputs struct {
+	column string
+	dir    string
+	script string
+
+	files   map[string]bool
+	scripts map[string]bool
+	pending map[string]*bytes.Buffer
+	size    int
+
+	// The table being extracted
+	index       int
+	tableHeader []byte
+	started     map[string]bool
+	batches     map[string]*tenantBatch
+	loaded      map[string]bool
+
+	rows         map[string]int64
+	sharedTables int
+	unassigned   int64
+}
+
+// tenantBatch is a tenant's pending INSERT statement
+type tenantBatch struct {
+	buf  bytes.Buffer
+	rows int
+}
+
+// newTenantOutputs replaces the tenant exports of an earlier run with the
+// same --output
+func newTenantOutputs(outputDir, column string) (*tenantOutputs, error) {
+	prefix := sink.Prefix(dataOutput)
+	t := &tenantOutputs{
+		column:  column,
+		dir:     filepath.Join(outputDir, prefix, "tenants"),
+		script:  filepath.Base(prefix) + ".sql",
+		files:   make(map[string]bool),
+		scripts: make(map[string]bool),
+		pending: make(map[string]*bytes.Buffer),
+		rows:    make(map[string]int64),
+		index:   -1,
+	}
+
+	var stale []string
+	filepath.WalkDir(t.dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			stale = append(stale, path)
+		}
+		return nil
+	})
+	if len(stale) > 0 {
+		if err := checksum.Forget(outputDir, stale...); err != nil {
+			return nil, err
+		}
+	}
+	if err := os.RemoveAll(t.dir); err != nil {
+		return nil, fmt.Errorf("failed to remove earlier tenant exports: %w", err)
+	}
+	return t, nil
+}
+
+// tenantDirName escapes a tenant value for use as a directory name. Letters,
+// digits, '-' and '_' are kept and other bytes written as %XX.
+func tenantDirName(tenant string) string {
+	var b strings.Builder
+	for i := 0; i < len(tenant); i++ {
+		c := tenant[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// tenantDir returns the directory of a tenant's export
+func (t *tenantOutputs) tenantDir(tenant string) string {
+	if tenant == sharedTenant {
+		return t.dir
+	}
+	return filepath.Join(t.dir, tenantDirName(tenant))
+}
+
+// scriptPath returns the path of a tenant's script
+func (t *tenantOutputs) scriptPath(tenant string) string {
+	if tenant == sharedTenant {
+		return filepath.Join(t.dir, "shared.sql")
+	}
+	return filepath.Join(t.tenantDir(tenant), t.script)
+}
+
+// scriptHeader returns the start of a tenant's script
+func (t *tenantOutputs) scriptHeader(tenant string) []byte {
+	var b bytes.Buffer
+	if tenant == sharedTenant {
+		fmt.Fprintf(&b, "-- MariaDB Data Extract: tables without a %s column\n", t.column)
+	} else {
+		fmt.Fprintf(&b, "-- MariaDB Data Extract: rows with %s = %s\n", t.column, formatSQLValue(tenant))
+	}
+	fmt.Fprintf(&b, "-- Source: %s:%d\n\n", dataHost, dataPort)
+	if dataFormat == "csv" {
+		name := filepath.Base(t.scriptPath(tenant))
+		if tenant == sharedTenant {
+			fmt.Fprintf(&b, "-- Table data is in the parent directory; load from this directory with:\n")
+		} else {
+			fmt.Fprintf(&b, "-- Table data is in this directory; load from it with:\n")
+		}
+		fmt.Fprintf(&b, "--   mysql --local-infile=1 < %s\n\n", name)
+	}
+	writeSessionHeader(&b)
+	return b.Bytes()
+}
+
+// write buffers p for appending to the file at path
+func (t *tenantOutputs) write(path string, p []byte) error {
+	t.files[path] = true
+	buf := t.pending[path]
+	if buf == nil {
+		buf = new(bytes.Buffer)
+		t.pending[path] = buf
+	}
+	buf.Write(p)
+	t.size += len(p)
+	if t.size >= tenantBufferSize {
+		return t.flush()
+	}
+	return nil
+}
+
+// flush appends the buffered output to the files
+func (t *tenantOutputs) flush() error {
+	for path, buf := range t.pending {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create tenant directory: %w", err)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open tenant file: %w", err)
+		}
+		_, err = file.Write(buf.Bytes())
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write tenant file: %w", err)
+		}
+	}
+	clear(t.pending)
+	t.size = 0
+	return nil
+}
+
+// writeScript writes p to a tenant's script, starting the script and the
+// current table's section in it as needed
+func (t *tenantOutputs) writeScript(tenant string, p []byte) error {
+	path := t.scriptPath(tenant)
+	if !t.scripts[path] {
+		t.scripts[path] = true
+		if err := t.write(path, t.scriptHeader(tenant)); err != nil {
+			return err
+		}
+	}
+	if !t.started[tenant] {
+		t.started[tenant] = true
+		if err := t.write(path, t.tableHeader); err != nil {
+			return err
+		}
+	}
+	return t.write(path, p)
+}
+
+// sharedScript writes to the shared script
+type sharedScript struct {
+	t *tenantOutputs
+}
+
+func (s sharedScript) Write(p []byte) (int, error) {
+	if err := s.t.writeScript(sharedTenant, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// shared returns a writer to the shared script
+func (t *tenantOutputs) shared() io.Writer {
+	return sharedScript{t}
+}
+
+// beginTable starts a table whose header, as written to the full extract, is
+// header, and returns the index of the partition column among columns, or -1
+// for a shared table
+func (t *tenantOutputs) beginTable(header []byte, columns []string) int {
+	t.tableHeader = header
+	t.started = make(map[string]bool)
+	t.batches = make(map[string]*tenantBatch)
+	t.loaded = make(map[string]bool)
+	t.index = -1
+	for i, col := range columns {
+		if strings.EqualFold(col, t.column) {
+			t.index = i
+			return i
+		}
+	}
+	t.sharedTables++
+	return -1
+}
+
+// tenantColumn returns the index of the partition column in the current
+// table, or -1 when its rows are not partitioned
+func (t *tenantOutputs) tenantColumn() int {
+	if t == nil {
+		return -1
+	}
+	return t.index
+}
+
+// tenant returns the tenant of a partition column value. NULL and empty
+// values belong to no tenant.
+func (t *tenantOutputs) tenant(v interface{}) (string, bool) {
+	var s string
+	switch v := v.(type) {
+	case nil:
+	case []byte:
+		s = string(v)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" {
+		t.unassigned++
+		return "", false
+	}
+	t.rows[s]++
+	return s, true
+}
+
+// insertRow adds a row of formatted SQL values to the tenant's INSERT batch,
+// cut by --batch-size and --max-statement-bytes like the full extract's
+func (t *tenantOutputs) insertRow(tenant, table, columnList string, rowValues []string) error {
+	b := t.batches[tenant]
+	if b == nil {
+		b = &tenantBatch{}
+		t.batches[tenant] = b
+	}
+	rowSize := int64(len(rowValues) + 1)
+	for _, v := range rowValues {
+		rowSize += int64(len(v))
+	}
+	if b.rows > 0 && int64(b.buf.Len())+2+rowSize+2 > dataStatementLimit {
+		if err := t.flushBatch(tenant, b); err != nil {
+			return err
+		}
+	}
+	if b.rows == 0 {
+		fmt.Fprintf(&b.buf, "INSERT INTO `%s`%s VALUES\n", table, columnList)
+	} else {
+		b.buf.WriteString(",\n")
+	}
+	b.buf.WriteByte('(')
+	b.buf.WriteString(strings.Join(rowValues, ","))
+	b.buf.WriteByte(')')
+	b.rows++
+	if b.rows >= dataBatchSize || int64(b.buf.Len())+2 >= dataStatementLimit {
+		return t.flushBatch(tenant, b)
+	}
+	return nil
+}
+
+// flushBatch ends a tenant's INSERT statement
+func (t *tenantOutputs) flushBatch(tenant string, b *tenantBatch) error {
+	if b.rows == 0 {
+		return nil
+	}
+	b.buf.WriteString(";\n")
+	err := t.writeScript(tenant, b.buf.Bytes())
+	b.buf.Reset()
+	b.rows = 0
+	return err
+}
+
+// writeCSVRow appends a row to the tenant's copy of the table file named
+// file, starting it with the header row
+func (t *tenantOutputs) writeCSVRow(tenant, file string, columns []string, values []interface{}) error {
+	var line bytes.Buffer
+	path := filepath.Join(t.tenantDir(tenant), file)
+	if !t.files[path] {
+		for i, col := range columns {
+			if i > 0 {
+				line.WriteByte(',')
+			}
+			line.WriteString(csvQuote(col))
+		}
+		line.WriteByte('\n')
+	}
+	for i, v := range values {
+		if i > 0 {
+			line.WriteByte(',')
+		}
+		line.WriteString(formatCSVValue(v))
+	}
+	line.WriteByte('\n')
+	t.loaded[tenant] = true
+	return t.write(path, line.Bytes())
+}
+
+// loadTableFiles writes the LOAD DATA statements for the table file named
+// file: the full extract's to the shared script for a shared table, else each
+// tenant's copy to its script
+func (t *tenantOutputs) loadTableFiles(table, file string, columns []string, kinds []columnKind) error {
+	if t == nil {
+		return nil
+	}
+	var b bytes.Buffer
+	if t.index < 0 {
+		writeLoadDataStatement(&b, "../"+file, table, columns, kinds)
+		return t.writeScript(sharedTenant, b.Bytes())
+	}
+	for _, tenant := range sortedTenants(t.loaded) {
+		b.Reset()
+		writeLoadDataStatement(&b, file, table, columns, kinds)
+		if err := t.writeScript(tenant, b.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endTable ends the current table's pending INSERT statements
+func (t *tenantOutputs) endTable() error {
+	if t == nil {
+		return nil
+	}
+	for _, tenant := range sortedTenants(t.batches) {
+		if err := t.flushBatch(tenant, t.batches[tenant]); err != nil {
+			return err
+		}
+		if dataFormat == "sql" {
+			if err := t.writeScript(tenant, []byte("\n")); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// finish ends every script, writes out the buffered output and records the
+// checksums of the tenant exports
+func (t *tenantOutputs) finish(outputDir string) error {
+	if t == nil {
+		return nil
+	}
+	for path := range t.scripts {
+		var footer bytes.Buffer
+		writeSessionFooter(&footer)
+		if err := t.write(path, footer.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := t.flush(); err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(t.files))
+	for path := range t.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if _, err := checksum.RecordFiles(outputDir, paths...); err != nil {
+		return err
+	}
+
+	fmt.Printf("🏢 Wrote %d tenant exports by %s to %s", len(t.rows), t.column, t.dir)
+	if t.sharedTables > 0 {
+		fmt.Printf(", %d tables without the column in shared.sql", t.sharedTables)
+	}
+	fmt.Printf("\n")
+	if t.unassigned > 0 {
+		fmt.Printf("⚠️  Warning: %d rows have no %s value and are only in the full extract\n", t.unassigned, t.column)
+	}
+	return nil
+}
+
+// sortedTenants returns the keys of a per-tenant map in order
+func sortedTenants[V any](m map[string]V) []string {
+	tenants := make([]string, 0, len(m))
+	for tenant := range m {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
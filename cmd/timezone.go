@@ -0,0 +1,111 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// utcTimeZoneParam makes the server send TIMESTAMP values in UTC for
+// --timestamp-zone utc; DSN parameters are session variables and their
+// values are URL-encoded
+const utcTimeZoneParam = "&time_zone=%27%2B00%3A00%27"
+
+// sourceTimeZone is the time zone TIMESTAMP values are read in
+type sourceTimeZone struct {
+	// name is @@global.time_zone, with the system zone in parentheses when
+	// it is SYSTEM
+	name string
+	// offset is the server's current UTC offset as +hh:mm
+	offset string
+}
+
+// detectTimeZone reads the source server's time zone
+func detectTimeZone(ctx context.Context, db *sql.DB) (sourceTimeZone, error) {
+	var tz sourceTimeZone
+	var system, diff string
+	err := queryRowWithRetry(ctx, db, dataMaxRetries,
+		"SELECT @@global.time_zone, @@system_time_zone, TIMEDIFF(NOW(), UTC_TIMESTAMP())", nil,
+		&tz.name, &system, &diff)
+	if err != nil {
+		return tz, fmt.Errorf("failed to read server time zone: %w", err)
+	}
+	if tz.name == "SYSTEM" {
+		tz.name = fmt.Sprintf("SYSTEM (%s)", system)
+	}
+
+	// TIMEDIFF gives hh:mm:ss, negative with a leading minus
+	sign := "+"
+	if rest, ok := strings.CutPrefix(diff, "-"); ok {
+		sign, diff = "-", rest
+	}
+	if len(diff) >= 5 {
+		diff = diff[:len(diff)-3]
+	}
+	if len(diff) == 4 {
+		diff = "0" + diff
+	}
+	tz.offset = sign + diff
+	return tz, nil
+}
+
+// timeZoneHeader returns the statement setting the import session's time
+// zone for --timestamp-zone, or "" for none. The source zone is pinned to
+// its current UTC offset, since the target may lack the time zone tables to
+// resolve its name.
+func timeZoneHeader(tz sourceTimeZone) string {
+	switch dataTimestampZone {
+	case "utc":
+		return "SET @OLD_TIME_ZONE=@@TIME_ZONE, TIME_ZONE='+00:00';"
+	case "source":
+		return fmt.Sprintf("SET @OLD_TIME_ZONE=@@TIME_ZONE, TIME_ZONE='%s';", tz.offset)
+	}
+	return ""
+}
+
+// importTimeZone returns the session time zone TIMESTAMP values of the
+// output are imported in, or "" for the importing session's own
+func importTimeZone(tz sourceTimeZone) string {
+	switch dataTimestampZone {
+	case "utc":
+		return "+00:00"
+	case "source":
+		return tz.offset
+	}
+	return ""
+}
+
+// checkTimestampZone records the source time zone in dataTimeZone and checks
+// that --timestamp-zone utc reads in UTC, which a connection opened without
+// the time_zone session parameter, such as a pipeline's, does not
+func checkTimestampZone(ctx context.Context, db *sql.DB) error {
+	tz, err := detectTimeZone(ctx, db)
+	if err != nil {
+		if dataTimestampZone == "source" {
+			return err
+		}
+		fmt.Printf("⚠️  Warning: %v\n", err)
+	}
+	dataTimeZone = tz
+
+	switch dataTimestampZone {
+	case "utc":
+		var session string
+		if err := queryRowWithRetry(ctx, db, dataMaxRetries, "SELECT @@session.time_zone", nil, &session); err != nil {
+			return fmt.Errorf("failed to read session time zone: %w", err)
+		}
+		if session != "+00:00" {
+			return fmt.Errorf("--timestamp-zone utc needs a connection in UTC, but the session time zone is %s", session)
+		}
+	case "source":
+		if !strings.HasPrefix(tz.name, "+") && !strings.HasPrefix(tz.name, "-") {
+			fmt.Printf("⚠️  Warning: time zone %s is pinned to its current offset UTC%s; TIMESTAMP values from the other side of a daylight saving change import shifted (use --timestamp-zone utc)\n",
+				tz.name, tz.offset)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// defaultTrashPatterns match backup, scratch and test copies of databases,
+// including names suffixed with a date or Unix timestamp
+var defaultTrashPatterns = []string{
+	"backup", "bak", "bkp", "old", "temp", "tmp", "test", "copy",
+	"archive", "dump", "save", "restore",
+	`_\d{8}$`, `_\d{4}-\d{2}-\d{2}$`, `_\d{10,}$`,
+}
+
+var (
+	// trashPatterns are case-insensitive regular expressions; a database
+	// whose name matches any of them is skipped by ddl, data and dump
+	trashPatterns []string
+	noSkipTrash   bool
+
+	// trashSkipped lists the databases skipped as trash by the current
+	// command, for its summary and the pipeline manifest
+	trashSkipped []string
+)
+
+func init() {
+	defaultPatterns := defaultTrashPatterns
+	if env := strings.Fields(os.Getenv("MARIADB_TRASH_PATTERNS")); len(env) > 0 {
+		defaultPatterns = env
+	}
+	rootCmd.PersistentFlags().StringArrayVar(&trashPatterns, "trash-pattern", defaultPatterns,
+		"Regular expression for names of backup or scratch databases to skip; repeatable, replaces the defaults (env: MARIADB_TRASH_PATTERNS, space-separated)")
+	rootCmd.PersistentFlags().BoolVar(&noSkipTrash, "no-skip-trash", os.Getenv("MARIADB_NO_SKIP_TRASH") == "true",
+		"Process databases matching --trash-pattern instead of skipping them (env: MARIADB_NO_SKIP_TRASH)")
+}
+
+// compiledTrashPatterns compiles --trash-pattern once per process
+var compiledTrashPatterns = sync.OnceValues(func() ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, pattern := range trashPatterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --trash-pattern %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+})
+
+// trashPattern returns the --trash-pattern dbName matches, or "" when the
+// database is kept. Nothing matches with --no-skip-trash.
+func trashPattern(dbName string) (string, error) {
+	if noSkipTrash {
+		return "", nil
+	}
+	patterns, err := compiledTrashPatterns()
+	if err != nil {
+		return "", err
+	}
+	for i, re := range patterns {
+		if re.MatchString(dbName) {
+			return trashPatterns[i], nil
+		}
+	}
+	return "", nil
+}
+
+// skipTrash records dbName as skipped and prints why
+func skipTrash(prefix, dbName, pattern string) {
+	trashSkipped = append(trashSkipped, dbName)
+	fmt.Printf("%s⏭️  Skipping trash database: %s (matches %q; use --no-skip-trash to include it)\n", prefix, dbName, pattern)
+}
+
+// printTrashSkipped lists the databases skipped as trash in a summary
+func printTrashSkipped(indent string) {
+	if len(trashSkipped) == 0 {
+		fmt.Printf("%sSkipped (trash): 0\n", indent)
+		return
+	}
+	fmt.Printf("%sSkipped (trash): %d (%s)\n", indent, len(trashSkipped), strings.Join(trashSkipped, ", "))
+}
@@ -0,0 +1,316 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// usageReportCmd represents the usage-report command
+var usageReportCmd = &cobra.Command{
+	Use:   "usage-report",
+	Short: "Report which tables and indexes are read or written",
+	Long: `Sample performance_schema table I/O counters over a time window and report
+the reads and writes of every table and index. Tables nobody touched are
+candidates to exclude from extraction and to archive; indexes nobody read
+only slow down writes.
+
+With --window 0 the counters since the server started are used instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runUsageReport(cmd.Context())
+	},
+}
+
+var (
+	usageHost       string
+	usagePort       int
+	usageUser       string
+	usagePassword   string
+	usageTimeout    int
+	usageMaxRetries int
+	usageDatabases  []string
+	usageWindow     time.Duration
+	usageFormat     string
+)
+
+// usageCounts are table I/O operations counted by performance_schema
+type usageCounts struct {
+	Reads  int64 `json:"reads"`
+	Writes int64 `json:"writes"`
+}
+
+// usageIndex is the I/O through one index of a table
+type usageIndex struct {
+	Name   string `json:"name"`
+	Unique bool   `json:"unique"`
+	usageCounts
+}
+
+// usageTable is the I/O of one table over the window
+type usageTable struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	usageCounts
+	Indexes []usageIndex `json:"indexes"`
+}
+
+// usageReport is the result of a usage-report run
+type usageReport struct {
+	Window        string       `json:"window"`
+	Tables        []usageTable `json:"tables"`
+	UnusedTables  []string     `json:"unused_tables"`
+	UnusedIndexes []string     `json:"unused_indexes"`
+}
+
+func init() {
+	rootCmd.AddCommand(usageReportCmd)
+
+	defaultUser := os.Getenv("MARIADB_USER")
+	defaultPassword := os.Getenv("MARIADB_PASSWORD")
+
+	usageReportCmd.Flags().StringVarP(&usageHost, "host", "H", getEnvWithDefault("MARIADB_HOST", "localhost"), "MariaDB host (env: MARIADB_HOST)")
+	usageReportCmd.Flags().IntVarP(&usagePort, "port", "P", getEnvIntWithDefault("MARIADB_PORT", 3306), "MariaDB port (env: MARIADB_PORT)")
+	usageReportCmd.Flags().StringVarP(&usageUser, "user", "u", defaultUser, "MariaDB username (env: MARIADB_USER)")
+	usageReportCmd.Flags().StringVarP(&usagePassword, "password", "p", defaultPassword, "MariaDB password (env: MARIADB_PASSWORD)")
+	usageReportCmd.Flags().IntVarP(&usageTimeout, "timeout", "t", getEnvIntWithDefault("MARIADB_TIMEOUT", 300), "Query timeout in seconds (env: MARIADB_TIMEOUT)")
+	usageReportCmd.Flags().IntVar(&usageMaxRetries, "max-retries", getEnvIntWithDefault("MARIADB_MAX_RETRIES", 3), "Maximum retry attempts for failed queries (env: MARIADB_MAX_RETRIES)")
+	usageReportCmd.Flags().StringSliceVarP(&usageDatabases, "databases", "d", []string{}, "Databases to report on (default: all user databases)")
+	usageReportCmd.Flags().DurationVar(&usageWindow, "window", 5*time.Minute, "How long to sample the counters, e.g. 1h (0 = since server start)")
+	usageReportCmd.Flags().StringVar(&usageFormat, "format", "text", "Report format: text or json")
+
+	if defaultUser == "" {
+		usageReportCmd.MarkFlagRequired("user")
+	}
+	if defaultPassword == "" {
+		usageReportCmd.MarkFlagRequired("password")
+	}
+}
+
+func runUsageReport(ctx context.Context) {
+	if usageFormat != "text" && usageFormat != "json" {
+		log.Fatalf("Invalid --format %q: must be text or json", usageFormat)
+	}
+	if usageWindow < 0 {
+		log.Fatalf("Invalid --window %s: must not be negative", usageWindow)
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&timeout=%ds&readTimeout=%ds",
+		usageUser, usagePassword, usageHost, usagePort, usageTimeout, usageTimeout)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if err := pingWithRetry(ctx, db, usageMaxRetries); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+
+	report, err := collectUsage(ctx, db)
+	if err != nil {
+		log.Fatalf("Usage report failed: %v", err)
+	}
+
+	if usageFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+		return
+	}
+	printUsageReport(report)
+}
+
+// collectUsage samples the I/O counters of the selected tables over
+// --window and returns the difference
+func collectUsage(ctx context.Context, db *sql.DB) (*usageReport, error) {
+	var enabled int
+	if err := queryRowWithRetry(ctx, db, usageMaxRetries, "SELECT @@performance_schema", nil, &enabled); err != nil {
+		return nil, fmt.Errorf("failed to check performance_schema: %w", err)
+	}
+	if enabled == 0 {
+		return nil, fmt.Errorf("performance_schema is disabled; set performance_schema=ON in the server configuration and restart it")
+	}
+
+	databases := usageDatabases
+	if len(databases) == 0 {
+		rows, err := queryWithRetry(ctx, db, usageMaxRetries, schemataQuery(false))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query databases: %w", err)
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan database name: %w", err)
+			}
+			databases = append(databases, name)
+		}
+		rows.Close()
+	}
+	if len(databases) == 0 {
+		return nil, fmt.Errorf("no databases found to report on")
+	}
+
+	report := &usageReport{Window: "since server start", Tables: []usageTable{}, UnusedTables: []string{}, UnusedIndexes: []string{}}
+	before := make(map[string]usageCounts)
+	if usageWindow > 0 {
+		var err error
+		if before, err = usageCounters(ctx, db, databases); err != nil {
+			return nil, err
+		}
+		report.Window = usageWindow.String()
+		fmt.Fprintf(os.Stderr, "Sampling table I/O for %s...\n", usageWindow)
+		select {
+		case <-time.After(usageWindow):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("sampling interrupted: %w", ctx.Err())
+		}
+	}
+	after, err := usageCounters(ctx, db, databases)
+	if err != nil {
+		return nil, err
+	}
+	delta := func(key string) usageCounts {
+		a, b := after[key], before[key]
+		// Counters restart with the server or a TRUNCATE of the summary
+		if a.Reads < b.Reads || a.Writes < b.Writes {
+			return a
+		}
+		return usageCounts{Reads: a.Reads - b.Reads, Writes: a.Writes - b.Writes}
+	}
+
+	// Indexes come from the table definitions, so indexes and tables that
+	// were never opened are reported with zero counts
+	query := fmt.Sprintf(`
+		SELECT t.TABLE_SCHEMA, t.TABLE_NAME, COALESCE(s.INDEX_NAME, ''), COALESCE(s.NON_UNIQUE, 1)
+		FROM information_schema.TABLES t
+		LEFT JOIN (
+			SELECT TABLE_SCHEMA, TABLE_NAME, INDEX_NAME, MIN(NON_UNIQUE) AS NON_UNIQUE
+			FROM information_schema.STATISTICS
+			GROUP BY TABLE_SCHEMA, TABLE_NAME, INDEX_NAME
+		) s ON s.TABLE_SCHEMA = t.TABLE_SCHEMA AND s.TABLE_NAME = t.TABLE_NAME
+		WHERE t.TABLE_TYPE = 'BASE TABLE' AND t.TABLE_SCHEMA IN (%s)
+		ORDER BY t.TABLE_SCHEMA, t.TABLE_NAME, s.INDEX_NAME`, usagePlaceholders(len(databases)))
+	rows, err := queryWithRetry(ctx, db, usageMaxRetries, query, usageArgs(databases)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var schema, table, index string
+		var nonUnique int
+		if err := rows.Scan(&schema, &table, &index, &nonUnique); err != nil {
+			return nil, fmt.Errorf("failed to scan table: %w", err)
+		}
+		name := schema + "." + table
+		if n := len(report.Tables); n == 0 || report.Tables[n-1].Database != schema || report.Tables[n-1].Table != table {
+			report.Tables = append(report.Tables, usageTable{Database: schema, Table: table, usageCounts: delta(name), Indexes: []usageIndex{}})
+		}
+		if index != "" {
+			t := &report.Tables[len(report.Tables)-1]
+			t.Indexes = append(t.Indexes, usageIndex{Name: index, Unique: nonUnique == 0, usageCounts: delta(name + "." + index)})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query tables: %w", err)
+	}
+
+	for _, t := range report.Tables {
+		name := t.Database + "." + t.Table
+		if t.Reads == 0 && t.Writes == 0 {
+			report.UnusedTables = append(report.UnusedTables, name)
+			continue
+		}
+		// The primary key is the row storage of InnoDB tables, and unique
+		// indexes are read by every write to check them
+		for _, index := range t.Indexes {
+			if index.Reads == 0 && index.Name != "PRIMARY" && !index.Unique {
+				report.UnusedIndexes = append(report.UnusedIndexes, name+"."+index.Name)
+			}
+		}
+	}
+	return report, nil
+}
+
+// usageCounters reads the table and index I/O counters of the databases,
+// keyed by db.table and db.table.index
+func usageCounters(ctx context.Context, db *sql.DB, databases []string) (map[string]usageCounts, error) {
+	counters := make(map[string]usageCounts)
+	query := fmt.Sprintf(`
+		SELECT OBJECT_SCHEMA, OBJECT_NAME, COALESCE(INDEX_NAME, ''), COUNT_READ, COUNT_WRITE
+		FROM performance_schema.table_io_waits_summary_by_index_usage
+		WHERE OBJECT_TYPE = 'TABLE' AND OBJECT_SCHEMA IN (%s)`, usagePlaceholders(len(databases)))
+	rows, err := queryWithRetry(ctx, db, usageMaxRetries, query, usageArgs(databases)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table I/O counters: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var schema, table, index string
+		var c usageCounts
+		if err := rows.Scan(&schema, &table, &index, &c.Reads, &c.Writes); err != nil {
+			return nil, fmt.Errorf("failed to scan table I/O counters: %w", err)
+		}
+		// Rows without an index are full scans and writes; all rows of a
+		// table add up to its total
+		total := counters[schema+"."+table]
+		total.Reads += c.Reads
+		total.Writes += c.Writes
+		counters[schema+"."+table] = total
+		if index != "" {
+			counters[schema+"."+table+"."+index] = c
+		}
+	}
+	return counters, rows.Err()
+}
+
+func usagePlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func usageArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
+
+func printUsageReport(report *usageReport) {
+	fmt.Printf("📊 Table usage (%s)\n\n", report.Window)
+
+	tables := append([]usageTable(nil), report.Tables...)
+	sort.SliceStable(tables, func(i, j int) bool {
+		return tables[i].Reads+tables[i].Writes > tables[j].Reads+tables[j].Writes
+	})
+	fmt.Printf("%-50s %12s %12s\n", "Table", "Reads", "Writes")
+	for _, t := range tables {
+		fmt.Printf("%-50s %12d %12d\n", t.Database+"."+t.Table, t.Reads, t.Writes)
+	}
+	fmt.Println()
+
+	fmt.Printf("Unused tables (%d)\n", len(report.UnusedTables))
+	for _, name := range report.UnusedTables {
+		fmt.Printf("  %s\n", name)
+	}
+	fmt.Println()
+
+	fmt.Printf("Unused indexes (%d)\n", len(report.UnusedIndexes))
+	for _, name := range report.UnusedIndexes {
+		fmt.Printf("  %s\n", name)
+	}
+}
@@ -0,0 +1,476 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"mariadb-extractor/internal/yaml"
+
+	"github.com/spf13/cobra"
+)
+
+// validateCmd represents the validate-data command
+var validateCmd = &cobra.Command{
+	Use:   "validate-data <rules.yaml>",
+	Short: "Check source data against validation rules and report violations per table",
+	Long: `Read the tables named in a rules file and check their rows against
+per-column rules: not_null, regex, min/max ranges and references to rows of
+another table. Tables are read like the data command reads them, in primary
+key chunks with reconnects and an optional --max-rate, so the checks can run
+against production. Violations are counted per table, column and rule, with
+the keys of the first violating rows.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runValidate(cmd.Context(), args[0])
+	},
+}
+
+var (
+	validateHost             string
+	validatePort             int
+	validateUser             string
+	validatePassword         string
+	validateTimeout          int
+	validateMaxRetries       int
+	validateChunkSize        int
+	validateMaxRate          string
+	validateSamples          int
+	validateFormat           string
+	validateFailOnViolations bool
+	validatePool             poolOptions
+)
+
+// validationRules is the rules file format: rules per column of each
+// db.table, with an optional condition limiting the rows checked
+type validationRules struct {
+	Tables map[string]validationTable `json:"tables"`
+}
+
+type validationTable struct {
+	Where   string                    `json:"where"`
+	Columns map[string]validationRule `json:"columns"`
+}
+
+// validationRule is checked on every value of a column. Min and max compare
+// numbers numerically and other values, such as dates, as text. References
+// is the column the values must exist in, as table.column in the same
+// database or db.table.column.
+type validationRule struct {
+	NotNull    bool        `json:"not_null"`
+	Regex      string      `json:"regex"`
+	Min        interface{} `json:"min"`
+	Max        interface{} `json:"max"`
+	References string      `json:"references"`
+
+	pattern *regexp.Regexp
+}
+
+// validationViolation counts the rows breaking one rule of a column
+type validationViolation struct {
+	Column  string   `json:"column"`
+	Rule    string   `json:"rule"`
+	Count   int64    `json:"count"`
+	Samples []string `json:"samples"`
+}
+
+// validationReport is the result of validating one table
+type validationReport struct {
+	Table      string                 `json:"table"`
+	Rows       int64                  `json:"rows"`
+	Violations []*validationViolation `json:"violations"`
+	Error      string                 `json:"error,omitempty"`
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	defaultTimeout := getEnvIntWithDefault("MARIADB_TIMEOUT", 300)
+	defaultUser := os.Getenv("MARIADB_USER")
+	defaultPassword := os.Getenv("MARIADB_PASSWORD")
+
+	validateCmd.Flags().StringVarP(&validateHost, "host", "H", getEnvWithDefault("MARIADB_HOST", "localhost"), "MariaDB host (env: MARIADB_HOST)")
+	validateCmd.Flags().IntVarP(&validatePort, "port", "P", getEnvIntWithDefault("MARIADB_PORT", 3306), "MariaDB port (env: MARIADB_PORT)")
+	validateCmd.Flags().StringVarP(&validateUser, "user", "u", defaultUser, "MariaDB username (env: MARIADB_USER)")
+	validateCmd.Flags().StringVarP(&validatePassword, "password", "p", defaultPassword, "MariaDB password (env: MARIADB_PASSWORD)")
+	validateCmd.Flags().IntVarP(&validateTimeout, "timeout", "t", defaultTimeout, "Query timeout in seconds (env: MARIADB_TIMEOUT)")
+	validateCmd.Flags().IntVar(&validateMaxRetries, "max-retries", getEnvIntWithDefault("MARIADB_MAX_RETRIES", 3), "Maximum retry attempts for failed queries (env: MARIADB_MAX_RETRIES)")
+	validateCmd.Flags().IntVar(&validateChunkSize, "chunk-size", getEnvIntWithDefault("MARIADB_CHUNK_SIZE", 10000), "Rows per chunk for large tables (env: MARIADB_CHUNK_SIZE)")
+	validateCmd.Flags().StringVar(&validateMaxRate, "max-rate", getEnvWithDefault("MARIADB_MAX_RATE", ""), "Maximum bandwidth read from the server, e.g. 50MB/s (env: MARIADB_MAX_RATE)")
+	validateCmd.Flags().IntVar(&validateSamples, "samples", 5, "Keys of violating rows listed per rule")
+	validateCmd.Flags().StringVar(&validateFormat, "format", "text", "Report format: text or json")
+	validateCmd.Flags().BoolVar(&validateFailOnViolations, "fail-on-violations", false, "Exit with an error when any rule is violated")
+	addPoolFlags(validateCmd, &validatePool, 2, 2, defaultTimeout)
+
+	if defaultUser == "" {
+		validateCmd.MarkFlagRequired("user")
+	}
+	if defaultPassword == "" {
+		validateCmd.MarkFlagRequired("password")
+	}
+}
+
+func runValidate(ctx context.Context, path string) {
+	if validateFormat != "text" && validateFormat != "json" {
+		log.Fatalf("Invalid --format %q: must be text or json", validateFormat)
+	}
+	if validateChunkSize <= 0 {
+		log.Fatalf("Invalid --chunk-size %d: must be positive", validateChunkSize)
+	}
+	rate, err := parseRate(validateMaxRate)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rules, err := loadValidationRules(path)
+	if err != nil {
+		log.Fatalf("Failed to load validation rules: %v", err)
+	}
+
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&parseTime=true&timeout=%ds&readTimeout=%ds&writeTimeout=%ds",
+		validateUser, validatePassword, validateHost, validatePort, validateTimeout, validateTimeout, validateTimeout)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	validatePool.apply(db)
+
+	if err := pingWithRetry(ctx, db, validateMaxRetries); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+	if validateFormat == "text" {
+		fmt.Printf("Connected to MariaDB at %s:%d\n", validateHost, validatePort)
+	}
+
+	// The table reader is shared with the data command and reads its settings
+	dataChunkSize, dataMaxRetries = validateChunkSize, validateMaxRetries
+	dataRateLimiter = newRateLimiter(rate)
+	dataRowLimiter = nil
+
+	tables := make([]string, 0, len(rules.Tables))
+	for table := range rules.Tables {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var reports []*validationReport
+	violated := false
+	for _, table := range tables {
+		if ctx.Err() != nil {
+			log.Fatalf("Validation interrupted before %s", table)
+		}
+		if validateFormat == "text" {
+			fmt.Printf("🔎 Validating %s...\n", table)
+		}
+		report, err := validateTable(ctx, db, table, rules.Tables[table])
+		if err != nil {
+			report.Error = err.Error()
+		}
+		if len(report.Violations) > 0 || report.Error != "" {
+			violated = true
+		}
+		reports = append(reports, report)
+	}
+
+	if validateFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(reports); err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+	} else {
+		printValidationReport(reports)
+	}
+
+	if violated && validateFailOnViolations {
+		log.Fatalf("Validation found violations")
+	}
+}
+
+// loadValidationRules reads and checks a rules file
+func loadValidationRules(path string) (validationRules, error) {
+	var rules validationRules
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return rules, err
+	}
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("invalid rules file %s: %w", path, err)
+	}
+	if len(rules.Tables) == 0 {
+		return rules, fmt.Errorf("rules file %s has no tables", path)
+	}
+
+	for table, t := range rules.Tables {
+		if _, _, ok := strings.Cut(table, "."); !ok {
+			return rules, fmt.Errorf("table %q must be written as db.table", table)
+		}
+		for column, rule := range t.Columns {
+			if rule.Regex != "" {
+				if rule.pattern, err = regexp.Compile(rule.Regex); err != nil {
+					return rules, fmt.Errorf("%s.%s: invalid regex: %w", table, column, err)
+				}
+			}
+			if rule.References != "" {
+				if n := strings.Count(rule.References, "."); n < 1 || n > 2 {
+					return rules, fmt.Errorf("%s.%s: references %q must be table.column or db.table.column", table, column, rule.References)
+				}
+			}
+			t.Columns[column] = rule
+		}
+	}
+	return rules, nil
+}
+
+// validateTable checks every row of table against its rules. The report
+// holds the rows checked so far when reading fails.
+func validateTable(ctx context.Context, db *sql.DB, table string, rules validationTable) (*validationReport, error) {
+	report := &validationReport{Table: table}
+	dbName, tableName, _ := strings.Cut(table, ".")
+
+	plan := TableExtractionPlan{DatabaseName: dbName, TableName: tableName, WhereClause: rules.Where}
+	reader, err := openTableReader(ctx, db, plan, 0)
+	if err != nil {
+		return report, err
+	}
+	defer reader.close()
+
+	columns := make([]string, 0, len(rules.Columns))
+	for column := range rules.Columns {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	index := make(map[string]int, len(reader.columns))
+	for i, col := range reader.columns {
+		index[col] = i
+	}
+	violations := make(map[string]*validationViolation)
+	violation := func(column, rule, key string) {
+		name := column + "\x00" + rule
+		v := violations[name]
+		if v == nil {
+			v = &validationViolation{Column: column, Rule: rule}
+			violations[name] = v
+			report.Violations = append(report.Violations, v)
+		}
+		v.Count++
+		if len(v.Samples) < validateSamples {
+			v.Samples = append(v.Samples, key)
+		}
+	}
+
+	refs := make(map[string]*referenceCheck)
+	for _, column := range columns {
+		if _, ok := index[column]; !ok {
+			return report, fmt.Errorf("column %s not found in %s", column, table)
+		}
+		if ref := rules.Columns[column].References; ref != "" {
+			refs[column] = newReferenceCheck(dbName, ref)
+		}
+	}
+
+	for {
+		more, err := reader.next()
+		if err != nil {
+			return report, err
+		}
+		if !more {
+			break
+		}
+		report.Rows++
+		key := validationRowKey(reader, report.Rows)
+
+		for _, column := range columns {
+			rule := rules.Columns[column]
+			value := reader.values[index[column]]
+			if value == nil {
+				if rule.NotNull {
+					violation(column, "not_null", key)
+				}
+				continue
+			}
+			text := validationText(value)
+			if rule.pattern != nil && !rule.pattern.MatchString(text) {
+				violation(column, "regex", key)
+			}
+			if rule.Min != nil && compareValidationValues(text, fmt.Sprint(rule.Min)) < 0 {
+				violation(column, "min", key)
+			}
+			if rule.Max != nil && compareValidationValues(text, fmt.Sprint(rule.Max)) > 0 {
+				violation(column, "max", key)
+			}
+			if ref := refs[column]; ref != nil {
+				ref.add(value, text, key)
+				if len(ref.pending) >= validateChunkSize {
+					if err := ref.flush(ctx, db, func(key string) { violation(column, "references", key) }); err != nil {
+						return report, err
+					}
+				}
+			}
+		}
+	}
+
+	for _, column := range columns {
+		if ref := refs[column]; ref != nil {
+			if err := ref.flush(ctx, db, func(key string) { violation(column, "references", key) }); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// referenceCheck batches the values of a column to look them up in the
+// referenced column with one query per batch
+type referenceCheck struct {
+	query   string
+	pending map[string]*pendingReference
+	order   []string
+}
+
+type pendingReference struct {
+	value interface{}
+	keys  []string
+}
+
+func newReferenceCheck(dbName, ref string) *referenceCheck {
+	parts := strings.Split(ref, ".")
+	if len(parts) == 2 {
+		parts = append([]string{dbName}, parts...)
+	}
+	return &referenceCheck{
+		query:   fmt.Sprintf("SELECT `%s` FROM `%s`.`%s` WHERE `%s` IN ", parts[2], parts[0], parts[1], parts[2]),
+		pending: make(map[string]*pendingReference),
+	}
+}
+
+// add queues a value of a row; values are compared case-insensitively, like
+// the default collations
+func (c *referenceCheck) add(value interface{}, text, key string) {
+	name := strings.ToLower(text)
+	p := c.pending[name]
+	if p == nil {
+		p = &pendingReference{value: value}
+		c.pending[name] = p
+		c.order = append(c.order, name)
+	}
+	p.keys = append(p.keys, key)
+}
+
+// flush looks up the queued values and calls missing with the key of every
+// row whose value was not found
+func (c *referenceCheck) flush(ctx context.Context, db *sql.DB, missing func(key string)) error {
+	if len(c.order) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(c.order))
+	for i, name := range c.order {
+		args[i] = c.pending[name].value
+	}
+	query := c.query + "(" + strings.TrimSuffix(strings.Repeat("?,", len(args)), ",") + ")"
+
+	rows, err := queryWithRetry(ctx, db, validateMaxRetries, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to check references: %w", err)
+	}
+	found := make(map[string]bool)
+	for rows.Next() {
+		var value interface{}
+		if err := rows.Scan(&value); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan reference: %w", err)
+		}
+		found[strings.ToLower(validationText(value))] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check references: %w", err)
+	}
+
+	for _, name := range c.order {
+		if !found[name] {
+			for _, key := range c.pending[name].keys {
+				missing(key)
+			}
+		}
+	}
+	c.pending = make(map[string]*pendingReference)
+	c.order = nil
+	return nil
+}
+
+// validationRowKey identifies a row in the report by its primary key, or by
+// its position when the table has none
+func validationRowKey(r *tableReader, n int64) string {
+	if r.keyIndexes == nil {
+		return fmt.Sprintf("row %d", n)
+	}
+	parts := make([]string, len(r.keyIndexes))
+	for i, idx := range r.keyIndexes {
+		parts[i] = fmt.Sprintf("%s=%s", r.key[i], validationText(r.values[idx]))
+	}
+	return strings.Join(parts, ",")
+}
+
+func validationText(v interface{}) string {
+	switch val := v.(type) {
+	case []byte:
+		return string(val)
+	case time.Time:
+		return formatDateTime(val, -1)
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// compareValidationValues compares a and b as numbers when both are, and as
+// text otherwise
+func compareValidationValues(a, b string) int {
+	x, errA := strconv.ParseFloat(a, 64)
+	y, errB := strconv.ParseFloat(b, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(a, b)
+}
+
+func printValidationReport(reports []*validationReport) {
+	fmt.Printf("\nValidation Report:\n")
+	total := int64(0)
+	for _, report := range reports {
+		if report.Error != "" {
+			fmt.Printf("  ❌ %s: %d rows checked, failed: %s\n", report.Table, report.Rows, report.Error)
+			continue
+		}
+		if len(report.Violations) == 0 {
+			fmt.Printf("  ✅ %s: %d rows checked, no violations\n", report.Table, report.Rows)
+			continue
+		}
+		fmt.Printf("  ⚠️  %s: %d rows checked\n", report.Table, report.Rows)
+		for _, v := range report.Violations {
+			total += v.Count
+			fmt.Printf("     %-20s %-10s %8d  (%s)\n", v.Column, v.Rule, v.Count, strings.Join(v.Samples, "; "))
+		}
+	}
+	fmt.Printf("  Total violations: %d\n", total)
+}
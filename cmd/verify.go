@@ -0,0 +1,220 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"mariadb-extractor/internal/sink"
+
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd represents the verify command
+var verifyCmd = &cobra.Command{
+	Use:   "verify [manifest.json]",
+	Short: "Check that the rows of an extract arrived intact on a target server",
+	Long: `Recompute the row checksums of a data run's manifest on the server its
+output was imported into, and compare the row counts and checksums of every
+completed table.
+
+Each table's checksum is the wrapping sum of a CRC-64 per row, computed
+after masks and transforms, so rows may be imported in any order. The
+target tables must hold only the imported rows. Tables continued from a
+checkpoint on resume and runs split with --partition-by-column have no
+checksums and are skipped.
+
+The manifest defaults to output/<MARIADB_OUTPUT_PREFIX>.manifest.json.
+
+Example:
+  mariadb-extractor verify output/data-extract.manifest.json --target-dsn 'dev:secret@tcp(localhost:3307)/'`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		input := filepath.Join("output", sink.Prefix(getEnvWithDefault("MARIADB_OUTPUT_PREFIX", "data-extract"))+".manifest.json")
+		if len(args) == 1 {
+			input = args[0]
+		}
+		runVerify(cmd.Context(), input)
+	},
+}
+
+var verifyTargetDSN string
+
+// verifyResult is the outcome of checking one table of the manifest:
+// ok, mismatch, missing (failed to read) or skipped (no checksum)
+type verifyResult struct {
+	status   string
+	rows     int64
+	checksum string
+	detail   string
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	verifyCmd.Flags().StringVar(&verifyTargetDSN, "target-dsn", os.Getenv("MARIADB_TARGET_DSN"), "Server the extract was imported into, e.g. user:pass@tcp(localhost:3306)/ (env: MARIADB_TARGET_DSN)")
+}
+
+func runVerify(ctx context.Context, input string) {
+	if verifyTargetDSN == "" {
+		log.Fatalf("--target-dsn is required")
+	}
+	data, err := os.ReadFile(input)
+	if err != nil {
+		log.Fatalf("Failed to read manifest: %v", err)
+	}
+	var manifest extractManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		log.Fatalf("Invalid manifest %s: %v", input, err)
+	}
+
+	db, err := openVerifyTarget(ctx, verifyTargetDSN, manifest.TimeZone)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	fmt.Printf("Verifying %s (run %s) against the target\n\n", input, manifest.RunID)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "TABLE\tEXPECTED ROWS\tROWS\tSTATUS\n")
+	counts := make(map[string]int)
+	for _, table := range manifest.Tables {
+		if table.Status != "completed" {
+			continue
+		}
+		result := verifyTable(ctx, db, table)
+		counts[result.status]++
+		rows := "-"
+		if result.status == "ok" || result.status == "mismatch" {
+			rows = fmt.Sprintf("%d", result.rows)
+		}
+		status := result.status
+		if result.detail != "" {
+			status += ": " + result.detail
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", table.Table, table.Rows, rows, status)
+	}
+	tw.Flush()
+
+	fmt.Printf("\n%d ok, %d mismatched, %d missing, %d skipped\n", counts["ok"], counts["mismatch"], counts["missing"], counts["skipped"])
+	if counts["mismatch"] > 0 || counts["missing"] > 0 {
+		log.Fatalf("❌ The target does not hold the extracted rows")
+	}
+	if counts["ok"] == 0 {
+		log.Fatalf("No table of %s has a checksum to verify", input)
+	}
+	fmt.Printf("✅ All checksummed tables arrived intact\n")
+}
+
+// openVerifyTarget connects to the target, reading TIMESTAMP values in the
+// time zone they were imported in
+func openVerifyTarget(ctx context.Context, dsn, timeZone string) (*sql.DB, error) {
+	cfg, err := parseTargetDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Params == nil {
+		cfg.Params = make(map[string]string)
+	}
+	if _, ok := cfg.Params["charset"]; !ok {
+		cfg.Params["charset"] = "utf8mb4"
+	}
+	if timeZone != "" {
+		cfg.Params["time_zone"] = "'" + timeZone + "'"
+	}
+	cfg.ParseTime = true
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to target: %w", err)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to target %s: %w", cfg.Addr, err)
+	}
+	return db, nil
+}
+
+// verifyTable recomputes the checksum of a table's recorded columns on the
+// target
+func verifyTable(ctx context.Context, db *sql.DB, table manifestTable) verifyResult {
+	var result verifyResult
+	dbName, tableName, ok := strings.Cut(table.Table, ".")
+	if table.Checksum == "" || len(table.Columns) == 0 || !ok {
+		result.status = "skipped"
+		return result
+	}
+	if !strings.HasPrefix(table.Checksum, tableSumAlgorithm+":") {
+		result.status, result.detail = "skipped", "unknown checksum "+table.Checksum
+		return result
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM `%s`.`%s`", quoteColumns(table.Columns), dbName, tableName)
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		result.status, result.detail = "missing", err.Error()
+		return result
+	}
+	defer rows.Close()
+
+	kinds, err := columnKinds(rows)
+	if err == nil {
+		var precisions []int
+		precisions, err = columnPrecisions(rows)
+		if err == nil {
+			err = sumTableRows(rows, kinds, precisions, &result)
+		}
+	}
+	if err != nil {
+		result.status, result.detail = "missing", err.Error()
+		return result
+	}
+
+	switch {
+	case result.rows != table.Rows:
+		result.status, result.detail = "mismatch", "row count differs"
+	case result.checksum != table.Checksum:
+		result.status, result.detail = "mismatch", "checksum differs"
+	default:
+		result.status = "ok"
+	}
+	return result
+}
+
+// sumTableRows reads rows into the count and checksum of result
+func sumTableRows(rows *sql.Rows, kinds []columnKind, precisions []int, result *verifyResult) error {
+	values := make([]interface{}, len(kinds))
+	ptrs := make([]interface{}, len(kinds))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	sum := &tableSum{}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return fmt.Errorf("failed to read row: %w", err)
+		}
+		// Zero dates reach the target as written by --zero-dates keep
+		for i, v := range values {
+			if t, ok := v.(time.Time); ok && t.IsZero() && kinds[i] == dateColumn {
+				values[i] = []byte("0000-00-00")
+			}
+		}
+		sum.add(values, precisions)
+		result.rows++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+	result.checksum = sum.String()
+	return nil
+}
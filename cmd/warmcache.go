@@ -0,0 +1,87 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"mariadb-extractor/internal/state"
+)
+
+// warmCache reads the tables that plans read in full into the server's
+// buffer pool before extraction, by counting their rows along the primary
+// key, which walks the clustered index and so every page of the table.
+// Tables are warmed in extraction order until their size would exceed
+// innodb_buffer_pool_size, since warming more would evict the first ones
+// before they are read. Warming is throttled to --warm-cache-rate of table
+// size per second and pauses under Galera flow control. Failures only warn.
+func warmCache(ctx context.Context, db *sql.DB, plans []TableExtractionPlan, progress *state.Progress) {
+	var poolSize int64
+	if err := queryRowWithRetry(ctx, db, dataMaxRetries, "SELECT @@innodb_buffer_pool_size", nil, &poolSize); err != nil {
+		fmt.Printf("⚠️  Warning: failed to read the buffer pool size, warming all tables: %v\n", err)
+	}
+	rate, _ := parseRate(dataWarmCacheRate)
+	limiter := newRateLimiter(rate)
+
+	fmt.Printf("🔥 Warming the buffer pool (%s)...\n", formatBytes(poolSize))
+	start := time.Now()
+	var warmed, total int64
+	for _, plan := range plans {
+		tableKey := plan.DatabaseName + "." + plan.TableName
+		if progress.Done(tableKey) || plan.SampleSize > 0 || plan.WhereClause != "" || plan.IncludedChild {
+			continue
+		}
+		if ctx.Err() != nil || throttleExtraction(ctx, db) != nil {
+			return
+		}
+
+		var size int64
+		err := queryRowWithRetry(ctx, db, dataMaxRetries, `
+			SELECT COALESCE(DATA_LENGTH, 0) + COALESCE(INDEX_LENGTH, 0)
+			FROM information_schema.TABLES
+			WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`, []interface{}{plan.DatabaseName, plan.TableName}, &size)
+		if err != nil {
+			fmt.Printf("⚠️  Warning: failed to read the size of %s: %v\n", tableKey, err)
+			continue
+		}
+		if poolSize > 0 && total+size > poolSize {
+			fmt.Printf("   Stopping before %s: the tables warmed so far fill the buffer pool\n", tableKey)
+			break
+		}
+
+		key, err := getPrimaryKey(ctx, db, plan.DatabaseName, plan.TableName)
+		if err != nil {
+			fmt.Printf("⚠️  Warning: failed to warm %s: %v\n", tableKey, err)
+			continue
+		}
+		query := fmt.Sprintf("SELECT COUNT(*) FROM `%s`.`%s`", plan.DatabaseName, plan.TableName)
+		if len(key) > 0 {
+			query += " FORCE INDEX (PRIMARY)"
+		}
+
+		tableStart := time.Now()
+		rows, cleanup, err := queryWithKill(ctx, db, dataMaxRetries, query)
+		if err == nil {
+			for rows.Next() {
+			}
+			err = rows.Err()
+			cleanup()
+		}
+		if err != nil {
+			fmt.Printf("⚠️  Warning: failed to warm %s: %v\n", tableKey, err)
+			continue
+		}
+		fmt.Printf("   %s (%s) in %v\n", tableKey, formatBytes(size), time.Since(tableStart).Round(time.Millisecond))
+		warmed++
+		total += size
+
+		if err := limiter.wait(ctx, size); err != nil {
+			return
+		}
+	}
+	fmt.Printf("✅ Warmed %d tables (%s) in %v\n\n", warmed, formatBytes(total), time.Since(start).Round(time.Second))
+}
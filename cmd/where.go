@@ -0,0 +1,116 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"mariadb-extractor/internal/yaml"
+)
+
+// planIndex resolves "table" and "db.table" names given on the command line
+// to planned tables
+type planIndex struct {
+	byKey  map[string]*TableExtractionPlan
+	byName map[string][]string
+}
+
+func newPlanIndex(plans []TableExtractionPlan) planIndex {
+	ix := planIndex{
+		byKey:  make(map[string]*TableExtractionPlan, len(plans)),
+		byName: make(map[string][]string),
+	}
+	for i := range plans {
+		key := plans[i].DatabaseName + "." + plans[i].TableName
+		ix.byKey[key] = &plans[i]
+		ix.byName[plans[i].TableName] = append(ix.byName[plans[i].TableName], key)
+	}
+	return ix
+}
+
+// resolve returns the db.table key of a planned table; option names the flag
+// in error messages
+func (ix planIndex) resolve(table, option string) (string, error) {
+	if _, ok := ix.byKey[table]; ok {
+		return table, nil
+	}
+	matches := ix.byName[table]
+	if len(matches) != 1 {
+		return "", fmt.Errorf("%s table %q must match exactly one planned table, found %d; use db.table", option, table, len(matches))
+	}
+	return matches[0], nil
+}
+
+// parseWhere splits "table:condition" or "db.table:condition"
+func parseWhere(spec string) (string, string, error) {
+	table, condition, ok := strings.Cut(spec, ":")
+	table, condition = strings.TrimSpace(table), strings.TrimSpace(condition)
+	if !ok || table == "" || condition == "" {
+		return "", "", fmt.Errorf("invalid --where %q: use table:condition, e.g. \"orders:created_at > '2024-01-01'\"", spec)
+	}
+	return table, condition, nil
+}
+
+// loadWhereFile reads a --where-file mapping table or db.table to a condition
+func loadWhereFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read where file: %w", err)
+	}
+	var conditions map[string]string
+	if err := yaml.Unmarshal(data, &conditions); err != nil {
+		return nil, fmt.Errorf("invalid where file %s: %w (quote conditions that start with a quote as a whole)", path, err)
+	}
+	for table, condition := range conditions {
+		if strings.TrimSpace(table) == "" || strings.TrimSpace(condition) == "" {
+			return nil, fmt.Errorf("invalid where file %s: empty table or condition for %q", path, table)
+		}
+	}
+	return conditions, nil
+}
+
+// applyWhereClauses restricts planned tables to the rows matching the
+// --where-file and --where conditions. Conditions for the same table are
+// combined with AND, also with a WhereClause the plan already has.
+func applyWhereClauses(plans []TableExtractionPlan, file map[string]string, specs []string) error {
+	ix := newPlanIndex(plans)
+	conditions := make(map[string][]string)
+
+	tables := make([]string, 0, len(file))
+	for table := range file {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		key, err := ix.resolve(table, "--where-file")
+		if err != nil {
+			return err
+		}
+		conditions[key] = append(conditions[key], strings.TrimSpace(file[table]))
+	}
+
+	for _, spec := range specs {
+		table, condition, err := parseWhere(spec)
+		if err != nil {
+			return err
+		}
+		key, err := ix.resolve(table, "--where")
+		if err != nil {
+			return err
+		}
+		conditions[key] = append(conditions[key], condition)
+	}
+
+	for key, conds := range conditions {
+		plan := ix.byKey[key]
+		if plan.WhereClause != "" {
+			conds = append([]string{plan.WhereClause}, conds...)
+		}
+		plan.WhereClause = "(" + strings.Join(conds, ") AND (") + ")"
+	}
+	return nil
+}
@@ -0,0 +1,430 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"mariadb-extractor/internal/pipeline"
+
+	"github.com/spf13/cobra"
+)
+
+// wizardCmd represents the wizard command
+var wizardCmd = &cobra.Command{
+	Use:   "wizard",
+	Short: "Interactively build a data extraction",
+	Long: `Connect to the server and build a data extraction step by step:
+pick databases and tables from numbered lists, choose sampling options,
+preview the plan, then run it, print the equivalent data command, or save
+it as a pipeline file for the run command.
+
+Lists accept numbers and ranges such as "1,3-5", or "all".`,
+	Run: func(cmd *cobra.Command, args []string) {
+		runWizard(cmd.Context())
+	},
+}
+
+var (
+	wizardHost     string
+	wizardPort     int
+	wizardUser     string
+	wizardPassword string
+)
+
+// wizardOption is one data flag chosen in the wizard, kept in prompt order
+type wizardOption struct {
+	name  string
+	value interface{}
+}
+
+// wizardTable is a table offered for selection
+type wizardTable struct {
+	name string
+	rows int64
+	size int64
+}
+
+func init() {
+	rootCmd.AddCommand(wizardCmd)
+
+	wizardCmd.Flags().StringVarP(&wizardHost, "host", "H", getEnvWithDefault("MARIADB_HOST", "localhost"), "MariaDB host (env: MARIADB_HOST)")
+	wizardCmd.Flags().IntVarP(&wizardPort, "port", "P", getEnvIntWithDefault("MARIADB_PORT", 3306), "MariaDB port (env: MARIADB_PORT)")
+	wizardCmd.Flags().StringVarP(&wizardUser, "user", "u", os.Getenv("MARIADB_USER"), "MariaDB username (env: MARIADB_USER)")
+	wizardCmd.Flags().StringVarP(&wizardPassword, "password", "p", os.Getenv("MARIADB_PASSWORD"), "MariaDB password (env: MARIADB_PASSWORD)")
+}
+
+func runWizard(ctx context.Context) {
+	in := bufio.NewReader(os.Stdin)
+
+	if wizardUser == "" {
+		wizardUser = prompt(in, "MariaDB user", "")
+	}
+
+	timeout := getEnvIntWithDefault("MARIADB_TIMEOUT", 300)
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/information_schema?charset=utf8mb4&parseTime=true&timeout=%ds",
+		wizardUser, wizardPassword, wizardHost, wizardPort, timeout)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	if err := pingWithRetry(ctx, db, 1); err != nil {
+		log.Fatalf("Failed to ping database: %v", err)
+	}
+	fmt.Printf("Connected to MariaDB at %s:%d\n\n", wizardHost, wizardPort)
+
+	// Databases
+	databases, err := wizardDatabases(ctx, db)
+	if err != nil {
+		log.Fatalf("Failed to list databases: %v", err)
+	}
+	if len(databases) == 0 {
+		log.Fatal("No user databases found")
+	}
+	fmt.Printf("📚 Databases:\n")
+	for i, name := range databases {
+		fmt.Printf("  %3d) %s\n", i+1, name)
+	}
+	selectedDBs := pickFromList(in, "Databases to extract", databases)
+
+	// Tables per database
+	var includeTables []string
+	var selectedTables []wizardTable
+	for _, dbName := range selectedDBs {
+		tables, err := wizardTables(ctx, db, dbName)
+		if err != nil {
+			log.Fatalf("Failed to list tables of %s: %v", dbName, err)
+		}
+		fmt.Printf("\n📋 Tables in %s:\n", dbName)
+		names := make([]string, len(tables))
+		for i, t := range tables {
+			names[i] = t.name
+			fmt.Printf("  %3d) %-40s ~%d rows, %s\n", i+1, t.name, t.rows, formatBytes(t.size))
+		}
+		picked := pickFromList(in, "Tables from "+dbName, names)
+		for _, t := range tables {
+			for _, name := range picked {
+				if t.name == name {
+					selectedTables = append(selectedTables, t)
+				}
+			}
+		}
+		if len(picked) < len(tables) {
+			includeTables = append(includeTables, picked...)
+		}
+	}
+
+	// Sampling and output options
+	fmt.Println()
+	samplePercent := promptInt(in, "Sample percentage of every table (0 = all rows)", 0)
+	sampleTables := prompt(in, "Per-table row limits (table:count, comma-separated)", "")
+	withSchema := promptYesNo(in, "Include CREATE TABLE statements (--with-schema)", false)
+	output := prompt(in, "Output file prefix", "data-extract")
+
+	options := []wizardOption{{"databases", toInterfaces(selectedDBs)}}
+	if len(includeTables) > 0 {
+		options = append(options, wizardOption{"include-tables", toInterfaces(includeTables)})
+	}
+	if samplePercent > 0 {
+		options = append(options, wizardOption{"sample-percent", samplePercent})
+	}
+	if sampleTables != "" {
+		options = append(options, wizardOption{"sample-tables", toInterfaces(splitList(sampleTables))})
+	}
+	if withSchema {
+		options = append(options, wizardOption{"with-schema", true})
+	}
+	if output != "data-extract" {
+		options = append(options, wizardOption{"output", output})
+	}
+
+	// Preview
+	var totalRows, totalSize int64
+	for _, t := range selectedTables {
+		totalRows += t.rows
+		totalSize += t.size
+	}
+	estimatedRows := totalRows
+	if samplePercent > 0 {
+		estimatedRows = totalRows * int64(samplePercent) / 100
+	}
+	fmt.Printf("\n🔎 Plan preview:\n")
+	fmt.Printf("   Databases: %s\n", strings.Join(selectedDBs, ", "))
+	fmt.Printf("   Tables: %d (~%d rows, %s on the server)\n", len(selectedTables), totalRows, formatBytes(totalSize))
+	fmt.Printf("   Estimated rows extracted: ~%d\n", estimatedRows)
+	fmt.Printf("   Output: output/%s.sql\n", output)
+	if len(includeTables) > 0 {
+		fmt.Printf("   Note: --include-tables matches table names in every selected database\n")
+	}
+
+	for {
+		fmt.Println()
+		switch strings.ToLower(prompt(in, "[r]un now, print [c]ommand, save [p]ipeline file, or [q]uit", "c")) {
+		case "r", "run":
+			conn := pipeline.Connection{Host: wizardHost, Port: wizardPort, User: wizardUser, Timeout: timeout}
+			step := pipeline.Step{Name: "wizard", Type: pipeline.StepData, Options: make(map[string]interface{})}
+			for _, opt := range options {
+				step.Options[opt.name] = opt.value
+			}
+			if err := applyStepOptions(dataCmd.Flags(), conn, wizardPassword, step); err != nil {
+				log.Fatalf("Invalid options: %v", err)
+			}
+			dataPool.apply(db)
+			if err := runDataWithDB(ctx, db); err != nil {
+				log.Fatalf("Data extraction failed: %v", err)
+			}
+			return
+		case "c", "command":
+			fmt.Printf("\n%s\n", wizardCommand(options))
+			fmt.Printf("(password is read from MARIADB_PASSWORD or --password)\n")
+		case "p", "pipeline":
+			path := prompt(in, "Pipeline file", "pipeline.yaml")
+			if err := os.WriteFile(path, []byte(wizardPipeline(options)), 0644); err != nil {
+				log.Fatalf("Failed to write pipeline: %v", err)
+			}
+			fmt.Printf("✅ Saved %s; run it with: mariadb-extractor run %s\n", path, path)
+		case "q", "quit":
+			return
+		}
+	}
+}
+
+// wizardDatabases lists the user databases
+func wizardDatabases(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := queryWithRetry(ctx, db, 1, schemataQuery(false))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var databases []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		databases = append(databases, name)
+	}
+	return databases, rows.Err()
+}
+
+// wizardTables lists the base tables of a database with size estimates
+func wizardTables(ctx context.Context, db *sql.DB, dbName string) ([]wizardTable, error) {
+	query := `
+		SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0) + COALESCE(INDEX_LENGTH, 0)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME
+	`
+	rows, err := queryWithRetry(ctx, db, 1, query, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []wizardTable
+	for rows.Next() {
+		var t wizardTable
+		if err := rows.Scan(&t.name, &t.rows, &t.size); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// wizardCommand renders the options as an equivalent data command line
+func wizardCommand(options []wizardOption) string {
+	parts := []string{"mariadb-extractor", "data",
+		"--host", wizardHost, "--port", strconv.Itoa(wizardPort), "--user", shellQuote(wizardUser)}
+	for _, opt := range options {
+		switch v := opt.value.(type) {
+		case bool:
+			parts = append(parts, "--"+opt.name)
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			parts = append(parts, "--"+opt.name, shellQuote(strings.Join(items, ",")))
+		default:
+			parts = append(parts, "--"+opt.name, shellQuote(fmt.Sprint(v)))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// wizardPipeline renders the options as a pipeline file for the run command
+func wizardPipeline(options []wizardOption) string {
+	var b strings.Builder
+	b.WriteString("# Generated by mariadb-extractor wizard\n")
+	b.WriteString("name: wizard-extraction\n")
+	b.WriteString("connection:\n")
+	fmt.Fprintf(&b, "  host: %s\n", strconv.Quote(wizardHost))
+	fmt.Fprintf(&b, "  port: %d\n", wizardPort)
+	fmt.Fprintf(&b, "  user: %s\n", strconv.Quote(wizardUser))
+	b.WriteString("  password_env: MARIADB_PASSWORD\n")
+	b.WriteString("steps:\n")
+	b.WriteString("  - type: data\n")
+	b.WriteString("    options:\n")
+	for _, opt := range options {
+		switch v := opt.value.(type) {
+		case []interface{}:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = strconv.Quote(fmt.Sprint(item))
+			}
+			fmt.Fprintf(&b, "      %s: [%s]\n", opt.name, strings.Join(items, ", "))
+		case string:
+			fmt.Fprintf(&b, "      %s: %s\n", opt.name, strconv.Quote(v))
+		default:
+			fmt.Fprintf(&b, "      %s: %v\n", opt.name, v)
+		}
+	}
+	return b.String()
+}
+
+// pickFromList asks for a selection from a numbered list; empty means all
+func pickFromList(in *bufio.Reader, label string, items []string) []string {
+	for {
+		answer := prompt(in, label+" (e.g. 1,3-5 or all)", "all")
+		indexes, err := parseSelection(answer, len(items))
+		if err != nil {
+			fmt.Printf("   %v\n", err)
+			continue
+		}
+		picked := make([]string, len(indexes))
+		for i, idx := range indexes {
+			picked[i] = items[idx]
+		}
+		return picked
+	}
+}
+
+// parseSelection turns "1,3-5" or "all" into sorted zero-based indexes
+func parseSelection(answer string, n int) ([]int, error) {
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	if answer == "all" || answer == "*" {
+		all := make([]int, n)
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+
+	seen := make(map[int]bool)
+	for _, part := range splitList(answer) {
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q", part)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(strings.TrimSpace(to)); err != nil {
+				return nil, fmt.Errorf("invalid selection %q", part)
+			}
+		}
+		if start < 1 || end > n || start > end {
+			return nil, fmt.Errorf("selection %q is outside 1-%d", part, n)
+		}
+		for i := start; i <= end; i++ {
+			seen[i-1] = true
+		}
+	}
+	if len(seen) == 0 {
+		return nil, errors.New("select at least one item")
+	}
+
+	indexes := make([]int, 0, len(seen))
+	for i := range seen {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
+
+// prompt reads one line of input, returning def for an empty answer
+func prompt(in *bufio.Reader, label, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", label, def)
+	} else {
+		fmt.Printf("%s: ", label)
+	}
+	answer, err := in.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		fmt.Println()
+		log.Fatal("Wizard aborted")
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+	return answer
+}
+
+func promptInt(in *bufio.Reader, label string, def int) int {
+	for {
+		answer := prompt(in, label, strconv.Itoa(def))
+		if n, err := strconv.Atoi(answer); err == nil && n >= 0 {
+			return n
+		}
+		fmt.Printf("   Enter a non-negative number\n")
+	}
+}
+
+func promptYesNo(in *bufio.Reader, label string, def bool) bool {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	switch strings.ToLower(prompt(in, label+" ("+hint+")", "")) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	default:
+		return def
+	}
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func toInterfaces(items []string) []interface{} {
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = item
+	}
+	return values
+}
+
+// shellQuote quotes s for a POSIX shell when it contains special characters
+func shellQuote(s string) string {
+	if s != "" && strings.IndexFunc(s, func(r rune) bool {
+		return !(r == '-' || r == '_' || r == '.' || r == ',' || r == ':' || r == '/' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'))
+	}) < 0 {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
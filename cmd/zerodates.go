@@ -0,0 +1,36 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+)
+
+// applyZeroDates handles the zero dates (0000-00-00) of a row read from
+// columns per --zero-dates. The driver reads them as the zero time.Time,
+// which would otherwise be written as 0001-01-01. With keep they become
+// the zero date text of their column type, with null NULL, and with error
+// the row is rejected.
+func applyZeroDates(plan TableExtractionPlan, columns []string, kinds []columnKind, values []interface{}) error {
+	for i, v := range values {
+		t, ok := v.(time.Time)
+		if !ok || !t.IsZero() || (kinds[i] != dateColumn && kinds[i] != datetimeColumn) {
+			continue
+		}
+		switch dataZeroDates {
+		case "null":
+			values[i] = nil
+		case "error":
+			return conversionError{fmt.Errorf("zero date in %s.%s.%s (use --zero-dates keep or null)", plan.DatabaseName, plan.TableName, columns[i])}
+		default:
+			if kinds[i] == dateColumn {
+				values[i] = []byte("0000-00-00")
+			} else {
+				values[i] = []byte("0000-00-00 00:00:00")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+// Package checksum computes SHA-256 digests of generated artifacts and
+// maintains SHA256SUMS files compatible with `sha256sum -c`.
+package checksum
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SumsFile is the name of the checksum list written next to the artifacts
+const SumsFile = "SHA256SUMS"
+
+// Writer hashes everything written through it
+type Writer struct {
+	w    io.Writer
+	hash hash.Hash
+	size int64
+}
+
+// NewWriter returns a writer that forwards to w while hashing the data
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, hash: sha256.New()}
+}
+
+func (c *Writer) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.hash.Write(p[:n])
+	c.size += int64(n)
+	return n, err
+}
+
+// Sum returns the hex-encoded SHA-256 of the data written so far
+func (c *Writer) Sum() string {
+	return hex.EncodeToString(c.hash.Sum(nil))
+}
+
+// Size returns the number of bytes written so far
+func (c *Writer) Size() int64 {
+	return c.size
+}
+
+// File returns the hex-encoded SHA-256 of the file at path
+func File(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Record adds or replaces entries in the SHA256SUMS file of dir. Keys of sums
+// are artifact paths, stored relative to dir. Existing entries for other files
+// are kept so several commands can share an output directory.
+func Record(dir string, sums map[string]string) error {
+	sumsPath := filepath.Join(dir, SumsFile)
+
+	entries, err := read(sumsPath)
+	if err != nil {
+		return err
+	}
+
+	for path, sum := range sums {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+		entries[filepath.ToSlash(rel)] = sum
+	}
+	return write(sumsPath, entries)
+}
+
+// Forget drops the entries of paths, such as artifacts that were replaced,
+// from the SHA256SUMS file of dir
+func Forget(dir string, paths ...string) error {
+	sumsPath := filepath.Join(dir, SumsFile)
+
+	entries, err := read(sumsPath)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			rel = path
+		}
+		delete(entries, filepath.ToSlash(rel))
+	}
+	return write(sumsPath, entries)
+}
+
+func write(sumsPath string, entries map[string]string) error {
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s  %s\n", entries[name], name)
+	}
+
+	if err := os.WriteFile(sumsPath, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", sumsPath, err)
+	}
+	return nil
+}
+
+// RecordFiles hashes the given files and records them in dir's SHA256SUMS
+func RecordFiles(dir string, paths ...string) (map[string]string, error) {
+	sums := make(map[string]string, len(paths))
+	for _, path := range paths {
+		sum, err := File(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash %s: %w", path, err)
+		}
+		sums[path] = sum
+	}
+	return sums, Record(dir, sums)
+}
+
+func read(path string) (map[string]string, error) {
+	entries := make(map[string]string)
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		sum, name, ok := strings.Cut(scanner.Text(), "  ")
+		if ok && len(sum) == sha256.Size*2 {
+			entries[name] = sum
+		}
+	}
+	return entries, scanner.Err()
+}
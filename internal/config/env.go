@@ -1,11 +1,69 @@
 package config
 
 import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
 	"github.com/joho/godotenv"
 )
 
+// DefaultEnvFile is loaded when --env-file is not given
+const DefaultEnvFile = ".env"
+
 func init() {
-	// Load .env file silently if it exists
-	// This runs before any other init functions
-	_ = godotenv.Load()
+	// Flag defaults are read from the environment while commands register
+	// their flags, so the selected files have to be loaded before flags are
+	// parsed. The cmd package imports this one to guarantee the ordering.
+	if err := Load(os.Args[1:]); err != nil {
+		log.Fatalf("Failed to load environment: %v", err)
+	}
+}
+
+// Load reads the environment files selected by --env-file and --profile in
+// args. With a profile, <env-file>.<profile> is loaded first so its values
+// take precedence over the shared file. Variables already set in the process
+// environment always win.
+func Load(args []string) error {
+	envFile, explicit := flagValue(args, "env-file")
+	if envFile == "" {
+		envFile = DefaultEnvFile
+	}
+
+	profile, _ := flagValue(args, "profile")
+	if profile == "" {
+		profile = os.Getenv("MARIADB_PROFILE")
+	}
+
+	if profile != "" {
+		profileFile := envFile + "." + profile
+		if err := godotenv.Load(profileFile); err != nil {
+			return fmt.Errorf("profile %q: %w", profile, err)
+		}
+	}
+
+	// The default file is optional; an explicitly named one is not
+	if err := godotenv.Load(envFile); err != nil && (explicit || !os.IsNotExist(err)) {
+		return fmt.Errorf("env file %s: %w", envFile, err)
+	}
+	return nil
+}
+
+// flagValue returns the value of --name from args, accepting both
+// "--name value" and "--name=value"
+func flagValue(args []string, name string) (string, bool) {
+	flag := "--" + name
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, flag+"="); ok {
+			return value, true
+		}
+		if arg == flag && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
 }
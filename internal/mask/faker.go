@@ -0,0 +1,97 @@
+package mask
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
+)
+
+// fakers are the kinds of plausible values the faker.<kind> rules generate
+var fakers = map[string]func(r *rand.Rand) string{
+	"name": func(r *rand.Rand) string {
+		return pick(r, firstNames) + " " + pick(r, lastNames)
+	},
+	"first_name": func(r *rand.Rand) string { return pick(r, firstNames) },
+	"last_name":  func(r *rand.Rand) string { return pick(r, lastNames) },
+	"email": func(r *rand.Rand) string {
+		return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(pick(r, firstNames)), strings.ToLower(pick(r, lastNames)),
+			r.IntN(100), pick(r, emailDomains))
+	},
+	"username": func(r *rand.Rand) string {
+		return fmt.Sprintf("%s%s%d", strings.ToLower(pick(r, firstNames)), strings.ToLower(pick(r, lastNames)[:1]), r.IntN(1000))
+	},
+	// Numbers in the 555-0100 to 555-0199 range are reserved for fiction
+	"phone": func(r *rand.Rand) string {
+		return fmt.Sprintf("+1-%d-555-01%02d", 200+r.IntN(800), r.IntN(100))
+	},
+	"address": func(r *rand.Rand) string {
+		return fmt.Sprintf("%d %s %s", 1+r.IntN(9999), pick(r, streetNames), pick(r, streetSuffixes))
+	},
+	"city":     func(r *rand.Rand) string { return pick(r, cities) },
+	"postcode": func(r *rand.Rand) string { return fmt.Sprintf("%05d", r.IntN(100000)) },
+	"country":  func(r *rand.Rand) string { return pick(r, countries) },
+	"company": func(r *rand.Rand) string {
+		return pick(r, lastNames) + " " + pick(r, companySuffixes)
+	},
+}
+
+// fake returns a value of kind derived from the salted original, so equal
+// inputs get equal fakes and a faked key still joins across tables
+func (s *Set) fake(kind string, v interface{}) interface{} {
+	sum := sha256.Sum256([]byte(s.salt + text(v)))
+	r := rand.New(rand.NewPCG(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16])))
+	return []byte(fakers[kind](r))
+}
+
+// fakerKinds returns the faker kinds in name order
+func fakerKinds() []string {
+	kinds := make([]string, 0, len(fakers))
+	for kind := range fakers {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func pick(r *rand.Rand, words []string) string {
+	return words[r.IntN(len(words))]
+}
+
+var firstNames = []string{
+	"James", "Mary", "Robert", "Patricia", "John", "Jennifer", "Michael", "Linda", "David", "Elizabeth",
+	"William", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Carlos", "Karen",
+	"Daniel", "Lisa", "Matthew", "Nancy", "Ana", "Sofia", "Lucas", "Yuki", "Omar", "Priya",
+	"Mateo", "Emma", "Noah", "Olivia", "Liam", "Ava", "Ethan", "Mia", "Hiro", "Fatima",
+}
+
+var lastNames = []string{
+	"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+	"Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Thomas", "Taylor", "Moore", "Jackson", "Martin",
+	"Lee", "Perez", "Thompson", "White", "Harris", "Clark", "Lewis", "Robinson", "Walker", "Young",
+	"Silva", "Santos", "Oliveira", "Tanaka", "Kim", "Nguyen", "Patel", "Schmidt", "Rossi", "Dubois",
+}
+
+// emailDomains are reserved for documentation and never deliver mail
+var emailDomains = []string{"example.com", "example.net", "example.org"}
+
+var streetNames = []string{
+	"Main", "Oak", "Pine", "Maple", "Cedar", "Elm", "Washington", "Lake", "Hill", "Park",
+	"Sunset", "Highland", "River", "Church", "Mill", "Spring", "Forest", "Meadow", "Ridge", "Valley",
+}
+
+var streetSuffixes = []string{"St", "Ave", "Rd", "Blvd", "Ln", "Dr", "Ct", "Way"}
+
+var cities = []string{
+	"Springfield", "Riverside", "Franklin", "Greenville", "Bristol", "Clinton", "Fairview", "Salem", "Madison", "Georgetown",
+	"Arlington", "Ashland", "Burlington", "Dover", "Hudson", "Kingston", "Milton", "Newport", "Oxford", "Winchester",
+}
+
+var countries = []string{
+	"United States", "Canada", "Brazil", "Mexico", "United Kingdom", "France", "Germany", "Spain", "Italy", "Portugal",
+	"Japan", "India", "Australia", "Argentina", "Netherlands", "Sweden", "Chile", "Ireland", "South Korea", "New Zealand",
+}
+
+var companySuffixes = []string{"Inc", "LLC", "Group", "Holdings", "& Sons", "Partners", "Labs", "Systems"}
@@ -0,0 +1,385 @@
+// Package mask replaces personal data in extracted rows before they are
+// written. A masking file lists rules matched against db.table.column, where
+// each part may use * and ? wildcards; the first matching rule wins:
+//
+//	salt: change-me
+//	rules:
+//	  - column: shop.customers.ssn
+//	    rule: null
+//	  - column: shop.customers.name
+//	    rule: fixed
+//	    value: Jane Doe
+//	  - column: "*.*.email"
+//	    rule: hash
+//	    length: 16
+//	  - column: shop.customers.city
+//	    rule: shuffle
+//	  - column: shop.customers.phone
+//	    rule: regex
+//	    pattern: '[0-9]{4}$'
+//	    replace: 'XXXX'
+//	  - column: "shop.*.customer_id"
+//	    rule: pseudonymize
+//	  - column: shop.customers.email
+//	    rule: faker.email
+//
+// NULL values stay NULL under every rule. pseudonymize needs a secret key,
+// which is kept out of the file.
+package mask
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"math/bits"
+	"math/rand/v2"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"mariadb-extractor/internal/transform"
+	"mariadb-extractor/internal/yaml"
+)
+
+// ShufflePool is how many rows shuffle permutes a column's values among
+const ShufflePool = 10000
+
+// File is the masking file format
+type File struct {
+	Salt  string `json:"salt"`
+	Rules []Rule `json:"rules"`
+}
+
+// Rule masks the columns matching Column with the rule Type. Value is used
+// by fixed, Length by hash and pseudonymize, and Pattern and Replace by regex.
+type Rule struct {
+	Column  string      `json:"column"`
+	Type    string      `json:"rule"`
+	Value   interface{} `json:"value"`
+	Length  int         `json:"length"`
+	Pattern string      `json:"pattern"`
+	Replace string      `json:"replace"`
+}
+
+// Set holds the validated rules of a masking file
+type Set struct {
+	salt  string
+	key   []byte
+	rules []compiledRule
+}
+
+type compiledRule struct {
+	Rule
+	pattern *regexp.Regexp
+}
+
+// Load reads and validates a masking file. key is the secret of
+// pseudonymize rules and may be empty if there are none.
+func Load(path, key string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read masking rules: %w", err)
+	}
+
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid masking file %s: %w", path, err)
+	}
+	return Compile(file, key)
+}
+
+// Compile validates the rules of file, see Load
+func Compile(file File, key string) (*Set, error) {
+	set := &Set{salt: file.Salt, key: []byte(key)}
+	for i, rule := range file.Rules {
+		parts := strings.Split(rule.Column, ".")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("masking rule %d: column %q must be db.table.column", i+1, rule.Column)
+		}
+		if _, err := path.Match(rule.Column, ""); err != nil {
+			return nil, fmt.Errorf("masking rule %d: invalid column pattern %q", i+1, rule.Column)
+		}
+
+		compiled := compiledRule{Rule: rule}
+		switch rule.Type {
+		case "", "null":
+			// An unquoted null decodes as no rule at all
+			compiled.Type = "null"
+		case "shuffle":
+		case "fixed":
+			if rule.Value == nil {
+				return nil, fmt.Errorf("masking rule %s: fixed needs a value; use rule null for NULL", rule.Column)
+			}
+		case "hash", "pseudonymize":
+			if rule.Length < 0 || rule.Length > 64 {
+				return nil, fmt.Errorf("masking rule %s: %s length must be between 1 and 64, or 0 for the full hash", rule.Column, rule.Type)
+			}
+			if rule.Type == "pseudonymize" && key == "" {
+				return nil, fmt.Errorf("masking rule %s: pseudonymize needs a secret key (--mask-key or MARIADB_MASK_KEY)", rule.Column)
+			}
+		case "regex":
+			pattern, err := regexp.Compile(rule.Pattern)
+			if err != nil || rule.Pattern == "" {
+				return nil, fmt.Errorf("masking rule %s: invalid regex pattern %q", rule.Column, rule.Pattern)
+			}
+			compiled.pattern = pattern
+		default:
+			kind, ok := strings.CutPrefix(rule.Type, "faker.")
+			if !ok {
+				return nil, fmt.Errorf("masking rule %s: unknown rule %q (use null, fixed, hash, pseudonymize, shuffle, regex or faker.<kind>)", rule.Column, rule.Type)
+			}
+			if fakers[kind] == nil {
+				return nil, fmt.Errorf("masking rule %s: unknown faker %q (use %s)", rule.Column, kind, strings.Join(fakerKinds(), ", "))
+			}
+		}
+		set.rules = append(set.rules, compiled)
+	}
+	return set, nil
+}
+
+// Row returns the masks of a table indexed like columns, and the columns of
+// rules naming this table exactly that it does not have. The Row is nil when
+// no column is masked. Shuffled columns are left as they are: permute them
+// across rows with Shuffle. The Row is safe for concurrent use.
+func (s *Set) Row(dbName, tableName string, columns []string) (transform.Row, []string) {
+	if s == nil {
+		return nil, nil
+	}
+
+	var row transform.Row
+	present := make(map[string]bool, len(columns))
+	for i, col := range columns {
+		present[col] = true
+		for _, rule := range s.rules {
+			if matched, _ := path.Match(rule.Column, dbName+"."+tableName+"."+col); !matched {
+				continue
+			}
+			if row == nil {
+				row = make(transform.Row, len(columns))
+			}
+			row[i] = s.compile(rule)
+			break
+		}
+	}
+
+	var missing []string
+	for _, rule := range s.rules {
+		table, col := cutLast(rule.Column)
+		if table == dbName+"."+tableName && !present[col] && !strings.ContainsAny(col, "*?[") {
+			missing = append(missing, col)
+		}
+	}
+	return row, missing
+}
+
+// Shuffled returns the indexes of the columns of a table masked by shuffle
+func (s *Set) Shuffled(dbName, tableName string, columns []string) []int {
+	var shuffled []int
+	for i, col := range columns {
+		if s.RuleFor(dbName, tableName, col) == "shuffle" {
+			shuffled = append(shuffled, i)
+		}
+	}
+	return shuffled
+}
+
+// RuleFor returns the rule masking a column, or "" when none matches
+func (s *Set) RuleFor(dbName, tableName, column string) string {
+	if s == nil {
+		return ""
+	}
+	for _, rule := range s.rules {
+		if matched, _ := path.Match(rule.Column, dbName+"."+tableName+"."+column); matched {
+			return rule.Type
+		}
+	}
+	return ""
+}
+
+// Uses reports whether any rule is of the given type
+func (s *Set) Uses(ruleType string) bool {
+	if s == nil {
+		return false
+	}
+	for _, rule := range s.rules {
+		if rule.Type == ruleType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Set) compile(rule compiledRule) transform.Func {
+	switch rule.Type {
+	case "null":
+		return func(v interface{}) interface{} { return nil }
+	case "fixed":
+		value := fixedValue(rule.Value)
+		return func(v interface{}) interface{} { return value }
+	case "hash":
+		return func(v interface{}) interface{} { return s.hash(v, rule.Length) }
+	case "pseudonymize":
+		return func(v interface{}) interface{} { return s.pseudonymize(v, rule.Length) }
+	case "regex":
+		return func(v interface{}) interface{} {
+			return []byte(rule.pattern.ReplaceAllString(text(v), rule.Replace))
+		}
+	case "shuffle":
+		// Values move between rows, see Shuffle
+		return func(v interface{}) interface{} { return v }
+	default:
+		kind := strings.TrimPrefix(rule.Type, "faker.")
+		return func(v interface{}) interface{} { return s.fake(kind, v) }
+	}
+}
+
+// hash replaces text with the hex SHA-256 of the salted value, cut to length
+// when it is positive, and integers with an integer of the same number of
+// digits derived from it. Other types have no safe hashed form and become
+// NULL.
+func (s *Set) hash(v interface{}, length int) interface{} {
+	sum := sha256.Sum256([]byte(s.salt + text(v)))
+	switch val := v.(type) {
+	case []byte, string:
+		digest := hex.EncodeToString(sum[:])
+		if length > 0 {
+			digest = digest[:length]
+		}
+		return []byte(digest)
+	case int64:
+		digits := len(strconv.FormatInt(val, 10))
+		if val < 0 {
+			digits--
+		}
+		var n uint64
+		for _, b := range sum[:8] {
+			n = n<<8 | uint64(b)
+		}
+		return int64(n % uint64(math.Pow10(min(digits, 18))))
+	default:
+		return nil
+	}
+}
+
+// pseudonymize replaces text with the hex HMAC-SHA256 of the value under the
+// secret key, cut to length when it is positive; an email address keeps its
+// shape as <hmac>@example.invalid. Integers are mapped by a keyed permutation
+// of the integers with the same sign and bit length, so distinct keys stay
+// distinct and still fit their column. Other types become NULL. The mapping
+// depends only on the key and the value, so a value pseudonymized in two
+// tables still joins.
+func (s *Set) pseudonymize(v interface{}, length int) interface{} {
+	switch val := v.(type) {
+	case []byte, string:
+		str := text(val)
+		mac := hmac.New(sha256.New, s.key)
+		mac.Write([]byte(str))
+		digest := hex.EncodeToString(mac.Sum(nil))
+		if length > 0 {
+			digest = digest[:length]
+		}
+		if at := strings.LastIndex(str, "@"); at > 0 && at < len(str)-1 {
+			digest += "@example.invalid"
+		}
+		return []byte(digest)
+	case int64:
+		if val < 0 && val != math.MinInt64 {
+			return -int64(s.permute(uint64(-val)))
+		}
+		if val >= 0 {
+			return int64(s.permute(uint64(val)))
+		}
+		return val
+	case uint64:
+		return s.permute(val)
+	default:
+		return nil
+	}
+}
+
+// permute maps n to another number of the same bit length, bijectively: a
+// four-round Feistel network keyed by HMAC permutes the bits below the top
+// one, cycle-walking when the network's even width exceeds them
+func (s *Set) permute(n uint64) uint64 {
+	width := bits.Len64(n) - 1
+	if width <= 0 {
+		return n
+	}
+	top := uint64(1) << width
+	half := (width + 1) / 2
+	mask := uint64(1)<<half - 1
+
+	x := n - top
+	for {
+		left, right := x>>half, x&mask
+		for round := byte(0); round < 4; round++ {
+			mac := hmac.New(sha256.New, s.key)
+			var buf [9]byte
+			buf[0] = round
+			binary.BigEndian.PutUint64(buf[1:], right)
+			mac.Write(buf[:])
+			left, right = right, left^binary.BigEndian.Uint64(mac.Sum(nil))&mask
+		}
+		if x = left<<half | right; x < top {
+			return top + x
+		}
+	}
+}
+
+// Shuffle permutes the values of each of columns among rows with a
+// Fisher–Yates shuffle, which keeps the column's value distribution. NULLs
+// stay in their rows; only the other values move.
+func Shuffle(rows [][]interface{}, columns []int) {
+	for _, col := range columns {
+		var filled []int
+		for i, row := range rows {
+			if row[col] != nil {
+				filled = append(filled, i)
+			}
+		}
+		rand.Shuffle(len(filled), func(i, j int) {
+			a, b := rows[filled[i]], rows[filled[j]]
+			a[col], b[col] = b[col], a[col]
+		})
+	}
+}
+
+// fixedValue converts a YAML value to a column value. YAML numbers decode
+// as float64, so whole numbers are written as integers.
+func fixedValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return []byte(val)
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < 1<<53 {
+			return int64(val)
+		}
+	}
+	return v
+}
+
+// text formats a column value as the server would print it
+func text(v interface{}) string {
+	switch val := v.(type) {
+	case []byte:
+		return string(val)
+	case string:
+		return val
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// cutLast splits db.table.column into db.table and column
+func cutLast(column string) (string, string) {
+	i := strings.LastIndex(column, ".")
+	return column[:i], column[i+1:]
+}
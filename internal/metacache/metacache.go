@@ -0,0 +1,164 @@
+// Package metacache caches the results of information_schema queries, such
+// as a database's tables or a table's primary key, so a run reads them from
+// the server once. A cache can be saved as JSON and loaded by a later run
+// against the same server; entries are dropped explicitly with Invalidate.
+package metacache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key identifies a cached result: the kind of lookup and the database and
+// table it is about. Table is empty for lookups of a whole database.
+type Key struct {
+	Kind     string
+	Database string
+	Table    string
+}
+
+func (k Key) String() string {
+	if k.Table == "" {
+		return k.Kind + ":" + k.Database
+	}
+	return k.Kind + ":" + k.Database + "." + k.Table
+}
+
+// Cache holds query results as rows of column values. Methods are safe on
+// nil, which caches nothing, and for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	source  string
+	entries map[string][][]string
+	hits    int
+	misses  int
+}
+
+// document is the saved form of a cache
+type document struct {
+	Source  string                `json:"source"`
+	SavedAt time.Time             `json:"saved_at"`
+	Entries map[string][][]string `json:"entries"`
+}
+
+// New returns an empty cache for the server source, e.g. host:port
+func New(source string) *Cache {
+	return &Cache{source: source, entries: make(map[string][][]string)}
+}
+
+// Load reads a cache saved for source. A missing file, or one saved for
+// another server, gives an empty cache.
+func Load(path, source string) (*Cache, error) {
+	c := New(source)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata cache: %w", err)
+	}
+
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid metadata cache %s: %w", path, err)
+	}
+	if doc.Source == source && doc.Entries != nil {
+		c.entries = doc.Entries
+	}
+	return c, nil
+}
+
+// Get returns the rows cached for key
+func (c *Cache) Get(key Key) ([][]string, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rows, ok := c.entries[key.String()]
+	if ok {
+		c.hits++
+	} else {
+		c.misses++
+	}
+	return rows, ok
+}
+
+// Put caches rows for key
+func (c *Cache) Put(key Key, rows [][]string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if rows == nil {
+		rows = [][]string{}
+	}
+	c.entries[key.String()] = rows
+}
+
+// Invalidate drops the entries of databases, or every entry when none are
+// given
+func (c *Cache) Invalidate(databases ...string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(databases) == 0 {
+		c.entries = make(map[string][][]string)
+		return
+	}
+	for name := range c.entries {
+		_, target, _ := strings.Cut(name, ":")
+		for _, dbName := range databases {
+			if target == dbName || strings.HasPrefix(target, dbName+".") {
+				delete(c.entries, name)
+				break
+			}
+		}
+	}
+}
+
+// Stats returns the lookups answered from the cache and those that were not
+func (c *Cache) Stats() (hits, misses int) {
+	if c == nil {
+		return 0, 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Save writes the cache to path as JSON
+func (c *Cache) Save(path string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	data, err := json.MarshalIndent(document{Source: c.source, SavedAt: time.Now().UTC(), Entries: c.entries}, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create metadata cache directory: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write metadata cache: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write metadata cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,196 @@
+// Package pipeline defines the YAML pipeline file run by the `run` command
+// and the manifest it writes.
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"mariadb-extractor/internal/yaml"
+)
+
+// Step types
+const (
+	StepDDL    = "ddl"
+	StepData   = "data"
+	StepGrants = "grants"
+	StepUpload = "upload"
+	StepNotify = "notify"
+)
+
+// Pipeline is a sequence of steps sharing one database connection
+type Pipeline struct {
+	Name            string     `json:"name"`
+	Connection      Connection `json:"connection"`
+	Manifest        string     `json:"manifest"`
+	ContinueOnError bool       `json:"continue_on_error"`
+	Steps           []Step     `json:"steps"`
+}
+
+// Connection overrides the connection settings taken from the environment.
+// PasswordEnv names an environment variable holding the password so secrets
+// stay out of the pipeline file.
+type Connection struct {
+	Host        string `json:"host"`
+	Port        int    `json:"port"`
+	User        string `json:"user"`
+	PasswordEnv string `json:"password_env"`
+	Timeout     int    `json:"timeout"`
+}
+
+// Step is one phase of a pipeline. Options are the flags of the matching
+// command (ddl, data) keyed by flag name; Command is a shell command for
+// upload and notify steps; Webhook is a URL notify steps POST a summary to.
+type Step struct {
+	Name            string                 `json:"name"`
+	Type            string                 `json:"type"`
+	ContinueOnError *bool                  `json:"continue_on_error"`
+	Options         map[string]interface{} `json:"options"`
+	Output          string                 `json:"output"`
+	Command         string                 `json:"command"`
+	Webhook         string                 `json:"webhook"`
+}
+
+// Load reads and validates a pipeline file
+func Load(path string) (*Pipeline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pipeline: %w", err)
+	}
+
+	var p Pipeline
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid pipeline %s: %w", path, err)
+	}
+	if err := p.validate(); err != nil {
+		return nil, fmt.Errorf("invalid pipeline %s: %w", path, err)
+	}
+
+	if p.Name == "" {
+		p.Name = filepath.Base(path)
+	}
+	if p.Manifest == "" {
+		p.Manifest = filepath.Join("output", "pipeline-manifest.json")
+	}
+	return &p, nil
+}
+
+func (p *Pipeline) validate() error {
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("no steps defined")
+	}
+
+	counts := make(map[string]int)
+	names := make(map[string]bool)
+	for i := range p.Steps {
+		step := &p.Steps[i]
+		switch step.Type {
+		case StepDDL, StepData, StepGrants:
+		case StepUpload:
+			if step.Command == "" {
+				return fmt.Errorf("step %d: upload requires a command", i+1)
+			}
+		case StepNotify:
+			if step.Command == "" && step.Webhook == "" {
+				return fmt.Errorf("step %d: notify requires a webhook or a command", i+1)
+			}
+		case "":
+			return fmt.Errorf("step %d: missing type", i+1)
+		default:
+			return fmt.Errorf("step %d: unknown type %q (expected ddl, data, grants, upload or notify)", i+1, step.Type)
+		}
+
+		// Unnamed steps are named after their type: data, data-2, ...
+		if step.Name == "" {
+			counts[step.Type]++
+			step.Name = step.Type
+			if counts[step.Type] > 1 {
+				step.Name = fmt.Sprintf("%s-%d", step.Type, counts[step.Type])
+			}
+		}
+		if names[step.Name] {
+			return fmt.Errorf("step %d: duplicate step name %q", i+1, step.Name)
+		}
+		names[step.Name] = true
+	}
+	return nil
+}
+
+// ContinuesOnError reports whether a failure of step lets the pipeline go on
+func (p *Pipeline) ContinuesOnError(step Step) bool {
+	if step.ContinueOnError != nil {
+		return *step.ContinueOnError
+	}
+	return p.ContinueOnError
+}
+
+// Step and pipeline statuses
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+)
+
+// Manifest is the combined record of a pipeline run
+type Manifest struct {
+	Pipeline   string       `json:"pipeline"`
+	Server     string       `json:"server"`
+	Status     string       `json:"status"`
+	StartedAt  time.Time    `json:"started_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Steps      []StepResult `json:"steps"`
+}
+
+// StepResult is the outcome of one step
+type StepResult struct {
+	Name       string     `json:"name"`
+	Type       string     `json:"type"`
+	Status     string     `json:"status"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	DurationMS int64      `json:"duration_ms"`
+	Error      string     `json:"error,omitempty"`
+	Artifacts  []Artifact `json:"artifacts,omitempty"`
+	// SkippedDatabases were skipped as trash by a ddl or data step
+	SkippedDatabases []string `json:"skipped_databases,omitempty"`
+	// Warnings were raised while planning a data step, such as foreign keys
+	// without indexes
+	Warnings []string `json:"warnings,omitempty"`
+	// Failures are the tables a data step failed or skipped
+	Failures []TableFailure `json:"failures,omitempty"`
+}
+
+// TableFailure is a table that a data extraction failed or skipped, with a
+// reason for tools to act on: timeout, permission, conversion, connection,
+// metadata_locked, too_large, interrupted or other
+type TableFailure struct {
+	Table  string `json:"table"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+	Error  string `json:"error"`
+}
+
+// Artifact is a file produced by a step
+type Artifact struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Write saves the manifest as indented JSON
+func (m *Manifest) Write(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package retry runs database operations with exponential backoff, retrying
+// only errors that are likely to succeed on a later attempt.
+package retry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Policy controls how often and how long Do waits between attempts
+type Policy struct {
+	MaxAttempts int           // Total attempts including the first; <= 1 disables retries
+	BaseDelay   time.Duration // Delay before the second attempt
+	MaxDelay    time.Duration // Upper bound for any single delay
+
+	// OnRetry, if set, is called before sleeping ahead of a retry
+	OnRetry func(attempt int, delay time.Duration, err error)
+}
+
+// DefaultPolicy returns a policy with the given attempt count, starting at one
+// second and capped at thirty seconds between attempts
+func DefaultPolicy(maxAttempts int) Policy {
+	return Policy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+// Do calls fn until it succeeds, returns a non-transient error, the attempts
+// are exhausted, or ctx is done
+func Do(ctx context.Context, p Policy, fn func(ctx context.Context) error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(ctx); err == nil {
+			return nil
+		}
+		if !IsTransient(err) || attempt == attempts {
+			break
+		}
+
+		delay := p.backoff(attempt)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if attempts > 1 && IsTransient(err) {
+		return fmt.Errorf("failed after %d attempts: %w", attempts, err)
+	}
+	return err
+}
+
+// backoff returns the delay after the given failed attempt: exponential
+// growth from BaseDelay with full jitter over the upper half of the window
+func (p Policy) backoff(attempt int) time.Duration {
+	base := p.BaseDelay
+	if base <= 0 {
+		base = time.Second
+	}
+
+	delay := base << (attempt - 1)
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay <= 0) {
+		delay = p.MaxDelay
+	}
+
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// transientMySQLErrors are server error numbers worth retrying
+var transientMySQLErrors = map[uint16]bool{
+	1040: true, // ER_CON_COUNT_ERROR: too many connections
+	1053: true, // ER_SERVER_SHUTDOWN
+	1158: true, // ER_NET_READ_ERROR
+	1159: true, // ER_NET_READ_INTERRUPTED
+	1160: true, // ER_NET_ERROR_ON_WRITE
+	1161: true, // ER_NET_WRITE_INTERRUPTED
+	1205: true, // ER_LOCK_WAIT_TIMEOUT
+	1213: true, // ER_LOCK_DEADLOCK
+	1927: true, // ER_CONNECTION_KILLED
+	2006: true, // CR_SERVER_GONE_ERROR
+	2013: true, // CR_SERVER_LOST
+}
+
+// IsTransient reports whether err is a connection or contention error that a
+// later attempt may not hit. Cancellation and SQL errors are never transient.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return transientMySQLErrors[mysqlErr.Number]
+	}
+
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
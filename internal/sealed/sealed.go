@@ -0,0 +1,215 @@
+// Package sealed encrypts streams with a passphrase. The key is derived with
+// PBKDF2-SHA256 and the stream sealed with AES-256-GCM in chunks, so large
+// files are encrypted without holding them in memory and a truncated or
+// modified file fails to open.
+package sealed
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Magic starts every sealed stream
+const Magic = "MXSEALED1"
+
+// ChunkSize is the amount of plaintext sealed per chunk
+const ChunkSize = 64 << 10
+
+// Iterations is the PBKDF2 iteration count of new streams
+const Iterations = 600000
+
+// MinIterations and MaxIterations bound the iteration count a stream header
+// may ask for. The header is not authenticated before the key is derived, so
+// a modified file must not be able to demand an arbitrarily long derivation.
+const (
+	MinIterations = Iterations / 4
+	MaxIterations = Iterations * 4
+)
+
+const (
+	saltSize   = 16
+	prefixSize = 8
+	headerSize = len(Magic) + saltSize + 4 + prefixSize
+)
+
+// ErrPassphrase is returned when a stream does not open with the passphrase,
+// or was modified
+var ErrPassphrase = errors.New("wrong passphrase or damaged file")
+
+// Writer seals everything written through it. Close must be called to seal
+// the last chunk; it does not close the underlying writer.
+type Writer struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	prefix []byte
+	count  uint32
+	buf    []byte
+	closed bool
+}
+
+// NewWriter writes the header of a new stream to w and returns a writer
+// sealing with a key derived from passphrase
+func NewWriter(w io.Writer, passphrase string) (*Writer, error) {
+	header := make([]byte, headerSize)
+	copy(header, Magic)
+	salt := header[len(Magic) : len(Magic)+saltSize]
+	prefix := header[len(Magic)+saltSize+4:]
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, err
+	}
+	binary.BigEndian.PutUint32(header[len(Magic)+saltSize:], Iterations)
+
+	aead, err := newAEAD(passphrase, salt, Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, ChunkSize)}, nil
+}
+
+func (s *Writer) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errors.New("sealed: write after close")
+	}
+	n := 0
+	for len(p) > 0 {
+		take := min(ChunkSize-len(s.buf), len(p))
+		s.buf = append(s.buf, p[:take]...)
+		p = p[take:]
+		n += take
+		if len(s.buf) == ChunkSize {
+			if err := s.seal(false); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Close seals the last chunk, which is shorter than ChunkSize and possibly
+// empty, so a reader can tell the stream is complete
+func (s *Writer) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.seal(true)
+}
+
+func (s *Writer) seal(final bool) error {
+	sealed := s.aead.Seal(nil, nonce(s.prefix, s.count), s.buf, additionalData(final))
+	s.count++
+	s.buf = s.buf[:0]
+	_, err := s.w.Write(sealed)
+	return err
+}
+
+// Reader opens a sealed stream
+type Reader struct {
+	r      io.Reader
+	aead   cipher.AEAD
+	prefix []byte
+	count  uint32
+	chunk  []byte
+	plain  []byte
+	done   bool
+}
+
+// NewReader reads the header of a sealed stream from r and returns a reader
+// of its plaintext
+func NewReader(r io.Reader, passphrase string) (*Reader, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:len(Magic)]) != Magic {
+		return nil, fmt.Errorf("not a sealed file")
+	}
+	salt := header[len(Magic) : len(Magic)+saltSize]
+	iterations := binary.BigEndian.Uint32(header[len(Magic)+saltSize:])
+	if iterations < MinIterations || iterations > MaxIterations {
+		return nil, fmt.Errorf("sealed file asks for %d key derivation iterations, outside %d-%d: damaged file", iterations, MinIterations, MaxIterations)
+	}
+	aead, err := newAEAD(passphrase, salt, int(iterations))
+	if err != nil {
+		return nil, err
+	}
+	prefix := append([]byte(nil), header[len(Magic)+saltSize+4:]...)
+	return &Reader{r: r, aead: aead, prefix: prefix, chunk: make([]byte, ChunkSize+aead.Overhead())}, nil
+}
+
+func (s *Reader) Read(p []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+// open reads and opens the next chunk. Only the final chunk is short.
+func (s *Reader) open() error {
+	n, err := io.ReadFull(s.r, s.chunk)
+	final := false
+	switch {
+	case err == io.EOF:
+		return fmt.Errorf("sealed file is truncated")
+	case err == io.ErrUnexpectedEOF:
+		final = true
+	case err != nil:
+		return err
+	}
+	plain, err := s.aead.Open(s.chunk[:0], nonce(s.prefix, s.count), s.chunk[:n], additionalData(final))
+	if err != nil {
+		if s.count == 0 {
+			return ErrPassphrase
+		}
+		return fmt.Errorf("sealed file is damaged at chunk %d", s.count)
+	}
+	s.count++
+	s.plain = plain
+	s.done = final
+	return nil
+}
+
+func newAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce is the stream's random prefix followed by the chunk number
+func nonce(prefix []byte, count uint32) []byte {
+	n := make([]byte, 0, prefixSize+4)
+	n = append(n, prefix...)
+	return binary.BigEndian.AppendUint32(n, count)
+}
+
+// additionalData marks the final chunk, so dropping chunks from the end is
+// detected
+func additionalData(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,64 @@
+package sink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// httpSink uploads each file with an HTTP PUT below a base URL. Credentials
+// in the URL are sent as basic auth.
+type httpSink struct {
+	base *url.URL
+}
+
+func (s *httpSink) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	target := *s.base
+	target.User = nil
+	target.Path = "/" + join(strings.Trim(s.base.Path, "/"), name)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if s.base.User != nil {
+		password, _ := s.base.User.Password()
+		req.SetBasicAuth(s.base.User.Username(), password)
+	}
+	return doPut(req)
+}
+
+func (s *httpSink) String() string {
+	redacted := *s.base
+	redacted.User = nil
+	return redacted.String()
+}
+
+// doPut sends an upload and turns non-2xx responses into errors
+func doPut(req *http.Request) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("PUT %s: %s: %s", req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package sink
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3Sink uploads objects with single signed PUT requests, so objects are
+// limited to S3's 5GB single upload size. Credentials and region come from
+// the standard AWS_* environment variables; AWS_ENDPOINT_URL selects an
+// S3-compatible service such as MinIO, addressed path-style.
+type s3Sink struct {
+	bucket       string
+	dir          string
+	region       string
+	endpoint     *url.URL
+	pathStyle    bool
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+func newS3Sink(bucket, dir string) (*s3Sink, error) {
+	s := &s3Sink{
+		bucket:       bucket,
+		dir:          dir,
+		region:       firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("s3 output needs AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if s.region == "" {
+		s.region = "us-east-1"
+	}
+
+	if endpoint := firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"); endpoint != "" {
+		u, err := url.Parse(endpoint)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid AWS_ENDPOINT_URL %q", endpoint)
+		}
+		s.endpoint, s.pathStyle = u, true
+	} else {
+		s.endpoint = &url.URL{Scheme: "https", Host: fmt.Sprintf("%s.s3.%s.amazonaws.com", bucket, s.region)}
+	}
+	return s, nil
+}
+
+func (s *s3Sink) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	key := join(s.dir, name)
+	objectPath := "/" + key
+	if s.pathStyle {
+		objectPath = "/" + s.bucket + "/" + key
+	}
+
+	target := *s.endpoint
+	target.Path = strings.TrimSuffix(target.Path, "/") + objectPath
+	target.RawPath = uriEncode(target.Path)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	s.sign(req, time.Now().UTC())
+	return doPut(req)
+}
+
+// sign adds an AWS Signature Version 4 Authorization header. The payload is
+// not hashed, which S3 allows over HTTPS, so files are streamed unread.
+func (s *s3Sink) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", "UNSIGNED-PAYLOAD")
+	if s.sessionToken != "" {
+		req.Header.Set("x-amz-security-token", s.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+
+	scope := date + "/" + s.region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.secretKey), date)
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+}
+
+func (s *s3Sink) String() string { return "s3://" + join(s.bucket, s.dir) }
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode escapes a path as SigV4 expects: everything but unreserved
+// characters and slashes is percent-encoded
+func uriEncode(p string) string {
+	var b strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
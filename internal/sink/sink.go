@@ -0,0 +1,223 @@
+// Package sink publishes finished output files to the destination selected
+// by a URI-style --output value. Commands keep writing to their local output
+// directory, which resumes and checksums rely on, and hand the files to a
+// Sink once they are complete, so new destinations only need a new Sink.
+//
+// Supported values:
+//
+//	data-extract                      local prefix, no sink
+//	file:///srv/extracts/data-extract copy to a local directory
+//	- or stdout://                    stream the primary file to stdout
+//	s3://bucket/path/data-extract     S3 PUT signed with the AWS_* environment
+//	ssh://user@host:22/path/prefix    upload through the ssh command
+//	https://host/path/data-extract    HTTP PUT per file
+package sink
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Sink stores output files at a destination
+type Sink interface {
+	// Put stores size bytes read from r under name, a slash-separated path
+	// relative to the destination
+	Put(ctx context.Context, name string, r io.Reader, size int64) error
+	// String describes the destination for progress messages
+	String() string
+}
+
+// Parse returns the sink selected by an --output value and the file prefix
+// to use locally, which is the last path element of a URI. Plain prefixes
+// return a nil Sink.
+func Parse(output string) (Sink, string, error) {
+	if output == "-" {
+		return &stdoutSink{w: os.Stdout}, "stdout", nil
+	}
+	if !strings.Contains(output, "://") {
+		return nil, output, nil
+	}
+
+	u, err := url.Parse(output)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid output %q: %w", output, err)
+	}
+	dir, prefix := path.Split(strings.TrimSuffix(u.Path, "/"))
+	dir = strings.Trim(dir, "/")
+	if prefix == "" && u.Scheme != "stdout" {
+		return nil, "", fmt.Errorf("invalid output %q: the path must end with a file prefix", output)
+	}
+
+	switch u.Scheme {
+	case "file":
+		if u.Host != "" {
+			return nil, "", fmt.Errorf("invalid output %q: use file:///absolute/path/prefix", output)
+		}
+		return &localSink{dir: localDir(dir)}, prefix, nil
+	case "stdout":
+		return &stdoutSink{w: os.Stdout}, "stdout", nil
+	case "s3":
+		if u.Host == "" {
+			return nil, "", fmt.Errorf("invalid output %q: missing bucket", output)
+		}
+		s, err := newS3Sink(u.Host, dir)
+		return s, prefix, err
+	case "ssh":
+		if u.Host == "" {
+			return nil, "", fmt.Errorf("invalid output %q: missing host", output)
+		}
+		return newSSHSink(u, dir), prefix, nil
+	case "http", "https":
+		base := *u
+		base.Path = "/" + dir
+		base.RawPath = ""
+		return &httpSink{base: &base}, prefix, nil
+	default:
+		return nil, "", fmt.Errorf("unsupported output scheme %q: use file, stdout, s3, ssh, http or https", u.Scheme)
+	}
+}
+
+// Prefix returns the local file prefix of an --output value without
+// checking the destination, or the value itself when it is not a URI
+func Prefix(output string) string {
+	if output == "-" || strings.HasPrefix(output, "stdout://") {
+		return "stdout"
+	}
+	if !strings.Contains(output, "://") {
+		return output
+	}
+	u, err := url.Parse(output)
+	if err != nil {
+		return output
+	}
+	return path.Base(strings.TrimSuffix(u.Path, "/"))
+}
+
+// IsStream reports whether s is a single byte stream, which only takes a
+// command's primary output file
+func IsStream(s Sink) bool {
+	_, ok := s.(*stdoutSink)
+	return ok
+}
+
+// PutFile stores the local file at localPath under name
+func PutFile(ctx context.Context, s Sink, name, localPath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := s.Put(ctx, name, file, info.Size()); err != nil {
+		return fmt.Errorf("failed to upload %s to %s: %w", localPath, s, err)
+	}
+	return nil
+}
+
+// localDir returns the local directory of a file URI path without its
+// leading slash. Windows paths keep their drive letter, as in
+// file:///C:/exports/prefix.
+func localDir(dir string) string {
+	if filepath.VolumeName(dir) != "" {
+		return filepath.FromSlash(dir) + string(filepath.Separator)
+	}
+	return "/" + dir
+}
+
+// join prefixes name with the destination directory
+func join(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return strings.TrimSuffix(dir, "/") + "/" + name
+}
+
+// localSink copies files into a directory
+type localSink struct {
+	dir string
+}
+
+func (s *localSink) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	target := filepath.Join(s.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s *localSink) String() string { return "file://" + s.dir }
+
+// stdoutSink writes file contents to a stream
+type stdoutSink struct {
+	w io.Writer
+}
+
+func (s *stdoutSink) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	_, err := io.Copy(s.w, r)
+	return err
+}
+
+func (s *stdoutSink) String() string { return "stdout" }
+
+// sshSink pipes each file into "cat" on the remote host using the ssh
+// command, so keys and settings from ~/.ssh/config apply
+type sshSink struct {
+	target string // [user@]host
+	port   string
+	dir    string
+}
+
+func newSSHSink(u *url.URL, dir string) *sshSink {
+	target := u.Hostname()
+	if u.User != nil {
+		target = u.User.Username() + "@" + target
+	}
+	// ssh://host/~/backups/x is relative to the remote home directory
+	if strings.HasPrefix(dir, "~/") || dir == "~" {
+		dir = strings.TrimPrefix(strings.TrimPrefix(dir, "~"), "/")
+	} else {
+		dir = "/" + dir
+	}
+	return &sshSink{target: target, port: u.Port(), dir: dir}
+}
+
+func (s *sshSink) Put(ctx context.Context, name string, r io.Reader, size int64) error {
+	remote := join(s.dir, name)
+	script := fmt.Sprintf("mkdir -p %s && cat > %s", shellQuote(path.Dir(remote)), shellQuote(remote))
+
+	var args []string
+	if s.port != "" {
+		args = append(args, "-p", s.port)
+	}
+	args = append(args, s.target, script)
+
+	cmd := exec.CommandContext(ctx, "ssh", args...)
+	cmd.Stdin = r
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (s *sshSink) String() string { return "ssh://" + s.target + "/" + strings.TrimPrefix(s.dir, "/") }
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,206 @@
+// Package snapshot persists extract runs into a local append-only catalog so
+// metadata can be compared across runs. The catalog is a JSON-lines file
+// rather than SQLite, which would need cgo or a large pure-Go port in a
+// binary built with CGO_ENABLED=0. It has no index: every query reads all
+// runs, and only the queries below are offered.
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Run is a single extract run as stored in the catalog
+type Run struct {
+	ID          string     `json:"id"`
+	Server      string     `json:"server"`
+	ExtractedAt time.Time  `json:"extracted_at"`
+	Databases   []Database `json:"databases"`
+}
+
+// Database is the per-database part of a run
+type Database struct {
+	Name   string  `json:"name"`
+	Tables []Table `json:"tables"`
+}
+
+// Table is the per-table part of a run
+type Table struct {
+	Name        string `json:"name"`
+	Engine      string `json:"engine,omitempty"`
+	RowCount    int64  `json:"row_count"`
+	DataLength  int64  `json:"data_length"`
+	IndexLength int64  `json:"index_length"`
+}
+
+// TablePoint is one observation of a table across runs
+type TablePoint struct {
+	RunID       string
+	Server      string
+	ExtractedAt time.Time
+	Table
+}
+
+// Store is a JSON-lines catalog file holding one run per line
+type Store struct {
+	path string
+}
+
+// Open returns a store backed by path; the file is created on first write
+func Open(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the catalog file location
+func (s *Store) Path() string {
+	return s.path
+}
+
+// NewRunID returns a sortable run identifier for the given time
+func NewRunID(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// Append adds a run to the end of the catalog
+func (s *Store) Append(run Run) error {
+	if dir := filepath.Dir(s.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create catalog directory: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open catalog: %w", err)
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to encode run: %w", err)
+	}
+	data = append(data, '\n')
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write catalog: %w", err)
+	}
+	return nil
+}
+
+// Runs returns all runs in the catalog, oldest first
+func (s *Store) Runs() ([]Run, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog: %w", err)
+	}
+	defer file.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 256*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var run Run
+		if err := json.Unmarshal(scanner.Bytes(), &run); err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid run record: %w", s.path, line, err)
+		}
+		runs = append(runs, run)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read catalog: %w", err)
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].ExtractedAt.Before(runs[j].ExtractedAt)
+	})
+	return runs, nil
+}
+
+// ServerRuns returns the runs of server ("" for every server), oldest first
+func (s *Store) ServerRuns(server string) ([]Run, error) {
+	runs, err := s.Runs()
+	if err != nil || server == "" {
+		return runs, err
+	}
+
+	var result []Run
+	for _, run := range runs {
+		if run.Server == server {
+			result = append(result, run)
+		}
+	}
+	return result, nil
+}
+
+// TableHistory returns observations of dbName.tableName on server ("" for
+// every server) from the last n runs that saw the table (all when n <= 0),
+// oldest first
+func (s *Store) TableHistory(server, dbName, tableName string, n int) ([]TablePoint, error) {
+	runs, err := s.ServerRuns(server)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []TablePoint
+	for _, run := range runs {
+		for _, db := range run.Databases {
+			if db.Name != dbName {
+				continue
+			}
+			for _, table := range db.Tables {
+				if table.Name == tableName {
+					points = append(points, TablePoint{RunID: run.ID, Server: run.Server, ExtractedAt: run.ExtractedAt, Table: table})
+				}
+			}
+		}
+	}
+	if n > 0 && len(points) > n {
+		points = points[len(points)-n:]
+	}
+	return points, nil
+}
+
+// DatabaseHistory returns the tables of dbName on server ("" for every
+// server) from the last n runs that saw the database (all when n <= 0),
+// oldest first
+func (s *Store) DatabaseHistory(server, dbName string, n int) ([]Run, error) {
+	runs, err := s.ServerRuns(server)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []Run
+	for _, run := range runs {
+		for _, db := range run.Databases {
+			if db.Name == dbName {
+				result = append(result, Run{
+					ID:          run.ID,
+					Server:      run.Server,
+					ExtractedAt: run.ExtractedAt,
+					Databases:   []Database{db},
+				})
+			}
+		}
+	}
+	return LastRuns(result, n), nil
+}
+
+// LastRuns returns the last n runs (all when n <= 0)
+func LastRuns(runs []Run, n int) []Run {
+	if n > 0 && len(runs) > n {
+		return runs[len(runs)-n:]
+	}
+	return runs
+}
@@ -0,0 +1,267 @@
+// Package state stores resumable run progress as versioned JSON documents in
+// a per-user state directory. State files never contain credentials.
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// SchemaVersion is the version of the progress document layout
+const SchemaVersion = 1
+
+// Progress is the state of one resumable run
+type Progress struct {
+	SchemaVersion int       `json:"schema_version"`
+	Command       string    `json:"command"`
+	RunID         string    `json:"run_id"`
+	Server        string    `json:"server"`
+	Output        string    `json:"output"`
+	StartedAt     time.Time `json:"started_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Completed     []Item    `json:"completed"`
+	// InterruptedAt is set when the run last stopped on an interrupt, and
+	// InterruptedIn names the item it stopped in, if one was in progress
+	InterruptedAt *time.Time `json:"interrupted_at,omitempty"`
+	InterruptedIn string     `json:"interrupted_in,omitempty"`
+	// Partial is the checkpoint of an item written in part, so a resumed
+	// run continues it after its Key instead of starting it again
+	Partial *Item `json:"partial,omitempty"`
+}
+
+// Item is a completed unit of work: a database, table or dump invocation.
+// Offset is the size of the output file once the item finished, Spool the
+// size of a secondary file written alongside it, if any, and Rows the rows
+// a table item wrote. Key is the key of the last row written, recorded for
+// partial items only.
+type Item struct {
+	Name        string    `json:"name"`
+	Offset      int64     `json:"offset"`
+	Spool       int64     `json:"spool,omitempty"`
+	Rows        int64     `json:"rows,omitempty"`
+	Key         []string  `json:"key,omitempty"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// Dir returns the state directory: $MARIADB_STATE_DIR, else
+// $XDG_STATE_HOME/mariadb-extractor, else %LOCALAPPDATA%\mariadb-extractor\state
+// on Windows and ~/.local/state/mariadb-extractor elsewhere
+func Dir() (string, error) {
+	if dir := os.Getenv("MARIADB_STATE_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "mariadb-extractor"), nil
+	}
+	if dir := os.Getenv("LOCALAPPDATA"); dir != "" && runtime.GOOS == "windows" {
+		return filepath.Join(dir, "mariadb-extractor", "state"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate state directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "state", "mariadb-extractor"), nil
+}
+
+// Path returns the state file for a command's run
+func Path(command, runID string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.json", command, runID)), nil
+}
+
+// New returns empty progress for a run
+func New(command, runID, server, output string) *Progress {
+	now := time.Now().UTC()
+	return &Progress{
+		SchemaVersion: SchemaVersion,
+		Command:       command,
+		RunID:         runID,
+		Server:        server,
+		Output:        output,
+		StartedAt:     now,
+		UpdatedAt:     now,
+		Completed:     []Item{},
+	}
+}
+
+// Load reads and validates the state of a command's run
+func Load(command, runID string) (*Progress, error) {
+	path, err := Path(command, runID)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no %s run %s found in %s", command, runID, filepath.Dir(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	var p Progress
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("state file %s is corrupt: %w", path, err)
+	}
+	if err := p.validate(command, runID); err != nil {
+		return nil, fmt.Errorf("state file %s is invalid: %w", path, err)
+	}
+	return &p, nil
+}
+
+func (p *Progress) validate(command, runID string) error {
+	if p.SchemaVersion != SchemaVersion {
+		return fmt.Errorf("unsupported schema version %d (expected %d)", p.SchemaVersion, SchemaVersion)
+	}
+	if p.Command != command {
+		return fmt.Errorf("belongs to command %q, not %q", p.Command, command)
+	}
+	if p.RunID != runID {
+		return fmt.Errorf("belongs to run %q, not %q", p.RunID, runID)
+	}
+	if p.Output == "" {
+		return errors.New("missing output")
+	}
+
+	seen := make(map[string]bool, len(p.Completed))
+	for i, item := range p.Completed {
+		if item.Name == "" {
+			return fmt.Errorf("completed item %d has no name", i)
+		}
+		if item.Offset < 0 {
+			return fmt.Errorf("completed item %q has negative offset %d", item.Name, item.Offset)
+		}
+		if seen[item.Name] {
+			return fmt.Errorf("completed item %q is listed twice", item.Name)
+		}
+		seen[item.Name] = true
+	}
+	if p.Partial != nil {
+		if p.Partial.Name == "" || len(p.Partial.Key) == 0 {
+			return errors.New("partial item has no name or key")
+		}
+		if seen[p.Partial.Name] {
+			return fmt.Errorf("partial item %q is also completed", p.Partial.Name)
+		}
+	}
+	return nil
+}
+
+// Done reports whether the named item has completed
+func (p *Progress) Done(name string) bool {
+	for _, item := range p.Completed {
+		if item.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Offset returns the output size after the most recently completed item, or
+// the partial item's checkpoint
+func (p *Progress) Offset() int64 {
+	var offset int64
+	for _, item := range p.items() {
+		offset = max(offset, item.Offset)
+	}
+	return offset
+}
+
+// SpoolOffset returns the secondary file size after the most recently
+// completed item, or the partial item's checkpoint
+func (p *Progress) SpoolOffset() int64 {
+	var offset, spool int64
+	for _, item := range p.items() {
+		if item.Offset >= offset {
+			offset, spool = item.Offset, item.Spool
+		}
+	}
+	return spool
+}
+
+// items returns the completed items followed by the partial one, if any
+func (p *Progress) items() []Item {
+	if p.Partial == nil {
+		return p.Completed
+	}
+	return append(p.Completed[:len(p.Completed):len(p.Completed)], *p.Partial)
+}
+
+// Complete marks the named item as done at the given output offset and saves
+func (p *Progress) Complete(name string, offset int64) error {
+	return p.CompleteItem(Item{Name: name, Offset: offset})
+}
+
+// CompleteItem is Complete for items recording more than their offset, such
+// as the size of a secondary file
+func (p *Progress) CompleteItem(item Item) error {
+	now := time.Now().UTC()
+	if !p.Done(item.Name) {
+		item.CompletedAt = now
+		p.Completed = append(p.Completed, item)
+	}
+	if p.Partial != nil && p.Partial.Name == item.Name {
+		p.Partial = nil
+	}
+	p.UpdatedAt = now
+	return p.Save()
+}
+
+// Checkpoint records how far a partially written item got and saves,
+// replacing any previous checkpoint
+func (p *Progress) Checkpoint(item Item) error {
+	now := time.Now().UTC()
+	item.CompletedAt = now
+	p.Partial, p.UpdatedAt = &item, now
+	return p.Save()
+}
+
+// Interrupt records that the run stopped on an interrupt, in the named item
+// or between items when it is empty, and saves
+func (p *Progress) Interrupt(item string) error {
+	now := time.Now().UTC()
+	p.InterruptedAt, p.InterruptedIn, p.UpdatedAt = &now, item, now
+	return p.Save()
+}
+
+// Rows returns the rows recorded for each completed item, by name
+func (p *Progress) Rows() map[string]int64 {
+	rows := make(map[string]int64, len(p.Completed))
+	for _, item := range p.Completed {
+		rows[item.Name] = item.Rows
+	}
+	return rows
+}
+
+// Save writes the progress atomically, replacing any previous state
+func (p *Progress) Save() error {
+	path, err := Path(p.Command, p.RunID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+// Package transform applies simple per-column expressions to rows as they
+// stream through the data extractor. A transforms file maps db.table.column
+// to a pipeline of functions separated by "|":
+//
+//	transforms:
+//	  shop.customers.email: lowercase
+//	  shop.customers.bio: trim | truncate(200)
+//	  shop.orders.created_at: date_shift(-30d)
+//	  shop.orders.status: map(statuses)
+//	lookups:
+//	  statuses: {pending: P, shipped: S}
+//
+// Functions: lowercase, uppercase, trim, truncate(n), date_shift(offset)
+// with an offset such as -30d or 12h, and map(lookup), which replaces values
+// found in the named lookup and keeps the others. NULL values are never
+// changed.
+package transform
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"mariadb-extractor/internal/yaml"
+)
+
+// Func transforms a single column value as returned by the driver
+type Func func(v interface{}) interface{}
+
+// File is the transforms file format
+type File struct {
+	Transforms map[string]string            `json:"transforms"`
+	Lookups    map[string]map[string]string `json:"lookups"`
+}
+
+// Set holds the compiled transforms keyed by db.table and column
+type Set struct {
+	tables map[string]map[string]Func
+}
+
+// Load reads and compiles a transforms file
+func Load(path string) (*Set, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transforms: %w", err)
+	}
+
+	var file File
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid transforms file %s: %w", path, err)
+	}
+	return Compile(file)
+}
+
+// Compile validates the expressions of file
+func Compile(file File) (*Set, error) {
+	set := &Set{tables: make(map[string]map[string]Func)}
+	for target, expr := range file.Transforms {
+		parts := strings.Split(target, ".")
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("transform target %q must be db.table.column", target)
+		}
+
+		fn, err := compileExpr(expr, file.Lookups)
+		if err != nil {
+			return nil, fmt.Errorf("transform %s: %w", target, err)
+		}
+
+		table := parts[0] + "." + parts[1]
+		if set.tables[table] == nil {
+			set.tables[table] = make(map[string]Func)
+		}
+		set.tables[table][parts[2]] = fn
+	}
+	return set, nil
+}
+
+// Row returns the transforms of a table indexed like columns, and the names
+// of transformed columns missing from columns. The Row is nil when the table
+// has no transforms.
+func (s *Set) Row(dbName, tableName string, columns []string) (Row, []string) {
+	if s == nil {
+		return nil, nil
+	}
+	funcs := s.tables[dbName+"."+tableName]
+	if len(funcs) == 0 {
+		return nil, nil
+	}
+
+	row := make(Row, len(columns))
+	found := make(map[string]bool)
+	for i, col := range columns {
+		if fn, ok := funcs[col]; ok {
+			row[i] = fn
+			found[col] = true
+		}
+	}
+	var missing []string
+	for col := range funcs {
+		if !found[col] {
+			missing = append(missing, col)
+		}
+	}
+	return row, missing
+}
+
+// Row holds a transform per result column; columns without one are nil
+type Row []Func
+
+// Apply transforms values in place. A nil Row leaves values unchanged.
+func (r Row) Apply(values []interface{}) {
+	for i, fn := range r {
+		if fn != nil && values[i] != nil {
+			values[i] = fn(values[i])
+		}
+	}
+}
+
+// Then returns a Row applying r and then next to each column. Either may be
+// nil.
+func (r Row) Then(next Row) Row {
+	if r == nil {
+		return next
+	}
+	if next == nil {
+		return r
+	}
+	row := make(Row, len(r))
+	for i := range r {
+		first, second := r[i], next[i]
+		switch {
+		case first == nil:
+			row[i] = second
+		case second == nil:
+			row[i] = first
+		default:
+			row[i] = func(v interface{}) interface{} {
+				if v = first(v); v == nil {
+					return nil
+				}
+				return second(v)
+			}
+		}
+	}
+	return row
+}
+
+// compileExpr compiles a "|"-separated chain of functions
+func compileExpr(expr string, lookups map[string]map[string]string) (Func, error) {
+	var chain []Func
+	for _, call := range strings.Split(expr, "|") {
+		fn, err := compileCall(strings.TrimSpace(call), lookups)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, fn)
+	}
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+	return func(v interface{}) interface{} {
+		for _, fn := range chain {
+			if v == nil {
+				break
+			}
+			v = fn(v)
+		}
+		return v
+	}, nil
+}
+
+func compileCall(call string, lookups map[string]map[string]string) (Func, error) {
+	name, arg := call, ""
+	if open := strings.Index(call, "("); open >= 0 {
+		if !strings.HasSuffix(call, ")") {
+			return nil, fmt.Errorf("malformed call %q", call)
+		}
+		name, arg = strings.TrimSpace(call[:open]), strings.TrimSpace(call[open+1:len(call)-1])
+	}
+
+	switch name {
+	case "lowercase":
+		return stringFunc(strings.ToLower), nil
+	case "uppercase":
+		return stringFunc(strings.ToUpper), nil
+	case "trim":
+		return stringFunc(strings.TrimSpace), nil
+	case "truncate":
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("truncate needs a non-negative length, got %q", arg)
+		}
+		return stringFunc(func(s string) string {
+			if runes := []rune(s); len(runes) > n {
+				return string(runes[:n])
+			}
+			return s
+		}), nil
+	case "date_shift":
+		offset, err := parseOffset(arg)
+		if err != nil {
+			return nil, err
+		}
+		return func(v interface{}) interface{} { return shiftDate(v, offset) }, nil
+	case "map":
+		lookup, ok := lookups[arg]
+		if !ok {
+			return nil, fmt.Errorf("unknown lookup %q", arg)
+		}
+		return stringFunc(func(s string) string {
+			if mapped, ok := lookup[s]; ok {
+				return mapped
+			}
+			return s
+		}), nil
+	case "":
+		return nil, fmt.Errorf("empty expression")
+	default:
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+}
+
+// stringFunc applies fn to text values and leaves other types unchanged
+func stringFunc(fn func(string) string) Func {
+	return func(v interface{}) interface{} {
+		switch val := v.(type) {
+		case []byte:
+			return []byte(fn(string(val)))
+		case string:
+			return fn(val)
+		}
+		return v
+	}
+}
+
+// parseOffset parses a day count such as -30d or a Go duration such as 12h
+func parseOffset(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid date_shift offset %q: use e.g. -30d or 12h", s)
+	}
+	return d, nil
+}
+
+// dateLayouts are the text forms of DATETIME and DATE values; parsing
+// accepts fractional seconds after the seconds field
+var dateLayouts = []string{"2006-01-02 15:04:05", "2006-01-02"}
+
+func shiftDate(v interface{}, offset time.Duration) interface{} {
+	switch val := v.(type) {
+	case time.Time:
+		return val.Add(offset)
+	case []byte:
+		for _, layout := range dateLayouts {
+			t, err := time.Parse(layout, string(val))
+			if err != nil {
+				continue
+			}
+			if len(layout) > len("2006-01-02") && strings.Contains(string(val), ".") {
+				layout += ".999999"
+			}
+			return []byte(t.Add(offset).Format(layout))
+		}
+	}
+	return v
+}
@@ -0,0 +1,94 @@
+// Package yaml decodes the extractor's config files with gopkg.in/yaml.v3.
+//
+// Documents are decoded into generic values and then into the target through
+// encoding/json, so targets use `json` struct tags. Only the first document
+// of a stream is read.
+package yaml
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Unmarshal decodes a YAML document into v. Unknown struct fields are
+// reported as errors so typos in config files do not go unnoticed.
+func Unmarshal(data []byte, v interface{}) error {
+	value, err := Parse(data)
+	if err != nil {
+		return err
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("yaml: %w", err)
+	}
+	return nil
+}
+
+// Parse decodes a YAML document into map[string]interface{},
+// []interface{}, string, int64, uint64, float64, bool or nil values.
+// Timestamps keep their text, and mapping keys their text as strings.
+func Parse(data []byte) (interface{}, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return convert(doc.Content[0])
+}
+
+// convert turns a node into the generic values of Parse
+func convert(n *yaml.Node) (interface{}, error) {
+	switch n.Kind {
+	case yaml.AliasNode:
+		return convert(n.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]interface{}, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key := n.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("yaml: line %d: mapping keys must be scalars", key.Line)
+			}
+			value, err := convert(n.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[key.Value] = value
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		list := make([]interface{}, 0, len(n.Content))
+		for _, item := range n.Content {
+			value, err := convert(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+
+	// Dates such as created_at > 2024-01-01 stay as written
+	if n.ShortTag() == "!!timestamp" {
+		return n.Value, nil
+	}
+	var value interface{}
+	if err := n.Decode(&value); err != nil {
+		return nil, err
+	}
+	if i, ok := value.(int); ok {
+		return int64(i), nil
+	}
+	return value, nil
+}